@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,6 +34,10 @@ type ReplicaClient struct {
 	AccountKey  string
 	Endpoint    string
 
+	// Shared access signature query string. If set, it is used instead of
+	// the account key.
+	SASToken string
+
 	// Azure Blob Storage container information
 	Bucket string
 	Path   string
@@ -57,16 +62,19 @@ func (c *ReplicaClient) Init(ctx context.Context) (err error) {
 		return nil
 	}
 
-	// Read account key from environment, if available.
-	accountKey := c.AccountKey
-	if accountKey == "" {
-		accountKey = os.Getenv("LITESTREAM_AZURE_ACCOUNT_KEY")
-	}
-
-	// Authenticate to ACS.
-	credential, err := azblob.NewSharedKeyCredential(c.AccountName, accountKey)
-	if err != nil {
-		return err
+	// Authenticate to ACS with a SAS token or the account key, reading the
+	// account key from environment, if available.
+	var credential azblob.Credential
+	if c.SASToken != "" {
+		credential = azblob.NewAnonymousCredential()
+	} else {
+		accountKey := c.AccountKey
+		if accountKey == "" {
+			accountKey = os.Getenv("LITESTREAM_AZURE_ACCOUNT_KEY")
+		}
+		if credential, err = azblob.NewSharedKeyCredential(c.AccountName, accountKey); err != nil {
+			return err
+		}
 	}
 
 	// Construct & parse endpoint unless already set.
@@ -78,6 +86,9 @@ func (c *ReplicaClient) Init(ctx context.Context) (err error) {
 	if err != nil {
 		return fmt.Errorf("cannot parse azure endpoint: %w", err)
 	}
+	if c.SASToken != "" {
+		endpointURL.RawQuery = strings.TrimPrefix(c.SASToken, "?")
+	}
 
 	// Build pipeline and reference to container.
 	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{
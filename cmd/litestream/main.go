@@ -418,6 +418,7 @@ type ReplicaConfig struct {
 	URL                string         `yaml:"url"`
 	SyncInterval       *time.Duration `yaml:"sync-interval"`
 	ValidationInterval *time.Duration `yaml:"validation-interval"`
+	UploadConcurrency  *int           `yaml:"upload-concurrency"`
 
 	// S3 settings
 	AccessKeyID     string `yaml:"access-key-id"`
@@ -427,6 +428,7 @@ type ReplicaConfig struct {
 	Endpoint        string `yaml:"endpoint"`
 	ForcePathStyle  *bool  `yaml:"force-path-style"`
 	SkipVerify      bool   `yaml:"skip-verify"`
+	StorageClass    string `yaml:"storage-class"`
 
 	// ABS settings
 	AccountName string `yaml:"account-name"`
@@ -457,6 +459,12 @@ func NewReplicaFromConfig(c *ReplicaConfig, db *litestream.DB) (_ *litestream.Re
 	if v := c.SyncInterval; v != nil {
 		r.SyncInterval = *v
 	}
+	if v := c.UploadConcurrency; v != nil {
+		if *v < 1 {
+			return nil, fmt.Errorf("upload-concurrency must be at least 1")
+		}
+		r.UploadConcurrency = *v
+	}
 	for _, str := range c.Age.Identities {
 		identities, err := age.ParseIdentities(strings.NewReader(str))
 		if err != nil {
@@ -594,6 +602,7 @@ func newS3ReplicaClientFromConfig(c *ReplicaConfig, _ *litestream.Replica) (_ *s
 	client.Endpoint = endpoint
 	client.ForcePathStyle = forcePathStyle
 	client.SkipVerify = skipVerify
+	client.StorageClass = c.StorageClass
 	return client, nil
 }
 
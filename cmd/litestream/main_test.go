@@ -160,6 +160,17 @@ func TestNewS3ReplicaFromConfig(t *testing.T) {
 			t.Fatalf("ForcePathStyle=%v, want %v", got, want)
 		}
 	})
+
+	t.Run("StorageClass", func(t *testing.T) {
+		r, err := main.NewReplicaFromConfig(&main.ReplicaConfig{URL: "s3://foo/bar", StorageClass: "STANDARD_IA"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if client, ok := r.Client.(*s3.ReplicaClient); !ok {
+			t.Fatal("unexpected replica type")
+		} else if got, want := client.StorageClass, "STANDARD_IA"; got != want {
+			t.Fatalf("StorageClass=%s, want %s", got, want)
+		}
+	})
 }
 
 func TestNewGCSReplicaFromConfig(t *testing.T) {
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -9,9 +12,12 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/benbjohnson/litestream"
 	"github.com/benbjohnson/litestream/s3"
@@ -28,6 +34,171 @@ type databaseInfo struct {
 	S3URL  string     // S3 URL if from S3 discovery
 }
 
+// outputPath returns where the database is restored to
+func (info databaseInfo) outputPath(outputDir string) string {
+	if info.Config != nil {
+		return configOutputPath(info.Config, outputDir)
+	}
+	return info.Path
+}
+
+// source returns where the database is restored from
+func (info databaseInfo) source() string {
+	if info.S3URL != "" {
+		return info.S3URL
+	}
+	return info.Config.Path
+}
+
+// errDBExists is returned when -if-db-not-exists skips a database
+var errDBExists = errors.New("database already exists")
+
+// Restore statuses recorded in a -report file
+const (
+	restoreStatusRestored = "restored"
+	restoreStatusSkipped  = "skipped"
+	restoreStatusFailed   = "failed"
+	restoreStatusCanceled = "canceled"
+)
+
+// restoreResult is the outcome of restoring one database, written to the
+// -report file
+type restoreResult struct {
+	Source   string  `json:"source"`
+	Output   string  `json:"output"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration_seconds"`
+	Bytes    int64   `json:"bytes"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// writeRestoreReport writes results to path as a JSON array. The file is
+// replaced atomically so readers never see a partial report.
+func writeRestoreReport(path string, results []restoreResult) error {
+	buf, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// errVerifyFailed is returned when a restored database fails -verify
+var errVerifyFailed = errors.New("verification failed")
+
+// verifyDatabase runs PRAGMA integrity_check against the database at path,
+// and PRAGMA foreign_key_check if foreignKeys is set. Any problem found is
+// returned wrapping errVerifyFailed.
+func verifyDatabase(ctx context.Context, path string, foreignKeys bool) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("%w: integrity check: %v", errVerifyFailed, err)
+	} else if result != "ok" {
+		return fmt.Errorf("%w: integrity check: %s", errVerifyFailed, result)
+	}
+
+	if foreignKeys {
+		rows, err := db.QueryContext(ctx, `PRAGMA foreign_key_check`)
+		if err != nil {
+			return fmt.Errorf("%w: foreign key check: %v", errVerifyFailed, err)
+		}
+		defer rows.Close()
+
+		if rows.Next() {
+			var table string
+			var rowid sql.NullInt64
+			var parent string
+			var fkid int
+			if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+				return fmt.Errorf("%w: foreign key check: %v", errVerifyFailed, err)
+			}
+			return fmt.Errorf("%w: foreign key violation in table %s referencing %s", errVerifyFailed, table, parent)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("%w: foreign key check: %v", errVerifyFailed, err)
+		}
+	}
+	return nil
+}
+
+// restoreState records databases restored by earlier runs so an
+// interrupted restore can resume without downloading them again
+type restoreState struct {
+	mu        sync.Mutex
+	path      string
+	completed map[string]bool // Output paths restored successfully
+}
+
+// restoreStateFile is the on-disk format of a restore state file
+type restoreStateFile struct {
+	Completed []string `json:"completed"`
+}
+
+// loadRestoreState reads the state file at path. A missing file starts an
+// empty state.
+func loadRestoreState(path string) (*restoreState, error) {
+	state := &restoreState{path: path, completed: make(map[string]bool)}
+
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var f restoreStateFile
+	if err := json.Unmarshal(buf, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, outputPath := range f.Completed {
+		state.completed[outputPath] = true
+	}
+	return state, nil
+}
+
+// Done returns true if outputPath was restored by an earlier run
+func (s *restoreState) Done(outputPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completed[outputPath]
+}
+
+// Complete marks outputPath as restored and rewrites the state file. The
+// file is replaced atomically so a crash never leaves it truncated.
+func (s *restoreState) Complete(outputPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed[outputPath] = true
+
+	f := restoreStateFile{Completed: make([]string, 0, len(s.completed))}
+	for p := range s.completed {
+		f.Completed = append(f.Completed, p)
+	}
+	sort.Strings(f.Completed)
+
+	buf, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
 // Run executes the pattern restore command.
 func (c *RestorePatternCommand) Run(ctx context.Context, args []string) error {
 	fs := flag.NewFlagSet("litestream-restore-pattern", flag.ContinueOnError)
@@ -36,6 +207,14 @@ func (c *RestorePatternCommand) Run(ctx context.Context, args []string) error {
 	parallelism := fs.Int("parallel", 10, "number of parallel restore operations")
 	showProgress := fs.Bool("progress", false, "show progress during restore")
 	ifDBNotExists := fs.Bool("if-db-not-exists", false, "skip if database already exists")
+	dirModeStr := fs.String("dir-mode", "0755", "permissions for created parent directories")
+	timestampStr := fs.String("timestamp", "", "restore to the state at this time (RFC3339)")
+	dryRun := fs.Bool("dry-run", false, "list databases that would be restored without restoring them")
+	stateFile := fs.String("state-file", "", "record restored databases here and skip them on the next run")
+	verify := fs.Bool("verify", false, "run PRAGMA integrity_check on each restored database")
+	verifyForeignKeys := fs.Bool("verify-foreign-keys", false, "also run PRAGMA foreign_key_check when verifying")
+	reportPath := fs.String("report", "", "write a JSON report of per-database results to this path")
+	failFast := fs.Bool("fail-fast", false, "cancel remaining restores after the first failure")
 	fs.Usage = c.Usage
 	
 	if err := fs.Parse(args); err != nil {
@@ -45,6 +224,18 @@ func (c *RestorePatternCommand) Run(ctx context.Context, args []string) error {
 	} else if fs.NArg() > 1 {
 		return fmt.Errorf("too many arguments")
 	}
+	
+	dirMode, err := parseFileMode(*dirModeStr)
+	if err != nil {
+		return fmt.Errorf("invalid -dir-mode: %w", err)
+	}
+
+	var timestamp time.Time
+	if *timestampStr != "" {
+		if timestamp, err = time.Parse(time.RFC3339, *timestampStr); err != nil {
+			return errors.New("invalid -timestamp, must specify in ISO 8601 format (e.g. 2000-01-01T00:00:00Z)")
+		}
+	}
 
 	pattern := fs.Arg(0)
 	
@@ -91,53 +282,182 @@ func (c *RestorePatternCommand) Run(ctx context.Context, args []string) error {
 	
 	slog.Info("found databases to restore", "count", len(databases))
 	
+	if *dryRun {
+		for _, info := range databases {
+			fmt.Printf("%s -> %s\n", info.source(), info.outputPath(*outputDir))
+		}
+		slog.Info("restore pattern dry run completed", "would_restore", len(databases))
+		return nil
+	}
+	
+	// Load progress from an interrupted run
+	var state *restoreState
+	if *stateFile != "" {
+		if state, err = loadRestoreState(*stateFile); err != nil {
+			return fmt.Errorf("cannot read state file: %w", err)
+		}
+	}
+	
+	// Canceled by -fail-fast on the first failure so in-progress restores
+	// stop and queued ones never start
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	
 	// Create semaphore for parallelism control
 	sem := make(chan struct{}, *parallelism)
 	var wg sync.WaitGroup
-	var successCount, errorCount int32
+	var successCount, errorCount, skippedCount, resumedCount, canceledCount int32
+	var verifiedCount, verifyFailedCount int32
+	
+	// Each goroutine writes only its own entry, so no locking is needed
+	results := make([]restoreResult, len(databases))
+	
+	// Verify restores before they are moved into place so a corrupt
+	// database never replaces the output path
+	var verifyFn func(path string) error
+	if *verify || *verifyForeignKeys {
+		verifyFn = func(path string) error {
+			if err := verifyDatabase(ctx, path, *verifyForeignKeys); err != nil {
+				return err
+			}
+			atomic.AddInt32(&verifiedCount, 1)
+			return nil
+		}
+	}
 	
 	// Restore each database
-	for _, dbInfo := range databases {
+	for i, dbInfo := range databases {
+		results[i] = restoreResult{
+			Source: dbInfo.source(),
+			Output: dbInfo.outputPath(*outputDir),
+		}
+		
+		if state != nil && state.Done(dbInfo.outputPath(*outputDir)) {
+			atomic.AddInt32(&resumedCount, 1)
+			results[i].Status = restoreStatusSkipped
+			slog.Debug("database restored by a previous run, skipping", "path", dbInfo.outputPath(*outputDir))
+			continue
+		}
+		
+		// Acquire semaphore, giving up on queued databases once canceled
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			atomic.AddInt32(&canceledCount, 1)
+			results[i].Status, results[i].Error = restoreStatusCanceled, ctx.Err().Error()
+			continue
+		}
 		wg.Add(1)
-		sem <- struct{}{} // Acquire semaphore
 		
-		go func(info databaseInfo) {
+		go func(info databaseInfo, result *restoreResult) {
 			defer wg.Done()
 			defer func() { <-sem }() // Release semaphore
 			
+			// The semaphore may be acquired in the same instant the
+			// batch is canceled
+			if err := ctx.Err(); err != nil {
+				atomic.AddInt32(&canceledCount, 1)
+				result.Status, result.Error = restoreStatusCanceled, err.Error()
+				return
+			}
+			
+			startTime := time.Now()
+			
 			var err error
 			if info.S3URL != "" {
 				// S3 restoration
-				err = c.restoreS3Database(ctx, info.S3URL, info.Path, *outputDir, *ifDBNotExists)
+				err = c.restoreS3Database(ctx, info.S3URL, info.Path, *outputDir, *ifDBNotExists, dirMode, timestamp, verifyFn)
 			} else {
 				// Config-based restoration
-				err = c.restoreDatabase(ctx, info.Config, *outputDir, *ifDBNotExists)
+				err = c.restoreDatabase(ctx, info.Config, *outputDir, *ifDBNotExists, dirMode, timestamp, verifyFn)
 			}
 			
-			if err != nil {
+			result.Duration = time.Since(startTime).Seconds()
+			
+			// Restores interrupted by cancellation didn't fail on their own.
+			// Replica clients don't all wrap context.Canceled, so any
+			// failure once the batch is canceled counts.
+			if err != nil && ctx.Err() != nil {
+				atomic.AddInt32(&canceledCount, 1)
+				result.Status, result.Error = restoreStatusCanceled, err.Error()
+				slog.Warn("restore canceled", "path", info.Path)
+			} else if err != nil && !timestamp.IsZero() && errors.Is(err, litestream.ErrTxNotAvailable) {
+				// A database created after the timestamp has nothing to restore
+				atomic.AddInt32(&skippedCount, 1)
+				result.Status, result.Error = restoreStatusSkipped, err.Error()
+				slog.Warn("no backup at or before timestamp, skipping", "path", info.Path, "timestamp", timestamp)
+			} else if errors.Is(err, errDBExists) {
+				atomic.AddInt32(&skippedCount, 1)
+				result.Status, result.Error = restoreStatusSkipped, err.Error()
+				slog.Info("database already exists, skipping", "path", result.Output)
+			} else if errors.Is(err, errVerifyFailed) {
+				atomic.AddInt32(&verifyFailedCount, 1)
 				atomic.AddInt32(&errorCount, 1)
+				result.Status, result.Error = restoreStatusFailed, err.Error()
+				slog.Error("restored database failed verification", "path", info.Path, "error", err)
+				if *failFast {
+					cancel()
+				}
+			} else if err != nil {
+				atomic.AddInt32(&errorCount, 1)
+				result.Status, result.Error = restoreStatusFailed, err.Error()
 				slog.Error("failed to restore database", "path", info.Path, "error", err)
+				if *failFast {
+					cancel()
+				}
 			} else {
 				atomic.AddInt32(&successCount, 1)
+				result.Status = restoreStatusRestored
+				if fi, err := os.Stat(result.Output); err == nil {
+					result.Bytes = fi.Size()
+				}
+				if state != nil {
+					if err := state.Complete(info.outputPath(*outputDir)); err != nil {
+						slog.Error("failed to update state file", "path", *stateFile, "error", err)
+					}
+				}
 				if *showProgress {
 					total := int32(len(databases))
-					current := atomic.LoadInt32(&successCount) + atomic.LoadInt32(&errorCount)
+					current := atomic.LoadInt32(&resumedCount) + atomic.LoadInt32(&successCount) + atomic.LoadInt32(&errorCount) + atomic.LoadInt32(&skippedCount) + atomic.LoadInt32(&canceledCount)
 					fmt.Printf("Progress: %d/%d databases restored\n", current, total)
 				}
 			}
-		}(dbInfo)
+		}(dbInfo, &results[i])
 	}
 	
 	wg.Wait()
 	
+	if *reportPath != "" {
+		if err := writeRestoreReport(*reportPath, results); err != nil {
+			return fmt.Errorf("cannot write report: %w", err)
+		}
+	}
+	
 	// Print summary
-	slog.Info("restore pattern completed", 
+	attrs := []any{
 		"total", len(databases),
 		"success", successCount,
-		"errors", errorCount)
+		"skipped", skippedCount,
+		"errors", errorCount,
+	}
+	if state != nil {
+		attrs = append(attrs, "resumed", resumedCount)
+	}
+	if canceledCount > 0 {
+		attrs = append(attrs, "canceled", canceledCount)
+	}
+	if verifyFn != nil {
+		attrs = append(attrs, "verified", verifiedCount, "verify_failed", verifyFailedCount)
+	}
+	if !timestamp.IsZero() {
+		attrs = append(attrs, "timestamp", timestamp)
+	}
+	slog.Info("restore pattern completed", attrs...)
 	
 	if errorCount > 0 {
 		return fmt.Errorf("failed to restore %d databases", errorCount)
+	} else if canceledCount > 0 {
+		return fmt.Errorf("canceled with %d databases not restored", canceledCount)
 	}
 	
 	return nil
@@ -299,12 +619,11 @@ func (c *RestorePatternCommand) discoverS3Databases(ctx context.Context, s3Patte
 }
 
 // restoreS3Database restores a database from S3
-func (c *RestorePatternCommand) restoreS3Database(ctx context.Context, s3URL string, outputPath string, outputDir string, ifDBNotExists bool) error {
+func (c *RestorePatternCommand) restoreS3Database(ctx context.Context, s3URL string, outputPath string, outputDir string, ifDBNotExists bool, dirMode os.FileMode, timestamp time.Time, verify func(string) error) error {
 	// Check if output already exists
 	if ifDBNotExists {
 		if _, err := os.Stat(outputPath); err == nil {
-			slog.Info("database already exists, skipping", "path", outputPath)
-			return nil
+			return errDBExists
 		}
 	}
 	
@@ -321,13 +640,14 @@ func (c *RestorePatternCommand) restoreS3Database(ctx context.Context, s3URL str
 	// Create restore options
 	opt := litestream.NewRestoreOptions()
 	opt.OutputPath = outputPath
+	opt.Timestamp = timestamp
 	
 	// Perform restore
-	return replica.Restore(ctx, opt)
+	return restoreAtomic(ctx, replica, opt, dirMode, verify)
 }
 
 // restoreDatabase restores a single database from config
-func (c *RestorePatternCommand) restoreDatabase(ctx context.Context, dbConfig *DBConfig, outputDir string, ifDBNotExists bool) error {
+func (c *RestorePatternCommand) restoreDatabase(ctx context.Context, dbConfig *DBConfig, outputDir string, ifDBNotExists bool, dirMode os.FileMode, timestamp time.Time, verify func(string) error) error {
 	// Create database and replica from config
 	db, err := NewDBFromConfig(dbConfig)
 	if err != nil {
@@ -338,27 +658,87 @@ func (c *RestorePatternCommand) restoreDatabase(ctx context.Context, dbConfig *D
 		return fmt.Errorf("no replica configured for database: %s", dbConfig.Path)
 	}
 	
-	// Determine output path
-	outputPath := dbConfig.Path
-	if outputDir != "" {
-		// Preserve relative structure under output directory
-		outputPath = filepath.Join(outputDir, filepath.Base(dbConfig.Path))
-	}
+	outputPath := configOutputPath(dbConfig, outputDir)
 	
 	// Create restore options
 	opt := litestream.NewRestoreOptions()
 	opt.OutputPath = outputPath
+	opt.Timestamp = timestamp
 	
 	// Skip if database already exists
 	if ifDBNotExists {
 		if _, err := os.Stat(outputPath); err == nil {
-			slog.Info("database already exists, skipping", "path", outputPath)
-			return nil
+			return errDBExists
 		}
 	}
 	
 	// Perform restore
-	return db.Replica.Restore(ctx, opt)
+	return restoreAtomic(ctx, db.Replica, opt, dirMode, verify)
+}
+
+// configOutputPath returns where a database from the config is restored to
+func configOutputPath(dbConfig *DBConfig, outputDir string) string {
+	if outputDir == "" {
+		return dbConfig.Path
+	}
+	return filepath.Join(outputDir, filepath.Base(dbConfig.Path))
+}
+
+// restoreAtomic restores into a temporary file next to opt.OutputPath and
+// renames it into place only once the restore succeeds, so a crash mid-restore
+// never leaves a partial database at the final path. Missing parent
+// directories are created with dirMode. If verify is set, it is called on
+// the restored temp file and the restore fails if it returns an error.
+func restoreAtomic(ctx context.Context, replica *litestream.Replica, opt litestream.RestoreOptions, dirMode os.FileMode, verify func(string) error) error {
+	outputPath := opt.OutputPath
+	dir := filepath.Dir(outputPath)
+	
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	
+	// Reserve a unique temp name in the same directory so the final rename
+	// stays on one filesystem. The replica refuses to restore over an
+	// existing file, so the placeholder is removed before restoring.
+	f, err := os.CreateTemp(dir, "."+filepath.Base(outputPath)+".restore-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := f.Name()
+	_ = f.Close()
+	_ = os.Remove(tmpPath)
+	
+	opt.OutputPath = tmpPath
+	if err := replica.Restore(ctx, opt); err != nil {
+		_ = os.Remove(tmpPath)
+		_ = os.Remove(tmpPath + ".tmp")
+		return err
+	}
+	
+	if verify != nil {
+		if err := verify(tmpPath); err != nil {
+			_ = os.Remove(tmpPath)
+			return err
+		}
+	}
+	
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename restored database: %w", err)
+	}
+	
+	return nil
+}
+
+// parseFileMode parses an octal permission string such as "0755".
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	} else if mode > 0777 {
+		return 0, fmt.Errorf("mode out of range: %s", s)
+	}
+	return os.FileMode(mode), nil
 }
 
 // Usage prints the help screen to STDOUT.
@@ -393,23 +773,76 @@ Arguments:
 	-if-db-not-exists
 	    Skip databases that already exist.
 
+	-dir-mode MODE
+	    Octal permissions for created parent directories.
+	    Defaults to 0755.
+
+	-timestamp TIMESTAMP
+	    Restore every database to its state at the given time
+	    (RFC3339). Databases with no backup at or before that time
+	    are skipped.
+
+	-state-file PATH
+	    Record each restored database in PATH and skip databases
+	    already recorded there, so an interrupted run can be resumed.
+	    The file is kept after the run completes.
+
+	-dry-run
+	    List each matched database and where it would be restored
+	    without downloading or writing anything.
+
+	-verify
+	    Run PRAGMA integrity_check on each restored database before
+	    moving it into place. Databases that fail are counted as
+	    errors and not written to the output path.
+
+	-verify-foreign-keys
+	    Also run PRAGMA foreign_key_check when verifying. Implies
+	    -verify.
+
+	-report PATH
+	    Write a JSON array to PATH with the source, output path,
+	    status (restored, skipped, or failed), duration, bytes
+	    restored, and error of each database. Status is canceled for
+	    databases not restored because the run was canceled.
+
+	-fail-fast
+	    Stop after the first failed restore. Restores in progress are
+	    canceled and no more are started; the number canceled is
+	    included in the summary.
+
 Examples:
 
 	# Restore all databases under /data
 	$ litestream restore-pattern "/data/**/*.db"
 
 	# Restore to different directory with progress
-	$ litestream restore-pattern "/data/**/*.db" -output-dir /restored -progress
+	$ litestream restore-pattern -output-dir /restored -progress "/data/**/*.db"
 
 	# Restore with custom parallelism
-	$ litestream restore-pattern "*.db" -parallel 20
+	$ litestream restore-pattern -parallel 20 "*.db"
 
 	# Restore from S3 with pattern
-	$ litestream restore-pattern "s3://mybucket/backups/**/*.db" -output-dir /restored
+	$ litestream restore-pattern -output-dir /restored "s3://mybucket/backups/**/*.db"
 
 	# Restore specific project from S3
 	$ litestream restore-pattern "s3://mybucket/project1/*.db"
 
+	# Resume a large restore after an interruption
+	$ litestream restore-pattern -output-dir /restored -state-file /tmp/restore.json "/data/**/*.db"
+
+	# Preview which databases a pattern matches in S3
+	$ litestream restore-pattern -output-dir /restored -dry-run "s3://mybucket/backups/**/*.db"
+
+	# Restore and check every database for corruption
+	$ litestream restore-pattern -output-dir /restored -verify "/data/**/*.db"
+
+	# Record the outcome of every database for later auditing
+	$ litestream restore-pattern -output-dir /restored -report /tmp/report.json "s3://mybucket/backups/**/*.db"
+
+	# Roll every database back to before a bad deploy
+	$ litestream restore-pattern -timestamp 2020-01-01T00:00:00Z -output-dir /restored "/data/**/*.db"
+
 `[1:],
 		DefaultConfigPath(),
 	)
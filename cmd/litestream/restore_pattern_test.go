@@ -0,0 +1,417 @@
+package main_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benbjohnson/litestream"
+	main "github.com/benbjohnson/litestream/cmd/litestream"
+	"github.com/benbjohnson/litestream/file"
+)
+
+func TestRestorePatternCommand_Run(t *testing.T) {
+	// Ensure a failed restore creates the output directory with the requested
+	// mode and leaves no partial database or temp files behind.
+	t.Run("FailedRestoreLeavesNoPartialFile", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+filepath.Join(dir, "replica")+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		outputDir := filepath.Join(dir, "restored", "nested")
+		err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-dir-mode", "0700",
+			dbPath,
+		})
+		if err == nil {
+			t.Fatal("expected error restoring without backups")
+		}
+
+		fi, err := os.Stat(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := fi.Mode().Perm(), os.FileMode(0700); got != want {
+			t.Fatalf("Mode=%v, want %v", got, want)
+		}
+
+		entries, err := os.ReadDir(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(entries) != 0 {
+			t.Fatalf("expected empty output directory, found %d entries", len(entries))
+		}
+	})
+
+	// Ensure a database with no backup before the timestamp is skipped
+	// rather than failing the run.
+	t.Run("TimestampSkipsMissingBackup", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+filepath.Join(dir, "replica")+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		outputDir := filepath.Join(dir, "restored")
+		if err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-timestamp", "2000-01-01T00:00:00Z",
+			dbPath,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(filepath.Join(outputDir, "db.sqlite")); !os.IsNotExist(err) {
+			t.Fatalf("expected no restored database, got err=%v", err)
+		}
+	})
+
+	// Ensure a dry run lists matches without restoring or creating anything.
+	t.Run("DryRun", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+filepath.Join(dir, "replica")+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		// Restoring would fail since there are no backups
+		outputDir := filepath.Join(dir, "restored")
+		if err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-dry-run",
+			filepath.Join(dir, "*.sqlite"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+			t.Fatalf("expected output directory not to be created, got err=%v", err)
+		}
+	})
+
+	// Ensure databases recorded in the state file by an earlier run are not
+	// restored again.
+	t.Run("StateFileSkipsCompleted", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+filepath.Join(dir, "replica")+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		// Restoring would fail since there are no backups
+		outputDir := filepath.Join(dir, "restored")
+		stateFile := filepath.Join(dir, "state.json")
+		if err := os.WriteFile(stateFile, []byte(`{"completed": ["`+filepath.Join(outputDir, "db.sqlite")+`"]}`), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-state-file", stateFile,
+			dbPath,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// Ensure a verified restore passes its integrity checks and leaves only
+	// the database in the output directory.
+	t.Run("Verify", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		replicaPath := filepath.Join(dir, "replica")
+		createBackup(t, dbPath, replicaPath,
+			`CREATE TABLE t (id INTEGER PRIMARY KEY, val TEXT)`,
+			`INSERT INTO t (val) VALUES ('foo')`,
+		)
+
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+replicaPath+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		outputDir := filepath.Join(dir, "restored")
+		if err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-verify-foreign-keys",
+			dbPath,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := os.ReadDir(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(entries) != 1 || entries[0].Name() != "db.sqlite" {
+			t.Fatalf("expected only the restored database, got %v", entries)
+		}
+	})
+
+	// Ensure a restore that fails verification is an error and is not
+	// moved into the output path.
+	t.Run("VerifyForeignKeysFails", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		replicaPath := filepath.Join(dir, "replica")
+		createBackup(t, dbPath, replicaPath,
+			`CREATE TABLE parent (id INTEGER PRIMARY KEY)`,
+			`CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent (id))`,
+			`INSERT INTO child (parent_id) VALUES (1)`,
+		)
+
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+replicaPath+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		outputDir := filepath.Join(dir, "restored")
+		err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-verify-foreign-keys",
+			dbPath,
+		})
+		if err == nil || err.Error() != "failed to restore 1 databases" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		entries, err := os.ReadDir(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(entries) != 0 {
+			t.Fatalf("expected empty output directory, got %v", entries)
+		}
+	})
+
+	// Ensure the report records each database's outcome, including
+	// failures, and the run still returns the restore error.
+	t.Run("Report", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		missingPath := filepath.Join(dir, "missing.sqlite")
+		replicaPath := filepath.Join(dir, "replica")
+		createBackup(t, dbPath, replicaPath, `CREATE TABLE t (id INTEGER PRIMARY KEY)`)
+
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+replicaPath+`
+  - path: `+missingPath+`
+    replicas:
+      - path: `+filepath.Join(dir, "missing-replica")+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		outputDir := filepath.Join(dir, "restored")
+		reportPath := filepath.Join(dir, "report.json")
+		err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-report", reportPath,
+			filepath.Join(dir, "*.sqlite"),
+		})
+		if err == nil || err.Error() != "failed to restore 1 databases" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		buf, err := os.ReadFile(reportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []struct {
+			Source string `json:"source"`
+			Output string `json:"output"`
+			Status string `json:"status"`
+			Bytes  int64  `json:"bytes"`
+			Error  string `json:"error"`
+		}
+		if err := json.Unmarshal(buf, &results); err != nil {
+			t.Fatal(err)
+		} else if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(results))
+		}
+
+		if got := results[0]; got.Source != dbPath || got.Output != filepath.Join(outputDir, "db.sqlite") || got.Status != "restored" || got.Bytes == 0 || got.Error != "" {
+			t.Fatalf("unexpected restored result: %+v", got)
+		}
+		if got := results[1]; got.Source != missingPath || got.Status != "failed" || got.Bytes != 0 || got.Error == "" {
+			t.Fatalf("unexpected failed result: %+v", got)
+		}
+	})
+
+	t.Run("FailFast", func(t *testing.T) {
+		dir := t.TempDir()
+		missingPath := filepath.Join(dir, "a.sqlite")
+		dbPath := filepath.Join(dir, "b.sqlite")
+		replicaPath := filepath.Join(dir, "replica")
+		createBackup(t, dbPath, replicaPath, `CREATE TABLE t (id INTEGER PRIMARY KEY)`)
+
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+missingPath+`
+    replicas:
+      - path: `+filepath.Join(dir, "missing-replica")+`
+  - path: `+dbPath+`
+    replicas:
+      - path: `+replicaPath+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		// With one restore at a time, the first failure cancels the second
+		// before it starts
+		outputDir := filepath.Join(dir, "restored")
+		reportPath := filepath.Join(dir, "report.json")
+		err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-output-dir", outputDir,
+			"-parallel", "1",
+			"-fail-fast",
+			"-report", reportPath,
+			filepath.Join(dir, "*.sqlite"),
+		})
+		if err == nil || err.Error() != "failed to restore 1 databases" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		buf, err := os.ReadFile(reportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(buf, &results); err != nil {
+			t.Fatal(err)
+		} else if len(results) != 2 || results[0].Status != "failed" || results[1].Status != "canceled" {
+			t.Fatalf("unexpected results: %+v", results)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "b.sqlite")); !os.IsNotExist(err) {
+			t.Fatalf("expected canceled database not to be restored, got %v", err)
+		}
+	})
+
+	t.Run("ErrCorruptStateFile", func(t *testing.T) {
+		dir := t.TempDir()
+		dbPath := filepath.Join(dir, "db.sqlite")
+		filename := filepath.Join(dir, "litestream.yml")
+		if err := os.WriteFile(filename, []byte(`
+dbs:
+  - path: `+dbPath+`
+    replicas:
+      - path: `+filepath.Join(dir, "replica")+`
+`[1:]), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		stateFile := filepath.Join(dir, "state.json")
+		if err := os.WriteFile(stateFile, []byte(`{not json`), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{
+			"-config", filename,
+			"-state-file", stateFile,
+			dbPath,
+		})
+		if err == nil || !strings.HasPrefix(err.Error(), "cannot read state file: ") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrInvalidTimestamp", func(t *testing.T) {
+		err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{"-timestamp", "yesterday", "/foo/*.db"})
+		if err == nil || err.Error() != `invalid -timestamp, must specify in ISO 8601 format (e.g. 2000-01-01T00:00:00Z)` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrInvalidDirMode", func(t *testing.T) {
+		err := (&main.RestorePatternCommand{}).Run(context.Background(), []string{"-dir-mode", "abc", "/foo/*.db"})
+		if err == nil || err.Error() != `invalid -dir-mode: strconv.ParseUint: parsing "abc": invalid syntax` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+// createBackup creates a database at dbPath, executes stmts against it, and
+// replicates it to a file replica at replicaPath.
+func createBackup(tb testing.TB, dbPath, replicaPath string, stmts ...string) {
+	tb.Helper()
+
+	db := litestream.NewDB(dbPath)
+	db.MonitorInterval = 0
+	db.Replica = litestream.NewReplicaWithClient(db, file.NewReplicaClient(replicaPath))
+	db.Replica.MonitorEnabled = false
+	if err := db.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	defer db.Close(context.Background())
+
+	sqldb, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer sqldb.Close()
+
+	for _, stmt := range stmts {
+		if _, err := sqldb.Exec(stmt); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	if err := db.Sync(context.Background()); err != nil {
+		tb.Fatal(err)
+	} else if err := db.Replica.Sync(context.Background()); err != nil {
+		tb.Fatal(err)
+	}
+}
@@ -359,16 +359,13 @@ func monitorSystem(ctx context.Context, manager *litestream.IntegratedMultiDBMan
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 	
-	lastPromotions := int32(0)
-	lastDemotions := int32(0)
-	
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			// Get current stats
-			total, hot, cold, connStats := manager.GetStatistics()
+			total, hot, cold, connStats, _ := manager.GetStatistics()
 			
 			// Calculate memory usage
 			var m runtime.MemStats
@@ -379,22 +376,10 @@ func monitorSystem(ctx context.Context, manager *litestream.IntegratedMultiDBMan
 			uptime := time.Since(stats.startTime).Seconds()
 			writeRate := float64(atomic.LoadInt64(&stats.totalWrites)) / uptime
 			
-			// Estimate promotions/demotions (would need actual tracking)
-			currentPromotions := int32(hot)
-			currentDemotions := int32(cold)
-			
-			promotionDelta := currentPromotions - lastPromotions
-			demotionDelta := currentDemotions - lastDemotions
-			
-			if promotionDelta > 0 {
-				atomic.AddInt32(&stats.promotions, promotionDelta)
-			}
-			if demotionDelta > 0 {
-				atomic.AddInt32(&stats.demotions, demotionDelta)
-			}
-			
-			lastPromotions = currentPromotions
-			lastDemotions = currentDemotions
+			// Tier transitions counted by the manager
+			promotions, demotions := manager.GetTierTransitions()
+			atomic.StoreInt32(&stats.promotions, int32(promotions))
+			atomic.StoreInt32(&stats.demotions, int32(demotions))
 			
 			// Print status
 			fmt.Printf("\r[%s] DBs: %d | Hot: %d | Cold: %d | Writes/s: %.1f | Mem: %.1f MB | Conns: %d",
@@ -411,7 +396,7 @@ func monitorSystem(ctx context.Context, manager *litestream.IntegratedMultiDBMan
 }
 
 func printFinalStats(stats *Stats, manager *litestream.IntegratedMultiDBManager) {
-	total, hot, cold, connStats := manager.GetStatistics()
+	total, hot, cold, connStats, _ := manager.GetStatistics()
 	uptime := time.Since(stats.startTime)
 	
 	fmt.Println("\n=== Final Statistics ===")
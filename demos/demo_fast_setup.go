@@ -428,7 +428,7 @@ func testHotCold(dbPaths []string) {
 }
 
 func printStats(manager *litestream.IntegratedMultiDBManager) {
-	total, hot, cold, connStats := manager.GetStatistics()
+	total, hot, cold, connStats, _ := manager.GetStatistics()
 	
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -200,7 +200,7 @@ func writeData(dbPath string, value string) {
 }
 
 func printStats(label string, manager *litestream.IntegratedMultiDBManager) {
-	total, hot, cold, connStats := manager.GetStatistics()
+	total, hot, cold, connStats, _ := manager.GetStatistics()
 	
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -211,7 +211,7 @@ func printStats(label string, manager *litestream.IntegratedMultiDBManager) {
 }
 
 func printDetailedStats(manager *litestream.IntegratedMultiDBManager) {
-	total, hot, cold, connStats := manager.GetStatistics()
+	total, hot, cold, connStats, _ := manager.GetStatistics()
 	
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -12,6 +12,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/superfly/ltx"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 
 	"github.com/benbjohnson/litestream"
 	"github.com/benbjohnson/litestream/internal"
@@ -31,6 +32,10 @@ type ReplicaClient struct {
 	// GCS bucket information
 	Bucket string
 	Path   string
+
+	// Path to a service account JSON key. If blank, Application Default
+	// Credentials are used.
+	CredentialsFile string
 }
 
 // NewReplicaClient returns a new instance of ReplicaClient.
@@ -52,7 +57,12 @@ func (c *ReplicaClient) Init(ctx context.Context) (err error) {
 		return nil
 	}
 
-	if c.client, err = storage.NewClient(ctx); err != nil {
+	var opts []option.ClientOption
+	if c.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(c.CredentialsFile))
+	}
+
+	if c.client, err = storage.NewClient(ctx, opts...); err != nil {
 		return err
 	}
 	c.bkt = c.client.Bucket(c.Bucket)
@@ -3,11 +3,26 @@ package litestreampp
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// DefaultBusyTimeout is the busy_timeout pragma, in milliseconds, set on
+// pooled connections unless overridden with SetPragmas
+const DefaultBusyTimeout = 5000
+
+// Pragma names and values are interpolated into SQL, so only identifiers
+// and numbers are accepted
+var (
+	pragmaNameRegex  = regexp.MustCompile(`^[a-z_]+$`)
+	pragmaValueRegex = regexp.MustCompile(`^-?[A-Za-z0-9_]+$`)
+)
+
 // ConnectionPool manages database connections with limits
 type ConnectionPool struct {
 	mu sync.RWMutex
@@ -15,15 +30,23 @@ type ConnectionPool struct {
 	// Configuration
 	maxConnections int
 	idleTimeout    time.Duration
+	waitOnFull     bool
+	pragmas        map[string]string // Run on each new connection
 	
 	// Active connections
 	connections    map[string]*PooledConnection
 	lru            *LRUCache
 	
+	// Closed and replaced whenever a connection is released or closed,
+	// waking callers waiting for a free slot
+	released       chan struct{}
+	
 	// Metrics
 	totalOpened    int64
 	totalClosed    int64
 	currentOpen    int
+	hits           atomic.Int64 // Gets that reused an open connection
+	misses         atomic.Int64 // Gets that had to open one
 }
 
 // PooledConnection wraps a database connection with metadata
@@ -33,6 +56,8 @@ type PooledConnection struct {
 	openedAt   time.Time
 	lastUsed   time.Time
 	useCount   int64
+	refs       int // Callers holding the connection between Get and Release
+	idleTimeout time.Duration // Overrides the pool's idle timeout when set
 	
 	// Cleanup function
 	onClose    func() error
@@ -45,31 +70,134 @@ func NewConnectionPool(maxConnections int, idleTimeout time.Duration) *Connectio
 		idleTimeout:    idleTimeout,
 		connections:    make(map[string]*PooledConnection),
 		lru:           NewLRUCache(maxConnections),
+		released:       make(chan struct{}),
+		pragmas:        map[string]string{"busy_timeout": fmt.Sprint(DefaultBusyTimeout)},
+	}
+}
+
+// SetPragmas sets SQLite pragmas (e.g. "journal_mode": "WAL",
+// "synchronous": "NORMAL") to run on each connection the pool opens from
+// now on. Pragmas not in the map keep their current values, so
+// busy_timeout stays at DefaultBusyTimeout unless overridden.
+func (p *ConnectionPool) SetPragmas(pragmas map[string]string) error {
+	for name, value := range pragmas {
+		if !pragmaNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid pragma name: %q", name)
+		}
+		if !pragmaValueRegex.MatchString(value) {
+			return fmt.Errorf("invalid value for pragma %s: %q", name, value)
+		}
+	}
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	for name, value := range pragmas {
+		p.pragmas[name] = value
 	}
+	return nil
 }
 
-// Get returns a connection from the pool, opening if necessary
+// SetWaitOnFull controls what Get does when the pool is at capacity and
+// every connection is in use. By default it closes the least recently used
+// connection anyway; with wait set it blocks until one is released.
+func (p *ConnectionPool) SetWaitOnFull(wait bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.waitOnFull = wait
+}
+
+// Get returns a connection from the pool, opening if necessary. Each Get
+// must be paired with a Release once the caller is done with the connection.
 func (p *ConnectionPool) Get(path string) (*sql.DB, error) {
+	return p.GetWithTTL(path, 0)
+}
+
+// GetWithTTL returns a connection like Get and sets how long it may sit
+// idle before Cleanup closes it, e.g. long for hot databases and short for
+// cold databases opened briefly for a snapshot. The latest non-zero idle
+// timeout requested for a connection wins; zero keeps the connection's
+// current timeout, which defaults to the pool's. Cleanup runs every half of
+// the pool's idle timeout, so shorter timeouts are enforced at that
+// granularity.
+func (p *ConnectionPool) GetWithTTL(path string, idle time.Duration) (*sql.DB, error) {
 	p.mu.Lock()
+	if p.waitOnFull {
+		p.mu.Unlock()
+		return p.GetContextWithTTL(context.Background(), path, idle)
+	}
 	defer p.mu.Unlock()
 	
 	// Check if already open
-	if conn, ok := p.connections[path]; ok {
-		conn.lastUsed = time.Now()
-		conn.useCount++
-		p.lru.Touch(path)
-		return conn.db, nil
+	if db, ok := p.acquireLocked(path, idle); ok {
+		return db, nil
 	}
 	
 	// Check connection limit
 	if p.currentOpen >= p.maxConnections {
-		// Evict LRU connection
-		if victim := p.lru.Evict(); victim != "" {
-			p.closeConnectionLocked(victim)
+		p.evictLocked(true)
+	}
+	
+	return p.openLocked(path, idle)
+}
+
+// GetContext returns a connection from the pool like Get, but never closes
+// a connection that is in use. If the pool is full it waits until a
+// connection is released or ctx is done.
+func (p *ConnectionPool) GetContext(ctx context.Context, path string) (*sql.DB, error) {
+	return p.GetContextWithTTL(ctx, path, 0)
+}
+
+// GetContextWithTTL returns a connection like GetContext with an idle
+// timeout as in GetWithTTL
+func (p *ConnectionPool) GetContextWithTTL(ctx context.Context, path string, idle time.Duration) (*sql.DB, error) {
+	for {
+		p.mu.Lock()
+		if db, ok := p.acquireLocked(path, idle); ok {
+			p.mu.Unlock()
+			return db, nil
+		}
+		if p.currentOpen < p.maxConnections || p.evictLocked(false) {
+			db, err := p.openLocked(path, idle)
+			p.mu.Unlock()
+			return db, err
 		}
+		released := p.released
+		p.mu.Unlock()
+		
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// acquireLocked takes a reference to an already open connection, replacing
+// its idle timeout if idle is set (must hold lock)
+func (p *ConnectionPool) acquireLocked(path string, idle time.Duration) (*sql.DB, bool) {
+	conn, ok := p.connections[path]
+	if !ok {
+		return nil, false
+	}
+	p.hits.Add(1)
+	
+	if idle > 0 {
+		conn.idleTimeout = idle
 	}
+	conn.lastUsed = time.Now()
+	conn.useCount++
+	conn.refs++
+	p.lru.Touch(path)
+	return conn.db, true
+}
+
+// openLocked opens a new connection and takes a reference to it. An idle
+// timeout of zero uses the pool's (must hold lock)
+func (p *ConnectionPool) openLocked(path string, idle time.Duration) (*sql.DB, error) {
+	p.misses.Add(1)
 	
-	// Open new connection
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, fmt.Errorf("open connection: %w", err)
@@ -80,6 +208,11 @@ func (p *ConnectionPool) Get(path string) (*sql.DB, error) {
 	db.SetMaxIdleConns(1)
 	db.SetConnMaxLifetime(0) // Managed by pool
 	
+	if err := p.applyPragmasLocked(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	
 	// Create pooled connection
 	conn := &PooledConnection{
 		db:       db,
@@ -87,6 +220,8 @@ func (p *ConnectionPool) Get(path string) (*sql.DB, error) {
 		openedAt: time.Now(),
 		lastUsed: time.Now(),
 		useCount: 1,
+		refs:     1,
+		idleTimeout: idle,
 	}
 	
 	p.connections[path] = conn
@@ -97,6 +232,56 @@ func (p *ConnectionPool) Get(path string) (*sql.DB, error) {
 	return db, nil
 }
 
+// applyPragmasLocked runs the configured pragmas on a new connection in
+// name order (must hold lock)
+func (p *ConnectionPool) applyPragmasLocked(db *sql.DB) error {
+	names := make([]string, 0, len(p.pragmas))
+	for name := range p.pragmas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	for _, name := range names {
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA %s = %s", name, p.pragmas[name])); err != nil {
+			return fmt.Errorf("set pragma %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// evictLocked closes the least recently used connection that is not in
+// use. If every connection is in use and force is set, it closes the least
+// recently used one regardless. Returns true if a connection was closed
+// (must hold lock)
+func (p *ConnectionPool) evictLocked(force bool) bool {
+	victim := p.lru.EvictFunc(func(path string) bool {
+		conn, ok := p.connections[path]
+		return !ok || conn.refs == 0
+	})
+	if victim == "" && force {
+		victim = p.lru.Evict()
+	}
+	if victim == "" {
+		return false
+	}
+	return p.closeConnectionLocked(victim) == nil
+}
+
+// SetMaxConnections changes the connection limit, closing the least
+// recently used connections if the pool is over the new limit
+func (p *ConnectionPool) SetMaxConnections(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.maxConnections = n
+	p.lru.capacity = n
+	for p.currentOpen > p.maxConnections {
+		if !p.evictLocked(!p.waitOnFull) {
+			break
+		}
+	}
+}
+
 // Release marks a connection as no longer in use
 func (p *ConnectionPool) Release(path string) {
 	p.mu.Lock()
@@ -104,9 +289,21 @@ func (p *ConnectionPool) Release(path string) {
 	
 	if conn, ok := p.connections[path]; ok {
 		conn.lastUsed = time.Now()
+		if conn.refs > 0 {
+			conn.refs--
+		}
+		if conn.refs == 0 {
+			p.notifyReleasedLocked()
+		}
 	}
 }
 
+// notifyReleasedLocked wakes callers waiting in GetContext (must hold lock)
+func (p *ConnectionPool) notifyReleasedLocked() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
 // Close explicitly closes a connection
 func (p *ConnectionPool) Close(path string) error {
 	p.mu.Lock()
@@ -139,11 +336,41 @@ func (p *ConnectionPool) closeConnectionLocked(path string) error {
 	p.lru.Remove(path)
 	p.currentOpen--
 	p.totalClosed++
+	p.notifyReleasedLocked()
 	
 	return nil
 }
 
-// Cleanup closes idle connections
+// CloseAll closes every connection, in use or not, for shutdown. Each
+// connection is removed from the pool even if its onClose callback or close
+// fails, and the failures are returned together.
+func (p *ConnectionPool) CloseAll() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	var errs []error
+	for path, conn := range p.connections {
+		if conn.onClose != nil {
+			if err := conn.onClose(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: onClose callback: %w", path, err))
+			}
+		}
+		if err := conn.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: close database: %w", path, err))
+		}
+		
+		delete(p.connections, path)
+		p.lru.Remove(path)
+		p.currentOpen--
+		p.totalClosed++
+	}
+	p.notifyReleasedLocked()
+	
+	return errors.Join(errs...)
+}
+
+// Cleanup closes connections that are not in use and have been idle longer
+// than their idle timeout, or the pool's if they have none
 func (p *ConnectionPool) Cleanup() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -152,7 +379,11 @@ func (p *ConnectionPool) Cleanup() {
 	toClose := []string{}
 	
 	for path, conn := range p.connections {
-		if now.Sub(conn.lastUsed) > p.idleTimeout {
+		idle := conn.idleTimeout
+		if idle == 0 {
+			idle = p.idleTimeout
+		}
+		if conn.refs == 0 && now.Sub(conn.lastUsed) > idle {
 			toClose = append(toClose, path)
 		}
 	}
@@ -182,20 +413,34 @@ func (p *ConnectionPool) Stats() ConnectionPoolStats {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	
+	hits, misses := p.hits.Load(), p.misses.Load()
+	var ratio float64
+	if hits+misses > 0 {
+		ratio = float64(hits) / float64(hits+misses)
+	}
+	
 	return ConnectionPoolStats{
 		CurrentOpen:  p.currentOpen,
 		TotalOpened:  p.totalOpened,
 		TotalClosed:  p.totalClosed,
 		MaxConnections: p.maxConnections,
+		Hits:         hits,
+		Misses:       misses,
+		HitRatio:     ratio,
 	}
 }
 
-// ConnectionPoolStats contains pool statistics
+// ConnectionPoolStats contains pool statistics. HitRatio is the fraction
+// of Gets that reused an open connection; a low ratio means the pool is
+// thrashing and maxConnections should be raised.
 type ConnectionPoolStats struct {
-	CurrentOpen    int
-	TotalOpened    int64
-	TotalClosed    int64
-	MaxConnections int
+	CurrentOpen    int     `json:"current_open"`
+	TotalOpened    int64   `json:"total_opened"`
+	TotalClosed    int64   `json:"total_closed"`
+	MaxConnections int     `json:"max_connections"`
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	HitRatio       float64 `json:"hit_ratio"`
 }
 
 // Simple LRU cache implementation
@@ -263,6 +508,19 @@ func (c *LRUCache) Evict() string {
 	return key
 }
 
+// EvictFunc removes and returns the least recently used key for which
+// evictable returns true, or "" if there is none
+func (c *LRUCache) EvictFunc(evictable func(key string) bool) string {
+	for item := c.tail; item != nil; item = item.prev {
+		if evictable(item.key) {
+			c.removeItem(item)
+			delete(c.items, item.key)
+			return item.key
+		}
+	}
+	return ""
+}
+
 func (c *LRUCache) moveToFront(item *lruItem) {
 	if item == c.head {
 		return
@@ -2,6 +2,10 @@ package litestreampp_test
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -160,6 +164,54 @@ func TestConnectionPool(t *testing.T) {
 		}
 	})
 
+	t.Run("MixedIdleTimeouts", func(t *testing.T) {
+		pool := litestreampp.NewConnectionPool(5, time.Second)
+		defer pool.Cleanup()
+
+		dir := t.TempDir()
+		hot, cold, def := dir+"/hot.db", dir+"/cold.db", dir+"/default.db"
+
+		if _, err := pool.GetWithTTL(hot, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.GetWithTTL(cold, 50*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Get(def); err != nil {
+			t.Fatal(err)
+		}
+		pool.Release(hot)
+		pool.Release(cold)
+		pool.Release(def)
+
+		// Only the short-lived connection has been idle long enough
+		time.Sleep(100 * time.Millisecond)
+		pool.Cleanup()
+		if stats := pool.Stats(); stats.CurrentOpen != 2 || stats.TotalClosed != 1 {
+			t.Fatalf("expected only the cold connection closed, got %+v", stats)
+		}
+
+		// A plain Get keeps the connection's timeout
+		if _, err := pool.Get(hot); err != nil {
+			t.Fatal(err)
+		}
+		pool.Release(hot)
+		if stats := pool.Stats(); stats.TotalOpened != 3 {
+			t.Fatalf("expected the hot connection to be reused, got %+v", stats)
+		}
+
+		// Requesting a shorter timeout, e.g. after demotion, replaces it
+		if _, err := pool.GetWithTTL(hot, 50*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		pool.Release(hot)
+		time.Sleep(100 * time.Millisecond)
+		pool.Cleanup()
+		if stats := pool.Stats(); stats.CurrentOpen != 1 || stats.TotalClosed != 2 {
+			t.Fatalf("expected only the default connection open, got %+v", stats)
+		}
+	})
+
 	t.Run("ExplicitClose", func(t *testing.T) {
 		pool := litestreampp.NewConnectionPool(5, 1*time.Second)
 		defer pool.Cleanup()
@@ -189,6 +241,280 @@ func TestConnectionPool(t *testing.T) {
 			t.Errorf("expected 0 open connections after close, got %d", stats.CurrentOpen)
 		}
 	})
+
+	t.Run("HitRatio", func(t *testing.T) {
+		pool := litestreampp.NewConnectionPool(1, time.Hour)
+		defer pool.CloseAll()
+
+		if stats := pool.Stats(); stats.HitRatio != 0 {
+			t.Errorf("expected 0 hit ratio before any Get, got %v", stats.HitRatio)
+		}
+
+		// Miss, hit, hit, then a miss that evicts db1
+		dir := t.TempDir()
+		for _, path := range []string{dir + "/db1.db", dir + "/db1.db", dir + "/db1.db", dir + "/db2.db"} {
+			if _, err := pool.Get(path); err != nil {
+				t.Fatal(err)
+			}
+			pool.Release(path)
+		}
+
+		stats := pool.Stats()
+		if stats.Hits != 2 || stats.Misses != 2 {
+			t.Errorf("expected 2 hits and 2 misses, got %d and %d", stats.Hits, stats.Misses)
+		}
+		if stats.HitRatio != 0.5 {
+			t.Errorf("expected 0.5 hit ratio, got %v", stats.HitRatio)
+		}
+	})
+
+	t.Run("CloseAll", func(t *testing.T) {
+		pool := litestreampp.NewConnectionPool(5, time.Hour)
+
+		dir := t.TempDir()
+		conn1, err := pool.Get(dir + "/db1.db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Get(dir + "/db2.db"); err != nil {
+			t.Fatal(err)
+		}
+		pool.Release(dir + "/db2.db")
+
+		// In-use connections are closed too
+		if err := pool.CloseAll(); err != nil {
+			t.Fatal(err)
+		}
+		if stats := pool.Stats(); stats.CurrentOpen != 0 || stats.TotalClosed != 2 {
+			t.Errorf("expected all connections closed, got %+v", stats)
+		}
+		if err := conn1.Ping(); err == nil {
+			t.Error("expected closed connection to fail ping")
+		}
+
+		// The pool remains usable
+		if _, err := pool.Get(dir + "/db1.db"); err != nil {
+			t.Fatal(err)
+		}
+		if err := pool.CloseAll(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("GetContextWaitsForRelease", func(t *testing.T) {
+		pool := litestreampp.NewConnectionPool(1, time.Second)
+		defer pool.Cleanup()
+
+		db1 := t.TempDir() + "/db1.db"
+		db2 := t.TempDir() + "/db2.db"
+
+		conn1, err := pool.GetContext(context.Background(), db1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// db1 is in use, so a second database cannot get a slot
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := pool.GetContext(ctx, db2); err != context.DeadlineExceeded {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+		if err := conn1.Ping(); err != nil {
+			t.Errorf("in-use connection was closed: %v", err)
+		}
+
+		// Releasing db1 unblocks a waiting caller
+		done := make(chan error, 1)
+		go func() {
+			_, err := pool.GetContext(context.Background(), db2)
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		pool.Release(db1)
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("GetContext did not unblock after Release")
+		}
+
+		if stats := pool.Stats(); stats.CurrentOpen != 1 || stats.TotalClosed != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("EvictsIdleBeforeInUse", func(t *testing.T) {
+		pool := litestreampp.NewConnectionPool(2, time.Second)
+		defer pool.Cleanup()
+
+		db1 := t.TempDir() + "/db1.db"
+		db2 := t.TempDir() + "/db2.db"
+		db3 := t.TempDir() + "/db3.db"
+
+		conn1, err := pool.Get(db1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Get(db2); err != nil {
+			t.Fatal(err)
+		}
+		pool.Release(db2)
+
+		// db1 is least recently used but still in use, so db2 is evicted
+		if _, err := pool.Get(db3); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn1.Ping(); err != nil {
+			t.Errorf("in-use connection was closed: %v", err)
+		}
+	})
+
+	t.Run("ConcurrentWaitOnFull", func(t *testing.T) {
+		const capacity = 3
+		pool := litestreampp.NewConnectionPool(capacity, time.Second)
+		pool.SetWaitOnFull(true)
+		defer pool.Cleanup()
+
+		dir := t.TempDir()
+		var wg sync.WaitGroup
+		var holders, maxHolders int32
+		errs := make(chan error, 100)
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				path := fmt.Sprintf("%s/db%d.db", dir, i%8)
+				for j := 0; j < 5; j++ {
+					db, err := pool.Get(path)
+					if err != nil {
+						errs <- err
+						return
+					}
+
+					n := atomic.AddInt32(&holders, 1)
+					for {
+						max := atomic.LoadInt32(&maxHolders)
+						if n <= max || atomic.CompareAndSwapInt32(&maxHolders, max, n) {
+							break
+						}
+					}
+
+					// The connection must stay open for as long as it is held
+					for k := 0; k < 3; k++ {
+						if err := db.Ping(); err != nil {
+							errs <- fmt.Errorf("%s closed while in use: %w", path, err)
+						}
+						time.Sleep(time.Millisecond)
+					}
+					if stats := pool.Stats(); stats.CurrentOpen > capacity {
+						errs <- fmt.Errorf("pool exceeded capacity: %d open", stats.CurrentOpen)
+					}
+
+					atomic.AddInt32(&holders, -1)
+					pool.Release(path)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+		if maxHolders < 2 {
+			t.Errorf("expected concurrent holders, got max %d", maxHolders)
+		}
+	})
+
+	t.Run("Pragmas", func(t *testing.T) {
+		dbPath := t.TempDir() + "/test.db"
+
+		setup, err := sql.Open("sqlite3", dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer setup.Close()
+		if _, err := setup.Exec("PRAGMA journal_mode = WAL; CREATE TABLE t (id INTEGER)"); err != nil {
+			t.Fatal(err)
+		}
+
+		// holdWriteLock has a competing writer hold the lock for d
+		holdWriteLock := func(d time.Duration) {
+			t.Helper()
+			writer, err := sql.Open("sqlite3", dbPath+"?_txlock=immediate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx, err := writer.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			go func() {
+				time.Sleep(d)
+				tx.Commit()
+				writer.Close()
+			}()
+		}
+
+		// The default busy_timeout waits for the competing writer
+		pool := litestreampp.NewConnectionPool(2, time.Second)
+		if err := pool.SetPragmas(map[string]string{"synchronous": "NORMAL"}); err != nil {
+			t.Fatal(err)
+		}
+		db, err := pool.Get(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var timeout, synchronous int
+		if err := db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil {
+			t.Fatal(err)
+		} else if timeout != litestreampp.DefaultBusyTimeout {
+			t.Errorf("expected busy_timeout %d, got %d", litestreampp.DefaultBusyTimeout, timeout)
+		}
+		if err := db.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil {
+			t.Fatal(err)
+		} else if synchronous != 1 { // NORMAL
+			t.Errorf("expected synchronous NORMAL (1), got %d", synchronous)
+		}
+
+		holdWriteLock(200 * time.Millisecond)
+		if _, err := db.Exec("INSERT INTO t VALUES (1)"); err != nil {
+			t.Errorf("expected write to wait for the lock, got %v", err)
+		}
+		pool.Release(dbPath)
+		pool.Close(dbPath)
+
+		// Without a busy_timeout the same write fails immediately
+		if err := pool.SetPragmas(map[string]string{"busy_timeout": "0"}); err != nil {
+			t.Fatal(err)
+		}
+		db, err = pool.Get(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close(dbPath)
+
+		holdWriteLock(200 * time.Millisecond)
+		if _, err := db.Exec("INSERT INTO t VALUES (2)"); err == nil {
+			t.Error("expected busy error without busy_timeout")
+		}
+		time.Sleep(300 * time.Millisecond)
+	})
+
+	t.Run("InvalidPragma", func(t *testing.T) {
+		pool := litestreampp.NewConnectionPool(1, time.Second)
+		if err := pool.SetPragmas(map[string]string{"busy_timeout; DROP TABLE t": "1"}); err == nil {
+			t.Error("expected error for invalid pragma name")
+		}
+		if err := pool.SetPragmas(map[string]string{"busy_timeout": "1; DROP TABLE t"}); err == nil {
+			t.Error("expected error for invalid pragma value")
+		}
+	})
 }
 
 func TestLRUCache(t *testing.T) {
@@ -222,6 +548,27 @@ func TestLRUCache(t *testing.T) {
 		}
 	})
 
+	t.Run("EvictFunc", func(t *testing.T) {
+		cache := litestreampp.NewLRUCache(3)
+		cache.Add("a")
+		cache.Add("b")
+		cache.Add("c") // Order: c (head), b, a (tail)
+
+		// Skip the tail
+		evicted := cache.EvictFunc(func(key string) bool { return key != "a" })
+		if evicted != "b" {
+			t.Errorf("expected 'b' to be evicted, got '%s'", evicted)
+		}
+
+		evicted = cache.EvictFunc(func(key string) bool { return false })
+		if evicted != "" {
+			t.Errorf("expected nothing evicted, got '%s'", evicted)
+		}
+		if evicted := cache.Evict(); evicted != "a" {
+			t.Errorf("expected 'a' to be evicted, got '%s'", evicted)
+		}
+	})
+
 	t.Run("EvictEmpty", func(t *testing.T) {
 		cache := litestreampp.NewLRUCache(3)
 		
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/litestream"
+	"github.com/superfly/ltx"
 )
 
 // DynamicDB wraps a regular DB with dynamic lifecycle management
@@ -15,9 +17,10 @@ type DynamicDB struct {
 	*litestream.DB
 	
 	mu           sync.RWMutex
-	state        DBLifecycleState
-	lastAccess   time.Time
-	accessCount  int64
+	state        atomic.Int32 // DBLifecycleState, readable without mu
+	stateSince   atomic.Int64 // Unix nanoseconds of last state change
+	lastAccess   atomic.Int64 // Unix nanoseconds, readable without mu
+	accessCount  atomic.Int64
 	
 	// Callbacks for state changes
 	onOpen       func(*DynamicDB) error
@@ -37,15 +40,45 @@ const (
 	DBStateClosing
 )
 
+// String returns the lowercase name of the state
+func (s DBLifecycleState) String() string {
+	switch s {
+	case DBStateClosed:
+		return "closed"
+	case DBStateOpening:
+		return "opening"
+	case DBStateOpen:
+		return "open"
+	case DBStateClosing:
+		return "closing"
+	default:
+		return "unknown"
+	}
+}
+
 // NewDynamicDB creates a new dynamically managed database
 func NewDynamicDB(path string, manager interface{}) *DynamicDB {
 	db := litestream.NewDB(path)
 	
-	return &DynamicDB{
+	d := &DynamicDB{
 		DB:      db,
-		state:   DBStateClosed,
 		manager: manager,
 	}
+	d.setState(DBStateClosed)
+	return d
+}
+
+// setState records a lifecycle transition and when it happened
+func (d *DynamicDB) setState(state DBLifecycleState) {
+	d.state.Store(int32(state))
+	d.stateSince.Store(time.Now().UnixNano())
+}
+
+// State returns the current lifecycle state and when it was entered.
+// It does not take the database lock, so it is safe to call while an
+// open or close is in progress.
+func (d *DynamicDB) State() (DBLifecycleState, time.Time) {
+	return DBLifecycleState(d.state.Load()), time.Unix(0, d.stateSince.Load())
 }
 
 // Open initializes the database connection and starts replication
@@ -54,7 +87,7 @@ func (d *DynamicDB) Open(ctx context.Context) error {
 	defer d.mu.Unlock()
 	
 	// Check current state
-	switch d.state {
+	switch DBLifecycleState(d.state.Load()) {
 	case DBStateOpen:
 		return nil // Already open
 	case DBStateOpening:
@@ -63,23 +96,23 @@ func (d *DynamicDB) Open(ctx context.Context) error {
 		return fmt.Errorf("database is closing")
 	}
 	
-	d.state = DBStateOpening
+	d.setState(DBStateOpening)
 	
 	// Open the underlying database
 	if err := d.DB.Open(); err != nil {
-		d.state = DBStateClosed
+		d.setState(DBStateClosed)
 		return fmt.Errorf("open database: %w", err)
 	}
 	
-	d.state = DBStateOpen
-	d.lastAccess = time.Now()
+	d.setState(DBStateOpen)
+	d.lastAccess.Store(time.Now().UnixNano())
 	
 	// Call callback if set
 	if d.onOpen != nil {
 		if err := d.onOpen(d); err != nil {
 			// Rollback on callback error
 			d.DB.Close(ctx)
-			d.state = DBStateClosed
+			d.setState(DBStateClosed)
 			return fmt.Errorf("onOpen callback: %w", err)
 		}
 	}
@@ -95,7 +128,7 @@ func (d *DynamicDB) Close(ctx context.Context) error {
 	defer d.mu.Unlock()
 	
 	// Check current state
-	switch d.state {
+	switch DBLifecycleState(d.state.Load()) {
 	case DBStateClosed:
 		return nil // Already closed
 	case DBStateClosing:
@@ -104,7 +137,7 @@ func (d *DynamicDB) Close(ctx context.Context) error {
 		return fmt.Errorf("database is opening")
 	}
 	
-	d.state = DBStateClosing
+	d.setState(DBStateClosing)
 	
 	// Call callback if set
 	if d.onClose != nil {
@@ -118,20 +151,39 @@ func (d *DynamicDB) Close(ctx context.Context) error {
 		slog.Error("close database failed", "path", d.Path(), "error", err)
 	}
 	
-	d.state = DBStateClosed
+	d.setState(DBStateClosed)
 	
 	slog.Info("dynamically closed database", "path", d.Path())
 	
 	return nil
 }
 
+// ForceClose closes a database recovered from a stuck open or close. It
+// waits for that operation to release the database rather than closing it
+// underneath, so it blocks for as long as the operation hangs and should
+// be run in the background. Unlike Close it skips the onClose callback and
+// leaves the database marked closed.
+func (d *DynamicDB) ForceClose(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	// The stuck operation may have finished closing it, or a caller that
+	// found it recovered may have closed it already
+	if DBLifecycleState(d.state.Load()) == DBStateClosed {
+		return nil
+	}
+	
+	err := d.DB.Close(ctx)
+	d.setState(DBStateClosed)
+	if err != nil {
+		return fmt.Errorf("force close database: %w", err)
+	}
+	return nil
+}
+
 // EnsureOpen opens the database if not already open
 func (d *DynamicDB) EnsureOpen(ctx context.Context) error {
-	d.mu.RLock()
-	state := d.state
-	d.mu.RUnlock()
-	
-	if state == DBStateOpen {
+	if DBLifecycleState(d.state.Load()) == DBStateOpen {
 		d.updateAccess()
 		return nil
 	}
@@ -141,31 +193,23 @@ func (d *DynamicDB) EnsureOpen(ctx context.Context) error {
 
 // updateAccess updates access tracking
 func (d *DynamicDB) updateAccess() {
-	d.mu.Lock()
-	d.lastAccess = time.Now()
-	d.accessCount++
-	d.mu.Unlock()
+	d.lastAccess.Store(time.Now().UnixNano())
+	d.accessCount.Add(1)
 }
 
 // IsOpen returns true if the database is open
 func (d *DynamicDB) IsOpen() bool {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.state == DBStateOpen
+	return DBLifecycleState(d.state.Load()) == DBStateOpen
 }
 
 // LastAccess returns the last access time
 func (d *DynamicDB) LastAccess() time.Time {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.lastAccess
+	return time.Unix(0, d.lastAccess.Load())
 }
 
 // AccessCount returns the total access count
 func (d *DynamicDB) AccessCount() int64 {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.accessCount
+	return d.accessCount.Load()
 }
 
 // Override DB methods to ensure open state
@@ -186,11 +230,26 @@ func (d *DynamicDB) Checkpoint(ctx context.Context, mode string) error {
 	return d.DB.Checkpoint(ctx, mode)
 }
 
-// WriteSnapshot ensures the database is open before writing snapshot
-// TODO: This method needs to be updated to use the correct DB API
-// func (d *DynamicDB) WriteSnapshot(ctx context.Context, path string) error {
-// 	if err := d.EnsureOpen(ctx); err != nil {
-// 		return err
-// 	}
-// 	return d.DB.WriteSnapshot(ctx, path)
-// }
\ No newline at end of file
+// Snapshot ensures the database is open, syncs its WAL, and uploads a full
+// snapshot at the current position to the replica's snapshot level. It lets
+// a database be snapshotted on demand, such as before demotion, without
+// waiting for the store's snapshot interval.
+func (d *DynamicDB) Snapshot(ctx context.Context) (*ltx.FileInfo, error) {
+	if err := d.EnsureOpen(ctx); err != nil {
+		return nil, err
+	}
+	if d.DB.Replica == nil {
+		return nil, fmt.Errorf("no replica configured")
+	}
+	
+	// Sync first so the snapshot includes the latest WAL frames
+	if err := d.DB.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("sync: %w", err)
+	}
+	
+	info, err := d.DB.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	return info, nil
+}
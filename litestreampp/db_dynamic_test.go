@@ -0,0 +1,107 @@
+package litestreampp
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/litestream"
+)
+
+func TestDynamicDBSnapshot(t *testing.T) {
+	t.Run("WritesSnapshotLevel", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.db")
+		if err := createTestDB(path); err != nil {
+			t.Fatal(err)
+		}
+
+		db := NewDynamicDB(path, nil)
+		client := &MockReplicaClient{Type_: "mock"}
+		db.DB.Replica = litestream.NewReplicaWithClient(db.DB, client)
+		defer db.Close(context.Background())
+
+		// The database is opened on demand
+		info, err := db.Snapshot(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		} else if !db.IsOpen() {
+			t.Error("expected snapshot to open the database")
+		}
+		if info.Level != litestream.SnapshotLevel {
+			t.Errorf("expected snapshot level %d, got %d", litestream.SnapshotLevel, info.Level)
+		}
+
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		var found bool
+		for _, f := range client.WrittenFiles {
+			if f.Level == litestream.SnapshotLevel && f.MinTXID == 1 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected an LTX file written at the snapshot level, got %+v", client.WrittenFiles)
+		}
+	})
+
+	t.Run("ErrNoReplica", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.db")
+		if err := createTestDB(path); err != nil {
+			t.Fatal(err)
+		}
+
+		db := NewDynamicDB(path, nil)
+		defer db.Close(context.Background())
+		if _, err := db.Snapshot(context.Background()); err == nil || err.Error() != "no replica configured" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestDynamicDBForceClose(t *testing.T) {
+	t.Run("WaitsForOpen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.db")
+		if err := createTestDB(path); err != nil {
+			t.Fatal(err)
+		}
+		db := NewDynamicDB(path, nil)
+
+		// Hold the lock the way a hung Open does
+		db.mu.Lock()
+		db.setState(DBStateOpening)
+
+		done := make(chan error, 1)
+		go func() { done <- db.ForceClose(context.Background()) }()
+
+		select {
+		case err := <-done:
+			t.Fatalf("force close ran underneath the open: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// The open completes, then the force close takes over
+		if err := db.DB.Open(); err != nil {
+			t.Fatal(err)
+		}
+		db.setState(DBStateOpen)
+		db.mu.Unlock()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("force close did not finish after the open")
+		}
+		if state, _ := db.State(); state != DBStateClosed {
+			t.Errorf("expected closed, got %v", state)
+		}
+
+		// Closing again, as a finished promotion does, is a no-op
+		if err := db.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
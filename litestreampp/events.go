@@ -0,0 +1,79 @@
+package litestreampp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TierEventType identifies what a TierEvent reports
+type TierEventType string
+
+// Tier event types published on the events channel
+const (
+	TierEventPromoted       TierEventType = "promoted"
+	TierEventDemoted        TierEventType = "demoted"
+	TierEventReplicaStarted TierEventType = "replica_started"
+	TierEventReplicaStopped TierEventType = "replica_stopped"
+	TierEventError          TierEventType = "error" // A promotion or replica operation failed
+)
+
+// TierEvent reports a change to a managed database, for reacting to tier
+// transitions without scraping logs or metrics
+type TierEvent struct {
+	Type TierEventType
+	Path string
+	Tier string // TierHot or TierCold after the event
+	Time time.Time
+	Err  error // Set for TierEventError, and for replica stops that failed
+
+	// DryRun marks a transition that was only planned, under
+	// HotColdConfig.DryRun
+	DryRun bool
+}
+
+// eventPublisher delivers events on a buffered channel without blocking.
+// Events that don't fit in the buffer are dropped and counted, so a slow
+// consumer never stalls replication. A nil publisher discards everything.
+type eventPublisher struct {
+	ch      chan TierEvent
+	dropped atomic.Int64
+	dryRun  bool // Stamped on every event
+}
+
+// newEventPublisher returns a publisher buffering size events, or nil if
+// size is not positive. Events of a dry run have DryRun set.
+func newEventPublisher(size int, dryRun bool) *eventPublisher {
+	if size <= 0 {
+		return nil
+	}
+	return &eventPublisher{ch: make(chan TierEvent, size), dryRun: dryRun}
+}
+
+// publish sends an event stamped with the current time, dropping it if the
+// buffer is full
+func (p *eventPublisher) publish(typ TierEventType, path, tier string, err error) {
+	if p == nil {
+		return
+	}
+	select {
+	case p.ch <- TierEvent{Type: typ, Path: path, Tier: tier, Time: time.Now(), Err: err, DryRun: p.dryRun}:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// events returns the receive side of the channel, or nil if disabled
+func (p *eventPublisher) events() <-chan TierEvent {
+	if p == nil {
+		return nil
+	}
+	return p.ch
+}
+
+// droppedCount returns how many events were dropped on a full buffer
+func (p *eventPublisher) droppedCount() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.dropped.Load()
+}
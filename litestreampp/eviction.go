@@ -0,0 +1,84 @@
+package litestreampp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Eviction policy names accepted by NewEvictionPolicy
+const (
+	EvictionLRU  = "lru"
+	EvictionLFU  = "lfu"
+	EvictionFIFO = "fifo"
+)
+
+// EvictionPolicy chooses which hot databases to demote when the number of
+// hot databases exceeds the limit
+type EvictionPolicy interface {
+	// SelectVictims returns the paths of up to n candidates to demote.
+	// Pinned databases are never passed as candidates.
+	SelectVictims(candidates []*WriteState, n int) []string
+}
+
+// NewEvictionPolicy returns the eviction policy with the given name.
+// An empty name selects LRU.
+func NewEvictionPolicy(name string) (EvictionPolicy, error) {
+	switch name {
+	case "", EvictionLRU:
+		return LRUEvictionPolicy{}, nil
+	case EvictionLFU:
+		return LFUEvictionPolicy{}, nil
+	case EvictionFIFO:
+		return FIFOEvictionPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy: %s", name)
+	}
+}
+
+// LRUEvictionPolicy evicts the databases written least recently
+type LRUEvictionPolicy struct{}
+
+func (LRUEvictionPolicy) SelectVictims(candidates []*WriteState, n int) []string {
+	return selectVictims(candidates, n, func(a, b *WriteState) bool {
+		return a.LastModTime.Before(b.LastModTime)
+	})
+}
+
+// LFUEvictionPolicy evicts the databases with the fewest detected writes,
+// breaking ties by least recent write
+type LFUEvictionPolicy struct{}
+
+func (LFUEvictionPolicy) SelectVictims(candidates []*WriteState, n int) []string {
+	return selectVictims(candidates, n, func(a, b *WriteState) bool {
+		if a.WriteCount != b.WriteCount {
+			return a.WriteCount < b.WriteCount
+		}
+		return a.LastModTime.Before(b.LastModTime)
+	})
+}
+
+// FIFOEvictionPolicy evicts the databases promoted earliest, regardless of
+// how recently they were written
+type FIFOEvictionPolicy struct{}
+
+func (FIFOEvictionPolicy) SelectVictims(candidates []*WriteState, n int) []string {
+	return selectVictims(candidates, n, func(a, b *WriteState) bool {
+		return a.PromotedAt.Before(b.PromotedAt)
+	})
+}
+
+// selectVictims returns the paths of the first n candidates ordered by less
+func selectVictims(candidates []*WriteState, n int, less func(a, b *WriteState) bool) []string {
+	sorted := make([]*WriteState, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+	victims := make([]string, 0, n)
+	for _, state := range sorted[:n] {
+		victims = append(victims, state.Path)
+	}
+	return victims
+}
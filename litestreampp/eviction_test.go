@@ -0,0 +1,66 @@
+package litestreampp_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/litestream/litestreampp"
+)
+
+func TestEvictionPolicies(t *testing.T) {
+	base := time.Now()
+	// a: promoted first, written often, but not recently
+	// b: promoted second, written once, most recently
+	// c: promoted last, written twice, in between
+	candidates := []*litestreampp.WriteState{
+		{Path: "a", PromotedAt: base, LastModTime: base.Add(1 * time.Second), WriteCount: 10},
+		{Path: "b", PromotedAt: base.Add(1 * time.Second), LastModTime: base.Add(3 * time.Second), WriteCount: 1},
+		{Path: "c", PromotedAt: base.Add(2 * time.Second), LastModTime: base.Add(2 * time.Second), WriteCount: 2},
+	}
+
+	tests := []struct {
+		name   string
+		policy litestreampp.EvictionPolicy
+		want   []string
+	}{
+		{"LRU", litestreampp.LRUEvictionPolicy{}, []string{"a", "c"}},
+		{"LFU", litestreampp.LFUEvictionPolicy{}, []string{"b", "c"}},
+		{"FIFO", litestreampp.FIFOEvictionPolicy{}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.SelectVictims(candidates, 2)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected victims %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("MoreThanCandidates", func(t *testing.T) {
+		got := litestreampp.LRUEvictionPolicy{}.SelectVictims(candidates, 5)
+		if len(got) != 3 {
+			t.Errorf("expected all 3 candidates, got %v", got)
+		}
+	})
+
+	t.Run("ByName", func(t *testing.T) {
+		for name, want := range map[string]litestreampp.EvictionPolicy{
+			"":     litestreampp.LRUEvictionPolicy{},
+			"lru":  litestreampp.LRUEvictionPolicy{},
+			"lfu":  litestreampp.LFUEvictionPolicy{},
+			"fifo": litestreampp.FIFOEvictionPolicy{},
+		} {
+			policy, err := litestreampp.NewEvictionPolicy(name)
+			if err != nil {
+				t.Fatalf("%q: %v", name, err)
+			}
+			if policy != want {
+				t.Errorf("%q: expected %T, got %T", name, want, policy)
+			}
+		}
+		if _, err := litestreampp.NewEvictionPolicy("random"); err == nil {
+			t.Error("expected error for unknown policy")
+		}
+	})
+}
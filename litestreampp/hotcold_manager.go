@@ -2,14 +2,18 @@ package litestreampp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
 )
 
 // HotColdManager manages the lifecycle of hot and cold databases
@@ -28,14 +32,35 @@ type HotColdManager struct {
 	hotDuration     time.Duration
 	replicaTemplate *ReplicaConfig // Template for creating replicas
 	replicaFactory  ReplicaClientFactory // Factory for creating replica clients
+	replicaOverrides []ReplicaOverride   // Per-pattern changes to the template
+	stuckTimeout    time.Duration
+	syncTimeout     time.Duration // Bound on the final replica sync before demotion
+	replicaRetryInterval time.Duration // First delay of background replica start retries
+	coldSyncInterval time.Duration // How often cold databases are snapshotted (0 = never)
+	managementInterval time.Duration // How often stats, metrics, and state are refreshed
+	stateFile       string
+	statePatterns   []string // Discovery patterns state file entries must still match
+	onStuck         func(path string, state DBLifecycleState, stuckFor time.Duration)
+	onTierChange    func(path, from, to string)
+	events          *eventPublisher // nil unless EventBufferSize is set
+	dryRun          bool            // Plan transitions without opening databases or starting replicas
+	paused          bool            // Replicas stopped and tiers frozen until Resume
 
 	// Database tracking
 	hotDatabases  map[string]*DynamicDB
 	coldDatabases map[string]*ColdDBInfo
 	hotReplicas   map[string]*litestream.Replica // Active replicas for hot databases
+	unreplicated  map[string]struct{}            // Hot databases whose replica failed to start, awaiting a retry
+	coldSnapshots map[string]*coldSnapshot       // Cold databases open for a snapshot
+	promotions    map[string]*promotion          // Hot databases not yet opened
+	promotionSlots chan struct{}                 // Bounds concurrent promotion opens
+	tierVersion   uint64                         // Bumped whenever a database changes tier or is tracked/untracked
 
 	// Metrics
-	metrics *HierarchicalMetrics
+	metrics         *HierarchicalMetrics
+	projectMetricsVersion atomic.Uint64 // tierVersion the project metrics were last computed at
+	totalPromotions atomic.Int64
+	totalDemotions  atomic.Int64
 
 	// Control
 	ctx    context.Context
@@ -43,15 +68,23 @@ type HotColdManager struct {
 	wg     sync.WaitGroup
 }
 
+// Database tiers reported by GetDatabaseTier
+const (
+	TierHot  = "hot"
+	TierCold = "cold"
+)
+
 // ColdDBInfo tracks minimal info for cold databases
 type ColdDBInfo struct {
 	Path         string
-	LastModTime  time.Time
-	LastSize     int64
+	LastModTime  time.Time // As of the last cold snapshot
+	LastSize     int64     // As of the last cold snapshot
+	LastSnapshot time.Time // Zero until snapshotted by ColdSyncInterval
 	Project      string
 	Database     string
 	Branch       string
 	Tenant       string
+	AccessCount  int64 // Accesses recorded while tracked, carried across tiers
 }
 
 // HotColdConfig contains configuration for the manager
@@ -59,11 +92,109 @@ type HotColdConfig struct {
 	MaxHotDatabases int
 	ScanInterval    time.Duration
 	HotDuration     time.Duration
+	MinHotDuration  time.Duration // Minimum time to stay hot after promotion
 	Store           *litestream.Store
 	SharedResources *SharedResourceManager
 	ConnectionPool  *ConnectionPool
 	ReplicaTemplate *ReplicaConfig // Template for creating replicas
 	ReplicaFactory  ReplicaClientFactory // Factory for creating replica clients
+	ReplicaOverrides []ReplicaOverride   // Per-pattern changes to the template, most specific match wins
+	WatchFilesystem bool                 // Detect writes with filesystem events, polling only as a fallback
+	ConfirmWithHeader bool               // Promote only when the database header or WAL shows a new transaction
+	SpreadScans     bool                 // Stat each database at its own offset within the scan interval instead of all at once
+	EvictionPolicy  EvictionPolicy       // Chooses hot databases to demote over the limit (default LRU)
+
+	// AccessCountThreshold promotes a database, or keeps it hot, when at
+	// least this many accesses are recorded between two scans, even if it
+	// wasn't written. Accesses are counted by DynamicDB.EnsureOpen and
+	// RecordAccess. Zero promotes on writes only.
+	AccessCountThreshold int64
+
+	// ExcludePatterns are globs whose matches AddDatabases skips, such as
+	// import staging directories or test fixtures. A pattern without a
+	// separator matches file names in any directory. SQLite -wal, -shm,
+	// and -journal files are always skipped.
+	ExcludePatterns []string
+
+	// PerProjectMaxHot caps the hot databases of the named projects, and
+	// DefaultProjectMaxHot caps every other project. A project over its cap
+	// has its own databases evicted. Zero means no cap beyond MaxHotDatabases.
+	PerProjectMaxHot     map[string]int
+	DefaultProjectMaxHot int
+
+	// StateFile, if set, is a JSON file the tier state is saved to
+	// periodically and on Stop. Start loads it to re-promote databases that
+	// were hot before a restart.
+	StateFile string
+
+	// Patterns are the globs databases are discovered from. Entries loaded
+	// from StateFile that no longer match one, or that match
+	// ExcludePatterns, are dropped. Empty keeps every entry.
+	Patterns []string
+
+	// StuckStateTimeout is how long a hot database may remain opening or
+	// closing before the watchdog force-closes and demotes it.
+	StuckStateTimeout time.Duration
+
+	// ManagementInterval is how often the management loop checks for stuck
+	// databases, updates metrics, logs statistics, and saves the state file
+	// (default 30s).
+	ManagementInterval time.Duration
+
+	// ColdSyncInterval, if set, is how often cold databases are snapshotted
+	// to their replica, so rarely written databases are backed up without
+	// waiting for a promotion. Databases unchanged since their last cold
+	// snapshot are skipped. Each is opened only for its snapshot, on the
+	// snapshot worker pool when SharedResources is set, and stays cold.
+	ColdSyncInterval time.Duration
+
+	// MaxConcurrentPromotions bounds how many promoted databases are
+	// opened, and have their replicas started, at once (default 16).
+	// Promotions open on the replica worker pool when SharedResources is
+	// set, so a burst of writes to many cold databases doesn't hold the
+	// manager's lock; a database counts as hot as soon as it is promoted.
+	MaxConcurrentPromotions int
+
+	// DemotionSyncTimeout bounds the final replica sync before a database
+	// is demoted. Writes not synced in time are replicated on the next
+	// promotion.
+	DemotionSyncTimeout time.Duration
+
+	// ReplicaStartRetryInterval is how long after a promotion whose replica
+	// failed to start it is retried in the background, doubling after each
+	// failure up to maxReplicaStartRetryInterval (default 10s). Retries stop
+	// once the database is demoted.
+	ReplicaStartRetryInterval time.Duration
+
+	// OnStuckDatabase is called when the watchdog detects a stuck database,
+	// before recovery is attempted.
+	OnStuckDatabase func(path string, state DBLifecycleState, stuckFor time.Duration)
+
+	// OnTierChange is called after each transition between tiers, with from
+	// and to set to TierHot or TierCold. It runs without the manager's lock
+	// held, on the goroutine that made the transition.
+	OnTierChange func(path, from, to string)
+
+	// DryRun plans tier transitions without making them, for evaluating
+	// thresholds against real traffic. Promotions and demotions are
+	// counted, logged, and published as events with DryRun set, and
+	// GetStatistics reports the resulting tiers, but no database is opened
+	// and no replica is started.
+	DryRun bool
+
+	// EventBufferSize enables the Events channel with room for this many
+	// undelivered events. Events that don't fit are dropped rather than
+	// stalling replication. Zero disables events.
+	EventBufferSize int
+
+	// Metrics receives the manager's aggregated metrics. If nil,
+	// GlobalMetrics is used. Give each manager in a process its own
+	// instance from NewHierarchicalMetricsWithRegistry.
+	Metrics *HierarchicalMetrics
+
+	// MetricsSink, if set and Metrics is nil, is the backend for a new
+	// metrics instance, such as one from NewOTelMetricsSink.
+	MetricsSink MetricsSink
 }
 
 // ReplicaClientFactory creates replica clients from configuration
@@ -82,6 +213,26 @@ func NewHotColdManager(config *HotColdConfig) *HotColdManager {
 	if config.MaxHotDatabases == 0 {
 		config.MaxHotDatabases = 1000
 	}
+	if config.StuckStateTimeout == 0 {
+		config.StuckStateTimeout = 2 * time.Minute
+	}
+	if config.DemotionSyncTimeout == 0 {
+		config.DemotionSyncTimeout = 30 * time.Second
+	}
+	if config.ManagementInterval == 0 {
+		config.ManagementInterval = 30 * time.Second
+	}
+	if config.ReplicaStartRetryInterval == 0 {
+		config.ReplicaStartRetryInterval = 10 * time.Second
+	}
+	if config.MaxConcurrentPromotions == 0 {
+		config.MaxConcurrentPromotions = defaultMaxConcurrentPromotions
+	}
+	if config.Metrics == nil && config.MetricsSink != nil {
+		config.Metrics = NewHierarchicalMetricsWithOptions(MetricsOptions{Sink: config.MetricsSink})
+	} else if config.Metrics == nil {
+		config.Metrics = GlobalMetrics
+	}
 
 	mgr := &HotColdManager{
 		store:           config.Store,
@@ -92,17 +243,38 @@ func NewHotColdManager(config *HotColdConfig) *HotColdManager {
 		hotDuration:     config.HotDuration,
 		replicaTemplate: config.ReplicaTemplate,
 		replicaFactory:  config.ReplicaFactory,
+		replicaOverrides: config.ReplicaOverrides,
+		stuckTimeout:    config.StuckStateTimeout,
+		syncTimeout:     config.DemotionSyncTimeout,
+		replicaRetryInterval: config.ReplicaStartRetryInterval,
+		coldSyncInterval: config.ColdSyncInterval,
+		managementInterval: config.ManagementInterval,
+		stateFile:       config.StateFile,
+		statePatterns:   config.Patterns,
+		onStuck:         config.OnStuckDatabase,
+		onTierChange:    config.OnTierChange,
+		dryRun:          config.DryRun,
+		events:          newEventPublisher(config.EventBufferSize, config.DryRun),
 		hotDatabases:    make(map[string]*DynamicDB),
 		coldDatabases:   make(map[string]*ColdDBInfo),
 		hotReplicas:     make(map[string]*litestream.Replica),
-		metrics:         GlobalMetrics,
+		unreplicated:    make(map[string]struct{}),
+		coldSnapshots:   make(map[string]*coldSnapshot),
+		promotions:      make(map[string]*promotion),
+		promotionSlots:  make(chan struct{}, config.MaxConcurrentPromotions),
+		metrics:         config.Metrics,
 	}
 
 	// Create write detector
-	mgr.writeDetector = NewWriteDetector(
+	newDetector := NewWriteDetector
+	if config.WatchFilesystem {
+		newDetector = NewWriteDetectorWithWatcher
+	}
+	mgr.writeDetector = newDetector(
 		config.ScanInterval,
 		config.HotDuration,
 		config.MaxHotDatabases,
+		config.EvictionPolicy,
 	)
 
 	// Set callbacks for promotion/demotion
@@ -114,6 +286,21 @@ func NewHotColdManager(config *HotColdConfig) *HotColdManager {
 	// Set shared resources
 	mgr.writeDetector.SetResources(config.SharedResources, config.ConnectionPool)
 
+	// Keep newly promoted databases hot for at least the grace window
+	mgr.writeDetector.SetMinHotDuration(config.MinHotDuration)
+
+	mgr.writeDetector.SetProjectQuotas(config.PerProjectMaxHot, config.DefaultProjectMaxHot)
+
+	mgr.writeDetector.SetExcludePatterns(config.ExcludePatterns)
+
+	mgr.writeDetector.SetConfirmWithHeader(config.ConfirmWithHeader)
+
+	mgr.writeDetector.SetSpreadScans(config.SpreadScans)
+
+	mgr.writeDetector.SetAccessThreshold(config.AccessCountThreshold, mgr.accessCounts)
+
+	mgr.writeDetector.SetMetrics(config.Metrics)
+
 	return mgr
 }
 
@@ -121,6 +308,9 @@ func NewHotColdManager(config *HotColdConfig) *HotColdManager {
 func (m *HotColdManager) Start(ctx context.Context) error {
 	m.ctx, m.cancel = context.WithCancel(ctx)
 
+	// Re-promote databases that were hot before a restart
+	m.loadState()
+
 	// Start write detector
 	m.writeDetector.Start(m.ctx)
 
@@ -128,6 +318,13 @@ func (m *HotColdManager) Start(ctx context.Context) error {
 	m.wg.Add(1)
 	go m.managementLoop()
 
+	// Snapshot cold databases if they have somewhere to go
+	replicated := m.replicaFactory != nil && (m.replicaTemplate != nil || len(m.replicaOverrides) > 0)
+	if m.coldSyncInterval > 0 && replicated && !m.dryRun {
+		m.wg.Add(1)
+		go m.coldSnapshotLoop()
+	}
+
 	slog.Info("hot/cold manager started",
 		"max_hot_dbs", m.maxHotDBs,
 		"scan_interval", m.scanInterval,
@@ -136,6 +333,62 @@ func (m *HotColdManager) Start(ctx context.Context) error {
 	return nil
 }
 
+// Pause stops promotions, demotions, and the replicas of hot databases
+// until Resume. Hot databases stay open and every database keeps its tier.
+// Running cold snapshots are canceled and no new ones start.
+func (m *HotColdManager) Pause() {
+	// The detector first, so no scan is promoting while replicas stop
+	m.writeDetector.Pause()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paused {
+		return
+	}
+	m.paused = true
+
+	for path, replica := range m.hotReplicas {
+		if err := replica.Stop(false); err != nil {
+			slog.Error("failed to stop replica during pause", "path", path, "error", err)
+		}
+	}
+	for _, snap := range m.coldSnapshots {
+		snap.cancel()
+	}
+
+	slog.Info("hot/cold manager paused", "hot_databases", len(m.hotDatabases))
+}
+
+// Resume restarts the replicas of hot databases and rescans every
+// database, promoting those written while paused
+func (m *HotColdManager) Resume() {
+	m.mu.Lock()
+	if !m.paused {
+		m.mu.Unlock()
+		return
+	}
+	m.paused = false
+
+	for path, replica := range m.hotReplicas {
+		if err := replica.Start(m.ctx); err != nil {
+			slog.Error("failed to restart replica after pause", "path", path, "error", err)
+		}
+	}
+	hot := len(m.hotDatabases)
+	m.mu.Unlock()
+
+	m.writeDetector.Resume()
+	slog.Info("hot/cold manager resumed", "hot_databases", hot)
+}
+
+// Paused reports whether the manager is paused
+func (m *HotColdManager) Paused() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.paused
+}
+
 // Stop stops the manager
 func (m *HotColdManager) Stop() error {
 	if m.cancel != nil {
@@ -148,6 +401,11 @@ func (m *HotColdManager) Stop() error {
 	// Wait for management loop
 	m.wg.Wait()
 
+	// Save tier state before hot databases are closed
+	if err := m.saveState(); err != nil {
+		slog.Error("failed to save tier state", "path", m.stateFile, "error", err)
+	}
+
 	// Close all hot databases and stop replicas
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -155,10 +413,12 @@ func (m *HotColdManager) Stop() error {
 	for path, db := range m.hotDatabases {
 		// Stop replica if exists
 		if replica, ok := m.hotReplicas[path]; ok {
-			if err := replica.Stop(true); err != nil {
+			err := replica.Stop(true)
+			if err != nil {
 				slog.Error("failed to stop replica", "path", path, "error", err)
 			}
 			delete(m.hotReplicas, path)
+			m.events.publish(TierEventReplicaStopped, path, TierHot, err)
 		}
 		
 		if err := db.Close(context.Background()); err != nil {
@@ -170,11 +430,56 @@ func (m *HotColdManager) Stop() error {
 	return nil
 }
 
+// Reconfigure applies new tier limits and timings to a running manager
+// without dropping hot/cold tracking state. Zero values leave a setting
+// unchanged. Lowering the hot limit demotes the excess on the next scan,
+// which is triggered immediately.
+func (m *HotColdManager) Reconfigure(maxHotDBs int, scanInterval, hotDuration, minHotDuration time.Duration) {
+	m.mu.Lock()
+	if maxHotDBs > 0 {
+		m.maxHotDBs = maxHotDBs
+	}
+	if scanInterval > 0 {
+		m.scanInterval = scanInterval
+	}
+	if hotDuration > 0 {
+		m.hotDuration = hotDuration
+	}
+	m.mu.Unlock()
+
+	m.writeDetector.SetMinHotDuration(minHotDuration)
+	m.writeDetector.Reconfigure(scanInterval, hotDuration, maxHotDBs)
+
+	slog.Info("hot/cold manager reconfigured",
+		"max_hot_dbs", maxHotDBs,
+		"scan_interval", scanInterval,
+		"hot_duration", hotDuration,
+		"min_hot_duration", minHotDuration)
+}
+
+// SetAccessCountThreshold changes how many accesses between two scans
+// promote a database of a running manager. Zero promotes on writes only.
+func (m *HotColdManager) SetAccessCountThreshold(threshold int64) {
+	m.writeDetector.SetAccessThreshold(threshold, m.accessCounts)
+}
+
+// SetProjectQuotas replaces the per-project hot limits of a running manager.
+// Projects over their new limit are trimmed on a scan triggered immediately.
+func (m *HotColdManager) SetProjectQuotas(perProject map[string]int, defaultMax int) {
+	m.writeDetector.SetProjectQuotas(perProject, defaultMax)
+}
+
+// SetSpreadScans switches a running manager between spreading scans across
+// the scan interval and scanning every database at once
+func (m *HotColdManager) SetSpreadScans(enabled bool) {
+	m.writeDetector.SetSpreadScans(enabled)
+}
+
 // managementLoop handles periodic management tasks
 func (m *HotColdManager) managementLoop() {
 	defer m.wg.Done()
 
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(m.managementInterval)
 	defer ticker.Stop()
 
 	for {
@@ -182,49 +487,193 @@ func (m *HotColdManager) managementLoop() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			m.checkStuckDatabases()
 			m.updateMetrics()
 			m.logStatistics()
+			if err := m.saveState(); err != nil {
+				slog.Error("failed to save tier state", "path", m.stateFile, "error", err)
+			}
+		}
+	}
+}
+
+// checkStuckDatabases finds hot databases that have been opening or closing
+// for longer than the stuck timeout and recovers them
+func (m *HotColdManager) checkStuckDatabases() {
+	now := time.Now()
+
+	type stuckDB struct {
+		path  string
+		state DBLifecycleState
+		since time.Time
+	}
+	var stuck []stuckDB
+
+	m.mu.RLock()
+	for path, db := range m.hotDatabases {
+		state, since := db.State()
+		if state != DBStateOpening && state != DBStateClosing {
+			continue
+		}
+		if now.Sub(since) > m.stuckTimeout {
+			stuck = append(stuck, stuckDB{path: path, state: state, since: since})
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, s := range stuck {
+		stuckFor := now.Sub(s.since)
+		slog.Warn("database stuck in transitional state",
+			"path", s.path,
+			"state", s.state,
+			"stuck_for", stuckFor)
+
+		if m.metrics != nil {
+			m.metrics.RecordStuckDatabase(s.state)
+		}
+		if m.onStuck != nil {
+			m.onStuck(s.path, s.state, stuckFor)
+		}
+
+		m.recoverStuckDatabase(s.path)
+	}
+}
+
+// recoverStuckDatabase removes a stuck database from the hot tier and
+// force-closes it. The close runs in the background because it waits for
+// the operation that got the database stuck to release it.
+func (m *HotColdManager) recoverStuckDatabase(path string) {
+	m.mu.Lock()
+	db, ok := m.hotDatabases[path]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+
+	if replica, ok := m.hotReplicas[path]; ok {
+		err := replica.Stop(false)
+		if err != nil {
+			slog.Error("failed to stop replica of stuck database", "path", path, "error", err)
 		}
+		delete(m.hotReplicas, path)
+		m.events.publish(TierEventReplicaStopped, path, TierCold, err)
+	}
+
+	delete(m.hotDatabases, path)
+	m.tierVersion++
+
+	// ForceClose skips the onClose callback, so deregister here
+	if m.store != nil {
+		m.store.RemoveDB(db.DB)
+	}
+
+	project, database, branch, tenant := ParseDBPath(path)
+	m.coldDatabases[path] = &ColdDBInfo{
+		Path:        path,
+		Project:     project,
+		Database:    database,
+		Branch:      branch,
+		Tenant:      tenant,
+		AccessCount: db.AccessCount(),
+	}
+	m.mu.Unlock()
+
+	m.recordTierChange(path, TierHot, TierCold)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.stuckTimeout)
+		defer cancel()
+		if err := db.ForceClose(ctx); err != nil {
+			slog.Error("failed to force close stuck database", "path", path, "error", err)
+		}
+	}()
+
+	slog.Info("stuck database demoted to cold tier", "path", filepath.Base(path))
+}
+
+// GetLifecycleStats returns the number of hot databases in each lifecycle state
+func (m *HotColdManager) GetLifecycleStats() map[DBLifecycleState]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[DBLifecycleState]int)
+	for _, db := range m.hotDatabases {
+		state, _ := db.State()
+		counts[state]++
 	}
+	return counts
 }
 
 // promoteToHot promotes a database to hot tier
 func (m *HotColdManager) promoteToHot(path string) error {
+	// Deferred before the unlock so it runs after it
+	var promoted bool
+	var pending *promotion
+	defer func() {
+		if promoted {
+			m.recordTierChange(path, TierCold, TierHot)
+		}
+		if pending != nil {
+			m.submitPromotion(path, pending)
+		}
+	}()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// A cold snapshot has the database open, so stop it first
+	m.cancelColdSnapshotLocked(path)
+
 	// Check if already hot
 	if _, ok := m.hotDatabases[path]; ok {
 		return nil
 	}
 
-	// Remove from cold if present
+	// Remove from cold if present, keeping its access count
+	var accessCount int64
+	info, ok := m.coldDatabases[path]
+	if ok {
+		accessCount = info.AccessCount
+	}
 	delete(m.coldDatabases, path)
 
 	// Create dynamic DB
 	db := litestream.NewDB(path)
 	dynamicDB := &DynamicDB{
 		DB:       db,
-		state:    DBStateClosed,
 		manager:  nil, // Not using MultiDBManager for now
-		lastAccess: time.Now(),
+	}
+	dynamicDB.lastAccess.Store(time.Now().UnixNano())
+	dynamicDB.accessCount.Store(accessCount)
+	dynamicDB.setState(DBStateClosed)
+
+	// A planned promotion tracks the database as hot without opening it.
+	// Demotion then closes nothing, since the database was never opened.
+	if m.dryRun {
+		m.hotDatabases[path] = dynamicDB
+		m.tierVersion++
+		promoted = true
+		slog.Info("dry run: would promote database to hot tier", "path", filepath.Base(path))
+		return nil
 	}
 
 	// Set callbacks for lifecycle events
 	dynamicDB.onOpen = func(d *DynamicDB) error {
-		// TODO: Add to store when opened
-		// The current Store doesn't support dynamic addition of DBs
-		// if m.store != nil {
-		//     m.store.AddDB(d.DB)
-		// }
+		// Register with the store so compactions and snapshots include it
+		if m.store != nil {
+			m.store.AddDB(d.DB)
+		}
 		
-		// Submit monitoring task to worker pool
+		// Submit monitoring task to worker pool. Don't block the promotion
+		// on a full queue; the database is still replicated without it.
 		if m.sharedResources != nil {
-			m.sharedResources.monitorPool.Submit(&MonitorTask{
+			if _, ok := m.sharedResources.monitorPool.TrySubmit(&MonitorTask{
 				Path:     path,
 				Interval: 1 * time.Second,
 				DB:       dynamicDB,
-			})
+			}); !ok {
+				slog.Warn("monitor pool full, skipping monitor task", "path", path)
+			}
 		}
 
 		slog.Debug("database promoted to hot", "path", path)
@@ -232,58 +681,49 @@ func (m *HotColdManager) promoteToHot(path string) error {
 	}
 
 	dynamicDB.onClose = func(d *DynamicDB) error {
-		// TODO: Remove from store when closed
-		// The current Store doesn't support dynamic removal of DBs
-		// if m.store != nil {
-		//     m.store.RemoveDB(d.DB)
-		// }
+		if m.store != nil {
+			m.store.RemoveDB(d.DB)
+		}
 		
 		slog.Debug("database closed", "path", path)
 		return nil
 	}
 
-	// Open the database
-	if err := dynamicDB.Open(context.Background()); err != nil {
-		return fmt.Errorf("open database: %w", err)
-	}
-
-	// Create and start replica if configured
-	if m.replicaTemplate != nil {
-		replica, err := m.createReplicaForDB(dynamicDB.DB, path)
-		if err != nil {
-			slog.Error("failed to create replica", "path", path, "error", err)
-			// Continue without replication rather than failing promotion
-		} else if replica != nil {
-			// Assign replica to database
-			dynamicDB.DB.Replica = replica
-			
-			// Start replica monitoring
-			if err := replica.Start(m.ctx); err != nil {
-				slog.Error("failed to start replica", "path", path, "error", err)
-			} else {
-				m.hotReplicas[path] = replica
-				slog.Debug("replica started", "path", path, "type", m.replicaTemplate.Type)
-			}
-		}
-	}
-
+	// The database is hot from here on. Opening it and starting its
+	// replica happen after the lock is released.
+	pending = &promotion{db: dynamicDB, info: info, done: make(chan struct{})}
+	m.promotions[path] = pending
 	m.hotDatabases[path] = dynamicDB
-
-	// Update metrics
-	if m.metrics != nil {
-		project, database, _, _ := ParseDBPath(path)
-		m.metrics.UpdateDatabaseStats(project, database, 1, 1, 1)
-	}
-
-	slog.Info("database promoted to hot tier", "path", filepath.Base(path))
+	m.tierVersion++
+	promoted = true
 	return nil
 }
 
 // demoteToCold demotes a database to cold tier
 func (m *HotColdManager) demoteToCold(path string) error {
+	// Flush the replica before taking the lock so a slow final sync doesn't
+	// stall transitions of other databases
+	m.mu.RLock()
+	replica := m.hotReplicas[path]
+	m.mu.RUnlock()
+	if replica != nil {
+		m.finalSync(path, replica)
+	}
+
+	// Deferred before the unlock so it runs after it
+	var demoted bool
+	defer func() {
+		if demoted {
+			m.recordTierChange(path, TierHot, TierCold)
+		}
+	}()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// A promotion still opening the database must finish first
+	m.settlePromotionLocked(path)
+
 	// Get hot database
 	db, ok := m.hotDatabases[path]
 	if !ok {
@@ -292,15 +732,12 @@ func (m *HotColdManager) demoteToCold(path string) error {
 
 	// Stop replica if exists
 	if replica, ok := m.hotReplicas[path]; ok {
-		// Perform final sync before stopping
-		if err := replica.Sync(context.Background()); err != nil {
-			slog.Debug("final sync before demotion failed", "path", path, "error", err)
-		}
-		
-		if err := replica.Stop(false); err != nil {
+		err := replica.Stop(false)
+		if err != nil {
 			slog.Error("failed to stop replica during demotion", "path", path, "error", err)
 		}
 		delete(m.hotReplicas, path)
+		m.events.publish(TierEventReplicaStopped, path, TierCold, err)
 		
 		// Clear replica from database
 		db.DB.Replica = nil
@@ -313,15 +750,19 @@ func (m *HotColdManager) demoteToCold(path string) error {
 
 	// Remove from hot
 	delete(m.hotDatabases, path)
+	delete(m.unreplicated, path)
+	m.tierVersion++
+	demoted = true
 
 	// Add to cold
 	project, database, branch, tenant := ParseDBPath(path)
 	m.coldDatabases[path] = &ColdDBInfo{
-		Path:     path,
-		Project:  project,
-		Database: database,
-		Branch:   branch,
-		Tenant:   tenant,
+		Path:        path,
+		Project:     project,
+		Database:    database,
+		Branch:      branch,
+		Tenant:      tenant,
+		AccessCount: db.AccessCount(),
 	}
 
 	// Update metrics
@@ -329,53 +770,132 @@ func (m *HotColdManager) demoteToCold(path string) error {
 		m.metrics.UpdateDatabaseStats(project, database, 1, 1, 0)
 	}
 
-	slog.Info("database demoted to cold tier", "path", filepath.Base(path))
+	if m.dryRun {
+		slog.Info("dry run: would demote database to cold tier", "path", filepath.Base(path))
+	} else {
+		slog.Info("database demoted to cold tier", "path", filepath.Base(path))
+	}
 	return nil
 }
 
-// AddDatabases adds databases to manage from glob patterns
+// finalSync syncs a replica about to be demoted, giving up after the
+// demotion sync timeout. A failure leaves the latest writes unreplicated
+// until the database is next promoted, so it is logged as a warning.
+func (m *HotColdManager) finalSync(path string, replica *litestream.Replica) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.syncTimeout)
+	defer cancel()
+
+	if err := replica.Sync(ctx); err != nil {
+		slog.Warn("final sync before demotion failed",
+			"path", path,
+			"timeout", m.syncTimeout,
+			"error", err)
+		if m.metrics != nil {
+			m.metrics.RecordDemotionSyncError()
+		}
+	}
+}
+
+// recordTierChange counts a transition and reports it to the OnTierChange
+// callback and the events channel. Must be called without the lock held.
+func (m *HotColdManager) recordTierChange(path, from, to string) {
+	if to == TierHot {
+		m.totalPromotions.Add(1)
+		m.events.publish(TierEventPromoted, path, to, nil)
+	} else {
+		m.totalDemotions.Add(1)
+		m.events.publish(TierEventDemoted, path, to, nil)
+	}
+	if m.onTierChange != nil {
+		m.onTierChange(path, from, to)
+	}
+}
+
+// Events returns the channel tier events are published on, or nil if
+// EventBufferSize was not set. Consumers must drain it continuously: events
+// arriving while the buffer is full are dropped and counted by
+// DroppedEvents. The channel is never closed.
+func (m *HotColdManager) Events() <-chan TierEvent {
+	return m.events.events()
+}
+
+// DroppedEvents returns how many events were dropped because the events
+// channel was full
+func (m *HotColdManager) DroppedEvents() int64 {
+	return m.events.droppedCount()
+}
+
+// AddDatabases adds databases to manage from glob patterns, which may use
+// **. Databases that could be added are tracked even if others failed; the
+// failures are returned as described by WriteDetector.AddDatabases.
 func (m *HotColdManager) AddDatabases(patterns []string) error {
 	// Add to write detector
-	if err := m.writeDetector.AddDatabases(patterns); err != nil {
-		return fmt.Errorf("add databases to write detector: %w", err)
-	}
+	paths, err := m.writeDetector.addDatabases(patterns)
 
 	// Track all databases as cold initially
 	m.mu.Lock()
-	for _, pattern := range patterns {
-		matches, err := filepath.Glob(pattern)
-		if err != nil {
-			slog.Error("glob pattern failed", "pattern", pattern, "error", err)
-			continue
-		}
-
-		for _, path := range matches {
-			if _, hotOk := m.hotDatabases[path]; hotOk {
-				continue // Already hot
-			}
-			if _, coldOk := m.coldDatabases[path]; coldOk {
-				continue // Already cold
-			}
-
-			// Add as cold
-			project, database, branch, tenant := ParseDBPath(path)
-			m.coldDatabases[path] = &ColdDBInfo{
-				Path:     path,
-				Project:  project,
-				Database: database,
-				Branch:   branch,
-				Tenant:   tenant,
-			}
-		}
+	for _, path := range paths {
+		m.trackColdLocked(path)
 	}
 	m.mu.Unlock()
 
 	// Update metrics after releasing lock
 	m.updateMetrics()
-	return nil
+	return err
+}
+
+// RemoveDatabase stops managing a database, demoting it first if it is hot
+func (m *HotColdManager) RemoveDatabase(path string) error {
+	err := m.writeDetector.RemoveDatabase(path)
+
+	m.mu.Lock()
+	delete(m.coldDatabases, path)
+	m.tierVersion++
+	m.mu.Unlock()
+
+	m.updateMetrics()
+	return err
+}
+
+// trackedPaths returns the paths of all hot and cold databases
+func (m *HotColdManager) trackedPaths() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	paths := make([]string, 0, len(m.hotDatabases)+len(m.coldDatabases))
+	for path := range m.hotDatabases {
+		paths = append(paths, path)
+	}
+	for path := range m.coldDatabases {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// trackColdLocked adds a database as cold unless it is already tracked
+// (must hold lock)
+func (m *HotColdManager) trackColdLocked(path string) {
+	if _, hotOk := m.hotDatabases[path]; hotOk {
+		return // Already hot
+	}
+	if _, coldOk := m.coldDatabases[path]; coldOk {
+		return // Already cold
+	}
+
+	project, database, branch, tenant := ParseDBPath(path)
+	m.coldDatabases[path] = &ColdDBInfo{
+		Path:     path,
+		Project:  project,
+		Database: database,
+		Branch:   branch,
+		Tenant:   tenant,
+	}
+	m.tierVersion++
 }
 
-// updateMetrics updates hierarchical metrics
+// updateMetrics updates hierarchical metrics. Per-project aggregation walks
+// every tracked database, so it is skipped when no database has changed
+// tier since the last update.
 func (m *HotColdManager) updateMetrics() {
 	if m.metrics == nil {
 		return
@@ -386,8 +906,23 @@ func (m *HotColdManager) updateMetrics() {
 
 	// Update tier counts
 	m.metrics.UpdateTierCounts(len(m.hotDatabases), len(m.coldDatabases))
+	m.metrics.UpdateUnreplicatedHotDatabases(len(m.unreplicated))
 
-	// Aggregate by project
+	// Update lifecycle state counts
+	lifecycleCounts := make(map[DBLifecycleState]int)
+	for _, db := range m.hotDatabases {
+		state, _ := db.State()
+		lifecycleCounts[state]++
+	}
+	m.metrics.UpdateLifecycleStateCounts(lifecycleCounts)
+
+	// Recompute how long each database has gone without a successful sync
+	m.metrics.UpdateSyncLag()
+
+	// Aggregate by project, unless the tracked set is unchanged
+	if m.projectMetricsVersion.Swap(m.tierVersion) == m.tierVersion {
+		return
+	}
 	projectStats := make(map[string]struct {
 		total int
 		hot   int
@@ -422,25 +957,48 @@ func (m *HotColdManager) logStatistics() {
 	m.mu.RUnlock()
 
 	total, detectorHot, _ := m.writeDetector.GetStatistics()
+	lifecycle := m.GetLifecycleStats()
 
-	slog.Info("hot/cold manager statistics",
+	args := []any{
 		"total_tracked", total,
 		"hot_databases", hotCount,
 		"cold_databases", coldCount,
-		"detector_hot", detectorHot)
+		"detector_hot", detectorHot,
+		"total_promotions", m.totalPromotions.Load(),
+		"total_demotions", m.totalDemotions.Load(),
+		"opening", lifecycle[DBStateOpening],
+		"closing", lifecycle[DBStateClosing],
+	}
+	if byAccess := m.GetHotDatabasesByAccess(); len(byAccess) > 0 {
+		most, least := byAccess[0], byAccess[len(byAccess)-1]
+		args = append(args,
+			"most_accessed", filepath.Base(most.Path),
+			"most_accessed_count", most.AccessCount,
+			"least_accessed", filepath.Base(least.Path),
+			"least_accessed_count", least.AccessCount)
+	}
+	slog.Info("hot/cold manager statistics", args...)
 }
 
-// GetStatistics returns current statistics
-func (m *HotColdManager) GetStatistics() (total, hot, cold int) {
+// GetStatistics returns current statistics. totalPromotions and
+// totalDemotions count tier transitions since the manager was created.
+func (m *HotColdManager) GetStatistics() (total, hot, cold int, totalPromotions, totalDemotions int64) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	hot = len(m.hotDatabases)
 	cold = len(m.coldDatabases)
 	total = hot + cold
+	totalPromotions = m.totalPromotions.Load()
+	totalDemotions = m.totalDemotions.Load()
 	return
 }
 
+// LastScan returns when the write detector last finished a scan
+func (m *HotColdManager) LastScan() time.Time {
+	return m.writeDetector.LastScan()
+}
+
 // GetHotDatabases returns list of hot database paths
 func (m *HotColdManager) GetHotDatabases() []string {
 	m.mu.RLock()
@@ -453,6 +1011,172 @@ func (m *HotColdManager) GetHotDatabases() []string {
 	return paths
 }
 
+// GetColdDatabases returns copies of the tracked cold databases, sorted by path
+func (m *HotColdManager) GetColdDatabases() []ColdDBInfo {
+	m.mu.RLock()
+	infos := make([]ColdDBInfo, 0, len(m.coldDatabases))
+	for _, info := range m.coldDatabases {
+		infos = append(infos, *info)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos
+}
+
+// GetProjectHotCounts returns the number of hot databases in each project
+func (m *HotColdManager) GetProjectHotCounts() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for path := range m.hotDatabases {
+		project, _, _, _ := ParseDBPath(path)
+		counts[project]++
+	}
+	return counts
+}
+
+// GetDatabaseTier returns TierHot or TierCold for a tracked database, or
+// false if the database is not tracked
+func (m *HotColdManager) GetDatabaseTier(path string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.hotDatabases[path]; ok {
+		return TierHot, true
+	}
+	if _, ok := m.coldDatabases[path]; ok {
+		return TierCold, true
+	}
+	return "", false
+}
+
+// InFlightOperations returns the operations currently queued or running
+// on the shared worker pools
+func (m *HotColdManager) InFlightOperations() []Operation {
+	if m.sharedResources == nil {
+		return nil
+	}
+	return m.sharedResources.InFlightOperations()
+}
+
+// CancelOperation cancels an in-flight operation by id
+func (m *HotColdManager) CancelOperation(id string) error {
+	if m.sharedResources == nil {
+		return fmt.Errorf("operation not found: %s", id)
+	}
+	return m.sharedResources.CancelOperation(id)
+}
+
+// PromoteNow promotes a database to hot immediately, e.g. ahead of a
+// known traffic spike. It then expires like any other hot database.
+func (m *HotColdManager) PromoteNow(path string) error {
+	if err := m.writeDetector.PromoteNow(path); err != nil {
+		return err
+	}
+	return m.waitPromotion(path)
+}
+
+// DemoteNow performs a final sync and demotes a database to cold
+// immediately, e.g. before maintenance. Pinned databases must be unpinned
+// first.
+func (m *HotColdManager) DemoteNow(path string) error {
+	return m.writeDetector.DemoteNow(path)
+}
+
+// Pin promotes a database to hot and keeps it hot until Unpin is called
+func (m *HotColdManager) Pin(path string) error {
+	if err := m.writeDetector.Pin(path); err != nil {
+		return err
+	}
+	return m.waitPromotion(path)
+}
+
+// Unpin returns a pinned database to normal hot duration expiry
+func (m *HotColdManager) Unpin(path string) error {
+	return m.writeDetector.Unpin(path)
+}
+
+// IsPinned checks if a database is pinned hot
+func (m *HotColdManager) IsPinned(path string) bool {
+	return m.writeDetector.IsPinned(path)
+}
+
+// RecordAccess counts an access to a tracked database, such as a read
+// served from it, toward AccessCountThreshold. Accesses through
+// DynamicDB.EnsureOpen are already counted.
+func (m *HotColdManager) RecordAccess(path string) error {
+	m.mu.RLock()
+	db, ok := m.hotDatabases[path]
+	m.mu.RUnlock()
+	if ok {
+		db.updateAccess()
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if db, ok := m.hotDatabases[path]; ok {
+		db.updateAccess() // Promoted since the check above
+		return nil
+	}
+	info, ok := m.coldDatabases[path]
+	if !ok {
+		return fmt.Errorf("database not tracked: %s", path)
+	}
+	info.AccessCount++
+	return nil
+}
+
+// accessCounts returns the cumulative access count of every hot database
+// and every cold database that has been accessed, for the write detector
+func (m *HotColdManager) accessCounts() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int64, len(m.hotDatabases))
+	for path, db := range m.hotDatabases {
+		counts[path] = db.AccessCount()
+	}
+	for path, info := range m.coldDatabases {
+		if info.AccessCount > 0 {
+			counts[path] = info.AccessCount
+		}
+	}
+	return counts
+}
+
+// DatabaseAccess is the access count of a hot database
+type DatabaseAccess struct {
+	Path        string
+	AccessCount int64
+	LastAccess  time.Time
+}
+
+// GetHotDatabasesByAccess returns the hot databases from most to least
+// accessed, with ties ordered by path
+func (m *HotColdManager) GetHotDatabasesByAccess() []DatabaseAccess {
+	m.mu.RLock()
+	dbs := make([]DatabaseAccess, 0, len(m.hotDatabases))
+	for path, db := range m.hotDatabases {
+		dbs = append(dbs, DatabaseAccess{
+			Path:        path,
+			AccessCount: db.AccessCount(),
+			LastAccess:  db.LastAccess(),
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(dbs, func(i, j int) bool {
+		if dbs[i].AccessCount != dbs[j].AccessCount {
+			return dbs[i].AccessCount > dbs[j].AccessCount
+		}
+		return dbs[i].Path < dbs[j].Path
+	})
+	return dbs
+}
+
 // IsHot checks if a database is hot
 func (m *HotColdManager) IsHot(path string) bool {
 	m.mu.RLock()
@@ -462,21 +1186,179 @@ func (m *HotColdManager) IsHot(path string) bool {
 	return ok
 }
 
+// replicaConfigFor returns the replica config for a database: the template
+// with the most specific matching override applied. Returns nil when there
+// is no template and no override matches.
+func (m *HotColdManager) replicaConfigFor(path string) *ReplicaConfig {
+	var override *ReplicaOverride
+	best := -1
+	for i := range m.replicaOverrides {
+		o := &m.replicaOverrides[i]
+		if ok, _ := filepath.Match(o.Pattern, path); !ok {
+			continue
+		}
+		if n := patternSpecificity(o.Pattern); n > best {
+			override, best = o, n
+		}
+	}
+
+	if m.replicaTemplate == nil && override == nil {
+		return nil
+	}
+
+	var config ReplicaConfig
+	if m.replicaTemplate != nil {
+		config = *m.replicaTemplate
+	}
+	if override != nil {
+		config = config.merge(override.ReplicaConfig)
+	}
+	return &config
+}
+
+// Replica start retries. A promotion tries replicaStartAttempts times with
+// a doubling backoff before leaving further retries to the background.
+const (
+	replicaStartAttempts         = 3
+	replicaStartBackoff          = 50 * time.Millisecond
+	maxReplicaStartRetryInterval = 5 * time.Minute
+)
+
+// startReplica creates and starts the replica of a database being
+// promoted, retrying failures with a short backoff. Called without the
+// lock; the caller records the replica. Returns nil if replication isn't
+// configured for the database.
+func (m *HotColdManager) startReplica(db *DynamicDB, path string) (*litestream.Replica, error) {
+	backoff := replicaStartBackoff
+	for attempt := 1; ; attempt++ {
+		replica, err := m.tryStartReplica(db, path)
+		if err == nil || attempt == replicaStartAttempts {
+			return replica, err
+		}
+		slog.Warn("replica start failed, retrying", "path", path, "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-m.ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// tryStartReplica makes one attempt at creating and starting the replica of
+// a hot database. The caller records the replica in hotReplicas.
+func (m *HotColdManager) tryStartReplica(db *DynamicDB, path string) (*litestream.Replica, error) {
+	replica, err := m.createReplicaForDB(db.DB, path)
+	if err != nil {
+		return nil, fmt.Errorf("create replica: %w", err)
+	} else if replica == nil {
+		return nil, nil
+	}
+
+	db.DB.Replica = replica
+	if err := replica.Start(m.ctx); err != nil {
+		db.DB.Replica = nil
+		return nil, fmt.Errorf("start replica: %w", err)
+	}
+	slog.Debug("replica started", "path", path, "type", replica.Client.Type())
+	m.events.publish(TierEventReplicaStarted, path, TierHot, nil)
+	return replica, nil
+}
+
+// scheduleReplicaRetry retries starting the replica of a hot database after
+// delay, on the monitor pool if there is one
+func (m *HotColdManager) scheduleReplicaRetry(db *DynamicDB, path string, delay time.Duration) {
+	task := &replicaStartTask{manager: m, db: db, path: path, delay: delay}
+	time.AfterFunc(delay, func() {
+		if m.ctx.Err() != nil {
+			return
+		}
+		if m.sharedResources == nil {
+			task.OnError(task.Execute())
+			return
+		}
+		if _, ok := m.sharedResources.monitorPool.TrySubmit(task); !ok {
+			m.scheduleReplicaRetry(db, path, delay) // Queue full, try again later
+		}
+	})
+}
+
+// retryReplica makes a background attempt at starting the replica of a
+// database, unless it has since been demoted or its replica started
+func (m *HotColdManager) retryReplica(db *DynamicDB, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paused {
+		return ErrPaused
+	}
+
+	if m.hotDatabases[path] != db {
+		return nil // Demoted, or promoted again with retries of its own
+	}
+	if _, ok := m.unreplicated[path]; !ok {
+		return nil
+	}
+	replica, err := m.tryStartReplica(db, path)
+	if err != nil {
+		return err
+	} else if replica != nil {
+		m.hotReplicas[path] = replica
+	}
+	delete(m.unreplicated, path)
+	slog.Info("replica started after retry", "path", path)
+	return nil
+}
+
+// replicaStartTask retries starting the replica of a hot database on the
+// monitor pool, scheduling another retry with twice the delay if it fails
+type replicaStartTask struct {
+	manager *HotColdManager
+	db      *DynamicDB
+	path    string
+	delay   time.Duration
+}
+
+func (t *replicaStartTask) Execute() error {
+	return t.manager.retryReplica(t.db, t.path)
+}
+
+// OnError records a failed retry and schedules the next one, unless the
+// manager is stopping
+func (t *replicaStartTask) OnError(err error) {
+	m := t.manager
+	if err == nil || m.ctx.Err() != nil {
+		return
+	} else if errors.Is(err, ErrPaused) {
+		m.scheduleReplicaRetry(t.db, t.path, t.delay)
+		return
+	}
+	delay := min(2*t.delay, maxReplicaStartRetryInterval)
+	slog.Error("replica start retry failed", "path", t.path, "retry_in", delay, "error", err)
+	m.events.publish(TierEventError, t.path, TierHot, err)
+	if m.metrics != nil {
+		m.metrics.RecordReplicaStartError()
+	}
+	m.scheduleReplicaRetry(t.db, t.path, delay)
+}
+
+func (t *replicaStartTask) String() string {
+	return "replica start " + t.path
+}
+
 // createReplicaForDB creates a replica for a database based on the template
 func (m *HotColdManager) createReplicaForDB(db *litestream.DB, path string) (*litestream.Replica, error) {
-	if m.replicaTemplate == nil || m.replicaFactory == nil {
+	config := m.replicaConfigFor(path)
+	if config == nil || m.replicaFactory == nil {
 		return nil, nil // No replication configured
 	}
 	
 	// Expand path template
-	expandedPath := m.expandPathTemplate(m.replicaTemplate.Path, path)
-	
-	// Create a copy of the config with expanded path
-	config := *m.replicaTemplate
-	config.Path = expandedPath
+	config.Path = m.expandPathTemplate(config.Path, path)
 	
 	// Use factory to create client
-	client, err := m.replicaFactory.CreateClient(&config, path)
+	client, err := m.replicaFactory.CreateClient(config, path)
 	if err != nil {
 		return nil, fmt.Errorf("create replica client: %w", err)
 	}
@@ -487,10 +1369,16 @@ func (m *HotColdManager) createReplicaForDB(db *litestream.DB, path string) (*li
 	
 	// Create replica with client
 	replica := litestream.NewReplicaWithClient(db, client)
+	if fc, ok := client.(*file.ReplicaClient); ok {
+		fc.Replica = replica // Lets the client match the database's file modes
+	}
 	
 	// Apply configuration from template
-	if m.replicaTemplate.SyncInterval > 0 {
-		replica.SyncInterval = m.replicaTemplate.SyncInterval
+	if config.SyncInterval > 0 {
+		replica.SyncInterval = config.SyncInterval
+	}
+	if config.UploadConcurrency > 0 {
+		replica.UploadConcurrency = config.UploadConcurrency
 	}
 	
 	return replica, nil
@@ -3,17 +3,23 @@ package litestreampp
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/litestream"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/superfly/ltx"
 )
 
 // MockReplicaClient is a mock implementation of ReplicaClient for testing
 type MockReplicaClient struct {
+	mu            sync.Mutex // Guards the fields below; replicas call from their monitor goroutine
 	Type_         string
 	InitCalled    int
 	SyncCalled    int
@@ -32,12 +38,16 @@ func (c *MockReplicaClient) Type() string {
 }
 
 func (c *MockReplicaClient) Init(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.InitCalled++
 	return nil
 }
 
 func (c *MockReplicaClient) LTXFiles(ctx context.Context, level int, seek ltx.TXID) (ltx.FileIterator, error) {
+	c.mu.Lock()
 	c.LTXFilesCalls = append(c.LTXFilesCalls, seek)
+	c.mu.Unlock()
 	// Return a mock iterator that immediately returns no more files
 	return &mockFileIterator{}, nil
 }
@@ -55,6 +65,13 @@ func (c *MockReplicaClient) OpenLTXFile(ctx context.Context, level int, minTXID,
 }
 
 func (c *MockReplicaClient) WriteLTXFile(ctx context.Context, level int, minTXID, maxTXID ltx.TXID, r io.Reader) (*ltx.FileInfo, error) {
+	// Drain the reader so snapshot encoders don't block
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.WriteCalled++
 	c.WrittenFiles = append(c.WrittenFiles, struct {
 		Level   int
@@ -70,6 +87,8 @@ func (c *MockReplicaClient) WriteLTXFile(ctx context.Context, level int, minTXID
 }
 
 func (c *MockReplicaClient) DeleteLTXFiles(ctx context.Context, a []*ltx.FileInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.DeleteCalled++
 	return nil
 }
@@ -153,7 +172,7 @@ func TestHotColdManagerWithReplica(t *testing.T) {
 	}
 	
 	// Promote to hot (should create replica)
-	if err := manager.promoteToHot(testDBPath); err != nil {
+	if err := promoteAndWait(manager, testDBPath); err != nil {
 		t.Fatalf("failed to promote to hot: %v", err)
 	}
 	
@@ -200,7 +219,258 @@ func TestHotColdManagerWithReplica(t *testing.T) {
 	}
 }
 
+func TestHotColdManagerWithFileReplica(t *testing.T) {
+	dir := t.TempDir()
+	
+	config := &HotColdConfig{
+		MaxHotDatabases: 10,
+		ScanInterval:    1 * time.Second,
+		HotDuration:     5 * time.Second,
+		Store:           litestream.NewStore(nil, litestream.CompactionLevels{}),
+		SharedResources: NewSharedResourceManager(),
+		ConnectionPool:  NewConnectionPool(10, 5*time.Second),
+		ReplicaTemplate: &ReplicaConfig{
+			Type:         "file",
+			Path:         dir + "/replica/{{filename}}",
+			SyncInterval: 1 * time.Second,
+		},
+		ReplicaFactory: NewDefaultReplicaClientFactory(),
+	}
+	
+	manager := NewHotColdManager(config)
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	if err := manager.Start(ctx); err != nil {
+		t.Fatalf("failed to start manager: %v", err)
+	}
+	defer manager.Stop()
+	
+	testDBPath := dir + "/test.db"
+	if err := createTestDB(testDBPath); err != nil {
+		t.Fatalf("failed to create test db: %v", err)
+	}
+	
+	if err := promoteAndWait(manager, testDBPath); err != nil {
+		t.Fatalf("failed to promote to hot: %v", err)
+	}
+	
+	manager.mu.RLock()
+	replica, exists := manager.hotReplicas[testDBPath]
+	manager.mu.RUnlock()
+	if !exists || replica == nil {
+		t.Fatal("expected file replica to be created")
+	}
+	
+	// Write through SQLite and wait for the database monitor and replica to
+	// ship it
+	sqldb, err := sql.Open("sqlite3", testDBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqldb.Close()
+	if _, err := sqldb.Exec(`INSERT INTO test (value) VALUES ('hello')`); err != nil {
+		t.Fatal(err)
+	}
+	
+	// LTX files should appear under the expanded replica path
+	ltxDir := litestream.LTXLevelDir(dir+"/replica/test", 0)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		entries, _ := os.ReadDir(ltxDir)
+		if len(entries) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected LTX files in %s", ltxDir)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestDefaultReplicaClientFactoryFileRequiresPath(t *testing.T) {
+	factory := NewDefaultReplicaClientFactory()
+	if _, err := factory.CreateClient(&ReplicaConfig{Type: "file"}, "/tmp/test.db"); err == nil {
+		t.Error("expected error for file replica without path")
+	}
+}
+
+func TestDefaultReplicaClientFactoryGCS(t *testing.T) {
+	t.Run("ConfigMapsThrough", func(t *testing.T) {
+		var got *ReplicaConfig
+		factory := NewDefaultReplicaClientFactory()
+		factory.CreateGCSClientFunc = func(config *ReplicaConfig) (litestream.ReplicaClient, error) {
+			got = config
+			return &MockReplicaClient{Type_: "gcs"}, nil
+		}
+
+		manager := &HotColdManager{
+			replicaFactory: factory,
+			replicaTemplate: &ReplicaConfig{
+				Type:            "gcs",
+				Bucket:          "my-bucket",
+				Path:            "backups/{{project}}/{{tenant}}",
+				CredentialsFile: "/etc/litestream/key.json",
+			},
+		}
+		dbPath := "/data/proj/databases/db/branches/main/tenants/t1.db"
+		replica, err := manager.createReplicaForDB(litestream.NewDB(dbPath), dbPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if replica == nil || replica.Client.Type() != "gcs" {
+			t.Fatalf("expected replica with gcs client, got %v", replica)
+		}
+
+		if got.Bucket != "my-bucket" {
+			t.Errorf("expected bucket my-bucket, got %q", got.Bucket)
+		}
+		if got.Path != "backups/proj/t1" {
+			t.Errorf("expected expanded path backups/proj/t1, got %q", got.Path)
+		}
+		if got.CredentialsFile != "/etc/litestream/key.json" {
+			t.Errorf("expected credentials file to map through, got %q", got.CredentialsFile)
+		}
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		factory := NewDefaultReplicaClientFactory()
+		if _, err := factory.CreateClient(&ReplicaConfig{Type: "gcs", Bucket: "my-bucket"}, "/tmp/test.db"); err == nil {
+			t.Error("expected error without a GCS client factory")
+		}
+	})
+
+	t.Run("RequiresBucket", func(t *testing.T) {
+		factory := NewDefaultReplicaClientFactory()
+		factory.CreateGCSClientFunc = func(config *ReplicaConfig) (litestream.ReplicaClient, error) {
+			t.Fatal("factory should not be called without a bucket")
+			return nil, nil
+		}
+		if _, err := factory.CreateClient(&ReplicaConfig{Type: "gcs"}, "/tmp/test.db"); err == nil {
+			t.Error("expected error for gcs replica without bucket")
+		}
+	})
+}
+
+func TestDefaultReplicaClientFactoryAzure(t *testing.T) {
+	for _, typ := range []string{"abs", "azure"} {
+		t.Run(typ, func(t *testing.T) {
+			var got *ReplicaConfig
+			factory := NewDefaultReplicaClientFactory()
+			factory.CreateAzureClientFunc = func(config *ReplicaConfig) (litestream.ReplicaClient, error) {
+				got = config
+				return &MockReplicaClient{Type_: "abs"}, nil
+			}
+
+			manager := &HotColdManager{
+				replicaFactory: factory,
+				replicaTemplate: &ReplicaConfig{
+					Type:        typ,
+					Bucket:      "backups",
+					Path:        "{{project}}/{{database}}/{{branch}}/{{tenant}}",
+					AccountName: "myaccount",
+					SASToken:    "sv=2020-08-04&sig=abc",
+				},
+			}
+			dbPath := "/data/proj/databases/db/branches/main/tenants/t1.db"
+			if _, err := manager.createReplicaForDB(litestream.NewDB(dbPath), dbPath); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Path != "proj/db/main/t1" {
+				t.Errorf("expected expanded blob prefix proj/db/main/t1, got %q", got.Path)
+			}
+			if got.Bucket != "backups" || got.AccountName != "myaccount" || got.SASToken != "sv=2020-08-04&sig=abc" {
+				t.Errorf("expected container and credentials to map through, got %+v", got)
+			}
+		})
+	}
+
+	t.Run("RequiresAccountName", func(t *testing.T) {
+		factory := NewDefaultReplicaClientFactory()
+		factory.CreateAzureClientFunc = func(config *ReplicaConfig) (litestream.ReplicaClient, error) {
+			t.Fatal("factory should not be called without an account name")
+			return nil, nil
+		}
+		if _, err := factory.CreateClient(&ReplicaConfig{Type: "abs", Bucket: "backups"}, "/tmp/test.db"); err == nil {
+			t.Error("expected error for azure replica without account name")
+		}
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		factory := NewDefaultReplicaClientFactory()
+		if _, err := factory.CreateClient(&ReplicaConfig{Type: "azure", Bucket: "backups", AccountName: "myaccount"}, "/tmp/test.db"); err == nil {
+			t.Error("expected error without an Azure client factory")
+		}
+	})
+}
+
 // createTestDB creates a simple SQLite database for testing
+func TestHotColdManagerReplicaOverrides(t *testing.T) {
+	factory := &MockReplicaClientFactory{}
+	manager := &HotColdManager{
+		replicaFactory: factory,
+		replicaTemplate: &ReplicaConfig{
+			Type:         "s3",
+			Bucket:       "backups",
+			Path:         "{{project}}/{{tenant}}",
+			SyncInterval: 30 * time.Second,
+		},
+		replicaOverrides: []ReplicaOverride{
+			{Pattern: "/data/*/databases/*/branches/*/tenants/*.db", ReplicaConfig: ReplicaConfig{SyncInterval: 10 * time.Second}},
+			{Pattern: "/data/critical/databases/*/branches/*/tenants/*.db", ReplicaConfig: ReplicaConfig{SyncInterval: time.Second}},
+			{Pattern: "/data/*/databases/*/branches/*/tenants/archive.db", ReplicaConfig: ReplicaConfig{Bucket: "archive", StorageClass: "GLACIER_IR"}},
+		},
+	}
+
+	for _, tt := range []struct {
+		path         string
+		syncInterval time.Duration
+		bucket       string
+		storageClass string
+	}{
+		{"/other/app.db", 30 * time.Second, "backups", ""},
+		{"/data/proj/databases/db/branches/main/tenants/t1.db", 10 * time.Second, "backups", ""},
+		{"/data/critical/databases/db/branches/main/tenants/t1.db", time.Second, "backups", ""},
+		{"/data/proj/databases/db/branches/main/tenants/archive.db", 30 * time.Second, "archive", "GLACIER_IR"},
+	} {
+		replica, err := manager.createReplicaForDB(litestream.NewDB(tt.path), tt.path)
+		if err != nil {
+			t.Fatal(err)
+		} else if replica == nil {
+			t.Fatalf("expected replica for %s", tt.path)
+		}
+		if replica.SyncInterval != tt.syncInterval {
+			t.Errorf("%s: expected sync interval %s, got %s", tt.path, tt.syncInterval, replica.SyncInterval)
+		}
+		if config := manager.replicaConfigFor(tt.path); config.Bucket != tt.bucket {
+			t.Errorf("%s: expected bucket %q, got %q", tt.path, tt.bucket, config.Bucket)
+		} else if config.StorageClass != tt.storageClass {
+			t.Errorf("%s: expected storage class %q, got %q", tt.path, tt.storageClass, config.StorageClass)
+		}
+	}
+
+	// Overrides alone enable replication for the databases they match
+	manager.replicaTemplate = nil
+	manager.replicaOverrides = []ReplicaOverride{
+		{Pattern: "/data/*.db", ReplicaConfig: ReplicaConfig{Type: "s3", Bucket: "only"}},
+	}
+	if replica, err := manager.createReplicaForDB(litestream.NewDB("/other/app.db"), "/other/app.db"); err != nil || replica != nil {
+		t.Errorf("expected no replica without a matching override, got %v, %v", replica, err)
+	}
+	if config := manager.replicaConfigFor("/data/app.db"); config == nil || config.Bucket != "only" {
+		t.Errorf("expected override config, got %+v", config)
+	}
+}
+
+// promoteAndWait promotes a database and waits for it to be opened
+func promoteAndWait(m *HotColdManager, path string) error {
+	if err := m.promoteToHot(path); err != nil {
+		return err
+	}
+	return m.waitPromotion(path)
+}
+
 func createTestDB(path string) error {
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
@@ -256,4 +526,425 @@ func TestHotColdManagerPathTemplateExpansion(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// hangingReplicaClient blocks every listing until the context is done,
+// simulating an unreachable replica destination
+type hangingReplicaClient struct {
+	MockReplicaClient
+}
+
+func (c *hangingReplicaClient) LTXFiles(ctx context.Context, level int, seek ltx.TXID) (ltx.FileIterator, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// replicaClientFactoryFunc adapts a function to ReplicaClientFactory
+type replicaClientFactoryFunc func(config *ReplicaConfig, path string) (litestream.ReplicaClient, error)
+
+func (f replicaClientFactoryFunc) CreateClient(config *ReplicaConfig, path string) (litestream.ReplicaClient, error) {
+	return f(config, path)
+}
+
+func TestHotColdManagerDemotionSyncTimeout(t *testing.T) {
+	dir := t.TempDir()
+
+	manager := NewHotColdManager(&HotColdConfig{
+		MaxHotDatabases: 10,
+		ScanInterval:    1 * time.Second,
+		HotDuration:     5 * time.Second,
+		ReplicaTemplate: &ReplicaConfig{
+			Type:         "mock",
+			Path:         "test/{{filename}}",
+			SyncInterval: 1 * time.Second,
+		},
+		ReplicaFactory: replicaClientFactoryFunc(func(config *ReplicaConfig, path string) (litestream.ReplicaClient, error) {
+			if filepath.Base(path) == "slow.db" {
+				return &hangingReplicaClient{MockReplicaClient{Type_: "mock"}}, nil
+			}
+			return &MockReplicaClient{Type_: "mock"}, nil
+		}),
+		DemotionSyncTimeout: 200 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	slowPath := filepath.Join(dir, "slow.db")
+	otherPath := filepath.Join(dir, "other.db")
+	for _, path := range []string{slowPath, otherPath} {
+		if err := createTestDB(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := promoteAndWait(manager, slowPath); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- manager.demoteToCold(slowPath) }()
+
+	// Other databases can be promoted while the final sync hangs
+	time.Sleep(50 * time.Millisecond)
+	if err := manager.promoteToHot(otherPath); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+		t.Fatal("demotion should still be waiting on the final sync")
+	default:
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("demotion did not complete after the sync timeout")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected demotion to wait for the sync timeout, took %s", elapsed)
+	}
+	if manager.IsHot(slowPath) {
+		t.Error("expected slow.db to be cold after demotion")
+	}
+	if !manager.IsHot(otherPath) {
+		t.Error("expected other.db to be hot")
+	}
+}
+
+func TestHotColdManagerDemotionReleasesDetector(t *testing.T) {
+	dir := t.TempDir()
+
+	manager := NewHotColdManager(&HotColdConfig{
+		MaxHotDatabases: 10,
+		ScanInterval:    time.Hour,
+		HotDuration:     time.Hour,
+		ReplicaTemplate: &ReplicaConfig{
+			Type:         "mock",
+			Path:         "test/{{filename}}",
+			SyncInterval: 1 * time.Second,
+		},
+		ReplicaFactory: replicaClientFactoryFunc(func(config *ReplicaConfig, path string) (litestream.ReplicaClient, error) {
+			if filepath.Base(path) == "slow.db" {
+				return &hangingReplicaClient{MockReplicaClient{Type_: "mock"}}, nil
+			}
+			return &MockReplicaClient{Type_: "mock"}, nil
+		}),
+		DemotionSyncTimeout: 2 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	slowPath := filepath.Join(dir, "slow.db")
+	otherPath := filepath.Join(dir, "other.db")
+	for _, path := range []string{slowPath, otherPath} {
+		if err := createTestDB(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manager.AddDatabases([]string{filepath.Join(dir, "*.db")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.PromoteNow(slowPath); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- manager.DemoteNow(slowPath) }()
+
+	// The detector's lock is released while the final sync hangs, so
+	// other databases can still be promoted through it
+	time.Sleep(50 * time.Millisecond)
+	promoted := make(chan error, 1)
+	go func() { promoted <- manager.PromoteNow(otherPath) }()
+	select {
+	case err := <-promoted:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("promotion blocked behind the demotion's final sync")
+	}
+	select {
+	case <-done:
+		t.Fatal("demotion should still be waiting on the final sync")
+	default:
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("demotion did not complete after the sync timeout")
+	}
+	if manager.IsHot(slowPath) {
+		t.Error("expected slow.db to be cold after demotion")
+	}
+	if !manager.IsHot(otherPath) {
+		t.Error("expected other.db to be hot")
+	}
+}
+
+func TestHotColdManagerProjectMetricsUnchanged(t *testing.T) {
+	metrics := NewHierarchicalMetricsWithRegistry(nil)
+	manager := NewHotColdManager(&HotColdConfig{Metrics: metrics})
+
+	path := "/data/proj1/databases/db1/branches/main/tenants/tenant1.db"
+	manager.mu.Lock()
+	manager.trackColdLocked(path)
+	manager.mu.Unlock()
+
+	lastUpdated := func() time.Time {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		if ps, ok := metrics.projectStats["proj1"]; ok {
+			return ps.LastUpdated
+		}
+		return time.Time{}
+	}
+
+	manager.updateMetrics()
+	first := lastUpdated()
+	if first.IsZero() {
+		t.Fatal("expected project stats after the first update")
+	}
+
+	// An unchanged tracked set skips the per-project aggregation
+	manager.updateMetrics()
+	if got := lastUpdated(); !got.Equal(first) {
+		t.Errorf("expected project stats to be left alone, updated at %s", got)
+	}
+
+	// Tracking another database recomputes them
+	manager.mu.Lock()
+	manager.trackColdLocked("/data/proj1/databases/db1/branches/main/tenants/tenant2.db")
+	manager.mu.Unlock()
+	manager.updateMetrics()
+	if got := lastUpdated(); got.Equal(first) {
+		t.Error("expected project stats to be recomputed after a change")
+	}
+	if ps := metrics.projectStats["proj1"]; ps.TotalDBs != 2 {
+		t.Errorf("expected 2 databases in proj1, got %d", ps.TotalDBs)
+	}
+}
+
+func TestHotColdManagerReplicaStartRetry(t *testing.T) {
+	dir := t.TempDir()
+	reg := prometheus.NewRegistry()
+
+	// Every start fails until the factory is healed
+	var mu sync.Mutex
+	var calls int
+	healed := false
+	manager := NewHotColdManager(&HotColdConfig{
+		MaxHotDatabases: 10,
+		ScanInterval:    time.Hour,
+		HotDuration:     time.Hour,
+		ReplicaTemplate: &ReplicaConfig{Type: "mock", Path: "test/{{filename}}"},
+		ReplicaFactory: replicaClientFactoryFunc(func(config *ReplicaConfig, path string) (litestream.ReplicaClient, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if !healed {
+				return nil, errors.New("service unavailable")
+			}
+			return &MockReplicaClient{Type_: "mock"}, nil
+		}),
+		SharedResources:           NewSharedResourceManager(),
+		ReplicaStartRetryInterval: 20 * time.Millisecond,
+		Metrics:                   NewHierarchicalMetricsWithRegistry(reg),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	path := filepath.Join(dir, "test.db")
+	if err := createTestDB(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// The promotion succeeds without a replica after retrying inline
+	if err := promoteAndWait(manager, path); err != nil {
+		t.Fatal(err)
+	}
+	if !manager.IsHot(path) {
+		t.Fatal("expected database to be hot without a replica")
+	}
+	mu.Lock()
+	if calls != replicaStartAttempts {
+		t.Errorf("expected %d start attempts during promotion, got %d", replicaStartAttempts, calls)
+	}
+	mu.Unlock()
+
+	waitUntil := func(cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for replica start retries")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	replicaStarted := func() bool {
+		manager.mu.RLock()
+		defer manager.mu.RUnlock()
+		_, ok := manager.hotReplicas[path]
+		return ok
+	}
+
+	// Background retries keep failing, each counted, until the start succeeds
+	waitUntil(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls >= replicaStartAttempts+2
+	})
+	manager.updateMetrics()
+	errorsTotal, unreplicated := replicaStartMetrics(t, reg)
+	if errorsTotal < 2 || unreplicated != 1 {
+		t.Errorf("expected repeated start errors and 1 unreplicated database, got %v and %v", errorsTotal, unreplicated)
+	}
+
+	mu.Lock()
+	healed = true
+	mu.Unlock()
+	waitUntil(replicaStarted)
+
+	manager.updateMetrics()
+	if _, unreplicated := replicaStartMetrics(t, reg); unreplicated != 0 {
+		t.Errorf("expected no unreplicated databases after the retry, got %v", unreplicated)
+	}
+}
+
+// replicaStartMetrics returns the replica start error count and the number
+// of unreplicated hot databases gathered from reg
+func replicaStartMetrics(t *testing.T, reg *prometheus.Registry) (errorsTotal, unreplicated float64) {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			switch mf.GetName() {
+			case "litestream_replica_start_errors_total":
+				errorsTotal = m.GetCounter().GetValue()
+			case "litestream_unreplicated_hot_databases":
+				unreplicated = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return errorsTotal, unreplicated
+}
+
+func TestHotColdManagerColdSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	store := litestream.NewStore(nil, litestream.CompactionLevels{})
+	manager := NewHotColdManager(&HotColdConfig{
+		MaxHotDatabases: 10,
+		ScanInterval:    time.Hour,
+		HotDuration:     time.Hour,
+		Store:           store,
+		SharedResources: NewSharedResourceManager(),
+		ReplicaTemplate: &ReplicaConfig{
+			Type: "file",
+			Path: dir + "/replica/{{filename}}",
+		},
+		ReplicaFactory:   NewDefaultReplicaClientFactory(),
+		ColdSyncInterval: time.Hour,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	path := dir + "/test.db"
+	if err := createTestDB(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.AddDatabases([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+
+	lastSnapshot := func() time.Time {
+		infos := manager.GetColdDatabases()
+		if len(infos) != 1 {
+			t.Fatalf("expected 1 cold database, got %d", len(infos))
+		}
+		return infos[0].LastSnapshot
+	}
+
+	manager.snapshotColdDatabases(ctx)
+	first := lastSnapshot()
+	if first.IsZero() {
+		t.Fatal("expected the cold database to be snapshotted")
+	}
+	snapshotDir := litestream.LTXLevelDir(dir+"/replica/test", litestream.SnapshotLevel)
+	if entries, _ := os.ReadDir(snapshotDir); len(entries) == 0 {
+		t.Errorf("expected a snapshot in %s", snapshotDir)
+	}
+
+	// The database was only open for the snapshot, and its own changes to
+	// the file don't count as a write
+	if len(store.DBs()) != 0 {
+		t.Error("expected the snapshotted database not to be registered with the store")
+	}
+	manager.writeDetector.performScan()
+	if manager.IsHot(path) {
+		t.Error("expected the database to stay cold after its snapshot")
+	}
+
+	// An unchanged database is not snapshotted again
+	manager.snapshotColdDatabases(ctx)
+	if got := lastSnapshot(); !got.Equal(first) {
+		t.Errorf("expected no second snapshot of an unchanged database, got one at %s", got)
+	}
+
+	// Promotion cancels a running snapshot and waits for it to close the
+	// database
+	manager.mu.Lock()
+	snap := &coldSnapshot{done: make(chan struct{})}
+	var canceled bool
+	snap.cancel = func() {
+		canceled = true
+		go func() {
+			manager.mu.Lock()
+			delete(manager.coldSnapshots, path)
+			manager.mu.Unlock()
+			close(snap.done)
+		}()
+	}
+	manager.coldSnapshots[path] = snap
+	manager.mu.Unlock()
+
+	if err := manager.promoteToHot(path); err != nil {
+		t.Fatal(err)
+	}
+	if !canceled || !manager.IsHot(path) {
+		t.Errorf("expected the snapshot canceled and the database hot, got canceled=%v hot=%v", canceled, manager.IsHot(path))
+	}
+}
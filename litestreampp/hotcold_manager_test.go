@@ -2,15 +2,20 @@ package litestreampp_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/litestream"
 	"github.com/benbjohnson/litestream/litestreampp"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestHotColdManager(t *testing.T) {
@@ -53,7 +58,7 @@ func TestHotColdManager(t *testing.T) {
 		}
 
 		// Initially both should be cold
-		total, hot, cold := manager.GetStatistics()
+		total, hot, cold, _, _ := manager.GetStatistics()
 		if total != 2 {
 			t.Errorf("expected 2 total databases, got %d", total)
 		}
@@ -75,8 +80,20 @@ func TestHotColdManager(t *testing.T) {
 		if !manager.IsHot(db1) {
 			t.Error("db1 should be hot after modification")
 		}
+		if tier, ok := manager.GetDatabaseTier(db1); !ok || tier != litestreampp.TierHot {
+			t.Errorf("expected db1 tier %q, got %q (ok=%v)", litestreampp.TierHot, tier, ok)
+		}
+		if tier, ok := manager.GetDatabaseTier(db2); !ok || tier != litestreampp.TierCold {
+			t.Errorf("expected db2 tier %q, got %q (ok=%v)", litestreampp.TierCold, tier, ok)
+		}
+		if _, ok := manager.GetDatabaseTier(filepath.Join(tmpDir, "missing.db")); ok {
+			t.Error("expected untracked database to have no tier")
+		}
+		if coldDBs := manager.GetColdDatabases(); len(coldDBs) != 1 || coldDBs[0].Path != db2 {
+			t.Errorf("expected only db2 to be cold, got %+v", coldDBs)
+		}
 
-		total, hot, cold = manager.GetStatistics()
+		total, hot, cold, _, _ = manager.GetStatistics()
 		if hot != 1 {
 			t.Errorf("expected 1 hot database after modification, got %d", hot)
 		}
@@ -92,7 +109,7 @@ func TestHotColdManager(t *testing.T) {
 			t.Error("db1 should be cold after hot duration expired")
 		}
 
-		total, hot, cold = manager.GetStatistics()
+		total, hot, cold, _, _ = manager.GetStatistics()
 		if hot != 0 {
 			t.Errorf("expected 0 hot databases after expiry, got %d", hot)
 		}
@@ -143,7 +160,7 @@ func TestHotColdManager(t *testing.T) {
 		time.Sleep(200 * time.Millisecond)
 
 		// Only 3 should be hot
-		total, hot, cold := manager.GetStatistics()
+		total, hot, cold, _, _ := manager.GetStatistics()
 		if total != 5 {
 			t.Errorf("expected 5 total databases, got %d", total)
 		}
@@ -190,7 +207,7 @@ func TestHotColdManager(t *testing.T) {
 		manager.AddDatabases([]string{pattern})
 
 		// Verify discovery
-		total, _, _ := manager.GetStatistics()
+		total, _, _, _, _ := manager.GetStatistics()
 		if total != 2 {
 			t.Errorf("expected 2 databases discovered, got %d", total)
 		}
@@ -207,6 +224,612 @@ func TestHotColdManager(t *testing.T) {
 			t.Error("tenant2.db should remain cold")
 		}
 	})
+
+	t.Run("RecursivePattern", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "project1", "databases", "db1", "branches", "main", "tenants", "tenant1.db")
+		db2 := filepath.Join(tmpDir, "project2", "tenant2.db")
+		for _, path := range []string{db1, db2} {
+			os.MkdirAll(filepath.Dir(path), 0755)
+			createTestDB(t, path)
+		}
+
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    100 * time.Millisecond,
+			HotDuration:     200 * time.Millisecond,
+			Store:           litestream.NewStore(nil, litestream.CompactionLevels{}),
+			SharedResources: litestreampp.NewSharedResourceManager(),
+			ConnectionPool:  litestreampp.NewConnectionPool(10, 5*time.Second),
+		})
+
+		if err := manager.AddDatabases([]string{filepath.Join(tmpDir, "**", "*.db")}); err != nil {
+			t.Fatalf("failed to add databases: %v", err)
+		}
+		if total, _, cold, _, _ := manager.GetStatistics(); total != 2 || cold != 2 {
+			t.Errorf("expected 2 cold databases discovered, got total=%d cold=%d", total, cold)
+		}
+	})
+
+	t.Run("ExcludePatterns", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db := filepath.Join(tmpDir, "app.db")
+		fixture := filepath.Join(tmpDir, "users.fixture.db")
+		staged := filepath.Join(tmpDir, "staging", "import.db")
+		wal := filepath.Join(tmpDir, "app.db-wal")
+		for _, path := range []string{db, fixture, staged, wal} {
+			createTestDB(t, path)
+		}
+
+		var mu sync.Mutex
+		var promoted []string
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    50 * time.Millisecond,
+			HotDuration:     time.Minute,
+			Store:           litestream.NewStore(nil, litestream.CompactionLevels{}),
+			SharedResources: litestreampp.NewSharedResourceManager(),
+			ConnectionPool:  litestreampp.NewConnectionPool(10, 5*time.Second),
+			ExcludePatterns: []string{"*.fixture.db", filepath.Join(tmpDir, "staging", "**")},
+			OnTierChange: func(path, from, to string) {
+				mu.Lock()
+				defer mu.Unlock()
+				if to == litestreampp.TierHot {
+					promoted = append(promoted, path)
+				}
+			},
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := manager.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		defer manager.Stop()
+
+		if err := manager.AddDatabases([]string{filepath.Join(tmpDir, "**", "*.db*")}); err != nil {
+			t.Fatalf("failed to add databases: %v", err)
+		}
+		if total, _, _, _, _ := manager.GetStatistics(); total != 1 {
+			t.Fatalf("expected only app.db to be tracked, got %d databases", total)
+		}
+
+		// Writes to excluded files never promote them
+		for _, path := range []string{fixture, staged, wal} {
+			modifyTestDB(t, path)
+			if err := manager.PromoteNow(path); err == nil {
+				t.Errorf("expected PromoteNow of excluded %s to fail", path)
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+
+		for _, path := range []string{fixture, staged, wal} {
+			if _, ok := manager.GetDatabaseTier(path); ok {
+				t.Errorf("excluded %s is tracked", path)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, path := range promoted {
+			if path != db {
+				t.Errorf("excluded %s was promoted", path)
+			}
+		}
+	})
+
+	t.Run("ProjectQuotas", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		// Four tenants in a busy project and two in a quiet one
+		var dbs []string
+		for project, tenants := range map[string]int{"busy": 4, "quiet": 2} {
+			projectPath := filepath.Join(tmpDir, project, "databases", "maindb", "branches", "main", "tenants")
+			os.MkdirAll(projectPath, 0755)
+			for i := 0; i < tenants; i++ {
+				db := filepath.Join(projectPath, fmt.Sprintf("tenant%d.db", i))
+				createTestDB(t, db)
+				dbs = append(dbs, db)
+			}
+		}
+
+		config := &litestreampp.HotColdConfig{
+			MaxHotDatabases:      10,
+			DefaultProjectMaxHot: 2,
+			ScanInterval:         100 * time.Millisecond,
+			HotDuration:          time.Hour,
+			Store:                litestream.NewStore(nil, litestream.CompactionLevels{}),
+			SharedResources:      litestreampp.NewSharedResourceManager(),
+			ConnectionPool:       litestreampp.NewConnectionPool(10, 5*time.Second),
+		}
+
+		manager := litestreampp.NewHotColdManager(config)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		manager.Start(ctx)
+		defer manager.Stop()
+
+		pattern := filepath.Join(tmpDir, "*", "databases", "*", "branches", "*", "tenants", "*.db")
+		manager.AddDatabases([]string{pattern})
+
+		for _, db := range dbs {
+			modifyTestDB(t, db)
+		}
+		time.Sleep(300 * time.Millisecond)
+
+		// The busy project is held to its quota without displacing the quiet one
+		counts := manager.GetProjectHotCounts()
+		if counts["busy"] != 2 {
+			t.Errorf("expected 2 hot busy databases, got %d", counts["busy"])
+		}
+		if counts["quiet"] != 2 {
+			t.Errorf("expected 2 hot quiet databases, got %d", counts["quiet"])
+		}
+	})
+
+	t.Run("TierChangeEvents", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		createTestDB(t, db1)
+
+		var mu sync.Mutex
+		var events []string
+		var manager *litestreampp.HotColdManager
+		manager = litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    100 * time.Millisecond,
+			HotDuration:     time.Hour,
+			OnTierChange: func(path, from, to string) {
+				// The lock is released, so the callback may query the manager
+				hot := manager.IsHot(path)
+
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, fmt.Sprintf("%s:%s->%s:%v", filepath.Base(path), from, to, hot))
+			},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		manager.Start(ctx)
+		defer manager.Stop()
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		if err := manager.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.DemoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, _, promotions, demotions := manager.GetStatistics(); promotions != 1 || demotions != 1 {
+			t.Errorf("expected 1 promotion and 1 demotion, got %d and %d", promotions, demotions)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		want := []string{"db1.db:cold->hot:true", "db1.db:hot->cold:false"}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("expected events %v, got %v", want, events)
+		}
+	})
+
+	t.Run("AccessCountThreshold", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		db2 := filepath.Join(tmpDir, "db2.db")
+		createTestDB(t, db1)
+		createTestDB(t, db2)
+
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases:      10,
+			ScanInterval:         50 * time.Millisecond,
+			HotDuration:          time.Hour,
+			AccessCountThreshold: 3,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		manager.Start(ctx)
+		defer manager.Stop()
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		// Accesses below the threshold leave it cold
+		for i := 0; i < 2; i++ {
+			if err := manager.RecordAccess(db1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		time.Sleep(150 * time.Millisecond)
+		if manager.IsHot(db1) {
+			t.Fatal("db1 should stay cold below the access threshold")
+		}
+
+		// Earlier accesses were seen by a scan, so it takes 3 more
+		for i := 0; i < 3; i++ {
+			manager.RecordAccess(db1)
+		}
+		time.Sleep(150 * time.Millisecond)
+		if !manager.IsHot(db1) {
+			t.Fatal("db1 should be promoted after reaching the access threshold")
+		}
+
+		if err := manager.PromoteNow(db2); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			manager.RecordAccess(db2)
+		}
+
+		// Counts recorded while cold carry over to the hot tier
+		byAccess := manager.GetHotDatabasesByAccess()
+		if len(byAccess) != 2 {
+			t.Fatalf("expected 2 hot databases, got %+v", byAccess)
+		}
+		if byAccess[0].Path != db2 || byAccess[0].AccessCount != 10 {
+			t.Errorf("expected db2 most accessed with 10, got %+v", byAccess[0])
+		}
+		if byAccess[1].Path != db1 || byAccess[1].AccessCount != 5 {
+			t.Errorf("expected db1 least accessed with 5, got %+v", byAccess[1])
+		}
+
+		if err := manager.RecordAccess(filepath.Join(tmpDir, "missing.db")); err == nil {
+			t.Error("expected error recording access to an untracked database")
+		}
+	})
+
+	t.Run("EventsChannel", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		createTestDB(t, db1)
+
+		newManager := func(bufferSize int) *litestreampp.HotColdManager {
+			manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+				MaxHotDatabases: 10,
+				ScanInterval:    100 * time.Millisecond,
+				HotDuration:     time.Hour,
+				ReplicaTemplate: &litestreampp.ReplicaConfig{
+					Type: "file",
+					Path: filepath.Join(tmpDir, "replica", "{{filename}}"),
+				},
+				ReplicaFactory:  litestreampp.NewDefaultReplicaClientFactory(),
+				EventBufferSize: bufferSize,
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			manager.Start(ctx)
+			t.Cleanup(func() { manager.Stop() })
+			manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+			return manager
+		}
+
+		manager := newManager(10)
+		if err := manager.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.DemoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for len(got) < 4 {
+			select {
+			case e := <-manager.Events():
+				if e.Path != db1 || e.Time.IsZero() || e.Err != nil {
+					t.Errorf("unexpected event: %+v", e)
+				}
+				got = append(got, fmt.Sprintf("%s:%s", e.Type, e.Tier))
+			case <-time.After(time.Second):
+				t.Fatalf("timed out waiting for events, got %v", got)
+			}
+		}
+		want := []string{"promoted:hot", "replica_started:hot", "replica_stopped:cold", "demoted:cold"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected events %v, got %v", want, got)
+		}
+
+		// Events beyond the buffer are dropped rather than blocking
+		manager = newManager(1)
+		if err := manager.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		if got := manager.DroppedEvents(); got != 1 {
+			t.Errorf("expected 1 dropped event, got %d", got)
+		}
+		if e := <-manager.Events(); e.Type != litestreampp.TierEventPromoted {
+			t.Errorf("expected buffered promoted event, got %s", e.Type)
+		}
+
+		if litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{}).Events() != nil {
+			t.Error("expected nil events channel when disabled")
+		}
+	})
+
+	t.Run("StoreRegistration", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		createTestDB(t, db1)
+
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    100 * time.Millisecond,
+			HotDuration:     time.Hour,
+			Store:           store,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		manager.Start(ctx)
+		defer manager.Stop()
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		inStore := func() bool {
+			for _, db := range store.DBs() {
+				if db.Path() == db1 {
+					return true
+				}
+			}
+			return false
+		}
+
+		if err := manager.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		if !inStore() {
+			t.Error("promoted database should be registered with the store")
+		}
+
+		if err := manager.DemoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		if inStore() {
+			t.Error("demoted database should be removed from the store")
+		}
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		createTestDB(t, db1)
+
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    100 * time.Millisecond,
+			HotDuration:     time.Hour,
+			Store:           store,
+			ReplicaTemplate: &litestreampp.ReplicaConfig{
+				Type: "file",
+				Path: filepath.Join(tmpDir, "replica", "{{filename}}"),
+			},
+			ReplicaFactory:  litestreampp.NewDefaultReplicaClientFactory(),
+			EventBufferSize: 10,
+			DryRun:          true,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		manager.Start(ctx)
+		defer manager.Stop()
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		if err := manager.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		if _, hot, cold, promotions, _ := manager.GetStatistics(); hot != 1 || cold != 0 || promotions != 1 {
+			t.Errorf("expected the planned promotion reported, got %d hot, %d cold, %d promotions", hot, cold, promotions)
+		}
+		if e := <-manager.Events(); e.Type != litestreampp.TierEventPromoted || !e.DryRun {
+			t.Errorf("expected a dry run promoted event, got %+v", e)
+		}
+
+		// Nothing was opened, registered, or replicated
+		if n := len(store.DBs()); n != 0 {
+			t.Errorf("expected no databases registered with the store, got %d", n)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, "replica")); !os.IsNotExist(err) {
+			t.Errorf("expected no replica, got %v", err)
+		}
+
+		if err := manager.DemoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		if _, hot, cold, _, demotions := manager.GetStatistics(); hot != 0 || cold != 1 || demotions != 1 {
+			t.Errorf("expected the planned demotion reported, got %d hot, %d cold, %d demotions", hot, cold, demotions)
+		}
+		if e := <-manager.Events(); e.Type != litestreampp.TierEventDemoted || !e.DryRun {
+			t.Errorf("expected a dry run demoted event, got %+v", e)
+		}
+	})
+
+	t.Run("Paused", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		db2 := filepath.Join(tmpDir, "db2.db")
+		createTestDB(t, db1)
+		createTestDB(t, db2)
+
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    50 * time.Millisecond,
+			HotDuration:     200 * time.Millisecond,
+			Store:           store,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		manager.Start(ctx)
+		defer manager.Stop()
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		if err := manager.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		manager.Pause()
+		if !manager.Paused() {
+			t.Fatal("expected manager to be paused")
+		}
+
+		// Neither a write nor an expired hot duration changes tiers
+		modifyTestDB(t, db2)
+		time.Sleep(500 * time.Millisecond)
+		if tier, _ := manager.GetDatabaseTier(db1); tier != litestreampp.TierHot {
+			t.Errorf("expected db1 to stay hot while paused, got %s", tier)
+		}
+		if tier, _ := manager.GetDatabaseTier(db2); tier != litestreampp.TierCold {
+			t.Errorf("expected db2 to stay cold while paused, got %s", tier)
+		}
+		if err := manager.PromoteNow(db2); !errors.Is(err, litestreampp.ErrPaused) {
+			t.Errorf("expected ErrPaused, got %v", err)
+		}
+
+		// The write made while paused promotes db2 on resume
+		manager.Resume()
+		waitFor(t, 2*time.Second, func() bool {
+			tier, _ := manager.GetDatabaseTier(db2)
+			return tier == litestreampp.TierHot
+		})
+		if manager.Paused() {
+			t.Error("expected manager to be resumed")
+		}
+	})
+
+	t.Run("AddDatabasesErrors", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		createTestDB(t, db1)
+
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    time.Hour,
+			HotDuration:     time.Hour,
+		})
+
+		err := manager.AddDatabases([]string{filepath.Join(tmpDir, "[*.db"), filepath.Join(tmpDir, "*.db")})
+		var patternErr *litestreampp.PatternError
+		if !errors.As(err, &patternErr) {
+			t.Fatalf("expected a pattern error, got %v", err)
+		}
+		if tier, ok := manager.GetDatabaseTier(db1); !ok || tier != litestreampp.TierCold {
+			t.Errorf("expected db1 tracked as cold despite the error, got %q", tier)
+		}
+	})
+
+	t.Run("PromotionStorm", func(t *testing.T) {
+		const n = 1000
+		tmpDir := t.TempDir()
+		paths := make([]string, n)
+		for i := range paths {
+			paths[i] = filepath.Join(tmpDir, fmt.Sprintf("db%04d.db", i))
+			if err := os.WriteFile(paths[i], nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases:         2 * n,
+			ScanInterval:            50 * time.Millisecond,
+			HotDuration:             time.Hour,
+			Store:                   store,
+			SharedResources:         litestreampp.NewSharedResourceManager(),
+			MaxConcurrentPromotions: 8,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		manager.Start(ctx)
+		defer manager.Stop()
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		// Touch every database at once, as a batch job would
+		later := time.Now().Add(time.Minute)
+		for _, path := range paths {
+			if err := os.Chtimes(path, later, later); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// IsHot keeps answering promptly while the promotions open
+		start := time.Now()
+		var slowest time.Duration
+		for len(store.DBs()) < n {
+			if time.Since(start) > 30*time.Second {
+				t.Fatalf("timed out with %d of %d databases opened", len(store.DBs()), n)
+			}
+			callStart := time.Now()
+			manager.IsHot(paths[n-1])
+			slowest = max(slowest, time.Since(callStart))
+			time.Sleep(time.Millisecond)
+		}
+		if slowest > 100*time.Millisecond {
+			t.Errorf("expected IsHot to stay responsive during promotions, slowest call took %s", slowest)
+		}
+		if _, hot, _, promotions, _ := manager.GetStatistics(); hot != n || promotions != n {
+			t.Errorf("expected %d hot databases and promotions, got %d hot, %d promotions", n, hot, promotions)
+		}
+	})
+
+	t.Run("SeparateMetrics", func(t *testing.T) {
+		// hotSeries counts the hot tenant series gathered from reg
+		hotSeries := func(t *testing.T, reg *prometheus.Registry) int {
+			t.Helper()
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, mf := range families {
+				if mf.GetName() == "litestream_database_hot_tenants" {
+					return len(mf.GetMetric())
+				}
+			}
+			return 0
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Two managers in one process, each with its own registry
+		var regs []*prometheus.Registry
+		var managers []*litestreampp.HotColdManager
+		for i := 0; i < 2; i++ {
+			reg := prometheus.NewRegistry()
+			manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+				MaxHotDatabases: 10,
+				ScanInterval:    time.Hour,
+				HotDuration:     time.Hour,
+				Store:           litestream.NewStore(nil, litestream.CompactionLevels{}),
+				Metrics:         litestreampp.NewHierarchicalMetricsWithRegistry(reg),
+			})
+			manager.Start(ctx)
+			defer manager.Stop()
+			regs, managers = append(regs, reg), append(managers, manager)
+		}
+
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		createTestDB(t, db1)
+		managers[0].AddDatabases([]string{db1})
+		if err := managers[0].PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := hotSeries(t, regs[0]); n != 1 {
+			t.Errorf("expected 1 hot tenant series in the first registry, got %d", n)
+		}
+		if n := hotSeries(t, regs[1]); n != 0 {
+			t.Errorf("expected no hot tenant series in the second registry, got %d", n)
+		}
+	})
 }
 
 func TestHotColdManagerIntegration(t *testing.T) {
@@ -286,6 +909,190 @@ func TestHotColdManagerIntegration(t *testing.T) {
 		// Connections might still be open depending on timing
 		t.Logf("Final connection pool stats: %+v", stats)
 	})
+
+	t.Run("StatePersistence", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		db2 := filepath.Join(tmpDir, "db2.db")
+		db3 := filepath.Join(tmpDir, "db3.db")
+		stateFile := filepath.Join(tmpDir, "state.json")
+
+		createTestDB(t, db1)
+		createTestDB(t, db2)
+		createTestDB(t, db3)
+
+		newManager := func() *litestreampp.HotColdManager {
+			return litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+				MaxHotDatabases: 10,
+				ScanInterval:    100 * time.Millisecond,
+				HotDuration:     time.Hour,
+				StateFile:       stateFile,
+			})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// First run: db1 hot, db2 and db3 cold
+		manager := newManager()
+		manager.Start(ctx)
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+		if err := manager.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+		manager.Stop()
+
+		// db2 is written while the process is down
+		modifyTestDB(t, db2)
+
+		// Second run: db1 is hot before any write or AddDatabases call, and
+		// the downtime write to db2 is detected on the first scan
+		manager = newManager()
+		manager.Start(ctx)
+		defer manager.Stop()
+
+		if !manager.IsHot(db1) {
+			t.Error("db1 should be hot after restart")
+		}
+		time.Sleep(200 * time.Millisecond)
+		if !manager.IsHot(db2) {
+			t.Error("db2 should be promoted for its downtime write")
+		}
+		if manager.IsHot(db3) {
+			t.Error("db3 should stay cold")
+		}
+		if total, _, _, _, _ := manager.GetStatistics(); total != 3 {
+			t.Errorf("expected 3 tracked databases, got %d", total)
+		}
+	})
+
+	t.Run("CorruptStateFile", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		stateFile := filepath.Join(tmpDir, "state.json")
+		createTestDB(t, db1)
+
+		if err := os.WriteFile(stateFile, []byte("{not json"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    100 * time.Millisecond,
+			HotDuration:     time.Hour,
+			StateFile:       stateFile,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := manager.Start(ctx); err != nil {
+			t.Fatalf("start with corrupt state file: %v", err)
+		}
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		if manager.IsHot(db1) {
+			t.Error("db1 should start cold")
+		}
+
+		// Stop replaces the corrupt file with valid state
+		manager.Stop()
+		data, err := os.ReadFile(stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid(data) {
+			t.Errorf("expected valid state file after stop, got %q", data)
+		}
+	})
+
+	t.Run("StateFilteredByPatterns", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		kept := filepath.Join(tmpDir, "kept.db")
+		excluded := filepath.Join(tmpDir, "excluded.tmp.db")
+		unmatched := filepath.Join(tmpDir, "other", "unmatched.db")
+		stateFile := filepath.Join(tmpDir, "state.json")
+
+		if err := os.Mkdir(filepath.Dir(unmatched), 0755); err != nil {
+			t.Fatal(err)
+		}
+		createTestDB(t, kept)
+		createTestDB(t, excluded)
+		createTestDB(t, unmatched)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// First run tracks and promotes all three
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    time.Hour,
+			HotDuration:     time.Hour,
+			StateFile:       stateFile,
+		})
+		manager.Start(ctx)
+		if err := manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db"), unmatched}); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{kept, excluded, unmatched} {
+			if err := manager.PromoteNow(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+		manager.Stop()
+
+		// The restart narrows the patterns and excludes temporary databases
+		manager = litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases: 10,
+			ScanInterval:    time.Hour,
+			HotDuration:     time.Hour,
+			StateFile:       stateFile,
+			Patterns:        []string{filepath.Join(tmpDir, "*.db")},
+			ExcludePatterns: []string{"*.tmp.db"},
+		})
+		manager.Start(ctx)
+		defer manager.Stop()
+
+		if !manager.IsHot(kept) {
+			t.Error("kept.db should be hot after restart")
+		}
+		if total, _, _, _, _ := manager.GetStatistics(); total != 1 {
+			t.Errorf("expected only kept.db tracked, got %d databases", total)
+		}
+	})
+
+	t.Run("ManagementInterval", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		stateFile := filepath.Join(tmpDir, "state.json")
+		createTestDB(t, filepath.Join(tmpDir, "db1.db"))
+
+		manager := litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MaxHotDatabases:    10,
+			ScanInterval:       time.Hour,
+			HotDuration:        time.Hour,
+			StateFile:          stateFile,
+			ManagementInterval: 50 * time.Millisecond,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := manager.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		defer manager.Stop()
+		manager.AddDatabases([]string{filepath.Join(tmpDir, "*.db")})
+
+		// The management loop saves state on each tick, long before the
+		// 30s default would
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if _, err := os.Stat(stateFile); err == nil {
+				break
+			} else if time.Now().After(deadline) {
+				t.Fatal("state file not saved by the management loop")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
 }
 
 // Helper to create a test SQLite database
@@ -0,0 +1,66 @@
+package litestreampp
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHotColdManagerStuckDatabaseRecovery(t *testing.T) {
+	tmpDir := t.TempDir()
+	stuckPath := filepath.Join(tmpDir, "stuck.db")
+	healthyPath := filepath.Join(tmpDir, "healthy.db")
+
+	var hookPath string
+	var hookState DBLifecycleState
+	mgr := NewHotColdManager(&HotColdConfig{
+		MaxHotDatabases:   10,
+		StuckStateTimeout: time.Minute,
+		OnStuckDatabase: func(path string, state DBLifecycleState, stuckFor time.Duration) {
+			hookPath, hookState = path, state
+		},
+	})
+
+	// Simulate an open that has been hanging past the timeout
+	stuck := NewDynamicDB(stuckPath, nil)
+	stuck.setState(DBStateOpening)
+	stuck.stateSince.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+
+	// A database that only just started opening is left alone
+	healthy := NewDynamicDB(healthyPath, nil)
+	healthy.setState(DBStateOpening)
+
+	mgr.hotDatabases[stuckPath] = stuck
+	mgr.hotDatabases[healthyPath] = healthy
+
+	if got := mgr.GetLifecycleStats()[DBStateOpening]; got != 2 {
+		t.Fatalf("expected 2 opening databases, got %d", got)
+	}
+
+	mgr.checkStuckDatabases()
+
+	if hookPath != stuckPath || hookState != DBStateOpening {
+		t.Errorf("hook called with (%q, %v), want (%q, opening)", hookPath, hookState, stuckPath)
+	}
+	if mgr.IsHot(stuckPath) {
+		t.Error("stuck database should have been demoted")
+	}
+	if !mgr.IsHot(healthyPath) {
+		t.Error("healthy database should remain hot")
+	}
+	if _, ok := mgr.coldDatabases[stuckPath]; !ok {
+		t.Error("stuck database should be tracked as cold")
+	}
+
+	// The force close runs in the background
+	deadline := time.Now().Add(time.Second)
+	for {
+		if state, _ := stuck.State(); state == DBStateClosed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stuck database was not force closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
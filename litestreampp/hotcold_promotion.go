@@ -0,0 +1,238 @@
+package litestreampp
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/benbjohnson/litestream"
+)
+
+// defaultMaxConcurrentPromotions is how many promoted databases are opened
+// at once when HotColdConfig.MaxConcurrentPromotions is unset
+const defaultMaxConcurrentPromotions = 16
+
+// promotion is the open of a database promoted to hot, which runs off the
+// lock so a burst of writes doesn't hold up every other operation. The
+// database counts as hot from the moment it is claimed. Demotion cancels a
+// promotion that hasn't started opening, and waits for one that has.
+type promotion struct {
+	db       *DynamicDB
+	info     *ColdDBInfo // Restored if the open fails
+	started  bool        // Opening, so demotion must wait for done
+	canceled bool        // Demoted before it started
+	done     chan struct{}
+	err      error // Set before done is closed
+}
+
+// submitPromotion opens a claimed database on the replica worker pool, or
+// inline when there are no shared resources. A full queue falls back to a
+// goroutine; the promotion semaphore still bounds the opens.
+func (m *HotColdManager) submitPromotion(path string, p *promotion) {
+	task := &promotionTask{manager: m, path: path, promotion: p}
+	m.wg.Add(1)
+	if m.sharedResources == nil {
+		task.ExecuteContext(context.Background())
+	} else if _, ok := m.sharedResources.replicaPool.TrySubmit(task); !ok {
+		go task.ExecuteContext(context.Background())
+	}
+}
+
+// openPromoted opens a promoted database and starts its replica, taking
+// the lock only to record the outcome. A database that fails to open goes
+// back to cold.
+func (m *HotColdManager) openPromoted(path string, p *promotion) {
+	m.mu.Lock()
+	if p.canceled {
+		m.mu.Unlock()
+		return
+	}
+	p.started = true
+	m.mu.Unlock()
+
+	err := p.db.Open(context.Background())
+
+	// Create and start replica if configured. Keep the database hot if it
+	// fails, and retry in the background until it is protected.
+	var replica *litestream.Replica
+	var replicaErr error
+	if err == nil && (m.replicaTemplate != nil || len(m.replicaOverrides) > 0) {
+		replica, replicaErr = m.startReplica(p.db, path)
+	}
+
+	m.mu.Lock()
+	delete(m.promotions, path)
+	p.err = err
+	close(p.done)
+
+	// Recovered as stuck while opening, so nothing is waiting on it. The
+	// watchdog's ForceClose waited for the open, and whichever of it and
+	// this Close runs second finds the database already closed.
+	if m.hotDatabases[path] != p.db {
+		m.mu.Unlock()
+		if replica != nil {
+			replica.Stop(false)
+		}
+		if err == nil {
+			p.db.Close(context.Background())
+		}
+		return
+	}
+
+	if err != nil {
+		m.revertPromotionLocked(path, p)
+		m.mu.Unlock()
+		slog.Error("failed to open promoted database", "path", path, "error", err)
+		m.events.publish(TierEventError, path, TierCold, err)
+		m.recordTierChange(path, TierHot, TierCold)
+		return
+	}
+
+	if replica != nil {
+		m.hotReplicas[path] = replica
+		if m.paused {
+			replica.Stop(false) // Resume restarts it
+		}
+	} else if replicaErr != nil {
+		slog.Error("failed to start replica", "path", path, "retry_in", m.replicaRetryInterval, "error", replicaErr)
+		m.events.publish(TierEventError, path, TierHot, replicaErr)
+		m.unreplicated[path] = struct{}{}
+		if m.metrics != nil {
+			m.metrics.RecordReplicaStartError()
+		}
+		m.scheduleReplicaRetry(p.db, path, m.replicaRetryInterval)
+	}
+
+	if m.metrics != nil {
+		project, database, _, _ := ParseDBPath(path)
+		m.metrics.UpdateDatabaseStats(project, database, 1, 1, 1)
+	}
+	m.mu.Unlock()
+
+	slog.Info("database promoted to hot tier", "path", filepath.Base(path))
+}
+
+// revertPromotionLocked returns a database whose promotion didn't open it
+// to the cold tier. Must be called with the lock held.
+func (m *HotColdManager) revertPromotionLocked(path string, p *promotion) {
+	delete(m.hotDatabases, path)
+	info := p.info
+	if info == nil {
+		project, database, branch, tenant := ParseDBPath(path)
+		info = &ColdDBInfo{
+			Path:     path,
+			Project:  project,
+			Database: database,
+			Branch:   branch,
+			Tenant:   tenant,
+		}
+	}
+	info.AccessCount = p.db.AccessCount()
+	m.coldDatabases[path] = info
+	m.tierVersion++
+}
+
+// settlePromotionLocked cancels a promotion of path that hasn't started
+// opening, or waits for one that has. The lock is released while waiting,
+// so callers must recheck state afterwards. Must be called with the lock
+// held.
+func (m *HotColdManager) settlePromotionLocked(path string) {
+	for {
+		p, ok := m.promotions[path]
+		if !ok {
+			return
+		}
+		if !p.started {
+			p.canceled = true
+			delete(m.promotions, path)
+			close(p.done)
+			return
+		}
+		m.mu.Unlock()
+		<-p.done
+		m.mu.Lock()
+	}
+}
+
+// waitPromotion waits for a promotion of path to finish opening the
+// database, returning its error
+func (m *HotColdManager) waitPromotion(path string) error {
+	m.mu.RLock()
+	p, ok := m.promotions[path]
+	m.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	<-p.done
+	return p.err
+}
+
+// promotionTask opens a promoted database on the replica worker pool once
+// one of the MaxConcurrentPromotions slots is free
+type promotionTask struct {
+	manager   *HotColdManager
+	path      string
+	promotion *promotion
+}
+
+func (t *promotionTask) Execute() error {
+	return t.ExecuteContext(context.Background())
+}
+
+func (t *promotionTask) ExecuteContext(ctx context.Context) error {
+	defer t.manager.wg.Done()
+
+	m := t.manager
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(m.ctx, cancel)
+	defer stop()
+
+	select {
+	case m.promotionSlots <- struct{}{}:
+	case <-ctx.Done():
+		m.abortPromotion(t.path, t.promotion)
+		return nil
+	}
+	defer func() { <-m.promotionSlots }()
+
+	if ctx.Err() != nil {
+		m.abortPromotion(t.path, t.promotion)
+		return nil
+	}
+	m.openPromoted(t.path, t.promotion)
+	return nil
+}
+
+// OnError is only called for tasks that never ran, since ExecuteContext
+// handles its own failures
+func (t *promotionTask) OnError(err error) {
+	defer t.manager.wg.Done()
+	t.manager.abortPromotion(t.path, t.promotion)
+}
+
+func (t *promotionTask) String() string {
+	return "promote " + t.path
+}
+
+// abortPromotion returns a database to cold when its promotion is canceled
+// before it opens, such as when the manager stops
+func (m *HotColdManager) abortPromotion(path string, p *promotion) {
+	m.mu.Lock()
+	if p.canceled {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.promotions, path)
+	p.err = context.Canceled
+	close(p.done)
+	reverted := m.hotDatabases[path] == p.db
+	if reverted {
+		m.revertPromotionLocked(path, p)
+	}
+	m.mu.Unlock()
+
+	if reverted {
+		m.recordTierChange(path, TierHot, TierCold)
+	}
+}
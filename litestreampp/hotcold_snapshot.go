@@ -0,0 +1,227 @@
+package litestreampp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// coldSnapshotTimeout bounds the snapshot of one cold database, so a hung
+// replica doesn't hold up the rest of a pass
+const coldSnapshotTimeout = 5 * time.Minute
+
+// coldSnapshot is a running snapshot of a cold database. Promotion cancels
+// it and waits for done, so a database is never open twice.
+type coldSnapshot struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// coldSnapshotLoop snapshots cold databases every cold sync interval. A
+// pass still running when the next is due delays it rather than overlapping.
+func (m *HotColdManager) coldSnapshotLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.coldSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.snapshotColdDatabases(m.ctx)
+		}
+	}
+}
+
+// snapshotColdDatabases snapshots every cold database changed since its
+// last cold snapshot and waits for them to finish. Snapshots run on the
+// snapshot worker pool, which bounds how many databases are open at once.
+func (m *HotColdManager) snapshotColdDatabases(ctx context.Context) {
+	start := time.Now()
+
+	type candidate struct {
+		path    string
+		modTime time.Time
+		size    int64
+	}
+	var candidates []candidate
+	m.mu.RLock()
+	if m.paused {
+		m.mu.RUnlock()
+		return
+	}
+	for path, info := range m.coldDatabases {
+		candidates = append(candidates, candidate{path, info.LastModTime, info.LastSize})
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	var queued int
+	for _, c := range candidates {
+		// Unchanged databases are covered by their last snapshot
+		fi, err := os.Stat(c.path)
+		if err != nil || (fi.ModTime().Equal(c.modTime) && fi.Size() == c.size) {
+			continue
+		}
+
+		wg.Add(1)
+		task := &coldSnapshotTask{manager: m, path: c.path, done: wg.Done}
+		if m.sharedResources == nil {
+			task.ExecuteContext(ctx)
+		} else if _, err := m.sharedResources.snapshotPool.SubmitContext(ctx, task); err != nil {
+			wg.Done()
+			break // Stopping
+		}
+		queued++
+	}
+	wg.Wait()
+
+	if queued > 0 {
+		slog.Info("cold snapshots complete",
+			"databases", queued,
+			"duration", time.Since(start))
+	}
+}
+
+// snapshotCold opens a cold database just long enough to upload a snapshot
+// to its replica. The database stays cold: the write detector ignores the
+// snapshot's own changes to the file, and a promotion meanwhile cancels the
+// snapshot rather than waiting on it. Writes by others while the snapshot
+// runs are included in it but don't promote the database.
+func (m *HotColdManager) snapshotCold(ctx context.Context, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, coldSnapshotTimeout)
+	defer cancel()
+
+	// The detector calls promoteToHot with its lock held, which waits for
+	// the snapshot, so the detector is only used while the database is
+	// not claimed
+	m.writeDetector.quiet(path)
+	before, statErr := os.Stat(path)
+	defer func() {
+		after, err := os.Stat(path)
+		if statErr != nil || err != nil {
+			before, after = nil, nil
+		}
+		m.writeDetector.unquiet(path, before, after)
+	}()
+	if statErr != nil {
+		return statErr
+	}
+
+	// Claim the database, unless it was promoted or the manager paused
+	// since the pass began
+	m.mu.Lock()
+	if _, ok := m.coldDatabases[path]; !ok || m.paused {
+		m.mu.Unlock()
+		return nil
+	}
+	snap := &coldSnapshot{cancel: cancel, done: make(chan struct{})}
+	m.coldSnapshots[path] = snap
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.coldSnapshots, path)
+		m.mu.Unlock()
+		close(snap.done)
+	}()
+
+	db := NewDynamicDB(path, nil)
+	replica, err := m.createReplicaForDB(db.DB, path)
+	if err != nil {
+		return fmt.Errorf("create replica: %w", err)
+	} else if replica == nil {
+		return nil
+	}
+	db.DB.Replica = replica
+
+	_, err = db.Snapshot(ctx)
+	if e := db.Close(ctx); e != nil && err == nil {
+		err = fmt.Errorf("close: %w", e)
+	}
+	if errors.Is(err, context.Canceled) {
+		slog.Debug("cold snapshot canceled", "path", path)
+		return nil // Promoted, paused, or stopping
+	} else if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if info, ok := m.coldDatabases[path]; ok {
+		if fi, err := os.Stat(path); err == nil {
+			info.LastModTime = fi.ModTime()
+			info.LastSize = fi.Size()
+		}
+		info.LastSnapshot = time.Now()
+	}
+	m.mu.Unlock()
+
+	if m.metrics != nil {
+		m.metrics.RecordColdSnapshot()
+	}
+	slog.Debug("cold database snapshotted", "path", path)
+	return nil
+}
+
+// cancelColdSnapshotLocked cancels a running snapshot of a cold database
+// and waits for it to close the database. The lock is released while
+// waiting, so callers must recheck state afterwards. Must be called with
+// the lock held.
+func (m *HotColdManager) cancelColdSnapshotLocked(path string) {
+	for {
+		snap, ok := m.coldSnapshots[path]
+		if !ok {
+			return
+		}
+		snap.cancel()
+		m.mu.Unlock()
+		<-snap.done
+		m.mu.Lock()
+	}
+}
+
+// coldSnapshotTask runs the snapshot of one cold database on the snapshot
+// worker pool. Failures are logged rather than retried; the database is
+// still changed, so the next pass tries again.
+type coldSnapshotTask struct {
+	manager *HotColdManager
+	path    string
+	done    func()
+}
+
+func (t *coldSnapshotTask) Execute() error {
+	return t.ExecuteContext(context.Background())
+}
+
+func (t *coldSnapshotTask) ExecuteContext(ctx context.Context) error {
+	defer t.done()
+
+	m := t.manager
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(m.ctx, cancel)
+	defer stop()
+
+	if err := m.snapshotCold(ctx, t.path); err != nil {
+		slog.Error("cold snapshot failed", "path", t.path, "error", err)
+		if m.metrics != nil {
+			m.metrics.RecordColdSnapshotError()
+		}
+	}
+	return nil
+}
+
+// OnError is only called for tasks that never ran, since ExecuteContext
+// handles its own failures
+func (t *coldSnapshotTask) OnError(err error) {
+	t.done()
+}
+
+func (t *coldSnapshotTask) String() string {
+	return "cold snapshot " + t.path
+}
@@ -0,0 +1,156 @@
+package litestreampp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+)
+
+// tierState is the tier assignment snapshot saved to HotColdConfig.StateFile
+type tierState struct {
+	SavedAt   time.Time        `json:"saved_at"`
+	Databases []tierStateEntry `json:"databases"`
+}
+
+// tierStateEntry records the last seen state of one tracked database
+type tierStateEntry struct {
+	Path        string    `json:"path"`
+	Tier        string    `json:"tier"`
+	Pinned      bool      `json:"pinned,omitempty"`
+	LastModTime time.Time `json:"last_mod_time"`
+	LastSize    int64     `json:"last_size"`
+}
+
+// saveState writes the current tier state to the state file. The file is
+// replaced atomically so a crash mid-write leaves the previous state intact.
+func (m *HotColdManager) saveState() error {
+	if m.stateFile == "" {
+		return nil
+	}
+
+	state := tierState{SavedAt: time.Now()}
+	for _, ws := range m.writeDetector.writeStates() {
+		tier := TierCold
+		if ws.IsHot {
+			tier = TierHot
+		}
+		state.Databases = append(state.Databases, tierStateEntry{
+			Path:        ws.Path,
+			Tier:        tier,
+			Pinned:      ws.Pinned,
+			LastModTime: ws.LastModTime,
+			LastSize:    ws.LastSize,
+		})
+	}
+	sort.Slice(state.Databases, func(i, j int) bool { return state.Databases[i].Path < state.Databases[j].Path })
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+
+	// Sync before the rename so a crash can't leave the new name pointing
+	// at unwritten data
+	tmp := m.stateFile + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync state: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := os.Rename(tmp, m.stateFile); err != nil {
+		return fmt.Errorf("rename state: %w", err)
+	}
+	return nil
+}
+
+// loadState restores tier state saved by a previous run. Databases are
+// tracked with their saved modification time, so writes made while the
+// process was down promote them on the first scan, and databases that were
+// hot are promoted immediately, most recently written first, up to the hot
+// limit. Entries that no longer match the discovery patterns, or that are
+// now excluded, are skipped. A missing or unreadable state file leaves
+// everything cold.
+func (m *HotColdManager) loadState() {
+	if m.stateFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(m.stateFile)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		slog.Warn("cannot read tier state, starting cold", "path", m.stateFile, "error", err)
+		return
+	}
+
+	var state tierState
+	if err := json.Unmarshal(data, &state); err != nil {
+		slog.Warn("corrupt tier state, starting cold", "path", m.stateFile, "error", err)
+		return
+	}
+
+	var hot []tierStateEntry
+	var skipped int
+	for _, entry := range state.Databases {
+		if (len(m.statePatterns) > 0 && !matchesAny(m.statePatterns, entry.Path)) || m.writeDetector.excluded(entry.Path) {
+			skipped++
+			continue // Configuration changed while the process was down
+		}
+		if err := m.writeDetector.AddDatabase(entry.Path); err != nil {
+			continue // Database removed while the process was down
+		}
+		m.writeDetector.seedState(entry.Path, entry.LastModTime, entry.LastSize)
+		m.mu.Lock()
+		m.trackColdLocked(entry.Path)
+		m.mu.Unlock()
+
+		if entry.Tier == TierHot {
+			hot = append(hot, entry)
+		}
+	}
+
+	// Pinned databases first, then most recently written
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Pinned != hot[j].Pinned {
+			return hot[i].Pinned
+		}
+		return hot[i].LastModTime.After(hot[j].LastModTime)
+	})
+
+	var promoted int
+	for _, entry := range hot {
+		if !entry.Pinned && promoted >= m.maxHotDBs {
+			continue
+		}
+
+		promote := m.writeDetector.PromoteNow
+		if entry.Pinned {
+			promote = m.writeDetector.Pin
+		}
+		if err := promote(entry.Path); err != nil {
+			slog.Error("failed to restore hot database", "path", entry.Path, "error", err)
+			continue
+		}
+		promoted++
+	}
+
+	slog.Info("tier state restored",
+		"path", m.stateFile,
+		"saved_at", state.SavedAt,
+		"databases", len(state.Databases),
+		"skipped", skipped,
+		"hot", promoted)
+}
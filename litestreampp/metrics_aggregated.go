@@ -1,15 +1,54 @@
 package litestreampp
 
 import (
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// DefaultMaxTenantCardinality is the default number of tenant label sets
+// emitted before new tenants are dropped from tenant-level metrics.
+const DefaultMaxTenantCardinality = 10000
+
+// DefaultStaleSyncThreshold is the default sync lag after which a database
+// is counted as stale.
+const DefaultStaleSyncThreshold = 5 * time.Minute
+
+// ScanDurationBuckets are the write detector scan duration histogram
+// buckets, in seconds, from a few hundred databases up to scans long enough
+// to overrun any sensible scan interval.
+var ScanDurationBuckets = []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 5, 15, 30, 60, 120}
+
+// LargeDatabaseSyncBuckets are sync duration histogram buckets, in seconds,
+// for workloads where snapshot uploads of large databases take minutes.
+var LargeDatabaseSyncBuckets = []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300}
+
+// MetricsOptions configures how metrics collectors are created
+type MetricsOptions struct {
+	// Registerer the collectors are registered with. If nil the collectors
+	// are not registered anywhere.
+	Registerer prometheus.Registerer
+
+	// Sink, if set, receives hierarchical metrics instead of Prometheus
+	// collectors registered with Registerer.
+	Sink MetricsSink
+
+	// SyncDurationBuckets are the buckets, in seconds, of the sync duration
+	// histograms. Defaults to prometheus.DefBuckets.
+	SyncDurationBuckets []float64
+}
+
+func (o MetricsOptions) syncDurationBuckets() []float64 {
+	if len(o.SyncDurationBuckets) == 0 {
+		return prometheus.DefBuckets
+	}
+	return o.SyncDurationBuckets
+}
+
 // GlobalMetrics is the global aggregated metrics instance
 var GlobalMetrics *HierarchicalMetrics
 
@@ -19,37 +58,84 @@ func init() {
 
 // HierarchicalMetrics provides aggregated metrics at multiple levels
 type HierarchicalMetrics struct {
-	mu sync.RWMutex
+	mu   sync.RWMutex
+	sink MetricsSink
 
 	// System-wide metrics (no labels)
-	totalHotDBs    prometheus.Gauge
-	totalColdDBs   prometheus.Gauge
-	totalDBSize    prometheus.Gauge
-	totalWALSize   prometheus.Gauge
-	totalWALBytes  prometheus.Counter
+	totalHotDBs    *MetricDesc
+	totalColdDBs   *MetricDesc
+	totalDBSize    *MetricDesc
+	totalWALSize   *MetricDesc
+	totalWALBytes  *MetricDesc
 
 	// Project-level metrics (label: project)
-	projectDBCount      *prometheus.GaugeVec
-	projectDBSize       *prometheus.GaugeVec
-	projectActiveDBs    *prometheus.GaugeVec
-	projectSyncOps      *prometheus.CounterVec
-	projectSyncDuration *prometheus.HistogramVec
+	projectDBCount      *MetricDesc
+	projectDBSize       *MetricDesc
+	projectActiveDBs    *MetricDesc
+	projectSyncOps      *MetricDesc
+	projectSyncDuration *MetricDesc
 
 	// Database-level metrics (labels: project, database)
-	databaseTenantCount *prometheus.GaugeVec
-	databaseBranchCount *prometheus.GaugeVec
-	databaseHotTenants  *prometheus.GaugeVec
-	databaseSize        *prometheus.GaugeVec
+	databaseTenantCount *MetricDesc
+	databaseBranchCount *MetricDesc
+	databaseHotTenants  *MetricDesc
+	databaseSize        *MetricDesc
+
+	// Branch-level metrics (labels: project, database, branch)
+	databaseBranchSize *MetricDesc
+	branchSyncOps      *MetricDesc
+
+	// Tenant-level metrics (labels: project, database, branch, tenant).
+	// Only emitted when enabled, for at most maxTenants label sets.
+	tenantSize           *MetricDesc
+	tenantSyncOps        *MetricDesc
+	tenantMetricsDropped *MetricDesc
 
 	// Tier-based metrics (label: tier = "hot" or "cold")
-	tierSyncOps      *prometheus.CounterVec
-	tierSyncDuration *prometheus.HistogramVec
-	tierSyncErrors   *prometheus.CounterVec
-	tierWALBytes     *prometheus.CounterVec
+	tierSyncOps      *MetricDesc
+	tierSyncDuration *MetricDesc
+	tierSyncErrors   *MetricDesc
+	tierWALBytes     *MetricDesc
+
+	// Sync lag metrics, recomputed by UpdateSyncLag
+	tierSyncLag     *MetricDesc // label: tier
+	projectSyncLag  *MetricDesc // label: project
+	databaseSyncLag *MetricDesc // labels: project, database
+	staleDatabases  *MetricDesc
+
+	// Lifecycle metrics (label: state)
+	lifecycleStateDBs *MetricDesc
+	stuckDBs          *MetricDesc
+
+	// Tier churn metrics
+	preventedDemotions *MetricDesc
+	demotionSyncErrors *MetricDesc
+
+	// Replica start metrics
+	replicaStartErrors *MetricDesc
+	unreplicatedHotDBs *MetricDesc
+
+	// Cold snapshot metrics
+	coldSnapshots      *MetricDesc
+	coldSnapshotErrors *MetricDesc
+
+	// Write detector scan metrics
+	scanDuration     *MetricDesc // label: scope = "full" or "slot"
+	scanOverruns     *MetricDesc // label: scope
+	trackedDatabases *MetricDesc
 
 	// Internal tracking
 	projectStats  map[string]*ProjectStats
 	databaseStats map[string]*DatabaseStats
+
+	// Tenant metrics cardinality tracking
+	tenantMetrics bool
+	maxTenants    int
+	tenantSeries  map[string]struct{}
+
+	tenantLimitLogged bool
+
+	staleThreshold time.Duration // Sync lag after which a database is stale
 }
 
 // ProjectStats tracks statistics for a project
@@ -70,96 +156,363 @@ type DatabaseStats struct {
 	HotTenants   int
 	TotalSize    int64
 	LastUpdated  time.Time
+	LastSynced   time.Time // Last successful sync, zero if never synced
+	SyncTier     string    // Tier of the last successful sync
 }
 
 // NewHierarchicalMetrics creates a new hierarchical metrics instance
+// registered with the default Prometheus registerer. Only one instance can
+// be registered there; use NewHierarchicalMetricsWithRegistry for others.
 func NewHierarchicalMetrics() *HierarchicalMetrics {
-	return &HierarchicalMetrics{
+	return NewHierarchicalMetricsWithRegistry(prometheus.DefaultRegisterer)
+}
+
+// NewHierarchicalMetricsWithRegistry creates a new hierarchical metrics
+// instance registered with reg. If reg is nil the collectors are not
+// registered anywhere.
+func NewHierarchicalMetricsWithRegistry(reg prometheus.Registerer) *HierarchicalMetrics {
+	return NewHierarchicalMetricsWithOptions(MetricsOptions{Registerer: reg})
+}
+
+// NewHierarchicalMetricsWithOptions creates a new hierarchical metrics
+// instance configured by opts. It panics if a metric can't be registered,
+// such as when its name is already registered with the Prometheus registerer.
+func NewHierarchicalMetricsWithOptions(opts MetricsOptions) *HierarchicalMetrics {
+	sink := opts.Sink
+	if sink == nil {
+		sink = NewPrometheusSink(opts.Registerer)
+	}
+	buckets := opts.syncDurationBuckets()
+	m := &HierarchicalMetrics{
+		sink: sink,
+
 		// System-wide metrics
-		totalHotDBs: promauto.NewGauge(prometheus.GaugeOpts{
+		totalHotDBs: &MetricDesc{
 			Name: "litestream_hot_databases_total",
 			Help: "Total number of hot databases across all projects",
-		}),
-		totalColdDBs: promauto.NewGauge(prometheus.GaugeOpts{
+			Kind: MetricGauge,
+		},
+		totalColdDBs: &MetricDesc{
 			Name: "litestream_cold_databases_total",
 			Help: "Total number of cold databases across all projects",
-		}),
-		totalDBSize: promauto.NewGauge(prometheus.GaugeOpts{
+			Kind: MetricGauge,
+		},
+		totalDBSize: &MetricDesc{
 			Name: "litestream_db_size_bytes_total",
 			Help: "Total size of all databases in bytes",
-		}),
-		totalWALSize: promauto.NewGauge(prometheus.GaugeOpts{
+			Kind: MetricGauge,
+		},
+		totalWALSize: &MetricDesc{
 			Name: "litestream_wal_size_bytes_total",
 			Help: "Total size of all WAL files in bytes",
-		}),
-		totalWALBytes: promauto.NewCounter(prometheus.CounterOpts{
+			Kind: MetricGauge,
+		},
+		totalWALBytes: &MetricDesc{
 			Name: "litestream_wal_bytes_written_total",
 			Help: "Total number of bytes written to shadow WAL",
-		}),
+			Kind: MetricCounter,
+		},
 
 		// Project-level metrics
-		projectDBCount: promauto.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "litestream_project_databases",
-			Help: "Number of databases per project",
-		}, []string{"project"}),
-		projectDBSize: promauto.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "litestream_project_size_bytes",
-			Help: "Total size of databases in project",
-		}, []string{"project"}),
-		projectActiveDBs: promauto.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "litestream_project_active_databases",
-			Help: "Number of active databases per project",
-		}, []string{"project"}),
-		projectSyncOps: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "litestream_project_sync_operations_total",
-			Help: "Total sync operations per project",
-		}, []string{"project"}),
-		projectSyncDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		projectDBCount: &MetricDesc{
+			Name:   "litestream_project_databases",
+			Help:   "Number of databases per project",
+			Kind:   MetricGauge,
+			Labels: []string{"project"},
+		},
+		projectDBSize: &MetricDesc{
+			Name:   "litestream_project_size_bytes",
+			Help:   "Total size of databases in project",
+			Kind:   MetricGauge,
+			Labels: []string{"project"},
+		},
+		projectActiveDBs: &MetricDesc{
+			Name:   "litestream_project_active_databases",
+			Help:   "Number of active databases per project",
+			Kind:   MetricGauge,
+			Labels: []string{"project"},
+		},
+		projectSyncOps: &MetricDesc{
+			Name:   "litestream_project_sync_operations_total",
+			Help:   "Total sync operations per project",
+			Kind:   MetricCounter,
+			Labels: []string{"project"},
+		},
+		projectSyncDuration: &MetricDesc{
 			Name:    "litestream_project_sync_duration_seconds",
 			Help:    "Sync operation duration per project",
-			Buckets: prometheus.DefBuckets,
-		}, []string{"project"}),
+			Kind:    MetricHistogram,
+			Labels:  []string{"project"},
+			Buckets: buckets,
+		},
 
 		// Database-level metrics
-		databaseTenantCount: promauto.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "litestream_database_tenants",
-			Help: "Number of tenants per database",
-		}, []string{"project", "database"}),
-		databaseBranchCount: promauto.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "litestream_database_branches",
-			Help: "Number of branches per database",
-		}, []string{"project", "database"}),
-		databaseHotTenants: promauto.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "litestream_database_hot_tenants",
-			Help: "Number of hot tenants per database",
-		}, []string{"project", "database"}),
-		databaseSize: promauto.NewGaugeVec(prometheus.GaugeOpts{
-			Name: "litestream_database_size_bytes",
-			Help: "Total size per database",
-		}, []string{"project", "database"}),
+		databaseTenantCount: &MetricDesc{
+			Name:   "litestream_database_tenants",
+			Help:   "Number of tenants per database",
+			Kind:   MetricGauge,
+			Labels: []string{"project", "database"},
+		},
+		databaseBranchCount: &MetricDesc{
+			Name:   "litestream_database_branches",
+			Help:   "Number of branches per database",
+			Kind:   MetricGauge,
+			Labels: []string{"project", "database"},
+		},
+		databaseHotTenants: &MetricDesc{
+			Name:   "litestream_database_hot_tenants",
+			Help:   "Number of hot tenants per database",
+			Kind:   MetricGauge,
+			Labels: []string{"project", "database"},
+		},
+		databaseSize: &MetricDesc{
+			Name:   "litestream_database_size_bytes",
+			Help:   "Total size per database",
+			Kind:   MetricGauge,
+			Labels: []string{"project", "database"},
+		},
+
+		// Branch-level metrics
+		databaseBranchSize: &MetricDesc{
+			Name:   "litestream_branch_size_bytes",
+			Help:   "Total size per database branch",
+			Kind:   MetricGauge,
+			Labels: []string{"project", "database", "branch"},
+		},
+		branchSyncOps: &MetricDesc{
+			Name:   "litestream_branch_sync_operations_total",
+			Help:   "Total sync operations per database branch",
+			Kind:   MetricCounter,
+			Labels: []string{"project", "database", "branch"},
+		},
+
+		// Tenant-level metrics
+		tenantSize: &MetricDesc{
+			Name:   "litestream_tenant_size_bytes",
+			Help:   "Size of each tenant database",
+			Kind:   MetricGauge,
+			Labels: []string{"project", "database", "branch", "tenant"},
+		},
+		tenantSyncOps: &MetricDesc{
+			Name:   "litestream_tenant_sync_operations_total",
+			Help:   "Total sync operations per tenant database",
+			Kind:   MetricCounter,
+			Labels: []string{"project", "database", "branch", "tenant"},
+		},
+		tenantMetricsDropped: &MetricDesc{
+			Name: "litestream_tenant_metrics_dropped_total",
+			Help: "Total tenant metric updates dropped by the tenant cardinality limit",
+			Kind: MetricCounter,
+		},
 
 		// Tier-based metrics
-		tierSyncOps: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "litestream_tier_sync_operations_total",
-			Help: "Total sync operations by tier",
-		}, []string{"tier"}),
-		tierSyncDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		tierSyncOps: &MetricDesc{
+			Name:   "litestream_tier_sync_operations_total",
+			Help:   "Total sync operations by tier",
+			Kind:   MetricCounter,
+			Labels: []string{"tier"},
+		},
+		tierSyncDuration: &MetricDesc{
 			Name:    "litestream_tier_sync_duration_seconds",
 			Help:    "Sync operation duration by tier",
-			Buckets: prometheus.DefBuckets,
-		}, []string{"tier"}),
-		tierSyncErrors: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "litestream_tier_sync_errors_total",
-			Help: "Total sync errors by tier",
-		}, []string{"tier"}),
-		tierWALBytes: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "litestream_tier_wal_bytes_total",
-			Help: "Total WAL bytes by tier",
-		}, []string{"tier"}),
+			Kind:    MetricHistogram,
+			Labels:  []string{"tier"},
+			Buckets: buckets,
+		},
+		tierSyncErrors: &MetricDesc{
+			Name:   "litestream_tier_sync_errors_total",
+			Help:   "Total sync errors by tier",
+			Kind:   MetricCounter,
+			Labels: []string{"tier"},
+		},
+		tierWALBytes: &MetricDesc{
+			Name:   "litestream_tier_wal_bytes_total",
+			Help:   "Total WAL bytes by tier",
+			Kind:   MetricCounter,
+			Labels: []string{"tier"},
+		},
+
+		// Sync lag metrics
+		tierSyncLag: &MetricDesc{
+			Name:   "litestream_tier_sync_lag_seconds",
+			Help:   "Largest time since the last successful sync of a database in the tier",
+			Kind:   MetricGauge,
+			Labels: []string{"tier"},
+		},
+		projectSyncLag: &MetricDesc{
+			Name:   "litestream_project_sync_lag_seconds",
+			Help:   "Largest time since the last successful sync of a database in the project",
+			Kind:   MetricGauge,
+			Labels: []string{"project"},
+		},
+		databaseSyncLag: &MetricDesc{
+			Name:   "litestream_database_sync_lag_seconds",
+			Help:   "Time since the last successful sync per database",
+			Kind:   MetricGauge,
+			Labels: []string{"project", "database"},
+		},
+		staleDatabases: &MetricDesc{
+			Name: "litestream_stale_databases",
+			Help: "Number of databases whose sync lag exceeds the stale threshold",
+			Kind: MetricGauge,
+		},
+
+		// Lifecycle metrics
+		lifecycleStateDBs: &MetricDesc{
+			Name:   "litestream_lifecycle_state_databases",
+			Help:   "Number of hot databases in each lifecycle state",
+			Kind:   MetricGauge,
+			Labels: []string{"state"},
+		},
+		stuckDBs: &MetricDesc{
+			Name:   "litestream_stuck_databases_total",
+			Help:   "Total databases recovered after being stuck in a transitional state",
+			Kind:   MetricCounter,
+			Labels: []string{"state"},
+		},
+
+		// Tier churn metrics
+		preventedDemotions: &MetricDesc{
+			Name: "litestream_prevented_demotions_total",
+			Help: "Total demotions deferred by the minimum hot duration",
+			Kind: MetricCounter,
+		},
+		demotionSyncErrors: &MetricDesc{
+			Name: "litestream_demotion_sync_errors_total",
+			Help: "Total final syncs before demotion that failed or timed out",
+			Kind: MetricCounter,
+		},
+
+		// Replica start metrics
+		replicaStartErrors: &MetricDesc{
+			Name: "litestream_replica_start_errors_total",
+			Help: "Total failures to start the replica of a hot database, at promotion or on a background retry",
+			Kind: MetricCounter,
+		},
+		unreplicatedHotDBs: &MetricDesc{
+			Name: "litestream_unreplicated_hot_databases",
+			Help: "Number of hot databases whose replica failed to start and is being retried",
+			Kind: MetricGauge,
+		},
+
+		// Cold snapshot metrics
+		coldSnapshots: &MetricDesc{
+			Name: "litestream_cold_snapshots_total",
+			Help: "Total snapshots of cold databases taken by the cold sync loop",
+			Kind: MetricCounter,
+		},
+		coldSnapshotErrors: &MetricDesc{
+			Name: "litestream_cold_snapshot_errors_total",
+			Help: "Total snapshots of cold databases that failed",
+			Kind: MetricCounter,
+		},
+
+		// Write detector scan metrics
+		scanDuration: &MetricDesc{
+			Name:    "litestream_write_detector_scan_duration_seconds",
+			Help:    "Duration of write detector scans, of every database (full) or one slot of spread scans",
+			Kind:    MetricHistogram,
+			Labels:  []string{"scope"},
+			Buckets: ScanDurationBuckets,
+		},
+		scanOverruns: &MetricDesc{
+			Name:   "litestream_write_detector_scan_overruns_total",
+			Help:   "Total write detector scans that took longer than the time until the next scan",
+			Kind:   MetricCounter,
+			Labels: []string{"scope"},
+		},
+		trackedDatabases: &MetricDesc{
+			Name: "litestream_write_detector_tracked_databases",
+			Help: "Number of databases tracked by the write detector",
+			Kind: MetricGauge,
+		},
 
 		projectStats:  make(map[string]*ProjectStats),
 		databaseStats: make(map[string]*DatabaseStats),
+		maxTenants:    DefaultMaxTenantCardinality,
+		tenantSeries:  make(map[string]struct{}),
+
+		staleThreshold: DefaultStaleSyncThreshold,
+	}
+
+	for _, desc := range []*MetricDesc{
+		m.totalHotDBs, m.totalColdDBs, m.totalDBSize, m.totalWALSize, m.totalWALBytes,
+		m.projectDBCount, m.projectDBSize, m.projectActiveDBs, m.projectSyncOps,
+		m.projectSyncDuration, m.databaseTenantCount, m.databaseBranchCount,
+		m.databaseHotTenants, m.databaseSize, m.databaseBranchSize, m.branchSyncOps,
+		m.tenantSize, m.tenantSyncOps, m.tenantMetricsDropped, m.tierSyncOps,
+		m.tierSyncDuration, m.tierSyncErrors, m.tierWALBytes, m.tierSyncLag, m.projectSyncLag,
+		m.databaseSyncLag, m.staleDatabases, m.lifecycleStateDBs, m.stuckDBs,
+		m.preventedDemotions, m.demotionSyncErrors, m.scanDuration, m.scanOverruns,
+		m.trackedDatabases, m.replicaStartErrors, m.unreplicatedHotDBs, m.coldSnapshots,
+		m.coldSnapshotErrors,
+	} {
+		if err := sink.Register(desc); err != nil {
+			panic(err)
+		}
+	}
+	return m
+}
+
+// SetStaleSyncThreshold sets the sync lag after which a database is counted
+// in litestream_stale_databases. A threshold of 0 uses
+// DefaultStaleSyncThreshold.
+func (m *HierarchicalMetrics) SetStaleSyncThreshold(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if d <= 0 {
+		d = DefaultStaleSyncThreshold
+	}
+	m.staleThreshold = d
+}
+
+// SetTenantMetrics enables or disables tenant-level metrics. Once
+// maxCardinality distinct tenants have been emitted, new tenants are left
+// out of tenant-level metrics; tenants already emitted keep updating.
+// A maxCardinality of 0 uses DefaultMaxTenantCardinality.
+func (m *HierarchicalMetrics) SetTenantMetrics(enabled bool, maxCardinality int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if maxCardinality <= 0 {
+		maxCardinality = DefaultMaxTenantCardinality
+	}
+	m.tenantMetrics = enabled
+	m.maxTenants = maxCardinality
+
+	// Drop emitted tenant series when disabled so they stop being scraped
+	if !enabled && len(m.tenantSeries) > 0 {
+		m.sink.Reset(m.tenantSize)
+		m.sink.Reset(m.tenantSyncOps)
+		m.tenantSeries = make(map[string]struct{})
+		m.tenantLimitLogged = false
+	}
+}
+
+// allowTenantLocked reports whether tenant-level metrics may be emitted
+// for a tenant, admitting it if under the cardinality limit. Must be called
+// with m.mu held.
+func (m *HierarchicalMetrics) allowTenantLocked(project, database, branch, tenant string) bool {
+	if !m.tenantMetrics {
+		return false
+	}
+
+	key := project + "/" + database + "/" + branch + "/" + tenant
+	if _, ok := m.tenantSeries[key]; ok {
+		return true
 	}
+	if len(m.tenantSeries) >= m.maxTenants {
+		m.sink.Add(m.tenantMetricsDropped, 1)
+		if !m.tenantLimitLogged {
+			slog.Warn("tenant metrics cardinality limit reached, new tenants will not be emitted",
+				"max_tenant_cardinality", m.maxTenants)
+			m.tenantLimitLogged = true
+		}
+		return false
+	}
+	m.tenantSeries[key] = struct{}{}
+	return true
 }
 
 // ParseDBPath extracts project, database, branch, and tenant from a database path
@@ -207,7 +560,7 @@ func (m *HierarchicalMetrics) RecordDBMetrics(path string, size, walSize int64,
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	project, database, _, _ := ParseDBPath(path)
+	project, database, branch, tenant := ParseDBPath(path)
 
 	// Update project stats
 	if _, ok := m.projectStats[project]; !ok {
@@ -231,10 +584,14 @@ func (m *HierarchicalMetrics) RecordDBMetrics(path string, size, walSize int64,
 	ds.LastUpdated = time.Now()
 
 	// Update metrics
-	m.totalDBSize.Add(float64(size))
-	m.totalWALSize.Add(float64(walSize))
-	m.projectDBSize.WithLabelValues(project).Add(float64(size))
-	m.databaseSize.WithLabelValues(project, database).Add(float64(size))
+	m.sink.Add(m.totalDBSize, float64(size))
+	m.sink.Add(m.totalWALSize, float64(walSize))
+	m.sink.Add(m.projectDBSize, float64(size), project)
+	m.sink.Add(m.databaseSize, float64(size), project, database)
+	m.sink.Add(m.databaseBranchSize, float64(size), project, database, branch)
+	if m.allowTenantLocked(project, database, branch, tenant) {
+		m.sink.Set(m.tenantSize, float64(size), project, database, branch, tenant)
+	}
 
 	if isHot {
 		ps.ActiveDBs++
@@ -244,7 +601,7 @@ func (m *HierarchicalMetrics) RecordDBMetrics(path string, size, walSize int64,
 
 // RecordSync records a sync operation
 func (m *HierarchicalMetrics) RecordSync(path string, duration time.Duration, bytes int64, isHot bool, err error) {
-	project, _, _, _ := ParseDBPath(path)
+	project, database, branch, tenant := ParseDBPath(path)
 
 	tier := "cold"
 	if isHot {
@@ -252,27 +609,89 @@ func (m *HierarchicalMetrics) RecordSync(path string, duration time.Duration, by
 	}
 
 	// Record tier metrics
-	m.tierSyncOps.WithLabelValues(tier).Inc()
-	m.tierSyncDuration.WithLabelValues(tier).Observe(duration.Seconds())
+	m.sink.Add(m.tierSyncOps, 1, tier)
+	m.sink.Observe(m.tierSyncDuration, duration.Seconds(), tier)
 	if bytes > 0 {
-		m.tierWALBytes.WithLabelValues(tier).Add(float64(bytes))
-		m.totalWALBytes.Add(float64(bytes))
+		m.sink.Add(m.tierWALBytes, float64(bytes), tier)
+		m.sink.Add(m.totalWALBytes, float64(bytes))
 	}
 	if err != nil {
-		m.tierSyncErrors.WithLabelValues(tier).Inc()
+		m.sink.Add(m.tierSyncErrors, 1, tier)
 	}
 
 	// Record project metrics
 	if project != "" {
-		m.projectSyncOps.WithLabelValues(project).Inc()
-		m.projectSyncDuration.WithLabelValues(project).Observe(duration.Seconds())
+		m.sink.Add(m.projectSyncOps, 1, project)
+		m.sink.Observe(m.projectSyncDuration, duration.Seconds(), project)
+		m.sink.Add(m.branchSyncOps, 1, project, database, branch)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A successful sync resets the database's lag
+	if err == nil {
+		now := time.Now()
+		dbKey := project + "/" + database
+		if _, ok := m.databaseStats[dbKey]; !ok {
+			m.databaseStats[dbKey] = &DatabaseStats{
+				Project:  project,
+				Database: database,
+			}
+		}
+		ds := m.databaseStats[dbKey]
+		ds.LastSynced = now
+		ds.LastUpdated = now
+		ds.SyncTier = tier
+		m.sink.Set(m.databaseSyncLag, 0, project, database)
+	}
+
+	// Record tenant metrics
+	if m.allowTenantLocked(project, database, branch, tenant) {
+		m.sink.Add(m.tenantSyncOps, 1, project, database, branch, tenant)
+	}
+}
+
+// UpdateSyncLag recomputes the sync lag of every database that has synced
+// successfully, along with the per-tier and per-project maximums and the
+// number of stale databases. It is called periodically by HotColdManager.
+func (m *HierarchicalMetrics) UpdateSyncLag() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	tierLag := map[string]float64{TierHot: 0, TierCold: 0}
+	projectLag := make(map[string]float64)
+	var stale int
+	for _, ds := range m.databaseStats {
+		if ds.LastSynced.IsZero() {
+			continue
+		}
+
+		lag := now.Sub(ds.LastSynced)
+		if lag > m.staleThreshold {
+			stale++
+		}
+
+		seconds := lag.Seconds()
+		m.sink.Set(m.databaseSyncLag, seconds, ds.Project, ds.Database)
+		tierLag[ds.SyncTier] = max(tierLag[ds.SyncTier], seconds)
+		projectLag[ds.Project] = max(projectLag[ds.Project], seconds)
+	}
+
+	for tier, seconds := range tierLag {
+		m.sink.Set(m.tierSyncLag, seconds, tier)
+	}
+	for project, seconds := range projectLag {
+		m.sink.Set(m.projectSyncLag, seconds, project)
 	}
+	m.sink.Set(m.staleDatabases, float64(stale))
 }
 
 // UpdateTierCounts updates the hot/cold database counts
 func (m *HierarchicalMetrics) UpdateTierCounts(hotCount, coldCount int) {
-	m.totalHotDBs.Set(float64(hotCount))
-	m.totalColdDBs.Set(float64(coldCount))
+	m.sink.Set(m.totalHotDBs, float64(hotCount))
+	m.sink.Set(m.totalColdDBs, float64(coldCount))
 }
 
 // UpdateProjectStats updates aggregated project statistics
@@ -288,8 +707,8 @@ func (m *HierarchicalMetrics) UpdateProjectStats(project string, dbCount, active
 	ps.ActiveDBs = activeCount
 	ps.LastUpdated = time.Now()
 
-	m.projectDBCount.WithLabelValues(project).Set(float64(dbCount))
-	m.projectActiveDBs.WithLabelValues(project).Set(float64(activeCount))
+	m.sink.Set(m.projectDBCount, float64(dbCount), project)
+	m.sink.Set(m.projectActiveDBs, float64(activeCount), project)
 }
 
 // UpdateDatabaseStats updates aggregated database statistics
@@ -310,7 +729,68 @@ func (m *HierarchicalMetrics) UpdateDatabaseStats(project, database string, tena
 	ds.HotTenants = hotTenants
 	ds.LastUpdated = time.Now()
 
-	m.databaseTenantCount.WithLabelValues(project, database).Set(float64(tenantCount))
-	m.databaseBranchCount.WithLabelValues(project, database).Set(float64(branchCount))
-	m.databaseHotTenants.WithLabelValues(project, database).Set(float64(hotTenants))
-}
\ No newline at end of file
+	m.sink.Set(m.databaseTenantCount, float64(tenantCount), project, database)
+	m.sink.Set(m.databaseBranchCount, float64(branchCount), project, database)
+	m.sink.Set(m.databaseHotTenants, float64(hotTenants), project, database)
+}
+
+// UpdateLifecycleStateCounts updates the number of databases in each lifecycle state
+func (m *HierarchicalMetrics) UpdateLifecycleStateCounts(counts map[DBLifecycleState]int) {
+	for _, state := range []DBLifecycleState{DBStateClosed, DBStateOpening, DBStateOpen, DBStateClosing} {
+		m.sink.Set(m.lifecycleStateDBs, float64(counts[state]), state.String())
+	}
+}
+
+// RecordStuckDatabase records a database found stuck in a transitional state
+func (m *HierarchicalMetrics) RecordStuckDatabase(state DBLifecycleState) {
+	m.sink.Add(m.stuckDBs, 1, state.String())
+}
+
+// RecordPreventedDemotion records a demotion deferred by the minimum hot duration
+func (m *HierarchicalMetrics) RecordPreventedDemotion() {
+	m.sink.Add(m.preventedDemotions, 1)
+}
+
+// RecordDemotionSyncError records a final sync before demotion that failed
+func (m *HierarchicalMetrics) RecordDemotionSyncError() {
+	m.sink.Add(m.demotionSyncErrors, 1)
+}
+
+// RecordReplicaStartError records a failure to start the replica of a hot
+// database
+func (m *HierarchicalMetrics) RecordReplicaStartError() {
+	m.sink.Add(m.replicaStartErrors, 1)
+}
+
+// UpdateUnreplicatedHotDatabases sets the number of hot databases left
+// without a replica, which are unprotected until a retry succeeds
+func (m *HierarchicalMetrics) UpdateUnreplicatedHotDatabases(count int) {
+	m.sink.Set(m.unreplicatedHotDBs, float64(count))
+}
+
+// RecordColdSnapshot records a snapshot of a cold database
+func (m *HierarchicalMetrics) RecordColdSnapshot() {
+	m.sink.Add(m.coldSnapshots, 1)
+}
+
+// RecordColdSnapshotError records a snapshot of a cold database that failed
+func (m *HierarchicalMetrics) RecordColdSnapshotError() {
+	m.sink.Add(m.coldSnapshotErrors, 1)
+}
+
+// RecordScan records a write detector scan of tracked databases that took
+// duration. A scan longer than budget, the time until the next scan, is
+// counted as an overrun: detection latency grows once scans overrun, so the
+// fleet has outgrown the scan interval. full is false for the scan of one
+// slot of a spread scan.
+func (m *HierarchicalMetrics) RecordScan(duration, budget time.Duration, tracked int, full bool) {
+	scope := "slot"
+	if full {
+		scope = "full"
+	}
+	m.sink.Observe(m.scanDuration, duration.Seconds(), scope)
+	if budget > 0 && duration > budget {
+		m.sink.Add(m.scanOverruns, 1, scope)
+	}
+	m.sink.Set(m.trackedDatabases, float64(tracked))
+}
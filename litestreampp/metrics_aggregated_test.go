@@ -1,10 +1,13 @@
 package litestreampp_test
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/litestream/litestreampp"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestParseDBPath(t *testing.T) {
@@ -72,8 +75,7 @@ func TestParseDBPath(t *testing.T) {
 
 func TestHierarchicalMetrics(t *testing.T) {
 	t.Run("RecordDBMetrics", func(t *testing.T) {
-		// Use the global metrics instance to avoid duplicate registration
-		metrics := litestreampp.GlobalMetrics
+		metrics := litestreampp.NewHierarchicalMetricsWithRegistry(prometheus.NewRegistry())
 		
 		// Record metrics for multiple databases
 		paths := []struct {
@@ -100,7 +102,7 @@ func TestHierarchicalMetrics(t *testing.T) {
 	})
 
 	t.Run("RecordSync", func(t *testing.T) {
-		metrics := litestreampp.GlobalMetrics
+		metrics := litestreampp.NewHierarchicalMetricsWithRegistry(prometheus.NewRegistry())
 		
 		// Record some sync operations
 		path1 := "/data/proj1/databases/db1/branches/main/tenants/tenant1.db"
@@ -114,8 +116,47 @@ func TestHierarchicalMetrics(t *testing.T) {
 		metrics.RecordSync(path2, 500*time.Millisecond, 0, false, &testError{"sync failed"})
 	})
 
+	t.Run("SyncDurationBuckets", func(t *testing.T) {
+		// bounds returns the bucket upper bounds of each histogram family
+		bounds := func(t *testing.T, reg prometheus.Gatherer) map[string][]float64 {
+			t.Helper()
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string][]float64)
+			for _, mf := range families {
+				for _, b := range mf.GetMetric()[0].GetHistogram().GetBucket() {
+					got[mf.GetName()] = append(got[mf.GetName()], b.GetUpperBound())
+				}
+			}
+			return got
+		}
+		path := "/data/proj1/databases/db1/branches/main/tenants/tenant1.db"
+
+		reg := prometheus.NewRegistry()
+		litestreampp.NewHierarchicalMetricsWithRegistry(reg).RecordSync(path, time.Second, 0, false, nil)
+		for name, b := range bounds(t, reg) {
+			if !reflect.DeepEqual(b, prometheus.DefBuckets) {
+				t.Errorf("%s: expected default buckets, got %v", name, b)
+			}
+		}
+
+		reg = prometheus.NewRegistry()
+		litestreampp.NewHierarchicalMetricsWithOptions(litestreampp.MetricsOptions{
+			Registerer:          reg,
+			SyncDurationBuckets: litestreampp.LargeDatabaseSyncBuckets,
+		}).RecordSync(path, time.Minute, 0, false, nil)
+		got := bounds(t, reg)
+		for _, name := range []string{"litestream_tier_sync_duration_seconds", "litestream_project_sync_duration_seconds"} {
+			if !reflect.DeepEqual(got[name], litestreampp.LargeDatabaseSyncBuckets) {
+				t.Errorf("%s: expected large database buckets, got %v", name, got[name])
+			}
+		}
+	})
+
 	t.Run("UpdateStats", func(t *testing.T) {
-		metrics := litestreampp.GlobalMetrics
+		metrics := litestreampp.NewHierarchicalMetricsWithRegistry(prometheus.NewRegistry())
 		
 		// Update project stats
 		metrics.UpdateProjectStats("project1", 100, 10)
@@ -128,8 +169,185 @@ func TestHierarchicalMetrics(t *testing.T) {
 	})
 }
 
+func TestHierarchicalMetricsScanOverruns(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := litestreampp.NewHierarchicalMetricsWithRegistry(reg)
+	
+	metrics.RecordScan(2*time.Second, 15*time.Second, 100, true)
+	metrics.RecordScan(20*time.Second, 15*time.Second, 100, true)
+	metrics.RecordScan(2*time.Second, time.Second, 100, false)
+	metrics.RecordScan(2*time.Second, 0, 100, false) // No budget, never an overrun
+	
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overruns := make(map[string]float64)
+	for _, mf := range families {
+		if mf.GetName() != "litestream_write_detector_scan_overruns_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			overruns[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+		}
+	}
+	if want := map[string]float64{"full": 1, "slot": 1}; !reflect.DeepEqual(overruns, want) {
+		t.Errorf("overruns = %v, want %v", overruns, want)
+	}
+}
+
+func TestHierarchicalMetricsBranchAndTenant(t *testing.T) {
+	// series returns the values of a metric's series for a project, keyed by
+	// the given label
+	series := func(t *testing.T, reg prometheus.Gatherer, name, project, label string) map[string]float64 {
+		t.Helper()
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values := make(map[string]float64)
+		for _, mf := range families {
+			if mf.GetName() != name {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				labels := make(map[string]string)
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				if labels["project"] != project {
+					continue
+				}
+				if m.GetGauge() != nil {
+					values[labels[label]] = m.GetGauge().GetValue()
+				} else {
+					values[labels[label]] = m.GetCounter().GetValue()
+				}
+			}
+		}
+		return values
+	}
+
+	reg := prometheus.NewRegistry()
+	metrics := litestreampp.NewHierarchicalMetricsWithRegistry(reg)
+
+	t.Run("BranchSize", func(t *testing.T) {
+		metrics.RecordDBMetrics("/data/branchproj/databases/db/branches/main/tenants/t1.db", 100, 0, true)
+		metrics.RecordDBMetrics("/data/branchproj/databases/db/branches/main/tenants/t2.db", 200, 0, false)
+		metrics.RecordDBMetrics("/data/branchproj/databases/db/branches/dev/tenants/t1.db", 50, 0, false)
+		metrics.RecordSync("/data/branchproj/databases/db/branches/dev/tenants/t1.db", time.Millisecond, 0, false, nil)
+
+		if got := series(t, reg, "litestream_branch_size_bytes", "branchproj", "branch"); got["main"] != 300 || got["dev"] != 50 {
+			t.Errorf("unexpected branch sizes: %v", got)
+		}
+		if got := series(t, reg, "litestream_branch_sync_operations_total", "branchproj", "branch"); got["dev"] != 1 {
+			t.Errorf("unexpected branch sync operations: %v", got)
+		}
+
+		// Tenant metrics are off by default
+		if got := series(t, reg, "litestream_tenant_size_bytes", "branchproj", "tenant"); len(got) != 0 {
+			t.Errorf("expected no tenant series when disabled, got %v", got)
+		}
+	})
+
+	t.Run("TenantCardinality", func(t *testing.T) {
+		metrics.SetTenantMetrics(true, 3)
+		for i := 1; i <= 5; i++ {
+			path := fmt.Sprintf("/data/tenantproj/databases/db/branches/main/tenants/t%d.db", i)
+			metrics.RecordDBMetrics(path, int64(i*100), 0, false)
+			metrics.RecordSync(path, time.Millisecond, 0, false, nil)
+		}
+
+		sizes := series(t, reg, "litestream_tenant_size_bytes", "tenantproj", "tenant")
+		if len(sizes) != 3 || sizes["t1"] != 100 || sizes["t3"] != 300 {
+			t.Errorf("expected only the first 3 tenants, got %v", sizes)
+		}
+		if got := series(t, reg, "litestream_tenant_sync_operations_total", "tenantproj", "tenant"); len(got) != 3 {
+			t.Errorf("expected 3 tenant sync series, got %v", got)
+		}
+
+		// Tenants already emitted keep updating past the limit
+		metrics.RecordDBMetrics("/data/tenantproj/databases/db/branches/main/tenants/t2.db", 250, 0, false)
+		if got := series(t, reg, "litestream_tenant_size_bytes", "tenantproj", "tenant"); got["t2"] != 250 {
+			t.Errorf("expected t2 size to update, got %v", got)
+		}
+
+		// Disabling removes the tenant series
+		metrics.SetTenantMetrics(false, 0)
+		if got := series(t, reg, "litestream_tenant_size_bytes", "tenantproj", "tenant"); len(got) != 0 {
+			t.Errorf("expected tenant series to be removed, got %v", got)
+		}
+	})
+}
+
+func TestHierarchicalMetricsSyncLag(t *testing.T) {
+	// gauge returns the value of a gauge series with the given label value
+	gauge := func(t *testing.T, reg prometheus.Gatherer, name, label, value string) float64 {
+		t.Helper()
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range families {
+			if mf.GetName() != name {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == label && l.GetValue() == value {
+						return m.GetGauge().GetValue()
+					}
+				}
+				if label == "" {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+		return -1
+	}
+
+	reg := prometheus.NewRegistry()
+	metrics := litestreampp.NewHierarchicalMetricsWithRegistry(reg)
+	metrics.SetStaleSyncThreshold(50 * time.Millisecond)
+
+	hot := "/data/lagproj/databases/hotdb/branches/main/tenants/t1.db"
+	cold := "/data/lagproj/databases/colddb/branches/main/tenants/t1.db"
+	metrics.RecordSync(hot, time.Millisecond, 0, true, nil)
+	metrics.RecordSync(cold, time.Millisecond, 0, false, nil)
+
+	time.Sleep(100 * time.Millisecond)
+	metrics.RecordSync(hot, time.Millisecond, 0, true, nil)
+	metrics.RecordSync(cold, time.Millisecond, 0, false, &testError{"sync failed"})
+	metrics.UpdateSyncLag()
+
+	// The failed sync doesn't reset the cold database's lag
+	if lag := gauge(t, reg, "litestream_tier_sync_lag_seconds", "tier", "cold"); lag < 0.1 {
+		t.Errorf("expected cold tier lag of at least 100ms, got %v", lag)
+	}
+	if lag := gauge(t, reg, "litestream_tier_sync_lag_seconds", "tier", "hot"); lag >= 0.05 {
+		t.Errorf("expected hot tier lag under 50ms, got %v", lag)
+	}
+	if lag := gauge(t, reg, "litestream_project_sync_lag_seconds", "project", "lagproj"); lag < 0.1 {
+		t.Errorf("expected project lag to be the cold database's lag, got %v", lag)
+	}
+	if n := gauge(t, reg, "litestream_stale_databases", "", ""); n != 1 {
+		t.Errorf("expected 1 stale database, got %v", n)
+	}
+
+	// A successful sync resets the database's lag immediately
+	metrics.RecordSync(cold, time.Millisecond, 0, false, nil)
+	if lag := gauge(t, reg, "litestream_database_sync_lag_seconds", "database", "colddb"); lag != 0 {
+		t.Errorf("expected database lag reset to 0, got %v", lag)
+	}
+	metrics.UpdateSyncLag()
+	if n := gauge(t, reg, "litestream_stale_databases", "", ""); n != 0 {
+		t.Errorf("expected no stale databases, got %v", n)
+	}
+}
+
 func TestHierarchicalMetricsIntegration(t *testing.T) {
-	metrics := litestreampp.GlobalMetrics
+	metrics := litestreampp.NewHierarchicalMetricsWithRegistry(prometheus.NewRegistry())
 	
 	// Simulate a complete workflow
 	basePath := "/data/myapp/databases/primary/branches"
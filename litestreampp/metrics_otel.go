@@ -0,0 +1,132 @@
+package litestreampp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// otelSink writes metrics to OpenTelemetry instruments. Counters and
+// histograms are synchronous instruments; gauges keep their last value per
+// attribute set and report it from an observable gauge callback.
+type otelSink struct {
+	meter      metric.Meter
+	counters   map[*MetricDesc]metric.Float64Counter
+	histograms map[*MetricDesc]metric.Float64Histogram
+	gauges     map[*MetricDesc]*otelGauge
+}
+
+// otelGauge holds the current values of an observable gauge
+type otelGauge struct {
+	mu     sync.Mutex
+	values map[string]*otelGaugeValue // Keyed by joined label values
+}
+
+type otelGaugeValue struct {
+	attrs attribute.Set
+	value float64
+}
+
+// NewOTelMetricsSink returns a MetricsSink that records through meter.
+// Instrument and attribute names match the Prometheus metric and label
+// names. OpenTelemetry can't delete series, so Reset only clears gauges.
+func NewOTelMetricsSink(meter metric.Meter) MetricsSink {
+	return &otelSink{
+		meter:      meter,
+		counters:   make(map[*MetricDesc]metric.Float64Counter),
+		histograms: make(map[*MetricDesc]metric.Float64Histogram),
+		gauges:     make(map[*MetricDesc]*otelGauge),
+	}
+}
+
+func (s *otelSink) Register(desc *MetricDesc) error {
+	var err error
+	switch desc.Kind {
+	case MetricCounter:
+		s.counters[desc], err = s.meter.Float64Counter(desc.Name, metric.WithDescription(desc.Help))
+	case MetricHistogram:
+		s.histograms[desc], err = s.meter.Float64Histogram(desc.Name,
+			metric.WithDescription(desc.Help),
+			metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(desc.Buckets...))
+	case MetricGauge:
+		g := &otelGauge{values: make(map[string]*otelGaugeValue)}
+		s.gauges[desc] = g
+		_, err = s.meter.Float64ObservableGauge(desc.Name,
+			metric.WithDescription(desc.Help),
+			metric.WithFloat64Callback(g.observe))
+	default:
+		err = fmt.Errorf("unknown metric kind %d", desc.Kind)
+	}
+	if err != nil {
+		return fmt.Errorf("create instrument %s: %w", desc.Name, err)
+	}
+	return nil
+}
+
+func (s *otelSink) Add(desc *MetricDesc, delta float64, labelValues ...string) {
+	if c, ok := s.counters[desc]; ok {
+		c.Add(context.Background(), delta, metric.WithAttributeSet(otelAttributes(desc, labelValues)))
+	} else if g, ok := s.gauges[desc]; ok {
+		g.update(desc, labelValues, func(v float64) float64 { return v + delta })
+	}
+}
+
+func (s *otelSink) Set(desc *MetricDesc, value float64, labelValues ...string) {
+	if g, ok := s.gauges[desc]; ok {
+		g.update(desc, labelValues, func(float64) float64 { return value })
+	}
+}
+
+func (s *otelSink) Observe(desc *MetricDesc, value float64, labelValues ...string) {
+	if h, ok := s.histograms[desc]; ok {
+		h.Record(context.Background(), value, metric.WithAttributeSet(otelAttributes(desc, labelValues)))
+	}
+}
+
+func (s *otelSink) Reset(desc *MetricDesc) {
+	if g, ok := s.gauges[desc]; ok {
+		g.mu.Lock()
+		g.values = make(map[string]*otelGaugeValue)
+		g.mu.Unlock()
+	}
+}
+
+// update replaces a gauge value with fn applied to its current value
+func (g *otelGauge) update(desc *MetricDesc, labelValues []string, fn func(float64) float64) {
+	key := strings.Join(labelValues, "\x00")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v, ok := g.values[key]
+	if !ok {
+		v = &otelGaugeValue{attrs: otelAttributes(desc, labelValues)}
+		g.values[key] = v
+	}
+	v.value = fn(v.value)
+}
+
+// observe reports the current gauge values at collection time
+func (g *otelGauge) observe(_ context.Context, o metric.Float64Observer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, v := range g.values {
+		o.Observe(v.value, metric.WithAttributeSet(v.attrs))
+	}
+	return nil
+}
+
+// otelAttributes pairs a metric's label names with their values
+func otelAttributes(desc *MetricDesc, labelValues []string) attribute.Set {
+	kvs := make([]attribute.KeyValue, len(labelValues))
+	for i, value := range labelValues {
+		kvs[i] = attribute.String(desc.Labels[i], value)
+	}
+	return attribute.NewSet(kvs...)
+}
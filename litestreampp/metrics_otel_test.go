@@ -0,0 +1,167 @@
+package litestreampp_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/litestream/litestreampp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// recordingMeter records counter adds and histogram records by instrument
+// name, and keeps gauge callbacks so tests can collect them
+type recordingMeter struct {
+	noop.Meter
+
+	mu      sync.Mutex
+	records map[string][]otelRecord
+	gauges  map[string]metric.Float64Callback
+	buckets map[string][]float64
+}
+
+type otelRecord struct {
+	value float64
+	attrs attribute.Set
+}
+
+func newRecordingMeter() *recordingMeter {
+	return &recordingMeter{
+		records: make(map[string][]otelRecord),
+		gauges:  make(map[string]metric.Float64Callback),
+		buckets: make(map[string][]float64),
+	}
+}
+
+func (m *recordingMeter) record(name string, value float64, attrs attribute.Set) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[name] = append(m.records[name], otelRecord{value: value, attrs: attrs})
+}
+
+func (m *recordingMeter) Float64Counter(name string, _ ...metric.Float64CounterOption) (metric.Float64Counter, error) {
+	return &recordingCounter{meter: m, name: name}, nil
+}
+
+func (m *recordingMeter) Float64Histogram(name string, opts ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	m.buckets[name] = metric.NewFloat64HistogramConfig(opts...).ExplicitBucketBoundaries()
+	return &recordingHistogram{meter: m, name: name}, nil
+}
+
+func (m *recordingMeter) Float64ObservableGauge(name string, opts ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	if callbacks := metric.NewFloat64ObservableGaugeConfig(opts...).Callbacks(); len(callbacks) > 0 {
+		m.gauges[name] = callbacks[0]
+	}
+	return noop.Float64ObservableGauge{}, nil
+}
+
+// collect runs a gauge's callback and returns the observed values
+func (m *recordingMeter) collect(t *testing.T, name string) []otelRecord {
+	t.Helper()
+	callback, ok := m.gauges[name]
+	if !ok {
+		t.Fatalf("gauge %s not created", name)
+	}
+	o := &recordingObserver{}
+	if err := callback(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	return o.records
+}
+
+type recordingCounter struct {
+	noop.Float64Counter
+	meter *recordingMeter
+	name  string
+}
+
+func (c *recordingCounter) Add(_ context.Context, v float64, opts ...metric.AddOption) {
+	c.meter.record(c.name, v, metric.NewAddConfig(opts).Attributes())
+}
+
+type recordingHistogram struct {
+	noop.Float64Histogram
+	meter *recordingMeter
+	name  string
+}
+
+func (h *recordingHistogram) Record(_ context.Context, v float64, opts ...metric.RecordOption) {
+	h.meter.record(h.name, v, metric.NewRecordConfig(opts).Attributes())
+}
+
+type recordingObserver struct {
+	noop.Float64Observer
+	records []otelRecord
+}
+
+func (o *recordingObserver) Observe(v float64, opts ...metric.ObserveOption) {
+	o.records = append(o.records, otelRecord{value: v, attrs: metric.NewObserveConfig(opts).Attributes()})
+}
+
+func TestOTelMetricsSink(t *testing.T) {
+	meter := newRecordingMeter()
+	metrics := litestreampp.NewHierarchicalMetricsWithOptions(litestreampp.MetricsOptions{
+		Sink:                litestreampp.NewOTelMetricsSink(meter),
+		SyncDurationBuckets: litestreampp.LargeDatabaseSyncBuckets,
+	})
+
+	path := "/data/proj1/databases/db1/branches/main/tenants/tenant1.db"
+	metrics.RecordSync(path, 2*time.Second, 1024, true, nil)
+	metrics.RecordSync(path, time.Second, 0, true, nil)
+	metrics.UpdateTierCounts(3, 7)
+	metrics.UpdateDatabaseStats("proj1", "db1", 10, 2, 3)
+
+	t.Run("Counters", func(t *testing.T) {
+		records := meter.records["litestream_tier_sync_operations_total"]
+		if len(records) != 2 {
+			t.Fatalf("expected 2 tier sync adds, got %d", len(records))
+		}
+		if tier, _ := records[0].attrs.Value("tier"); tier.AsString() != "hot" {
+			t.Errorf("expected tier attribute hot, got %q", tier.AsString())
+		}
+		if records := meter.records["litestream_wal_bytes_written_total"]; len(records) != 1 || records[0].value != 1024 {
+			t.Errorf("expected one WAL bytes add of 1024, got %v", records)
+		}
+	})
+
+	t.Run("Histograms", func(t *testing.T) {
+		records := meter.records["litestream_project_sync_duration_seconds"]
+		if len(records) != 2 || records[0].value != 2 {
+			t.Fatalf("expected 2 project sync durations, got %v", records)
+		}
+		if project, _ := records[0].attrs.Value("project"); project.AsString() != "proj1" {
+			t.Errorf("expected project attribute proj1, got %q", project.AsString())
+		}
+		if got := meter.buckets["litestream_tier_sync_duration_seconds"]; len(got) != len(litestreampp.LargeDatabaseSyncBuckets) {
+			t.Errorf("expected configured bucket boundaries, got %v", got)
+		}
+	})
+
+	t.Run("Gauges", func(t *testing.T) {
+		if records := meter.collect(t, "litestream_hot_databases_total"); len(records) != 1 || records[0].value != 3 {
+			t.Errorf("expected hot database gauge of 3, got %v", records)
+		}
+
+		records := meter.collect(t, "litestream_database_tenants")
+		if len(records) != 1 || records[0].value != 10 {
+			t.Fatalf("expected database tenants gauge of 10, got %v", records)
+		}
+		want := attribute.NewSet(attribute.String("project", "proj1"), attribute.String("database", "db1"))
+		if !records[0].attrs.Equals(&want) {
+			t.Errorf("expected attributes %v, got %v", want.Encoded(attribute.DefaultEncoder()), records[0].attrs.Encoded(attribute.DefaultEncoder()))
+		}
+	})
+
+	t.Run("Manager", func(t *testing.T) {
+		meter := newRecordingMeter()
+		litestreampp.NewHotColdManager(&litestreampp.HotColdConfig{
+			MetricsSink: litestreampp.NewOTelMetricsSink(meter),
+		})
+		if _, ok := meter.gauges["litestream_hot_databases_total"]; !ok {
+			t.Error("expected manager metrics to be created through the sink")
+		}
+	})
+}
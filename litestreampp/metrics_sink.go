@@ -0,0 +1,131 @@
+package litestreampp
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricKind is the type of a metric written to a MetricsSink
+type MetricKind int
+
+const (
+	MetricCounter   MetricKind = iota // Total that only increases
+	MetricGauge                       // Value that can go up and down
+	MetricHistogram                   // Distribution of observations
+)
+
+// MetricDesc describes a metric written to a MetricsSink. Sinks key their
+// instruments by the descriptor pointer.
+type MetricDesc struct {
+	Name    string
+	Help    string
+	Kind    MetricKind
+	Labels  []string  // Label names, in the order values are passed
+	Buckets []float64 // Histogram bucket boundaries, in seconds
+}
+
+// MetricsSink is the backend HierarchicalMetrics writes through. Every
+// metric is registered once before any values are recorded, and label
+// values are passed in the order of the descriptor's labels.
+type MetricsSink interface {
+	// Register creates the instrument for a metric
+	Register(desc *MetricDesc) error
+
+	// Add adds delta to a counter or gauge
+	Add(desc *MetricDesc, delta float64, labelValues ...string)
+
+	// Set sets the value of a gauge
+	Set(desc *MetricDesc, value float64, labelValues ...string)
+
+	// Observe records a value in a histogram
+	Observe(desc *MetricDesc, value float64, labelValues ...string)
+
+	// Reset removes every series of a metric, where the backend allows it
+	Reset(desc *MetricDesc)
+}
+
+// prometheusSink writes metrics to Prometheus collectors
+type prometheusSink struct {
+	reg        prometheus.Registerer
+	counters   map[*MetricDesc]*prometheus.CounterVec
+	gauges     map[*MetricDesc]*prometheus.GaugeVec
+	histograms map[*MetricDesc]*prometheus.HistogramVec
+}
+
+// NewPrometheusSink returns a MetricsSink that registers its collectors
+// with reg. If reg is nil the collectors are not registered anywhere.
+func NewPrometheusSink(reg prometheus.Registerer) MetricsSink {
+	return &prometheusSink{
+		reg:        reg,
+		counters:   make(map[*MetricDesc]*prometheus.CounterVec),
+		gauges:     make(map[*MetricDesc]*prometheus.GaugeVec),
+		histograms: make(map[*MetricDesc]*prometheus.HistogramVec),
+	}
+}
+
+func (s *prometheusSink) Register(desc *MetricDesc) error {
+	var c prometheus.Collector
+	switch desc.Kind {
+	case MetricCounter:
+		vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: desc.Name, Help: desc.Help}, desc.Labels)
+		s.counters[desc] = vec
+		c = vec
+	case MetricGauge:
+		vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: desc.Name, Help: desc.Help}, desc.Labels)
+		s.gauges[desc] = vec
+		c = vec
+	case MetricHistogram:
+		vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: desc.Name, Help: desc.Help, Buckets: desc.Buckets}, desc.Labels)
+		s.histograms[desc] = vec
+		c = vec
+	default:
+		return fmt.Errorf("unknown metric kind %d for %s", desc.Kind, desc.Name)
+	}
+	if s.reg != nil {
+		if err := s.reg.Register(c); err != nil {
+			return fmt.Errorf("register %s: %w", desc.Name, err)
+		}
+	}
+
+	// Unlabeled metrics are exported as zero before their first update
+	if len(desc.Labels) == 0 {
+		switch desc.Kind {
+		case MetricCounter:
+			s.counters[desc].WithLabelValues()
+		case MetricGauge:
+			s.gauges[desc].WithLabelValues()
+		}
+	}
+	return nil
+}
+
+func (s *prometheusSink) Add(desc *MetricDesc, delta float64, labelValues ...string) {
+	if vec, ok := s.counters[desc]; ok {
+		vec.WithLabelValues(labelValues...).Add(delta)
+	} else if vec, ok := s.gauges[desc]; ok {
+		vec.WithLabelValues(labelValues...).Add(delta)
+	}
+}
+
+func (s *prometheusSink) Set(desc *MetricDesc, value float64, labelValues ...string) {
+	if vec, ok := s.gauges[desc]; ok {
+		vec.WithLabelValues(labelValues...).Set(value)
+	}
+}
+
+func (s *prometheusSink) Observe(desc *MetricDesc, value float64, labelValues ...string) {
+	if vec, ok := s.histograms[desc]; ok {
+		vec.WithLabelValues(labelValues...).Observe(value)
+	}
+}
+
+func (s *prometheusSink) Reset(desc *MetricDesc) {
+	if vec, ok := s.counters[desc]; ok {
+		vec.Reset()
+	} else if vec, ok := s.gauges[desc]; ok {
+		vec.Reset()
+	} else if vec, ok := s.histograms[desc]; ok {
+		vec.Reset()
+	}
+}
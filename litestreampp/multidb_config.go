@@ -1,25 +1,150 @@
 package litestreampp
 
 import (
+	"fmt"
+	"path/filepath"
+	"reflect"
 	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
 )
 
 // MultiDBConfig represents multi-database configuration
 type MultiDBConfig struct {
 	Enabled          bool                  `yaml:"enabled"`
 	Patterns         []string              `yaml:"patterns"`
+	ExcludePatterns  []string              `yaml:"exclude-patterns"` // Globs whose matches are never tracked
 	MaxHotDatabases  int                   `yaml:"max-hot-databases"`
 	ScanInterval     time.Duration         `yaml:"scan-interval"`
 	ReplicaTemplate  *ReplicaConfig        `yaml:"replica-template"`
-	ColdSyncInterval time.Duration         `yaml:"cold-sync-interval"`
-	ColdSyncMode     string                `yaml:"cold-sync-mode"`
+	ReplicaOverrides []ReplicaOverride     `yaml:"replica-overrides"` // Per-pattern changes to the replica template
+	ColdSyncInterval time.Duration         `yaml:"cold-sync-interval"` // How often changed cold databases are snapshotted (0 = never)
+	ColdSyncMode     string                `yaml:"cold-sync-mode"`     // "snapshot" (default) or "none"
 	HotPromotion     HotPromotionConfig    `yaml:"hot-promotion"`
+	WatchFilesystem  bool                  `yaml:"watch-filesystem"` // Promote on filesystem write events instead of waiting for a scan
+	ConfirmWithHeader bool                 `yaml:"confirm-with-header"` // Ignore changes that don't commit a transaction, like reads and checkpoints
+	SpreadScans      bool                  `yaml:"spread-scans"`     // Stat databases spread across the scan interval instead of all at once
+	EvictionPolicy   string                `yaml:"eviction-policy"`  // "lru" (default), "lfu", or "fifo"
+	StateFile        string                `yaml:"state-file"`       // JSON file persisting the hot set across restarts
+
+	// ManagementInterval is how often statistics are logged and metrics are
+	// aggregated (0 = 30s). Large deployments may want it less frequent.
+	ManagementInterval time.Duration `yaml:"management-interval"`
+
+	// EventBufferSize enables IntegratedMultiDBManager.Events with room for
+	// this many undelivered events (0 = disabled)
+	EventBufferSize int `yaml:"event-buffer-size"`
+
+	// DemotionSyncTimeout bounds the final replica sync before demotion
+	DemotionSyncTimeout time.Duration `yaml:"demotion-sync-timeout"`
+
+	// MaxConcurrentPromotions bounds how many promoted databases are opened
+	// at once (0 = 16). Raise it to absorb bursts of writes across many
+	// databases faster, at the cost of more simultaneous opens.
+	MaxConcurrentPromotions int `yaml:"max-concurrent-promotions"`
+
+	// Per-project hot database caps (0 = no cap beyond max-hot-databases)
+	PerProjectMaxHot     map[string]int `yaml:"per-project-max-hot"`
+	DefaultProjectMaxHot int            `yaml:"default-project-max-hot"`
+
+	// Tenant-level metrics, capped at MaxTenantCardinality label sets
+	// (0 = DefaultMaxTenantCardinality) to bound Prometheus series
+	TenantMetrics        bool `yaml:"tenant-metrics"`
+	MaxTenantCardinality int  `yaml:"max-tenant-cardinality"`
+
+	// StaleSyncThreshold is the sync lag after which a database counts as
+	// stale in metrics (0 = DefaultStaleSyncThreshold)
+	StaleSyncThreshold time.Duration `yaml:"stale-sync-threshold"`
+
+	// Metrics receives the manager's aggregated metrics (nil = GlobalMetrics)
+	Metrics *HierarchicalMetrics `yaml:"-"`
+
+	// MetricsSink is the backend for the manager's metrics when Metrics is
+	// nil, e.g. NewOTelMetricsSink for OTLP export
+	MetricsSink MetricsSink `yaml:"-"`
+}
+
+// Validate reports the first problem with the configuration that would
+// otherwise only surface once the manager runs. Zero values are left to
+// their defaults; negative limits and intervals are errors.
+func (c *MultiDBConfig) Validate() error {
+	if len(c.Patterns) == 0 {
+		return fmt.Errorf("at least one pattern required")
+	}
+	for _, pattern := range c.Patterns {
+		if !doublestar.ValidatePathPattern(pattern) {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, doublestar.ErrBadPattern)
+		}
+	}
+	for _, pattern := range c.ExcludePatterns {
+		if !doublestar.ValidatePathPattern(pattern) {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, doublestar.ErrBadPattern)
+		}
+	}
+	if _, err := NewEvictionPolicy(c.EvictionPolicy); err != nil {
+		return err
+	}
+	switch c.ColdSyncMode {
+	case "", "snapshot", "none":
+	default:
+		return fmt.Errorf("unknown cold-sync-mode %q", c.ColdSyncMode)
+	}
+
+	for _, v := range []struct {
+		name  string
+		value int64
+	}{
+		{"max-hot-databases", int64(c.MaxHotDatabases)},
+		{"default-project-max-hot", int64(c.DefaultProjectMaxHot)},
+		{"event-buffer-size", int64(c.EventBufferSize)},
+		{"max-concurrent-promotions", int64(c.MaxConcurrentPromotions)},
+		{"max-tenant-cardinality", int64(c.MaxTenantCardinality)},
+		{"hot-promotion.access-count-threshold", c.HotPromotion.AccessCountThreshold},
+		{"scan-interval", int64(c.ScanInterval)},
+		{"cold-sync-interval", int64(c.ColdSyncInterval)},
+		{"management-interval", int64(c.ManagementInterval)},
+		{"demotion-sync-timeout", int64(c.DemotionSyncTimeout)},
+		{"stale-sync-threshold", int64(c.StaleSyncThreshold)},
+		{"hot-promotion.recent-modify-threshold", int64(c.HotPromotion.RecentModifyThreshold)},
+		{"hot-promotion.min-hot-duration", int64(c.HotPromotion.MinHotDuration)},
+	} {
+		if v.value < 0 {
+			return fmt.Errorf("%s must not be negative", v.name)
+		}
+	}
+	for project, max := range c.PerProjectMaxHot {
+		if max < 0 {
+			return fmt.Errorf("per-project-max-hot for %q must not be negative", project)
+		}
+	}
+
+	if c.ReplicaTemplate != nil {
+		if err := c.ReplicaTemplate.Validate(); err != nil {
+			return fmt.Errorf("replica template: %w", err)
+		}
+	}
+	for _, o := range c.ReplicaOverrides {
+		if _, err := filepath.Match(o.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid replica override pattern %q: %w", o.Pattern, err)
+		}
+
+		// Overrides only need to be complete once applied to the template
+		var config ReplicaConfig
+		if c.ReplicaTemplate != nil {
+			config = *c.ReplicaTemplate
+		}
+		if err := config.merge(o.ReplicaConfig).Validate(); err != nil {
+			return fmt.Errorf("replica override %q: %w", o.Pattern, err)
+		}
+	}
+	return nil
 }
 
 // HotPromotionConfig defines criteria for promoting databases to hot tier
 type HotPromotionConfig struct {
 	RecentModifyThreshold time.Duration `yaml:"recent-modify-threshold"`
 	AccessCountThreshold  int64         `yaml:"access-count-threshold"`
+	MinHotDuration        time.Duration `yaml:"min-hot-duration"`
 }
 
 // ReplicaConfig represents configuration for a replica
@@ -34,9 +159,104 @@ type ReplicaConfig struct {
 	Endpoint     string         `yaml:"endpoint"`
 	SyncInterval time.Duration  `yaml:"sync-interval"`
 	
+	// UploadConcurrency is the number of LTX files uploaded in parallel per database
+	UploadConcurrency int `yaml:"upload-concurrency"`
+	
 	// S3 specific
 	AccessKeyID     string `yaml:"access-key-id"`
 	SecretAccessKey string `yaml:"secret-access-key"`
+	
+	// StorageClass is the S3 storage class of uploads (e.g. STANDARD_IA or
+	// GLACIER_IR). Overrides can pick a colder class for rarely restored
+	// databases. Blank uses the bucket default.
+	StorageClass string `yaml:"storage-class"`
+	
+	// GCS specific. Application Default Credentials are used if blank.
+	CredentialsFile string `yaml:"credentials-file"`
+	
+	// Azure specific. Bucket is the container name; SASToken, if set, is
+	// used instead of AccountKey.
+	AccountName string `yaml:"account-name"`
+	AccountKey  string `yaml:"account-key"`
+	SASToken    string `yaml:"sas-token"`
+}
+
+// Validate checks the replica type is one DefaultReplicaClientFactory
+// supports and that the fields the type requires are set. Path may still
+// contain template variables.
+func (c ReplicaConfig) Validate() error {
+	switch c.Type {
+	case "":
+		return fmt.Errorf("replica type required")
+	case "s3":
+	case "gcs":
+		if c.Bucket == "" {
+			return fmt.Errorf("bucket required for gcs replica")
+		}
+	case "abs", "azure":
+		if c.Bucket == "" {
+			return fmt.Errorf("container required for azure replica")
+		} else if c.AccountName == "" {
+			return fmt.Errorf("account name required for azure replica")
+		}
+	case "file":
+		if c.Path == "" {
+			return fmt.Errorf("file replica path required")
+		}
+	default:
+		return fmt.Errorf("unsupported replica type: %s", c.Type)
+	}
+
+	if c.SyncInterval < 0 {
+		return fmt.Errorf("sync-interval must not be negative")
+	}
+	if c.UploadConcurrency < 0 {
+		return fmt.Errorf("upload-concurrency must not be negative")
+	}
+	return nil
+}
+
+// merge returns c with every non-zero field of o copied over it
+func (c ReplicaConfig) merge(o ReplicaConfig) ReplicaConfig {
+	dst := reflect.ValueOf(&c).Elem()
+	src := reflect.ValueOf(o)
+	for i := 0; i < src.NumField(); i++ {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+	return c
+}
+
+// ReplicaOverride changes the replica template for databases matching a
+// glob pattern. Only the non-zero fields of the embedded ReplicaConfig are
+// applied, so an override can change just the sync interval. When several
+// overrides match, the most specific pattern wins.
+type ReplicaOverride struct {
+	Pattern       string `yaml:"pattern"`
+	ReplicaConfig `yaml:",inline"`
+}
+
+// patternSpecificity ranks how specific a glob pattern is by its number of
+// literal characters. An exact path outranks any pattern matching it.
+func patternSpecificity(pattern string) int {
+	var n int
+	var inClass bool
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '*' || c == '?':
+		case c == '\\':
+			i++
+			n++
+		default:
+			n++
+		}
+	}
+	return n
 }
 
 // DefaultMultiDBConfig returns default multi-database configuration
@@ -47,6 +267,7 @@ func DefaultMultiDBConfig() *MultiDBConfig {
 		ScanInterval:     30 * time.Second,
 		ColdSyncInterval: 30 * time.Second,
 		ColdSyncMode:     "snapshot",
+		ManagementInterval: 30 * time.Second,
 		HotPromotion: HotPromotionConfig{
 			RecentModifyThreshold: 5 * time.Minute,
 			AccessCountThreshold:  10,
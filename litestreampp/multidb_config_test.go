@@ -0,0 +1,74 @@
+package litestreampp_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/litestreampp"
+)
+
+func TestMultiDBConfigValidate(t *testing.T) {
+	valid := func() *litestreampp.MultiDBConfig {
+		return &litestreampp.MultiDBConfig{
+			Enabled:         true,
+			Patterns:        []string{"/data/**/*.db"},
+			MaxHotDatabases: 10,
+			ScanInterval:    time.Second,
+			ReplicaTemplate: &litestreampp.ReplicaConfig{
+				Type:   "s3",
+				Bucket: "backups",
+				Path:   "{{project}}/{{database}}",
+			},
+		}
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		modify func(c *litestreampp.MultiDBConfig)
+		want   string
+	}{
+		{"NoPatterns", func(c *litestreampp.MultiDBConfig) { c.Patterns = nil }, "at least one pattern"},
+		{"BadPattern", func(c *litestreampp.MultiDBConfig) { c.Patterns = []string{"/data/[*.db"} }, "invalid pattern"},
+		{"BadExcludePattern", func(c *litestreampp.MultiDBConfig) { c.ExcludePatterns = []string{"[tmp"} }, "invalid exclude pattern"},
+		{"NegativeMaxHot", func(c *litestreampp.MultiDBConfig) { c.MaxHotDatabases = -1 }, "max-hot-databases"},
+		{"NegativeScanInterval", func(c *litestreampp.MultiDBConfig) { c.ScanInterval = -time.Second }, "scan-interval"},
+		{"NegativeProjectMaxHot", func(c *litestreampp.MultiDBConfig) { c.PerProjectMaxHot = map[string]int{"proj1": -1} }, "proj1"},
+		{"UnknownColdSyncMode", func(c *litestreampp.MultiDBConfig) { c.ColdSyncMode = "wal" }, "cold-sync-mode"},
+		{"UnknownEvictionPolicy", func(c *litestreampp.MultiDBConfig) { c.EvictionPolicy = "random" }, "random"},
+		{"UnknownReplicaType", func(c *litestreampp.MultiDBConfig) { c.ReplicaTemplate.Type = "ftp" }, "unsupported replica type: ftp"},
+		{"MissingReplicaType", func(c *litestreampp.MultiDBConfig) { c.ReplicaTemplate.Type = "" }, "replica type required"},
+		{"IncompleteOverride", func(c *litestreampp.MultiDBConfig) {
+			c.ReplicaOverrides = []litestreampp.ReplicaOverride{{
+				Pattern:       "/data/archive/*.db",
+				ReplicaConfig: litestreampp.ReplicaConfig{Type: "gcs", Bucket: " "},
+			}, {
+				Pattern:       "/data/eu/*.db",
+				ReplicaConfig: litestreampp.ReplicaConfig{Type: "azure"},
+			}}
+		}, `replica override "/data/eu/*.db": account name required`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("RejectedByManager", func(t *testing.T) {
+		config := valid()
+		config.Patterns = nil
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		if _, err := litestreampp.NewIntegratedMultiDBManager(store, config); err == nil || !strings.Contains(err.Error(), "invalid multi-db config") {
+			t.Errorf("expected invalid config error, got %v", err)
+		}
+	})
+}
@@ -2,14 +2,23 @@ package litestreampp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/litestream"
+	"github.com/bmatcuk/doublestar/v4"
 )
 
+// workerPoolDrainTimeout bounds how long Stop waits for queued and running
+// worker pool tasks before abandoning them
+const workerPoolDrainTimeout = 30 * time.Second
+
 // IntegratedMultiDBManager combines MultiDBManager with HotColdManager for Phase 3
 type IntegratedMultiDBManager struct {
 	mu sync.RWMutex
@@ -19,18 +28,29 @@ type IntegratedMultiDBManager struct {
 	hotColdManager  *HotColdManager
 	sharedResources *SharedResourceManager
 	connectionPool  *ConnectionPool
+	metrics         *HierarchicalMetrics
 
 	// Configuration
-	config *MultiDBConfig
+	config             *MultiDBConfig
+	managementInterval time.Duration // How often monitorLoop logs statistics
 
 	// Control
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running atomic.Bool // Between a successful Start and Stop
 }
 
 // NewIntegratedMultiDBManager creates a new integrated manager
 func NewIntegratedMultiDBManager(store *litestream.Store, config *MultiDBConfig) (*IntegratedMultiDBManager, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid multi-db config: %w", err)
+	}
+	evictionPolicy, err := NewEvictionPolicy(config.EvictionPolicy)
+	if err != nil {
+		return nil, err
+	}
+	
 	// Create shared resources
 	sharedResources := NewSharedResourceManager()
 	
@@ -39,34 +59,64 @@ func NewIntegratedMultiDBManager(store *litestream.Store, config *MultiDBConfig)
 	
 	// Create replica factory if replication is configured
 	var replicaFactory ReplicaClientFactory
-	if config.ReplicaTemplate != nil {
+	if config.ReplicaTemplate != nil || len(config.ReplicaOverrides) > 0 {
 		factory := NewDefaultReplicaClientFactory()
 		// Note: S3 client creation function must be injected from cmd package
 		// to avoid import cycles
 		replicaFactory = factory
 	}
 	
+	// Snapshot cold databases unless disabled
+	coldSyncInterval := config.ColdSyncInterval
+	if config.ColdSyncMode == "none" {
+		coldSyncInterval = 0
+	}
+
 	// Create hot/cold configuration
 	hotColdConfig := &HotColdConfig{
-		MaxHotDatabases: config.MaxHotDatabases,
-		ScanInterval:    config.ScanInterval,
-		HotDuration:     config.HotPromotion.RecentModifyThreshold,
-		Store:           store,
-		SharedResources: sharedResources,
-		ConnectionPool:  connectionPool,
-		ReplicaTemplate: config.ReplicaTemplate, // Pass replica template
-		ReplicaFactory:  replicaFactory,
+		MaxHotDatabases:      config.MaxHotDatabases,
+		ScanInterval:         config.ScanInterval,
+		HotDuration:          config.HotPromotion.RecentModifyThreshold,
+		MinHotDuration:       config.HotPromotion.MinHotDuration,
+		AccessCountThreshold: config.HotPromotion.AccessCountThreshold,
+		Store:                store,
+		SharedResources:      sharedResources,
+		ConnectionPool:       connectionPool,
+		ReplicaTemplate:      config.ReplicaTemplate, // Pass replica template
+		ReplicaFactory:       replicaFactory,
+		ReplicaOverrides:     config.ReplicaOverrides,
+		ExcludePatterns:      config.ExcludePatterns,
+		WatchFilesystem:      config.WatchFilesystem,
+		ConfirmWithHeader:    config.ConfirmWithHeader,
+		SpreadScans:          config.SpreadScans,
+		EvictionPolicy:       evictionPolicy,
+		StateFile:            config.StateFile,
+		Patterns:             config.Patterns,
+		PerProjectMaxHot:     config.PerProjectMaxHot,
+		DefaultProjectMaxHot: config.DefaultProjectMaxHot,
+		DemotionSyncTimeout:  config.DemotionSyncTimeout,
+		MaxConcurrentPromotions: config.MaxConcurrentPromotions,
+		ColdSyncInterval:     coldSyncInterval,
+		ManagementInterval:   config.ManagementInterval,
+		EventBufferSize:      config.EventBufferSize,
+		Metrics:              config.Metrics,
+		MetricsSink:          config.MetricsSink,
 	}
 	
 	// Create hot/cold manager
 	hotColdManager := NewHotColdManager(hotColdConfig)
 	
+	hotColdConfig.Metrics.SetTenantMetrics(config.TenantMetrics, config.MaxTenantCardinality)
+	hotColdConfig.Metrics.SetStaleSyncThreshold(config.StaleSyncThreshold)
+	
 	return &IntegratedMultiDBManager{
 		store:           store,
 		hotColdManager:  hotColdManager,
 		sharedResources: sharedResources,
 		connectionPool:  connectionPool,
+		metrics:         hotColdConfig.Metrics,
 		config:          config,
+		managementInterval: hotColdConfig.ManagementInterval,
 	}, nil
 }
 
@@ -87,7 +137,7 @@ func (m *IntegratedMultiDBManager) Start(ctx context.Context) error {
 	}
 	
 	// Add databases from patterns
-	if err := m.hotColdManager.AddDatabases(m.config.Patterns); err != nil {
+	if err := m.addDatabases(m.config.Patterns); err != nil {
 		return fmt.Errorf("add databases: %w", err)
 	}
 	
@@ -95,6 +145,8 @@ func (m *IntegratedMultiDBManager) Start(ctx context.Context) error {
 	m.wg.Add(1)
 	go m.monitorLoop()
 	
+	m.running.Store(true)
+	
 	slog.Info("integrated multi-DB manager started",
 		"patterns", m.config.Patterns,
 		"max_hot_databases", m.config.MaxHotDatabases,
@@ -105,6 +157,8 @@ func (m *IntegratedMultiDBManager) Start(ctx context.Context) error {
 
 // Stop stops the manager
 func (m *IntegratedMultiDBManager) Stop() error {
+	m.running.Store(false)
+	
 	if m.cancel != nil {
 		m.cancel()
 	}
@@ -117,6 +171,18 @@ func (m *IntegratedMultiDBManager) Stop() error {
 	// Wait for goroutines
 	m.wg.Wait()
 	
+	// Drain shared worker pools
+	ctx, cancel := context.WithTimeout(context.Background(), workerPoolDrainTimeout)
+	defer cancel()
+	if err := m.sharedResources.Shutdown(ctx); err != nil {
+		slog.Error("failed to drain worker pools", "error", err)
+	}
+	
+	// Close connections left open by hot databases and snapshot reads
+	if err := m.connectionPool.CloseAll(); err != nil {
+		slog.Error("failed to close pooled connections", "error", err)
+	}
+	
 	slog.Info("integrated multi-DB manager stopped")
 	return nil
 }
@@ -125,7 +191,7 @@ func (m *IntegratedMultiDBManager) Stop() error {
 func (m *IntegratedMultiDBManager) monitorLoop() {
 	defer m.wg.Done()
 	
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(m.managementInterval)
 	defer ticker.Stop()
 	
 	for {
@@ -140,18 +206,28 @@ func (m *IntegratedMultiDBManager) monitorLoop() {
 
 // logStatistics logs current system statistics
 func (m *IntegratedMultiDBManager) logStatistics() {
-	total, hot, cold := m.hotColdManager.GetStatistics()
+	total, hot, cold, promotions, demotions := m.hotColdManager.GetStatistics()
 	connStats := m.connectionPool.Stats()
 	
 	slog.Info("system statistics",
+		"paused", m.hotColdManager.Paused(),
 		"total_databases", total,
 		"hot_databases", hot,
 		"cold_databases", cold,
+		"total_promotions", promotions,
+		"total_demotions", demotions,
 		"open_connections", connStats.CurrentOpen,
-		"total_connections", connStats.TotalOpened)
+		"total_connections", connStats.TotalOpened,
+		"connection_hit_ratio", connStats.HitRatio)
 	
-	// Log shared resource stats (if we add ActiveCount method later)
-	// Currently worker pools don't expose active count
+	for _, pool := range m.sharedResources.WorkerPoolStats() {
+		slog.Info("worker pool statistics",
+			"pool", pool.Name,
+			"workers", pool.Workers,
+			"active", pool.Active,
+			"queued", pool.Queued,
+			"queue_capacity", pool.QueueCapacity)
+	}
 }
 
 
@@ -165,10 +241,54 @@ func (m *IntegratedMultiDBManager) SetS3ClientFactory(fn func(*ReplicaConfig) (l
 	}
 }
 
+// SetGCSClientFactory sets the GCS client creation function
+// This must be called from the cmd package to avoid import cycles
+func (m *IntegratedMultiDBManager) SetGCSClientFactory(fn func(*ReplicaConfig) (litestream.ReplicaClient, error)) {
+	if m.hotColdManager != nil && m.hotColdManager.replicaFactory != nil {
+		if factory, ok := m.hotColdManager.replicaFactory.(*DefaultReplicaClientFactory); ok {
+			factory.CreateGCSClientFunc = fn
+		}
+	}
+}
+
+// SetAzureClientFactory sets the Azure Blob Storage client creation function
+// This must be called from the cmd package to avoid import cycles
+func (m *IntegratedMultiDBManager) SetAzureClientFactory(fn func(*ReplicaConfig) (litestream.ReplicaClient, error)) {
+	if m.hotColdManager != nil && m.hotColdManager.replicaFactory != nil {
+		if factory, ok := m.hotColdManager.replicaFactory.(*DefaultReplicaClientFactory); ok {
+			factory.CreateAzureClientFunc = fn
+		}
+	}
+}
+
 // GetStatistics returns current statistics
-func (m *IntegratedMultiDBManager) GetStatistics() (total, hot, cold int, connStats ConnectionPoolStats) {
-	total, hot, cold = m.hotColdManager.GetStatistics()
+func (m *IntegratedMultiDBManager) GetStatistics() (total, hot, cold int, connStats ConnectionPoolStats, poolStats []WorkerPoolStats) {
+	total, hot, cold, _, _ = m.hotColdManager.GetStatistics()
 	connStats = m.connectionPool.Stats()
+	poolStats = m.sharedResources.WorkerPoolStats()
+	return
+}
+
+// Pause stops promotions, demotions, and replica syncs, such as during
+// maintenance, while keeping every database tracked and hot databases open
+func (m *IntegratedMultiDBManager) Pause() {
+	m.hotColdManager.Pause()
+}
+
+// Resume undoes Pause, promoting databases written to while paused
+func (m *IntegratedMultiDBManager) Resume() {
+	m.hotColdManager.Resume()
+}
+
+// Paused reports whether the manager is paused
+func (m *IntegratedMultiDBManager) Paused() bool {
+	return m.hotColdManager.Paused()
+}
+
+// GetTierTransitions returns the number of promotions to and demotions from
+// the hot tier since the manager was created
+func (m *IntegratedMultiDBManager) GetTierTransitions() (totalPromotions, totalDemotions int64) {
+	_, _, _, totalPromotions, totalDemotions = m.hotColdManager.GetStatistics()
 	return
 }
 
@@ -177,12 +297,271 @@ func (m *IntegratedMultiDBManager) GetHotDatabases() []string {
 	return m.hotColdManager.GetHotDatabases()
 }
 
+// GetHotDatabasesByAccess returns the hot databases from most to least
+// accessed
+func (m *IntegratedMultiDBManager) GetHotDatabasesByAccess() []DatabaseAccess {
+	return m.hotColdManager.GetHotDatabasesByAccess()
+}
+
+// RecordAccess counts an access to a tracked database toward the hot
+// promotion access count threshold
+func (m *IntegratedMultiDBManager) RecordAccess(path string) error {
+	return m.hotColdManager.RecordAccess(path)
+}
+
+// InFlightOperations returns the operations currently queued or running
+func (m *IntegratedMultiDBManager) InFlightOperations() []Operation {
+	return m.hotColdManager.InFlightOperations()
+}
+
+// CancelOperation cancels an in-flight operation by id
+func (m *IntegratedMultiDBManager) CancelOperation(id string) error {
+	return m.hotColdManager.CancelOperation(id)
+}
+
+// PromoteNow promotes a database to hot immediately
+func (m *IntegratedMultiDBManager) PromoteNow(path string) error {
+	return m.hotColdManager.PromoteNow(path)
+}
+
+// DemoteNow demotes a database to cold immediately
+func (m *IntegratedMultiDBManager) DemoteNow(path string) error {
+	return m.hotColdManager.DemoteNow(path)
+}
+
+// Pin keeps a database hot until Unpin is called
+func (m *IntegratedMultiDBManager) Pin(path string) error {
+	return m.hotColdManager.Pin(path)
+}
+
+// Unpin returns a pinned database to normal expiry
+func (m *IntegratedMultiDBManager) Unpin(path string) error {
+	return m.hotColdManager.Unpin(path)
+}
+
+// GetColdDatabases returns copies of the tracked cold databases
+func (m *IntegratedMultiDBManager) GetColdDatabases() []ColdDBInfo {
+	return m.hotColdManager.GetColdDatabases()
+}
+
+// GetDatabaseTier returns the tier of a tracked database
+func (m *IntegratedMultiDBManager) GetDatabaseTier(path string) (string, bool) {
+	return m.hotColdManager.GetDatabaseTier(path)
+}
+
+// Events returns the channel promotions, demotions, replica starts and
+// stops, and errors are published on, or nil if event-buffer-size is zero.
+// Consumers must drain it: events that arrive while the buffer is full are
+// dropped so replication never waits on a slow consumer.
+func (m *IntegratedMultiDBManager) Events() <-chan TierEvent {
+	return m.hotColdManager.Events()
+}
+
 // IsHot checks if a database is hot
 func (m *IntegratedMultiDBManager) IsHot(path string) bool {
 	return m.hotColdManager.IsHot(path)
 }
 
-// RefreshPatterns re-scans the patterns for new databases
+// Reconfigure applies a new configuration to the running manager while
+// preserving hot/cold tracking state. The hot database limit, scan interval,
+// hot duration, and minimum hot duration are applied live, and newly added
+// patterns are scanned. Changes that require a restart, such as disabling
+// multi-DB mode, removing patterns, or changing the replica template,
+// return an error and leave the current configuration in place.
+func (m *IntegratedMultiDBManager) Reconfigure(cfg *MultiDBConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid multi-db config: %w", err)
+	}
+	old := m.config
+	if cfg.Enabled != old.Enabled {
+		return fmt.Errorf("cannot change enabled without restart")
+	}
+	if !reflect.DeepEqual(cfg.ReplicaTemplate, old.ReplicaTemplate) {
+		return fmt.Errorf("cannot change replica template without restart")
+	}
+	if !reflect.DeepEqual(cfg.ReplicaOverrides, old.ReplicaOverrides) {
+		return fmt.Errorf("cannot change replica overrides without restart")
+	}
+	if !slices.Equal(cfg.ExcludePatterns, old.ExcludePatterns) {
+		return fmt.Errorf("cannot change exclude-patterns without restart")
+	}
+	if cfg.WatchFilesystem != old.WatchFilesystem {
+		return fmt.Errorf("cannot change watch-filesystem without restart")
+	}
+	if cfg.ConfirmWithHeader != old.ConfirmWithHeader {
+		return fmt.Errorf("cannot change confirm-with-header without restart")
+	}
+	if cfg.EvictionPolicy != old.EvictionPolicy {
+		return fmt.Errorf("cannot change eviction-policy without restart")
+	}
+	if cfg.StateFile != old.StateFile {
+		return fmt.Errorf("cannot change state-file without restart")
+	}
+	if cfg.ManagementInterval != old.ManagementInterval {
+		return fmt.Errorf("cannot change management-interval without restart")
+	}
+	if cfg.EventBufferSize != old.EventBufferSize {
+		return fmt.Errorf("cannot change event-buffer-size without restart")
+	}
+	if cfg.MaxConcurrentPromotions != old.MaxConcurrentPromotions {
+		return fmt.Errorf("cannot change max-concurrent-promotions without restart")
+	}
+	if cfg.ColdSyncInterval != old.ColdSyncInterval || cfg.ColdSyncMode != old.ColdSyncMode {
+		return fmt.Errorf("cannot change cold sync settings without restart")
+	}
+
+	existing := make(map[string]bool, len(old.Patterns))
+	for _, pattern := range old.Patterns {
+		existing[pattern] = true
+	}
+	requested := make(map[string]bool, len(cfg.Patterns))
+	var added []string
+	for _, pattern := range cfg.Patterns {
+		requested[pattern] = true
+		if !existing[pattern] {
+			added = append(added, pattern)
+		}
+	}
+	for _, pattern := range old.Patterns {
+		if !requested[pattern] {
+			return fmt.Errorf("cannot remove pattern without restart: %s", pattern)
+		}
+	}
+
+	m.hotColdManager.Reconfigure(
+		cfg.MaxHotDatabases,
+		cfg.ScanInterval,
+		cfg.HotPromotion.RecentModifyThreshold,
+		cfg.HotPromotion.MinHotDuration,
+	)
+	if cfg.MaxHotDatabases > 0 {
+		m.connectionPool.SetMaxConnections(cfg.MaxHotDatabases)
+	}
+	if cfg.HotPromotion.AccessCountThreshold != old.HotPromotion.AccessCountThreshold {
+		m.hotColdManager.SetAccessCountThreshold(cfg.HotPromotion.AccessCountThreshold)
+	}
+	if cfg.SpreadScans != old.SpreadScans {
+		m.hotColdManager.SetSpreadScans(cfg.SpreadScans)
+	}
+	if cfg.DefaultProjectMaxHot != old.DefaultProjectMaxHot || !reflect.DeepEqual(cfg.PerProjectMaxHot, old.PerProjectMaxHot) {
+		m.hotColdManager.SetProjectQuotas(cfg.PerProjectMaxHot, cfg.DefaultProjectMaxHot)
+	}
+	if cfg.TenantMetrics != old.TenantMetrics || cfg.MaxTenantCardinality != old.MaxTenantCardinality {
+		m.metrics.SetTenantMetrics(cfg.TenantMetrics, cfg.MaxTenantCardinality)
+	}
+	if cfg.StaleSyncThreshold != old.StaleSyncThreshold {
+		m.metrics.SetStaleSyncThreshold(cfg.StaleSyncThreshold)
+	}
+
+	if len(added) > 0 {
+		if err := m.addDatabases(added); err != nil {
+			return fmt.Errorf("add databases: %w", err)
+		}
+	}
+
+	copied := *cfg
+	m.config = &copied
+
+	slog.Info("integrated multi-DB manager reconfigured",
+		"patterns", cfg.Patterns,
+		"max_hot_databases", cfg.MaxHotDatabases,
+		"scan_interval", cfg.ScanInterval)
+	return nil
+}
+
+// addDatabases tracks the matches of patterns. A pattern that can't be
+// matched at all fails, but matches that couldn't be added, such as files
+// deleted since the glob, are only logged; RefreshPatterns retries them.
+func (m *IntegratedMultiDBManager) addDatabases(patterns []string) error {
+	err := m.hotColdManager.AddDatabases(patterns)
+	var patternErr *PatternError
+	if errors.As(err, &patternErr) {
+		return err
+	} else if err != nil {
+		slog.Warn("some databases could not be added", "error", err)
+	}
+	return nil
+}
+
+// RefreshPatterns re-scans the patterns for new databases, returning every
+// failure to add one
 func (m *IntegratedMultiDBManager) RefreshPatterns() error {
-	return m.hotColdManager.AddDatabases(m.config.Patterns)
+	m.mu.RLock()
+	patterns := m.config.Patterns
+	m.mu.RUnlock()
+
+	return m.hotColdManager.AddDatabases(patterns)
+}
+
+// AddPattern starts managing databases matching a glob pattern, which may
+// use **, tracking existing matches immediately. Adding a pattern already configured has no
+// effect.
+func (m *IntegratedMultiDBManager) AddPattern(pattern string) error {
+	if !doublestar.ValidatePathPattern(pattern) {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, doublestar.ErrBadPattern)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slices.Contains(m.config.Patterns, pattern) {
+		return nil
+	}
+
+	if err := m.addDatabases([]string{pattern}); err != nil {
+		return fmt.Errorf("add databases: %w", err)
+	}
+
+	copied := *m.config
+	copied.Patterns = append(slices.Clone(m.config.Patterns), pattern)
+	m.config = &copied
+
+	slog.Info("pattern added", "pattern", pattern)
+	return nil
+}
+
+// RemovePattern stops managing databases matched only by pattern. Databases
+// also matched by another configured pattern stay tracked.
+func (m *IntegratedMultiDBManager) RemovePattern(pattern string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.Index(m.config.Patterns, pattern)
+	if i < 0 {
+		return fmt.Errorf("pattern not configured: %s", pattern)
+	}
+	remaining := slices.Delete(slices.Clone(m.config.Patterns), i, i+1)
+
+	var removed int
+	var errs []error
+	for _, path := range m.hotColdManager.trackedPaths() {
+		if ok, _ := doublestar.PathMatch(pattern, path); !ok || matchesAny(remaining, path) {
+			continue
+		}
+		if err := m.hotColdManager.RemoveDatabase(path); err != nil {
+			errs = append(errs, err)
+		}
+		removed++
+	}
+
+	copied := *m.config
+	copied.Patterns = remaining
+	m.config = &copied
+
+	slog.Info("pattern removed", "pattern", pattern, "databases_removed", removed)
+	return errors.Join(errs...)
+}
+
+// matchesAny returns true if path matches any of the glob patterns, which
+// may use **
+func matchesAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.PathMatch(pattern, path); ok {
+			return true
+		}
+	}
+	return false
 }
\ No newline at end of file
@@ -2,7 +2,10 @@ package litestreampp_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -60,7 +63,7 @@ func TestIntegratedMultiDBManager(t *testing.T) {
 		time.Sleep(150 * time.Millisecond)
 		
 		// Check initial state (all should be cold)
-		total, hot, cold, _ := manager.GetStatistics()
+		total, hot, cold, _, _ := manager.GetStatistics()
 		if total != 3 {
 			t.Errorf("expected 3 total databases, got %d", total)
 		}
@@ -89,7 +92,10 @@ func TestIntegratedMultiDBManager(t *testing.T) {
 			t.Error("db3 should remain cold")
 		}
 		
-		total, hot, cold, connStats := manager.GetStatistics()
+		total, hot, cold, connStats, poolStats := manager.GetStatistics()
+		if len(poolStats) != 3 {
+			t.Errorf("expected stats for 3 worker pools, got %d", len(poolStats))
+		}
 		if hot != 2 {
 			t.Errorf("expected 2 hot databases, got %d", hot)
 		}
@@ -104,7 +110,7 @@ func TestIntegratedMultiDBManager(t *testing.T) {
 		time.Sleep(300 * time.Millisecond)
 		
 		// All should be cold again
-		total, hot, cold, _ = manager.GetStatistics()
+		total, hot, cold, _, _ = manager.GetStatistics()
 		if hot != 0 {
 			t.Errorf("expected 0 hot databases after expiry, got %d", hot)
 		}
@@ -159,7 +165,7 @@ func TestIntegratedMultiDBManager(t *testing.T) {
 		time.Sleep(150 * time.Millisecond)
 		
 		// Check that only 2 are hot (max limit)
-		total, hot, cold, _ := manager.GetStatistics()
+		total, hot, cold, _, _ := manager.GetStatistics()
 		if total != 5 {
 			t.Errorf("expected 5 total databases, got %d", total)
 		}
@@ -211,7 +217,7 @@ func TestIntegratedMultiDBManager(t *testing.T) {
 		time.Sleep(150 * time.Millisecond)
 		
 		// Check initial count
-		total, _, _, _ := manager.GetStatistics()
+		total, _, _, _, _ := manager.GetStatistics()
 		if total != 1 {
 			t.Errorf("expected 1 database initially, got %d", total)
 		}
@@ -229,9 +235,268 @@ func TestIntegratedMultiDBManager(t *testing.T) {
 		time.Sleep(150 * time.Millisecond)
 		
 		// Check updated count
-		total, _, _, _ = manager.GetStatistics()
+		total, _, _, _, _ = manager.GetStatistics()
 		if total != 2 {
 			t.Errorf("expected 2 databases after refresh, got %d", total)
 		}
 	})
+	
+	t.Run("DynamicPatterns", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		dirA := filepath.Join(tmpDir, "a")
+		dirB := filepath.Join(tmpDir, "b")
+		os.MkdirAll(dirA, 0755)
+		os.MkdirAll(dirB, 0755)
+		
+		// a/shared.db matches both the a/* and */shared.db patterns
+		aOnly := filepath.Join(dirA, "only.db")
+		aShared := filepath.Join(dirA, "shared.db")
+		bShared := filepath.Join(dirB, "shared.db")
+		for _, db := range []string{aOnly, aShared, bShared} {
+			createTestDB(t, db)
+		}
+		
+		config := &litestreampp.MultiDBConfig{
+			Enabled:         true,
+			Patterns:        []string{filepath.Join(dirA, "*.db")},
+			MaxHotDatabases: 10,
+			ScanInterval:    100 * time.Millisecond,
+			HotPromotion: litestreampp.HotPromotionConfig{
+				RecentModifyThreshold: time.Hour,
+			},
+		}
+		
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		manager, err := litestreampp.NewIntegratedMultiDBManager(store, config)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		
+		manager.Start(ctx)
+		defer manager.Stop()
+		
+		tracked := func(path string) bool {
+			_, ok := manager.GetDatabaseTier(path)
+			return ok
+		}
+		
+		// Adding a pattern tracks its matches without a refresh
+		sharedPattern := filepath.Join(tmpDir, "*", "shared.db")
+		if err := manager.AddPattern(sharedPattern); err != nil {
+			t.Fatal(err)
+		}
+		if total, _, _, _, _ := manager.GetStatistics(); total != 3 {
+			t.Errorf("expected 3 databases after adding pattern, got %d", total)
+		}
+		
+		// Removing a/* keeps a/shared.db, which */shared.db still covers
+		if err := manager.PromoteNow(aOnly); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.RemovePattern(filepath.Join(dirA, "*.db")); err != nil {
+			t.Fatal(err)
+		}
+		if tracked(aOnly) || manager.IsHot(aOnly) {
+			t.Error("a/only.db should no longer be tracked")
+		}
+		if !tracked(aShared) || !tracked(bShared) {
+			t.Error("databases still matched by a pattern should stay tracked")
+		}
+		if total, _, _, _, _ := manager.GetStatistics(); total != 2 {
+			t.Errorf("expected 2 databases after removing pattern, got %d", total)
+		}
+		
+		if err := manager.RemovePattern(filepath.Join(dirA, "*.db")); err == nil {
+			t.Error("expected error removing a pattern that is not configured")
+		}
+		if err := manager.AddPattern("[invalid"); err == nil {
+			t.Error("expected error adding an invalid pattern")
+		}
+		
+		// ** patterns match nested databases, both when removing and when
+		// counting as coverage for another pattern's databases
+		nested := filepath.Join(tmpDir, "c", "deep", "er", "nested.db")
+		os.MkdirAll(filepath.Dir(nested), 0755)
+		createTestDB(t, nested)
+		nestedPattern := filepath.Join(tmpDir, "c", "**", "*.db")
+		if err := manager.AddPattern(nestedPattern); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.AddPattern(filepath.Join(filepath.Dir(nested), "*.db")); err != nil {
+			t.Fatal(err)
+		}
+		if !tracked(nested) {
+			t.Fatal("c/deep/er/nested.db should be tracked")
+		}
+		if err := manager.RemovePattern(filepath.Join(filepath.Dir(nested), "*.db")); err != nil {
+			t.Fatal(err)
+		}
+		if !tracked(nested) {
+			t.Error("c/deep/er/nested.db should stay tracked while c/** covers it")
+		}
+		if err := manager.RemovePattern(nestedPattern); err != nil {
+			t.Fatal(err)
+		}
+		if tracked(nested) {
+			t.Error("c/deep/er/nested.db should no longer be tracked")
+		}
+	})
+	
+	t.Run("Status", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		createTestDB(t, filepath.Join(tmpDir, "db1.db"))
+		
+		config := &litestreampp.MultiDBConfig{
+			Enabled:         true,
+			Patterns:        []string{filepath.Join(tmpDir, "*.db")},
+			MaxHotDatabases: 10,
+			ScanInterval:    50 * time.Millisecond,
+			HotPromotion: litestreampp.HotPromotionConfig{
+				RecentModifyThreshold: time.Hour,
+			},
+		}
+		
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		manager, err := litestreampp.NewIntegratedMultiDBManager(store, config)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+		
+		// getStatus fetches and decodes the status from the HTTP handler
+		getStatus := func(t *testing.T) (int, litestreampp.ManagerStatus) {
+			t.Helper()
+			rec := httptest.NewRecorder()
+			manager.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("expected JSON content type, got %q", got)
+			}
+			var status litestreampp.ManagerStatus
+			if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+				t.Fatal(err)
+			}
+			return rec.Code, status
+		}
+		
+		if code, status := getStatus(t); code != http.StatusServiceUnavailable || status.Running {
+			t.Errorf("expected 503 before start, got %d running=%v", code, status.Running)
+		}
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		
+		manager.Start(ctx)
+		time.Sleep(150 * time.Millisecond)
+		
+		code, status := getStatus(t)
+		if code != http.StatusOK || !status.Running {
+			t.Errorf("expected 200 while running, got %d running=%v", code, status.Running)
+		}
+		if status.TotalDatabases != 1 || status.ColdDatabases != 1 {
+			t.Errorf("expected 1 cold database, got %+v", status)
+		}
+		if status.LastScan.IsZero() {
+			t.Error("expected last scan time to be set")
+		}
+		if len(status.WorkerPools) != 3 {
+			t.Errorf("expected 3 worker pools, got %d", len(status.WorkerPools))
+		}
+		if status.ConnectionPool.MaxConnections != 10 {
+			t.Errorf("expected max connections 10, got %d", status.ConnectionPool.MaxConnections)
+		}
+		
+		manager.Pause()
+		if code, status := getStatus(t); code != http.StatusOK || !status.Paused {
+			t.Errorf("expected 200 and paused, got %d paused=%v", code, status.Paused)
+		}
+		manager.Resume()
+		if _, status := getStatus(t); status.Paused {
+			t.Error("expected not paused after resume")
+		}
+		
+		manager.Stop()
+		if code, _ := getStatus(t); code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503 after stop, got %d", code)
+		}
+		
+		rec := httptest.NewRecorder()
+		manager.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405 for POST, got %d", rec.Code)
+		}
+	})
+	
+	t.Run("Reconfigure", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		subDir := filepath.Join(tmpDir, "subdir")
+		os.MkdirAll(subDir, 0755)
+		
+		var dbs []string
+		for i := 0; i < 4; i++ {
+			db := filepath.Join(tmpDir, fmt.Sprintf("db%d.db", i))
+			createTestDB(t, db)
+			dbs = append(dbs, db)
+		}
+		createTestDB(t, filepath.Join(subDir, "extra.db"))
+		
+		config := &litestreampp.MultiDBConfig{
+			Enabled:         true,
+			Patterns:        []string{filepath.Join(tmpDir, "*.db")},
+			MaxHotDatabases: 4,
+			ScanInterval:    100 * time.Millisecond,
+			HotPromotion: litestreampp.HotPromotionConfig{
+				RecentModifyThreshold: 5 * time.Second,
+			},
+		}
+		
+		store := litestream.NewStore(nil, litestream.CompactionLevels{})
+		manager, err := litestreampp.NewIntegratedMultiDBManager(store, config)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+		
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		
+		manager.Start(ctx)
+		defer manager.Stop()
+		
+		time.Sleep(150 * time.Millisecond)
+		for _, db := range dbs {
+			modifyTestDB(t, db)
+		}
+		time.Sleep(250 * time.Millisecond)
+		
+		// Incompatible changes are rejected.
+		disabled := *config
+		disabled.Enabled = false
+		if err := manager.Reconfigure(&disabled); err == nil {
+			t.Error("expected error when disabling multi-DB mode")
+		}
+		removed := *config
+		removed.Patterns = nil
+		if err := manager.Reconfigure(&removed); err == nil {
+			t.Error("expected error when removing a pattern")
+		}
+		
+		// Lower the hot limit and add a pattern.
+		updated := *config
+		updated.MaxHotDatabases = 1
+		updated.Patterns = []string{filepath.Join(tmpDir, "*.db"), filepath.Join(subDir, "*.db")}
+		if err := manager.Reconfigure(&updated); err != nil {
+			t.Fatalf("failed to reconfigure: %v", err)
+		}
+		
+		time.Sleep(250 * time.Millisecond)
+		
+		total, hot, _, _, _ := manager.GetStatistics()
+		if total != 5 {
+			t.Errorf("expected 5 databases after reconfigure, got %d", total)
+		}
+		if hot > 1 {
+			t.Errorf("expected at most 1 hot database after reconfigure, got %d", hot)
+		}
+	})
 }
\ No newline at end of file
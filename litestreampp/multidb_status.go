@@ -0,0 +1,63 @@
+package litestreampp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ManagerStatus is a snapshot of an IntegratedMultiDBManager for health
+// checks and dashboards
+type ManagerStatus struct {
+	Running         bool                `json:"running"`
+	Paused          bool                `json:"paused"`
+	TotalDatabases  int                 `json:"total_databases"`
+	HotDatabases    int                 `json:"hot_databases"`
+	ColdDatabases   int                 `json:"cold_databases"`
+	TotalPromotions int64               `json:"total_promotions"`
+	TotalDemotions  int64               `json:"total_demotions"`
+	LastScan        time.Time           `json:"last_scan"` // Zero until the first scan finishes
+	DroppedEvents   int64               `json:"dropped_events"` // Events dropped on a full events channel
+	ConnectionPool  ConnectionPoolStats `json:"connection_pool"`
+	WorkerPools     []WorkerPoolStats   `json:"worker_pools"`
+}
+
+// Status returns the current status of the manager
+func (m *IntegratedMultiDBManager) Status() ManagerStatus {
+	total, hot, cold, promotions, demotions := m.hotColdManager.GetStatistics()
+	return ManagerStatus{
+		Running:         m.running.Load(),
+		Paused:          m.hotColdManager.Paused(),
+		TotalDatabases:  total,
+		HotDatabases:    hot,
+		ColdDatabases:   cold,
+		TotalPromotions: promotions,
+		TotalDemotions:  demotions,
+		LastScan:        m.hotColdManager.LastScan(),
+		DroppedEvents:   m.hotColdManager.DroppedEvents(),
+		ConnectionPool:  m.connectionPool.Stats(),
+		WorkerPools:     m.sharedResources.WorkerPoolStats(),
+	}
+}
+
+// ServeHTTP writes the manager status as JSON so it can be mounted on a
+// mux as a health endpoint. It responds 503 Service Unavailable while the
+// manager is not running, for use as a readiness probe.
+func (m *IntegratedMultiDBManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := m.Status()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Running {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		slog.Debug("failed to write status", "error", err)
+	}
+}
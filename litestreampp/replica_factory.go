@@ -2,15 +2,23 @@ package litestreampp
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/benbjohnson/litestream"
+	"github.com/benbjohnson/litestream/file"
 )
 
 // DefaultReplicaClientFactory is the default implementation of ReplicaClientFactory
-// Note: The actual S3 client creation is done in the cmd package to avoid import cycles
+// Note: The actual S3, GCS and Azure client creation is done in the cmd package to avoid import cycles
 type DefaultReplicaClientFactory struct {
 	// CreateS3ClientFunc is injected to avoid import cycles
 	CreateS3ClientFunc func(config *ReplicaConfig) (litestream.ReplicaClient, error)
+	
+	// CreateGCSClientFunc is injected to avoid import cycles
+	CreateGCSClientFunc func(config *ReplicaConfig) (litestream.ReplicaClient, error)
+	
+	// CreateAzureClientFunc is injected to avoid import cycles
+	CreateAzureClientFunc func(config *ReplicaConfig) (litestream.ReplicaClient, error)
 }
 
 // NewDefaultReplicaClientFactory creates a new default replica client factory
@@ -31,16 +39,50 @@ func (f *DefaultReplicaClientFactory) CreateClient(config *ReplicaConfig, dbPath
 		}
 		return nil, fmt.Errorf("S3 client factory not configured")
 		
+	case "gcs":
+		if config.Bucket == "" {
+			return nil, fmt.Errorf("bucket required for gcs replica")
+		}
+		if f.CreateGCSClientFunc != nil {
+			return f.CreateGCSClientFunc(config)
+		}
+		return nil, fmt.Errorf("GCS client factory not configured")
+		
+	case "abs", "azure":
+		if config.Bucket == "" {
+			return nil, fmt.Errorf("container required for azure replica")
+		} else if config.AccountName == "" {
+			return nil, fmt.Errorf("account name required for azure replica")
+		}
+		if f.CreateAzureClientFunc != nil {
+			return f.CreateAzureClientFunc(config)
+		}
+		return nil, fmt.Errorf("Azure client factory not configured")
+		
 	case "file":
-		// File-based replication for testing
-		// TODO: Implement file replica client
-		return nil, nil
+		// File-based replication for local/NFS targets and testing
+		return f.createFileClient(config)
 		
 	default:
 		return nil, fmt.Errorf("unsupported replica type: %s", config.Type)
 	}
 }
 
+// createFileClient returns a replica client that writes LTX files under the
+// expanded config.Path. The directory is created up front so a bad path
+// fails at promotion rather than on the first sync.
+func (f *DefaultReplicaClientFactory) createFileClient(config *ReplicaConfig) (litestream.ReplicaClient, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("file replica path required")
+	}
+	
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, fmt.Errorf("create file replica directory: %w", err)
+	}
+	
+	return file.NewReplicaClient(config.Path), nil
+}
+
 // CreateS3ReplicaClient is a helper that will be injected from cmd package
 // This avoids import cycles while keeping the factory pattern
 func CreateS3ReplicaClient(config *ReplicaConfig) (litestream.ReplicaClient, error) {
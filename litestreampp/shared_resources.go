@@ -1,12 +1,20 @@
 package litestreampp
 
 import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 	
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 // SharedResourceManager provides shared resources across all databases
@@ -32,11 +40,11 @@ type SharedResourceManager struct {
 func NewSharedResourceManager() *SharedResourceManager {
 	return &SharedResourceManager{
 		connectionPool: NewConnectionPool(1000, 5*time.Second), // 5 second idle timeout
-		s3ClientPool:   NewS3ClientPool(200), // 200 shared S3 clients for high throughput
+		s3ClientPool:   NewS3ClientPool(200, newDefaultS3Client), // Up to 200 shared S3 clients for high throughput
 		monitorPool:    NewWorkerPool("monitor", 100),
 		snapshotPool:   NewWorkerPool("snapshot", 50),
 		replicaPool:    NewWorkerPool("replica", 200),
-		walHeaderCache: NewTTLCache(1*time.Hour),
+		walHeaderCache: NewTTLCache(walHeaderCacheCleanup, walHeaderCacheMaxEntries),
 		bufferPool: &sync.Pool{
 			New: func() interface{} {
 				return make([]byte, 8192) // 8KB buffers
@@ -46,66 +54,210 @@ func NewSharedResourceManager() *SharedResourceManager {
 	}
 }
 
-// S3ClientPool manages a pool of S3 clients
+// S3ClientPool manages a bounded pool of S3 clients. Clients are created
+// lazily by the factory up to the pool size; once that many are checked
+// out, Get blocks until one is returned.
 type S3ClientPool struct {
-	mu       sync.Mutex
-	clients  []*s3.S3
+	mu        sync.Mutex
+	factory   func() *s3.S3
+	size      int
+	created   int
+	inUse     int
 	available chan *s3.S3
 }
 
-func NewS3ClientPool(size int) *S3ClientPool {
-	pool := &S3ClientPool{
-		clients:   make([]*s3.S3, 0, size),
+// S3ClientPoolStats reports S3 client pool usage
+type S3ClientPoolStats struct {
+	Size      int // Maximum number of clients
+	Created   int // Clients created so far
+	InUse     int // Clients checked out with Get
+	Available int // Idle clients ready for Get
+}
+
+func NewS3ClientPool(size int, factory func() *s3.S3) *S3ClientPool {
+	return &S3ClientPool{
+		factory:   factory,
+		size:      size,
 		available: make(chan *s3.S3, size),
 	}
-	
-	// Pre-create clients
-	for i := 0; i < size; i++ {
-		// Client creation would happen here
-		// For now, placeholder
-	}
-	
-	return pool
 }
 
-func (p *S3ClientPool) Get() *s3.S3 {
+// Get returns an idle client, creating one if the pool is below its size,
+// or waits for a client to be returned. The client must be returned with Put.
+func (p *S3ClientPool) Get(ctx context.Context) (*s3.S3, error) {
 	select {
 	case client := <-p.available:
-		return client
+		p.checkout()
+		return client, nil
 	default:
-		// Create new client if pool empty
-		// This is simplified - real implementation would have limits
-		return nil
 	}
+	
+	if client, err := p.create(); client != nil || err != nil {
+		return client, err
+	}
+	
+	select {
+	case client := <-p.available:
+		p.checkout()
+		return client, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// create makes a new client if the pool has not reached its size.
+// Returns nil, nil when the pool is full.
+func (p *S3ClientPool) create() (*s3.S3, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if p.created >= p.size {
+		return nil, nil
+	}
+	if p.factory == nil {
+		return nil, fmt.Errorf("s3 client pool has no factory")
+	}
+	
+	client := p.factory()
+	if client == nil {
+		return nil, fmt.Errorf("s3 client factory returned nil")
+	}
+	p.created++
+	p.inUse++
+	return client, nil
 }
 
+func (p *S3ClientPool) checkout() {
+	p.mu.Lock()
+	p.inUse++
+	p.mu.Unlock()
+}
+
+// Put returns a client obtained from Get to the pool
 func (p *S3ClientPool) Put(client *s3.S3) {
+	if client == nil {
+		return
+	}
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
 	select {
 	case p.available <- client:
-		// Returned to pool
+		p.inUse--
 	default:
-		// Pool full, discard
+		// Pool full (client was not from this pool), discard
 	}
 }
 
+// Stats returns the current pool usage
+func (p *S3ClientPool) Stats() S3ClientPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	return S3ClientPoolStats{
+		Size:      p.size,
+		Created:   p.created,
+		InUse:     p.inUse,
+		Available: len(p.available),
+	}
+}
+
+// newDefaultS3Client creates an S3 client from the default AWS credential
+// chain. All clients share one session, created on first use. A failed
+// session is not cached, so the next client retries it.
+func newDefaultS3Client() *s3.S3 {
+	defaultS3SessionMu.Lock()
+	defer defaultS3SessionMu.Unlock()
+	
+	if defaultS3Session == nil {
+		sess, err := session.NewSession()
+		if err != nil {
+			slog.Error("failed to create aws session", "error", err)
+			return nil
+		}
+		defaultS3Session = sess
+	}
+	return s3.New(defaultS3Session)
+}
+
+var (
+	defaultS3SessionMu sync.Mutex
+	defaultS3Session   *session.Session
+)
+
 // WorkerPool manages a pool of workers for background tasks
 type WorkerPool struct {
-	name    string
-	workers int
-	tasks   chan Task
-	wg      sync.WaitGroup
+	name     string
+	workers  int
+	tasks    chan *operation
+	wg       sync.WaitGroup
+	active   atomic.Int64 // Tasks currently executing
+	rejected atomic.Int64 // Tasks not queued by TrySubmit or SubmitContext
+	
+	// Closed by Stop or StopContext; workers drain the queue and exit
+	quit     chan struct{}
+	stopped  atomic.Bool
+	stopOnce sync.Once
+
+	// In-flight operation tracking
+	opsMu  sync.Mutex
+	ops    map[string]*operation
+	nextID uint64
 }
 
+// WorkerPoolStats reports how busy a worker pool is. A pool whose Active
+// count stays at Workers with a growing Queued count is saturated.
+type WorkerPoolStats struct {
+	Name          string `json:"name"`
+	Workers       int    `json:"workers"`
+	Active        int    `json:"active"`         // Tasks currently executing
+	Queued        int    `json:"queued"`         // Tasks submitted but not yet picked up by a worker
+	QueueCapacity int    `json:"queue_capacity"` // Queued tasks at which Submit blocks
+	Rejected      int64  `json:"rejected"`       // Tasks turned away by TrySubmit or SubmitContext
+}
+
+// ErrWorkerPoolStopped is passed to OnError, or returned, for tasks
+// submitted after a worker pool has started stopping
+var ErrWorkerPoolStopped = errors.New("worker pool stopped")
+
 type Task interface {
 	Execute() error
 	OnError(error)
 }
 
+// ContextTask is a Task that can be canceled. Worker pools call
+// ExecuteContext instead of Execute when a task implements it.
+type ContextTask interface {
+	Task
+	ExecuteContext(ctx context.Context) error
+}
+
+// Operation describes a task submitted to a worker pool that has not finished
+type Operation struct {
+	ID          string
+	Pool        string
+	Description string
+	Running     bool
+	SubmittedAt time.Time
+	StartedAt   time.Time
+}
+
+// operation tracks a submitted task and its cancellation
+type operation struct {
+	Operation
+	task   Task
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
 func NewWorkerPool(name string, workers int) *WorkerPool {
 	pool := &WorkerPool{
 		name:    name,
 		workers: workers,
-		tasks:   make(chan Task, workers*10), // Buffer 10x workers
+		tasks:   make(chan *operation, workers*10), // Buffer 10x workers
+		ops:     make(map[string]*operation),
+		quit:    make(chan struct{}),
 	}
 	
 	// Start workers
@@ -120,20 +272,227 @@ func NewWorkerPool(name string, workers int) *WorkerPool {
 func (p *WorkerPool) worker(id int) {
 	defer p.wg.Done()
 	
-	for task := range p.tasks {
-		if err := task.Execute(); err != nil {
-			task.OnError(err)
+	for {
+		select {
+		case op := <-p.tasks:
+			p.run(op)
+		case <-p.quit:
+			// Finish what was queued before stopping
+			for {
+				select {
+				case op := <-p.tasks:
+					p.run(op)
+				default:
+					return
+				}
+			}
 		}
 	}
 }
 
-func (p *WorkerPool) Submit(task Task) {
-	p.tasks <- task
+// run executes a single operation unless it was canceled while queued
+func (p *WorkerPool) run(op *operation) {
+	defer func() {
+		op.cancel()
+		p.opsMu.Lock()
+		delete(p.ops, op.ID)
+		p.opsMu.Unlock()
+	}()
+	
+	if err := op.ctx.Err(); err != nil {
+		op.task.OnError(err)
+		return
+	}
+	
+	p.opsMu.Lock()
+	op.Running = true
+	op.StartedAt = time.Now()
+	p.opsMu.Unlock()
+	
+	p.active.Add(1)
+	defer p.active.Add(-1)
+	
+	var err error
+	if t, ok := op.task.(ContextTask); ok {
+		err = t.ExecuteContext(op.ctx)
+	} else {
+		err = op.task.Execute()
+	}
+	if err != nil {
+		op.task.OnError(err)
+	}
+}
+
+// Submit queues a task, blocking while the queue is full, and returns the
+// id of its operation. Once the pool is stopping the task is not queued;
+// its OnError gets ErrWorkerPoolStopped and the id is empty.
+func (p *WorkerPool) Submit(task Task) string {
+	if p.stopped.Load() {
+		task.OnError(ErrWorkerPoolStopped)
+		return ""
+	}
+	
+	op := p.newOperation(task)
+	select {
+	case p.tasks <- op:
+		return op.ID
+	case <-p.quit:
+		p.reject(op)
+		task.OnError(ErrWorkerPoolStopped)
+		return ""
+	}
+}
+
+// TrySubmit queues a task only if the queue has room, returning the id of
+// its operation and false if the queue is full or the pool is stopping
+func (p *WorkerPool) TrySubmit(task Task) (string, bool) {
+	if p.stopped.Load() {
+		return "", false
+	}
+	
+	op := p.newOperation(task)
+	select {
+	case p.tasks <- op:
+		return op.ID, true
+	default:
+		p.reject(op)
+		return "", false
+	}
 }
 
+// SubmitContext queues a task, waiting for room in the queue until ctx is
+// done, and returns the id of its operation
+func (p *WorkerPool) SubmitContext(ctx context.Context, task Task) (string, error) {
+	if p.stopped.Load() {
+		return "", ErrWorkerPoolStopped
+	}
+	
+	op := p.newOperation(task)
+	select {
+	case p.tasks <- op:
+		return op.ID, nil
+	case <-p.quit:
+		p.reject(op)
+		return "", ErrWorkerPoolStopped
+	case <-ctx.Done():
+		p.reject(op)
+		return "", ctx.Err()
+	}
+}
+
+// newOperation creates and tracks the operation for a task about to be queued
+func (p *WorkerPool) newOperation(task Task) *operation {
+	ctx, cancel := context.WithCancel(context.Background())
+	
+	desc := fmt.Sprintf("%T", task)
+	if s, ok := task.(fmt.Stringer); ok {
+		desc = s.String()
+	}
+	
+	p.opsMu.Lock()
+	defer p.opsMu.Unlock()
+	
+	p.nextID++
+	op := &operation{
+		Operation: Operation{
+			ID:          fmt.Sprintf("%s-%d", p.name, p.nextID),
+			Pool:        p.name,
+			Description: desc,
+			SubmittedAt: time.Now(),
+		},
+		task:   task,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	p.ops[op.ID] = op
+	return op
+}
+
+// reject stops tracking an operation that could not be queued
+func (p *WorkerPool) reject(op *operation) {
+	op.cancel()
+	p.rejected.Add(1)
+	
+	p.opsMu.Lock()
+	delete(p.ops, op.ID)
+	p.opsMu.Unlock()
+}
+
+// InFlightOperations returns the queued and running operations
+func (p *WorkerPool) InFlightOperations() []Operation {
+	p.opsMu.Lock()
+	defer p.opsMu.Unlock()
+	
+	ops := make([]Operation, 0, len(p.ops))
+	for _, op := range p.ops {
+		ops = append(ops, op.Operation)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].SubmittedAt.Before(ops[j].SubmittedAt) })
+	return ops
+}
+
+// CancelOperation cancels a queued or running operation. Queued operations
+// are skipped; running operations see their context canceled.
+func (p *WorkerPool) CancelOperation(id string) error {
+	p.opsMu.Lock()
+	op, ok := p.ops[id]
+	p.opsMu.Unlock()
+	
+	if !ok {
+		return fmt.Errorf("operation not found: %s", id)
+	}
+	op.cancel()
+	return nil
+}
+
+// Stats returns the pool's current activity
+func (p *WorkerPool) Stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		Name:          p.name,
+		Workers:       p.workers,
+		Active:        int(p.active.Load()),
+		Queued:        len(p.tasks),
+		QueueCapacity: cap(p.tasks),
+		Rejected:      p.rejected.Load(),
+	}
+}
+
+// Stop stops accepting tasks and waits for every queued and running task
+// to finish
 func (p *WorkerPool) Stop() {
-	close(p.tasks)
-	p.wg.Wait()
+	p.StopContext(context.Background())
+}
+
+// StopContext stops accepting tasks and waits for the queued and running
+// ones to finish until ctx is done. At the deadline every remaining task is
+// canceled: queued ones are skipped and running ContextTasks see their
+// context canceled. The returned error reports how many were abandoned.
+func (p *WorkerPool) StopContext(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		p.stopped.Store(true)
+		close(p.quit)
+	})
+	
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	
+	p.opsMu.Lock()
+	abandoned := len(p.ops)
+	for _, op := range p.ops {
+		op.cancel()
+	}
+	p.opsMu.Unlock()
+	
+	return fmt.Errorf("%s pool: %d tasks abandoned: %w", p.name, abandoned, ctx.Err())
 }
 
 // MonitorTask represents a database monitoring task
@@ -153,24 +512,48 @@ func (t MonitorTask) OnError(err error) {
 	slog.Error("monitor task failed", "path", t.Path, "error", err)
 }
 
-// TTLCache provides a simple time-based cache
+func (t MonitorTask) String() string {
+	return "monitor " + t.Path
+}
+
+// WAL header cache limits. Cleanup runs every minute, however long the
+// headers' TTLs are, so expired entries don't pile up between passes.
+const (
+	walHeaderCacheCleanup    = time.Minute
+	walHeaderCacheMaxEntries = 100000
+)
+
+// TTLCache provides a simple time-based cache. When a Set would exceed
+// maxEntries, the entries closest to expiring are evicted first.
 type TTLCache struct {
-	mu    sync.RWMutex
-	items map[string]*cacheItem
+	mu         sync.RWMutex
+	items      map[string]*cacheItem
+	expiry     cacheExpiryHeap // Items ordered by expiration, soonest first
+	maxEntries int
+
+	done      chan struct{} // Closed by Close to stop the cleanup goroutine
+	closeOnce sync.Once
 }
 
 type cacheItem struct {
+	key        string
 	value      interface{}
 	expiration time.Time
+	index      int // Position in the expiry heap
 }
 
-func NewTTLCache(ttl time.Duration) *TTLCache {
+// NewTTLCache returns a cache holding at most maxEntries items (0 = no
+// limit). Expired items are removed every cleanupInterval, independent of
+// the TTLs passed to Set, until Close is called.
+func NewTTLCache(cleanupInterval time.Duration, maxEntries int) *TTLCache {
 	cache := &TTLCache{
-		items: make(map[string]*cacheItem),
+		items:      make(map[string]*cacheItem),
+		maxEntries: maxEntries,
+		done:       make(chan struct{}),
 	}
 	
 	// Start cleanup goroutine
-	go cache.cleanup(ttl)
+	go cache.cleanup(cleanupInterval)
 	
 	return cache
 }
@@ -191,28 +574,86 @@ func (c *TTLCache) Set(key string, value interface{}, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	c.items[key] = &cacheItem{
-		value:      value,
-		expiration: time.Now().Add(ttl),
+	expiration := time.Now().Add(ttl)
+	if item, ok := c.items[key]; ok {
+		item.value = value
+		item.expiration = expiration
+		heap.Fix(&c.expiry, item.index)
+		return
+	}
+	
+	// Make room by dropping the entries that would expire soonest
+	for c.maxEntries > 0 && len(c.items) >= c.maxEntries {
+		item := heap.Pop(&c.expiry).(*cacheItem)
+		delete(c.items, item.key)
 	}
+	
+	item := &cacheItem{key: key, value: value, expiration: expiration}
+	c.items[key] = item
+	heap.Push(&c.expiry, item)
+}
+
+// Len returns the number of items in the cache, including expired items
+// not yet cleaned up
+func (c *TTLCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// Close stops the cleanup goroutine. The cache remains usable, but expired
+// items are no longer removed in the background. Close is idempotent.
+func (c *TTLCache) Close() {
+	c.closeOnce.Do(func() { close(c.done) })
 }
 
 func (c *TTLCache) cleanup(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	
-	for range ticker.C {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		
 		c.mu.Lock()
 		now := time.Now()
-		for key, item := range c.items {
-			if now.After(item.expiration) {
-				delete(c.items, key)
-			}
+		for len(c.expiry) > 0 && now.After(c.expiry[0].expiration) {
+			item := heap.Pop(&c.expiry).(*cacheItem)
+			delete(c.items, item.key)
 		}
 		c.mu.Unlock()
 	}
 }
 
+// cacheExpiryHeap is a min-heap of cache items by expiration
+type cacheExpiryHeap []*cacheItem
+
+func (h cacheExpiryHeap) Len() int           { return len(h) }
+func (h cacheExpiryHeap) Less(i, j int) bool { return h[i].expiration.Before(h[j].expiration) }
+
+func (h cacheExpiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *cacheExpiryHeap) Push(x interface{}) {
+	item := x.(*cacheItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *cacheExpiryHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
 // AggregatedMetrics provides tier-based metrics instead of per-DB
 type AggregatedMetrics struct {
 	// Tier metrics
@@ -239,38 +680,46 @@ type ProjectMetrics struct {
 }
 
 func NewAggregatedMetrics() *AggregatedMetrics {
+	return NewAggregatedMetricsWithOptions(MetricsOptions{})
+}
+
+// NewAggregatedMetricsWithOptions creates aggregated metrics configured by
+// opts. Unlike HierarchicalMetrics, they are unregistered by default.
+func NewAggregatedMetricsWithOptions(opts MetricsOptions) *AggregatedMetrics {
+	factory := promauto.With(opts.Registerer)
 	return &AggregatedMetrics{
-		hotDBCount: prometheus.NewGauge(prometheus.GaugeOpts{
+		hotDBCount: factory.NewGauge(prometheus.GaugeOpts{
 			Name: "litestream_hot_databases_total",
 			Help: "Total number of hot databases",
 		}),
-		coldDBCount: prometheus.NewGauge(prometheus.GaugeOpts{
+		coldDBCount: factory.NewGauge(prometheus.GaugeOpts{
 			Name: "litestream_cold_databases_total",
 			Help: "Total number of cold databases",
 		}),
-		hotDBSize: prometheus.NewGauge(prometheus.GaugeOpts{
+		hotDBSize: factory.NewGauge(prometheus.GaugeOpts{
 			Name: "litestream_hot_databases_size_bytes",
 			Help: "Total size of hot databases",
 		}),
-		coldDBSize: prometheus.NewGauge(prometheus.GaugeOpts{
+		coldDBSize: factory.NewGauge(prometheus.GaugeOpts{
 			Name: "litestream_cold_databases_size_bytes",
 			Help: "Total size of cold databases",
 		}),
-		syncCounterVec: prometheus.NewCounterVec(
+		syncCounterVec: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "litestream_sync_total",
 				Help: "Total number of sync operations",
 			},
 			[]string{"tier"}, // Just "hot" or "cold"
 		),
-		syncDurationVec: prometheus.NewHistogramVec(
+		syncDurationVec: factory.NewHistogramVec(
 			prometheus.HistogramOpts{
-				Name: "litestream_sync_duration_seconds",
-				Help: "Sync operation duration",
+				Name:    "litestream_sync_duration_seconds",
+				Help:    "Sync operation duration",
+				Buckets: opts.syncDurationBuckets(),
 			},
 			[]string{"tier"},
 		),
-		uploadBytesVec: prometheus.NewCounterVec(
+		uploadBytesVec: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "litestream_upload_bytes_total",
 				Help: "Total bytes uploaded",
@@ -294,6 +743,68 @@ func (m *AggregatedMetrics) UpdateTierCounts(hot, cold int) {
 	m.coldDBCount.Set(float64(cold))
 }
 
+// InFlightOperations returns the queued and running operations of all worker pools
+func (m *SharedResourceManager) InFlightOperations() []Operation {
+	var ops []Operation
+	for _, pool := range m.workerPools() {
+		ops = append(ops, pool.InFlightOperations()...)
+	}
+	return ops
+}
+
+// CancelOperation cancels an operation in whichever worker pool owns it
+func (m *SharedResourceManager) CancelOperation(id string) error {
+	for _, pool := range m.workerPools() {
+		if err := pool.CancelOperation(id); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("operation not found: %s", id)
+}
+
+// Shutdown stops the monitor, snapshot, and replica worker pools, draining
+// their queues until ctx is done, and stops the WAL header cache's cleanup.
+// The pools drain concurrently so they share the deadline rather than each
+// getting it in turn.
+func (m *SharedResourceManager) Shutdown(ctx context.Context) error {
+	m.walHeaderCache.Close()
+	
+	pools := m.workerPools()
+	errs := make([]error, len(pools))
+	
+	var wg sync.WaitGroup
+	for i, pool := range pools {
+		wg.Add(1)
+		go func(i int, pool *WorkerPool) {
+			defer wg.Done()
+			errs[i] = pool.StopContext(ctx)
+		}(i, pool)
+	}
+	wg.Wait()
+	
+	return errors.Join(errs...)
+}
+
+// WorkerPoolStats returns the activity of the monitor, snapshot, and
+// replica worker pools
+func (m *SharedResourceManager) WorkerPoolStats() []WorkerPoolStats {
+	pools := m.workerPools()
+	stats := make([]WorkerPoolStats, 0, len(pools))
+	for _, pool := range pools {
+		stats = append(stats, pool.Stats())
+	}
+	return stats
+}
+
+func (m *SharedResourceManager) workerPools() []*WorkerPool {
+	return []*WorkerPool{m.monitorPool, m.snapshotPool, m.replicaPool}
+}
+
+// S3ClientPool returns the shared S3 client pool
+func (m *SharedResourceManager) S3ClientPool() *S3ClientPool {
+	return m.s3ClientPool
+}
+
 // GetBuffer gets a buffer from the pool
 func (m *SharedResourceManager) GetBuffer() []byte {
 	return m.bufferPool.Get().([]byte)
@@ -1,12 +1,23 @@
 package litestreampp_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/benbjohnson/litestream/litestreampp"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestWorkerPool(t *testing.T) {
@@ -91,11 +102,219 @@ func TestWorkerPool(t *testing.T) {
 			t.Errorf("expected 100 tasks processed, got %d", got)
 		}
 	})
+
+	t.Run("CancelOperation", func(t *testing.T) {
+		pool := litestreampp.NewWorkerPool("test", 1)
+		defer pool.Stop()
+
+		started := make(chan struct{})
+		errs := make(chan error, 2)
+		
+		// First task blocks the only worker, second stays queued
+		runningID := pool.Submit(&blockingTask{started: started, errs: errs})
+		<-started
+		queuedID := pool.Submit(&blockingTask{started: make(chan struct{}, 1), errs: errs})
+		
+		ops := pool.InFlightOperations()
+		if len(ops) != 2 {
+			t.Fatalf("expected 2 in-flight operations, got %d", len(ops))
+		}
+		if ops[0].ID != runningID || !ops[0].Running {
+			t.Errorf("expected %s to be running, got %+v", runningID, ops[0])
+		}
+		if ops[1].ID != queuedID || ops[1].Running {
+			t.Errorf("expected %s to be queued, got %+v", queuedID, ops[1])
+		}
+		
+		if err := pool.CancelOperation(queuedID); err != nil {
+			t.Fatal(err)
+		}
+		if err := pool.CancelOperation(runningID); err != nil {
+			t.Fatal(err)
+		}
+		
+		for i := 0; i < 2; i++ {
+			select {
+			case err := <-errs:
+				if err != context.Canceled {
+					t.Errorf("expected context.Canceled, got %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("canceled operation did not finish")
+			}
+		}
+		
+		if err := pool.CancelOperation("test-999"); err == nil {
+			t.Error("expected error canceling unknown operation")
+		}
+	})
+
+	t.Run("BoundedSubmission", func(t *testing.T) {
+		pool := litestreampp.NewWorkerPool("test", 1)
+		defer pool.Stop()
+
+		errs := make(chan error, 11)
+		var ids []string
+		
+		// Block the only worker, then fill the queue (10x workers)
+		started := make(chan struct{})
+		ids = append(ids, pool.Submit(&blockingTask{started: started, errs: errs}))
+		<-started
+		for i := 0; i < 10; i++ {
+			id, ok := pool.TrySubmit(&blockingTask{started: make(chan struct{}), errs: errs})
+			if !ok {
+				t.Fatalf("expected task %d to fit in the queue", i)
+			}
+			ids = append(ids, id)
+		}
+		
+		if _, ok := pool.TrySubmit(&blockingTask{started: make(chan struct{}), errs: errs}); ok {
+			t.Error("expected TrySubmit to fail on a full queue")
+		}
+		
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := pool.SubmitContext(ctx, &blockingTask{started: make(chan struct{}), errs: errs}); err != context.DeadlineExceeded {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+		
+		// Rejected tasks are not tracked as in flight
+		if ops := pool.InFlightOperations(); len(ops) != 11 {
+			t.Errorf("expected 11 in-flight operations, got %d", len(ops))
+		}
+		if stats := pool.Stats(); stats.Rejected != 2 {
+			t.Errorf("expected 2 rejected tasks, got %d", stats.Rejected)
+		}
+		
+		// Draining the queue makes room for SubmitContext again
+		for _, id := range ids {
+			pool.CancelOperation(id)
+		}
+		for range ids {
+			<-errs
+		}
+		id, err := pool.SubmitContext(context.Background(), &blockingTask{started: make(chan struct{}), errs: errs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.CancelOperation(id)
+		<-errs
+	})
+
+	t.Run("StopContextDrainsQueue", func(t *testing.T) {
+		pool := litestreampp.NewWorkerPool("test", 1)
+
+		var counter int32
+		for i := 0; i < 5; i++ {
+			pool.Submit(&testTask{id: i, counter: &counter, immediate: true})
+		}
+		
+		if err := pool.StopContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := atomic.LoadInt32(&counter); got != 5 {
+			t.Errorf("expected 5 tasks processed before stopping, got %d", got)
+		}
+		
+		// No new tasks once stopped
+		errs := make(chan error, 1)
+		if id := pool.Submit(&blockingTask{started: make(chan struct{}), errs: errs}); id != "" {
+			t.Errorf("expected no operation id after stop, got %s", id)
+		}
+		if err := <-errs; err != litestreampp.ErrWorkerPoolStopped {
+			t.Errorf("expected ErrWorkerPoolStopped, got %v", err)
+		}
+		if _, ok := pool.TrySubmit(&testTask{counter: &counter, immediate: true}); ok {
+			t.Error("expected TrySubmit to fail after stop")
+		}
+		if _, err := pool.SubmitContext(context.Background(), &testTask{counter: &counter, immediate: true}); err != litestreampp.ErrWorkerPoolStopped {
+			t.Errorf("expected ErrWorkerPoolStopped, got %v", err)
+		}
+		
+		// Stopping again is a no-op
+		pool.Stop()
+	})
+
+	t.Run("StopContextAbandonsAtDeadline", func(t *testing.T) {
+		pool := litestreampp.NewWorkerPool("test", 1)
+
+		// One task runs until canceled, two more wait behind it
+		errs := make(chan error, 3)
+		started := make(chan struct{})
+		pool.Submit(&blockingTask{started: started, errs: errs})
+		<-started
+		pool.Submit(&blockingTask{started: make(chan struct{}), errs: errs})
+		pool.Submit(&blockingTask{started: make(chan struct{}), errs: errs})
+		
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := pool.StopContext(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "3 tasks abandoned") {
+			t.Errorf("expected abandoned count in error, got %q", err)
+		}
+		
+		// The running and queued tasks are all canceled
+		for i := 0; i < 3; i++ {
+			select {
+			case err := <-errs:
+				if err != context.Canceled {
+					t.Errorf("expected context.Canceled, got %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("abandoned task was not canceled")
+			}
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		pool := litestreampp.NewWorkerPool("test", 2)
+		defer pool.Stop()
+
+		errs := make(chan error, 5)
+		var ids []string
+		
+		// Two tasks occupy both workers, three more wait in the queue
+		for i := 0; i < 2; i++ {
+			started := make(chan struct{})
+			ids = append(ids, pool.Submit(&blockingTask{started: started, errs: errs}))
+			<-started
+		}
+		for i := 0; i < 3; i++ {
+			ids = append(ids, pool.Submit(&blockingTask{started: make(chan struct{}), errs: errs}))
+		}
+		
+		stats := pool.Stats()
+		if stats.Name != "test" || stats.Workers != 2 || stats.QueueCapacity != 20 {
+			t.Errorf("unexpected pool config in stats: %+v", stats)
+		}
+		if stats.Active != 2 || stats.Queued != 3 {
+			t.Errorf("expected 2 active and 3 queued, got %+v", stats)
+		}
+		
+		for _, id := range ids {
+			pool.CancelOperation(id)
+		}
+		for range ids {
+			<-errs
+		}
+		
+		// Active drops once the canceled tasks return
+		deadline := time.Now().Add(time.Second)
+		for pool.Stats().Active != 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if stats := pool.Stats(); stats.Active != 0 || stats.Queued != 0 {
+			t.Errorf("expected idle pool, got %+v", stats)
+		}
+	})
 }
 
 func TestTTLCache(t *testing.T) {
 	t.Run("StoresAndRetrieves", func(t *testing.T) {
-		cache := litestreampp.NewTTLCache(100 * time.Millisecond)
+		cache := litestreampp.NewTTLCache(100 * time.Millisecond, 0)
 		
 		cache.Set("key1", "value1", 1*time.Second)
 		cache.Set("key2", "value2", 1*time.Second)
@@ -111,7 +330,7 @@ func TestTTLCache(t *testing.T) {
 	})
 
 	t.Run("ExpiresItems", func(t *testing.T) {
-		cache := litestreampp.NewTTLCache(50 * time.Millisecond)
+		cache := litestreampp.NewTTLCache(50 * time.Millisecond, 0)
 		
 		cache.Set("key1", "value1", 100*time.Millisecond)
 		
@@ -130,7 +349,7 @@ func TestTTLCache(t *testing.T) {
 	})
 
 	t.Run("CleanupRemovesExpired", func(t *testing.T) {
-		cache := litestreampp.NewTTLCache(50 * time.Millisecond)
+		cache := litestreampp.NewTTLCache(50 * time.Millisecond, 0)
 		
 		// Add items with short TTL
 		for i := 0; i < 10; i++ {
@@ -148,6 +367,48 @@ func TestTTLCache(t *testing.T) {
 				t.Errorf("expected %s to be cleaned up", key)
 			}
 		}
+		if n := cache.Len(); n != 0 {
+			t.Errorf("expected empty cache after cleanup, got %d items", n)
+		}
+	})
+
+	t.Run("CloseStopsCleanup", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		
+		caches := make([]*litestreampp.TTLCache, 10)
+		for i := range caches {
+			caches[i] = litestreampp.NewTTLCache(time.Millisecond, 0)
+		}
+		for _, cache := range caches {
+			cache.Close()
+			cache.Close() // Idempotent
+		}
+		
+		waitFor(t, time.Second, func() bool { return runtime.NumGoroutine() <= before })
+	})
+
+	t.Run("MaxEntries", func(t *testing.T) {
+		cache := litestreampp.NewTTLCache(time.Hour, 100)
+
+		// Later keys expire later, so the earliest keys are evicted
+		for i := 0; i < 1000; i++ {
+			cache.Set(fmt.Sprintf("key%d", i), i, time.Hour+time.Duration(i)*time.Millisecond)
+		}
+		if n := cache.Len(); n != 100 {
+			t.Fatalf("expected cache capped at 100 items, got %d", n)
+		}
+		if _, ok := cache.Get("key0"); ok {
+			t.Error("expected key0 to be evicted")
+		}
+		if val, ok := cache.Get("key999"); !ok || val != 999 {
+			t.Errorf("expected key999 to be kept, got %v, ok=%v", val, ok)
+		}
+
+		// Updating an existing key doesn't evict anything
+		cache.Set("key999", "updated", time.Minute)
+		if n := cache.Len(); n != 100 {
+			t.Errorf("expected 100 items after update, got %d", n)
+		}
 	})
 }
 
@@ -166,6 +427,41 @@ func TestAggregatedMetrics(t *testing.T) {
 		// Note: Can't easily verify Prometheus metrics without setting up
 		// a full metrics registry, but this tests that the methods don't panic
 	})
+
+	t.Run("CustomBuckets", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		metrics := litestreampp.NewAggregatedMetricsWithOptions(litestreampp.MetricsOptions{
+			Registerer:          reg,
+			SyncDurationBuckets: litestreampp.LargeDatabaseSyncBuckets,
+		})
+		metrics.RecordSync("cold", 90*time.Second, 1024)
+
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range families {
+			if mf.GetName() != "litestream_sync_duration_seconds" {
+				continue
+			}
+			var bounds []float64
+			var under120 uint64
+			for _, b := range mf.GetMetric()[0].GetHistogram().GetBucket() {
+				bounds = append(bounds, b.GetUpperBound())
+				if b.GetUpperBound() == 120 {
+					under120 = b.GetCumulativeCount()
+				}
+			}
+			if !reflect.DeepEqual(bounds, litestreampp.LargeDatabaseSyncBuckets) {
+				t.Errorf("expected buckets %v, got %v", litestreampp.LargeDatabaseSyncBuckets, bounds)
+			}
+			if under120 != 1 {
+				t.Errorf("expected the 90s sync in the 120s bucket, got count %d", under120)
+			}
+			return
+		}
+		t.Fatal("sync duration histogram not registered")
+	})
 }
 
 func TestSharedResourceManager(t *testing.T) {
@@ -192,6 +488,168 @@ func TestSharedResourceManager(t *testing.T) {
 			t.Log("Buffer was not reused (this is OK)")
 		}
 	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		mgr := litestreampp.NewSharedResourceManager()
+		
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := mgr.Shutdown(ctx); err != nil {
+			t.Fatalf("expected idle pools to drain, got %v", err)
+		}
+	})
+
+	t.Run("ShutdownStopsGoroutines", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		
+		mgr := litestreampp.NewSharedResourceManager()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := mgr.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+		
+		waitFor(t, time.Second, func() bool { return runtime.NumGoroutine() <= before })
+	})
+
+	t.Run("WorkerPoolStats", func(t *testing.T) {
+		mgr := litestreampp.NewSharedResourceManager()
+		
+		stats := mgr.WorkerPoolStats()
+		if len(stats) != 3 {
+			t.Fatalf("expected 3 worker pools, got %d", len(stats))
+		}
+		for i, name := range []string{"monitor", "snapshot", "replica"} {
+			if stats[i].Name != name {
+				t.Errorf("expected pool %d to be %s, got %s", i, name, stats[i].Name)
+			}
+			if stats[i].Active != 0 || stats[i].Queued != 0 {
+				t.Errorf("expected idle %s pool, got %+v", name, stats[i])
+			}
+		}
+	})
+}
+
+func TestS3ClientPool(t *testing.T) {
+	newClient := func() *s3.S3 {
+		return s3.New(session.Must(session.NewSession(&aws.Config{Region: aws.String("us-east-1")})))
+	}
+	
+	t.Run("CreatesUpToSize", func(t *testing.T) {
+		pool := litestreampp.NewS3ClientPool(2, newClient)
+		ctx := context.Background()
+		
+		c1, err := pool.Get(ctx)
+		if err != nil || c1 == nil {
+			t.Fatalf("expected client, got %v, %v", c1, err)
+		}
+		c2, err := pool.Get(ctx)
+		if err != nil || c2 == nil {
+			t.Fatalf("expected client, got %v, %v", c2, err)
+		}
+		if c1 == c2 {
+			t.Error("expected distinct clients")
+		}
+		
+		stats := pool.Stats()
+		if stats.Created != 2 || stats.InUse != 2 || stats.Available != 0 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+		
+		pool.Put(c1)
+		c3, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c3 != c1 {
+			t.Error("expected returned client to be reused")
+		}
+		if stats := pool.Stats(); stats.Created != 2 {
+			t.Errorf("expected no new clients, got %d created", stats.Created)
+		}
+	})
+	
+	t.Run("BlocksWhenExhausted", func(t *testing.T) {
+		pool := litestreampp.NewS3ClientPool(1, newClient)
+		
+		c1, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := pool.Get(ctx); err != context.DeadlineExceeded {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+		
+		// A returned client unblocks a waiting Get
+		done := make(chan *s3.S3)
+		go func() {
+			c, _ := pool.Get(context.Background())
+			done <- c
+		}()
+		time.Sleep(10 * time.Millisecond)
+		pool.Put(c1)
+		
+		select {
+		case c := <-done:
+			if c != c1 {
+				t.Error("expected waiting Get to receive returned client")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Get did not unblock after Put")
+		}
+		
+		pool.Put(c1)
+		if stats := pool.Stats(); stats.InUse != 0 || stats.Available != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+	
+	t.Run("NilFactory", func(t *testing.T) {
+		pool := litestreampp.NewS3ClientPool(1, nil)
+		if _, err := pool.Get(context.Background()); err == nil {
+			t.Error("expected error without factory")
+		}
+	})
+	
+	t.Run("PutForeignClient", func(t *testing.T) {
+		pool := litestreampp.NewS3ClientPool(1, newClient)
+		
+		c1, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.Put(c1)
+		
+		// The pool is full, so a client it never handed out is discarded
+		// without counting as returned
+		pool.Put(newClient())
+		if stats := pool.Stats(); stats.InUse != 0 || stats.Available != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+	
+	t.Run("DefaultFactoryRetriesSession", func(t *testing.T) {
+		t.Setenv("AWS_REGION", "us-east-1")
+		mgr := litestreampp.NewSharedResourceManager()
+		defer mgr.Shutdown(context.Background())
+		pool := mgr.S3ClientPool()
+		
+		// A missing CA bundle fails session creation
+		t.Run("Fails", func(t *testing.T) {
+			t.Setenv("AWS_CA_BUNDLE", filepath.Join(t.TempDir(), "missing.pem"))
+			if _, err := pool.Get(context.Background()); err == nil {
+				t.Fatal("expected error creating session")
+			}
+		})
+		
+		c, err := pool.Get(context.Background())
+		if err != nil || c == nil {
+			t.Fatalf("expected client after session error, got %v, %v", c, err)
+		}
+	})
 }
 
 // Test task implementation
@@ -214,6 +672,26 @@ func (t *testTask) OnError(err error) {
 	// Not used in success case
 }
 
+// Blocking task implementation that runs until canceled
+type blockingTask struct {
+	started chan struct{}
+	errs    chan error
+}
+
+func (t *blockingTask) Execute() error {
+	return nil
+}
+
+func (t *blockingTask) ExecuteContext(ctx context.Context) error {
+	close(t.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (t *blockingTask) OnError(err error) {
+	t.errs <- err
+}
+
 // Error task implementation
 type errorTask struct {
 	errorCount *int32
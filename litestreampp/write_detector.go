@@ -2,14 +2,31 @@ package litestreampp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fsnotify/fsnotify"
 )
 
+// scanConcurrency is the number of goroutines stat'ing databases during a scan
+const scanConcurrency = 32
+
+// spreadSlots is how many parts of the scan interval a spread scan is
+// divided into. Each database is stat'd in the part its path hashes to.
+const spreadSlots = 16
+
+// ErrPaused is returned for manual tier changes while the detector is paused
+var ErrPaused = errors.New("manager is paused")
+
 // WriteDetector handles write detection and hot/cold tier management
 type WriteDetector struct {
 	mu sync.RWMutex
@@ -18,23 +35,53 @@ type WriteDetector struct {
 	scanInterval   time.Duration // How often to scan (15s)
 	hotDuration    time.Duration // How long to keep hot after write (15s)
 	maxHotDBs      int          // Maximum hot databases
+	minHotDuration time.Duration // Minimum time to stay hot after promotion
+	evictionPolicy EvictionPolicy // Chooses which hot DBs to demote over the limit
+
+	// Per-project hot limits (0 = no limit beyond maxHotDBs)
+	projectMaxHot        map[string]int // Overrides defaultProjectMaxHot for named projects
+	defaultProjectMaxHot int
+
+	excludePatterns []string // Glob matches skipped by AddDatabases
+
+	confirmWithHeader bool // Only count changes that commit a transaction as writes
+
+	spreadScans bool // Stat each database in its own part of the interval
+
+	// Access-based promotion (accessThreshold 0 = writes only)
+	accessThreshold int64
+	accessCounts    func() map[string]int64
 
 	// State tracking
 	databases      map[string]*WriteState
 	hotList        []string // Ordered list of hot DBs for LRU
+	lastScan       time.Time // When the last scan finished
+	paused         bool      // Scans and write events leave tiers alone until Resume
 
 	// Callbacks
 	onPromoteToHot func(path string) error
 	onDemoteToCold func(path string) error
 
+	// Databases demoted while the lock was held, whose demotion callbacks
+	// flushDemotions runs after it is released
+	pendingDemotions []string
+
 	// Shared resources
 	sharedResources *SharedResourceManager
 	connectionPool  *ConnectionPool
+	metrics         *HierarchicalMetrics // Tier counts and prevented demotions (nil = none)
 
 	// Control
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	rescanCh chan struct{} // Signals the scan loop to rescan and reset its ticker
+
+	// Filesystem watching (optional)
+	useWatcher  bool
+	watcher     *fsnotify.Watcher
+	watchedDirs map[string]bool
+	pollAll     bool // Next scan stats watched databases too
 }
 
 // WriteState tracks write detection state for a database
@@ -44,18 +91,143 @@ type WriteState struct {
 	LastSize    int64
 	IsHot       bool
 	HotUntil    time.Time
+	PromotedAt  time.Time
 	LastChecked time.Time
+	Pinned      bool // Stays hot, exempt from expiry and eviction, until unpinned
+	Watched     bool // Writes arrive as filesystem events; skipped by polling scans
+	WriteCount  int64 // Writes detected since tracking began, for LFU eviction
+	AccessCount int64 // Access count seen by the last scan, when promoting on access
+
+	header dbHeader // Last commit position seen, when confirming writes
+	quiet  bool     // Being changed by the manager, so changes aren't writes
 }
 
-// NewWriteDetector creates a new write detector
-func NewWriteDetector(scanInterval, hotDuration time.Duration, maxHotDBs int) *WriteDetector {
+// NewWriteDetector creates a new write detector. The eviction policy picks
+// which hot databases to demote when more than maxHotDBs are hot; nil
+// selects LRU.
+func NewWriteDetector(scanInterval, hotDuration time.Duration, maxHotDBs int, policy EvictionPolicy) *WriteDetector {
+	if policy == nil {
+		policy = LRUEvictionPolicy{}
+	}
 	return &WriteDetector{
-		scanInterval: scanInterval,
-		hotDuration:  hotDuration,
-		maxHotDBs:    maxHotDBs,
-		databases:    make(map[string]*WriteState),
-		hotList:      make([]string, 0),
+		scanInterval:   scanInterval,
+		hotDuration:    hotDuration,
+		maxHotDBs:      maxHotDBs,
+		evictionPolicy: policy,
+		databases:      make(map[string]*WriteState),
+		hotList:        make([]string, 0),
+		rescanCh:       make(chan struct{}, 1),
+		metrics:        GlobalMetrics,
+	}
+}
+
+// NewWriteDetectorWithWatcher creates a write detector that watches database
+// directories for writes and promotes on each write event instead of waiting
+// for the next scan. Scans still run every scanInterval to expire hot
+// databases, and databases whose directory can't be watched (for example
+// once the inotify watch limit is reached) are polled as usual.
+func NewWriteDetectorWithWatcher(scanInterval, hotDuration time.Duration, maxHotDBs int, policy EvictionPolicy) *WriteDetector {
+	w := NewWriteDetector(scanInterval, hotDuration, maxHotDBs, policy)
+	w.useWatcher = true
+	w.watchedDirs = make(map[string]bool)
+	return w
+}
+
+// Reconfigure updates the scan interval, hot duration, and hot database
+// limit of a running detector. Tracking state is preserved, and a scan runs
+// immediately so a lowered limit demotes excess databases right away.
+func (w *WriteDetector) Reconfigure(scanInterval, hotDuration time.Duration, maxHotDBs int) {
+	w.mu.Lock()
+	if scanInterval > 0 {
+		w.scanInterval = scanInterval
+	}
+	if hotDuration > 0 {
+		w.hotDuration = hotDuration
 	}
+	if maxHotDBs > 0 {
+		w.maxHotDBs = maxHotDBs
+	}
+	w.mu.Unlock()
+
+	w.requestRescan()
+}
+
+// SetProjectQuotas limits how many databases of each project, as parsed by
+// ParseDBPath, may be hot at once. perProject overrides defaultMax for the
+// projects it names; a limit of zero means no limit beyond maxHotDBs. A
+// scan runs immediately so the new quotas apply right away.
+func (w *WriteDetector) SetProjectQuotas(perProject map[string]int, defaultMax int) {
+	quotas := make(map[string]int, len(perProject))
+	for project, max := range perProject {
+		quotas[project] = max
+	}
+
+	w.mu.Lock()
+	w.projectMaxHot = quotas
+	w.defaultProjectMaxHot = defaultMax
+	w.mu.Unlock()
+
+	w.requestRescan()
+}
+
+// requestRescan asks a running scan loop to scan now and reset its ticker
+func (w *WriteDetector) requestRescan() {
+	select {
+	case w.rescanCh <- struct{}{}:
+	default:
+	}
+}
+
+// SetExcludePatterns sets patterns whose matches AddDatabases skips. See
+// isExcluded for how patterns are matched. Databases already tracked are
+// not removed.
+func (w *WriteDetector) SetExcludePatterns(patterns []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.excludePatterns = append([]string(nil), patterns...)
+}
+
+// excluded returns true if AddDatabases should skip path
+func (w *WriteDetector) excluded(path string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return isExcluded(path, w.excludePatterns)
+}
+
+// SetConfirmWithHeader makes size and mtime changes count as writes only
+// when the database header or WAL shows a new transaction, so reads and
+// checkpoints don't promote. Databases tracked before it was enabled count
+// their next change as a write.
+func (w *WriteDetector) SetConfirmWithHeader(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.confirmWithHeader = enabled
+}
+
+// SetSpreadScans spreads scans across the scan interval instead of stat'ing
+// every database at once. The interval is divided into spreadSlots parts and
+// each database is stat'd in the part its path hashes to, so each is still
+// checked once per interval while the stat load is smoothed out. Expiry and
+// eviction run at every part. Scans after a rescan request still stat
+// everything at once.
+func (w *WriteDetector) SetSpreadScans(enabled bool) {
+	w.mu.Lock()
+	w.spreadScans = enabled
+	w.mu.Unlock()
+
+	w.requestRescan()
+}
+
+// SetAccessThreshold makes a database with at least threshold accesses
+// between two scans count as written, promoting it or keeping it hot. counts
+// returns the cumulative access count of each database; it is called once
+// per scan without the detector's lock held. A threshold of zero disables
+// access-based promotion.
+func (w *WriteDetector) SetAccessThreshold(threshold int64, counts func() map[string]int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.accessThreshold = threshold
+	w.accessCounts = counts
 }
 
 // SetCallbacks sets the promotion/demotion callbacks
@@ -64,6 +236,22 @@ func (w *WriteDetector) SetCallbacks(onPromote, onDemote func(path string) error
 	w.onDemoteToCold = onDemote
 }
 
+// SetMinHotDuration sets the minimum time a database stays hot after
+// promotion, even if its hot period expires sooner
+func (w *WriteDetector) SetMinHotDuration(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.minHotDuration = d
+}
+
+// SetMetrics sets the metrics the detector records into. The default is
+// GlobalMetrics; nil disables recording.
+func (w *WriteDetector) SetMetrics(metrics *HierarchicalMetrics) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = metrics
+}
+
 // SetResources sets shared resources
 func (w *WriteDetector) SetResources(shared *SharedResourceManager, connPool *ConnectionPool) {
 	w.sharedResources = shared
@@ -74,6 +262,10 @@ func (w *WriteDetector) SetResources(shared *SharedResourceManager, connPool *Co
 func (w *WriteDetector) Start(ctx context.Context) {
 	w.ctx, w.cancel = context.WithCancel(ctx)
 
+	if w.useWatcher {
+		w.startWatcher()
+	}
+
 	w.wg.Add(1)
 	go w.scanLoop()
 }
@@ -84,80 +276,195 @@ func (w *WriteDetector) Stop() {
 		w.cancel()
 	}
 	w.wg.Wait()
+
+	w.mu.Lock()
+	if w.watcher != nil {
+		w.watcher.Close()
+		w.watcher = nil
+	}
+	w.mu.Unlock()
 }
 
 // scanLoop is the main detection loop
 func (w *WriteDetector) scanLoop() {
 	defer w.wg.Done()
 
-	ticker := time.NewTicker(w.scanInterval)
+	interval, spread := w.tickInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	// Initial scan
 	w.performScan()
 
+	slot := 0
 	for {
 		select {
 		case <-w.ctx.Done():
 			return
 		case <-ticker.C:
+			if !spread {
+				w.performScan()
+				continue
+			}
+			w.scan(slot)
+			slot = (slot + 1) % spreadSlots
+		case <-w.rescanCh:
+			interval, spread = w.tickInterval()
+			ticker.Reset(interval)
+			slot = 0
 			w.performScan()
 		}
 	}
 }
 
+// tickInterval returns how often the scan loop ticks, and whether each tick
+// scans only one slot of databases
+func (w *WriteDetector) tickInterval() (time.Duration, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.spreadScans {
+		return slotInterval(w.scanInterval), true
+	}
+	return w.scanInterval, false
+}
+
+// slotInterval returns how long each slot of a spread scan has
+func slotInterval(scanInterval time.Duration) time.Duration {
+	return max(scanInterval/spreadSlots, time.Millisecond)
+}
+
+// spreadSlot returns the slot of the scan interval a database is stat'd in
+// when scans are spread
+func spreadSlot(path string) int {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return int(h.Sum32() % spreadSlots)
+}
+
 // performScan scans all databases for write activity
 func (w *WriteDetector) performScan() {
+	w.scan(-1)
+}
+
+// scan checks databases for write activity, stat'ing only those in slot
+// unless slot is negative. Expiry and eviction cover every database.
+func (w *WriteDetector) scan(slot int) {
 	start := time.Now()
-	now := time.Now()
 
+	// Watched databases are promoted by write events, so only polled
+	// databases need a stat. A request to poll everything waits for a full
+	// scan.
+	w.mu.Lock()
+	if w.paused {
+		w.mu.Unlock()
+		return
+	}
+	pollAll := w.pollAll && slot < 0
+	if slot < 0 {
+		w.pollAll = false
+	}
+	confirm := w.confirmWithHeader
+	paths := make([]string, 0, len(w.databases))
+	var last []statResult
+	for path, state := range w.databases {
+		if slot >= 0 && spreadSlot(path) != slot {
+			continue
+		}
+		if !state.Watched || pollAll {
+			paths = append(paths, path)
+			if confirm {
+				last = append(last, statResult{modTime: state.LastModTime, size: state.LastSize})
+			}
+		}
+	}
+	accessThreshold, accessCounts := w.accessThreshold, w.accessCounts
+	w.mu.Unlock()
+
+	// Stat without the lock so callers aren't blocked for the whole scan
+	stats := statDatabases(paths, last)
+
+	var accesses map[string]int64
+	if accessThreshold > 0 && accessCounts != nil {
+		accesses = accessCounts()
+	}
+
+	// Deferred before the unlock so it runs after it
+	defer w.flushDemotions()
+
+	now := time.Now()
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	lockedAt := time.Now()
+
+	// Paused while stat'ing, so the results are discarded. Resume rescans.
+	if w.paused {
+		return
+	}
 
 	var promoted, demoted int
 	newHotList := make([]string, 0, len(w.hotList))
 
-	// Check all tracked databases
+	// Check all tracked databases. Databases added since the stats were
+	// taken have no result and are checked on the next scan.
 	for path, state := range w.databases {
-		info, err := os.Stat(path)
-		if err != nil {
-			if os.IsNotExist(err) {
-				// Database was deleted
-				delete(w.databases, path)
-				if state.IsHot {
-					w.demoteToColLocked(path)
-					demoted++
+		modified := false
+		if st, ok := stats[path]; ok && !state.quiet {
+			if st.err != nil {
+				if os.IsNotExist(st.err) {
+					// Database was deleted
+					delete(w.databases, path)
+					if state.IsHot {
+						w.queueDemotionLocked(path)
+						demoted++
+					}
 				}
+				continue
+			}
+
+			// A write event may have recorded a newer state since the
+			// stat, so only newer results count as modifications
+			if !st.modTime.Before(state.LastModTime) {
+				modified = st.modTime.After(state.LastModTime) || st.size != state.LastSize
+
+				// Reads and checkpoints move the mtime without committing
+				if modified && confirm {
+					modified = !st.header.valid || st.header.newTransactions(state.header)
+					state.header = st.header
+				}
+
+				// Update tracking
+				state.LastModTime = st.modTime
+				state.LastSize = st.size
 			}
-			continue
 		}
 
-		// Check for modifications
-		modified := info.ModTime().After(state.LastModTime) || info.Size() != state.LastSize
+		// Frequently accessed databases are promoted like written ones
+		if n, ok := accesses[path]; ok {
+			if n-state.AccessCount >= accessThreshold {
+				modified = true
+			}
+			state.AccessCount = n
+		}
 
 		if modified {
-			// Database was modified - promote to hot
-			if !state.IsHot {
-				if err := w.promoteToHotLocked(path); err != nil {
-					slog.Error("failed to promote to hot", "path", path, "error", err)
-				} else {
-					promoted++
-				}
+			// Database was modified or accessed - promote to hot
+			if w.markWrittenLocked(state, now) {
+				promoted++
 			}
-			state.IsHot = true
-			state.HotUntil = now.Add(w.hotDuration)
 			newHotList = append(newHotList, path)
-
-			// Update tracking
-			state.LastModTime = info.ModTime()
-			state.LastSize = info.Size()
+		} else if state.IsHot && state.Pinned {
+			// Pinned databases stay hot until explicitly unpinned
+			newHotList = append(newHotList, path)
+		} else if state.IsHot && now.After(state.HotUntil) && now.Sub(state.PromotedAt) < w.minHotDuration {
+			// Hot period expired but still within the post-promotion grace window
+			newHotList = append(newHotList, path)
+			if w.metrics != nil {
+				w.metrics.RecordPreventedDemotion()
+			}
 		} else if state.IsHot && now.After(state.HotUntil) {
 			// No recent modifications and hot period expired - demote to cold
-			if err := w.demoteToColLocked(path); err != nil {
-				slog.Error("failed to demote to cold", "path", path, "error", err)
-			} else {
-				demoted++
-			}
+			w.queueDemotionLocked(path)
+			demoted++
 			state.IsHot = false
 		} else if state.IsHot {
 			// Still hot, keep in list
@@ -167,39 +474,173 @@ func (w *WriteDetector) performScan() {
 		state.LastChecked = now
 	}
 
-	// Enforce max hot databases limit (LRU eviction)
-	if len(newHotList) > w.maxHotDBs {
-		// Sort by HotUntil time (oldest first)
-		toEvict := len(newHotList) - w.maxHotDBs
-		for i := 0; i < toEvict; i++ {
-			path := newHotList[i]
-			if state, ok := w.databases[path]; ok {
-				if err := w.demoteToColLocked(path); err != nil {
-					slog.Error("failed to evict hot database", "path", path, "error", err)
-				} else {
-					state.IsHot = false
-					demoted++
-				}
-			}
-		}
-		newHotList = newHotList[toEvict:]
-	}
+	newHotList, evicted := w.evictLocked(newHotList)
+	demoted += evicted
 
 	w.hotList = newHotList
+	w.lastScan = time.Now()
 
-	// Update metrics
-	if GlobalMetrics != nil {
-		GlobalMetrics.UpdateTierCounts(len(newHotList), len(w.databases)-len(newHotList))
+	// Update metrics. A slot of a spread scan must finish before the next
+	// slot's tick.
+	if w.metrics != nil {
+		w.metrics.UpdateTierCounts(len(newHotList), len(w.databases)-len(newHotList))
+		budget := w.scanInterval
+		if slot >= 0 {
+			budget = slotInterval(w.scanInterval)
+		}
+		w.metrics.RecordScan(time.Since(start), budget, len(w.databases), slot < 0)
 	}
 
 	slog.Debug("write detection scan complete",
 		"duration", time.Since(start),
+		"lock_held", time.Since(lockedAt),
 		"total", len(w.databases),
 		"hot", len(newHotList),
 		"promoted", promoted,
 		"demoted", demoted)
 }
 
+// statResult is the outcome of stat'ing one database during a scan
+type statResult struct {
+	modTime time.Time
+	size    int64
+	header  dbHeader // Only read when confirming writes
+	err     error
+}
+
+// statDatabases stats paths concurrently and returns the results by path.
+// If last is set, it holds the previous stat of each path, and the header
+// of every database whose size or mtime changed is read as well.
+func statDatabases(paths []string, last []statResult) map[string]statResult {
+	results := make([]statResult, len(paths))
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < min(scanConcurrency, len(paths)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j := int(next.Add(1)) - 1
+				if j >= len(paths) {
+					return
+				}
+				info, err := os.Stat(paths[j])
+				if err != nil {
+					results[j] = statResult{err: err}
+					continue
+				}
+				results[j] = statResult{modTime: info.ModTime(), size: info.Size()}
+				if last != nil && (!info.ModTime().Equal(last[j].modTime) || info.Size() != last[j].size) {
+					results[j].header, _ = readDBHeader(paths[j])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	m := make(map[string]statResult, len(paths))
+	for i, path := range paths {
+		m[path] = results[i]
+	}
+	return m
+}
+
+// markWrittenLocked marks a database hot after a write, promoting it if it
+// was cold. Returns true if it was promoted (must hold lock)
+func (w *WriteDetector) markWrittenLocked(state *WriteState, now time.Time) bool {
+	promoted := false
+	state.WriteCount++
+	if !state.IsHot {
+		if err := w.promoteToHotLocked(state.Path); err != nil {
+			slog.Error("failed to promote to hot", "path", state.Path, "error", err)
+		} else {
+			promoted = true
+			state.PromotedAt = now
+		}
+	}
+	state.IsHot = true
+	state.HotUntil = now.Add(w.hotDuration)
+	return promoted
+}
+
+// evictLocked enforces the per-project quotas and then the max hot
+// databases limit on hotList, demoting the victims chosen by the eviction
+// policy. A project over its quota gives up its own databases before the
+// global limit is considered, so one busy project can't push out the
+// others. Pinned databases are never evicted, so pinning more than a limit
+// exceeds it. Returns the remaining hot list and the number demoted (must
+// hold lock)
+func (w *WriteDetector) evictLocked(hotList []string) ([]string, int) {
+	evicted := make(map[string]bool)
+
+	if w.defaultProjectMaxHot > 0 || len(w.projectMaxHot) > 0 {
+		byProject := make(map[string][]string)
+		for _, path := range hotList {
+			project, _, _, _ := ParseDBPath(path)
+			byProject[project] = append(byProject[project], path)
+		}
+		for project, paths := range byProject {
+			if quota := w.projectQuotaLocked(project); quota > 0 && len(paths) > quota {
+				w.evictVictimsLocked(paths, len(paths)-quota, evicted)
+			}
+		}
+	}
+
+	if remaining := len(hotList) - len(evicted); remaining > w.maxHotDBs {
+		paths := make([]string, 0, remaining)
+		for _, path := range hotList {
+			if !evicted[path] {
+				paths = append(paths, path)
+			}
+		}
+		w.evictVictimsLocked(paths, remaining-w.maxHotDBs, evicted)
+	}
+
+	if len(evicted) == 0 {
+		return hotList, 0
+	}
+
+	kept := hotList[:0]
+	for _, path := range hotList {
+		if !evicted[path] {
+			kept = append(kept, path)
+		}
+	}
+	return kept, len(evicted)
+}
+
+// evictVictimsLocked queues the demotion of up to n of paths chosen by the
+// eviction policy, skipping pinned databases, and adds them to evicted
+// (must hold lock)
+func (w *WriteDetector) evictVictimsLocked(paths []string, n int, evicted map[string]bool) {
+	candidates := make([]*WriteState, 0, len(paths))
+	for _, path := range paths {
+		if state, ok := w.databases[path]; ok && !state.Pinned {
+			candidates = append(candidates, state)
+		}
+	}
+
+	for _, path := range w.evictionPolicy.SelectVictims(candidates, n) {
+		state, ok := w.databases[path]
+		if !ok || !state.IsHot || state.Pinned {
+			continue
+		}
+		w.queueDemotionLocked(path)
+		state.IsHot = false
+		evicted[path] = true
+	}
+}
+
+// projectQuotaLocked returns the hot limit for a project, or 0 if it has
+// none (must hold lock)
+func (w *WriteDetector) projectQuotaLocked(project string) int {
+	if max, ok := w.projectMaxHot[project]; ok {
+		return max
+	}
+	return w.defaultProjectMaxHot
+}
+
 // AddDatabase adds a database to track
 func (w *WriteDetector) AddDatabase(path string) error {
 	w.mu.Lock()
@@ -214,35 +655,367 @@ func (w *WriteDetector) AddDatabase(path string) error {
 		return fmt.Errorf("stat database: %w", err)
 	}
 
-	w.databases[path] = &WriteState{
+	state := &WriteState{
 		Path:        path,
 		LastModTime: info.ModTime(),
 		LastSize:    info.Size(),
 		LastChecked: time.Now(),
 	}
+	if w.confirmWithHeader {
+		state.header, _ = readDBHeader(path)
+	}
+	w.databases[path] = state
+
+	if w.watcher != nil {
+		w.watchLocked(state)
+	}
 
 	return nil
 }
 
-// AddDatabases adds multiple databases from glob patterns
+// PatternError is returned, joined with any other failures, by
+// AddDatabases for a pattern that is malformed or whose directories can't
+// be read, as opposed to a single match that couldn't be added
+type PatternError struct {
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("glob pattern %q: %v", e.Pattern, e.Err)
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+// AddDatabases adds multiple databases from glob patterns. Patterns may use
+// ** to match any number of directories, as restore-pattern does. Every
+// pattern and match is processed; failures are returned together with
+// errors.Join, each pattern failure as a *PatternError.
 func (w *WriteDetector) AddDatabases(patterns []string) error {
+	_, err := w.addDatabases(patterns)
+	return err
+}
+
+// addDatabases adds the matches of patterns and returns the matches now
+// tracked, including those already tracked, along with any failures
+func (w *WriteDetector) addDatabases(patterns []string) ([]string, error) {
+	var tracked []string
+	var errs []error
 	for _, pattern := range patterns {
-		matches, err := filepath.Glob(pattern)
+		matches, err := doublestar.FilepathGlob(pattern, doublestar.WithFailOnIOErrors())
 		if err != nil {
-			slog.Error("glob pattern failed", "pattern", pattern, "error", err)
+			errs = append(errs, &PatternError{Pattern: pattern, Err: err})
 			continue
 		}
 
 		for _, path := range matches {
+			if w.excluded(path) {
+				continue
+			}
 			if err := w.AddDatabase(path); err != nil {
-				slog.Error("failed to add database", "path", path, "error", err)
+				errs = append(errs, fmt.Errorf("add database %s: %w", path, err))
+				continue
+			}
+			tracked = append(tracked, path)
+		}
+	}
+
+	return tracked, errors.Join(errs...)
+}
+
+// sqliteSidecarSuffixes are the files SQLite keeps next to a database,
+// which discovery globs like *.db* can match but must never be tracked
+var sqliteSidecarSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// isExcluded returns true if path is a SQLite sidecar file or matches one
+// of patterns. Patterns may use **, and a pattern without a separator is
+// matched against the file name only, so "*.tmp.db" excludes such files in
+// any directory.
+func isExcluded(path string, patterns []string) bool {
+	for _, suffix := range sqliteSidecarSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range patterns {
+		name := path
+		if !strings.ContainsRune(pattern, filepath.Separator) {
+			name = filepath.Base(path)
+		}
+		if ok, _ := doublestar.PathMatch(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveDatabase stops tracking a database, demoting it first if it is hot.
+// Pinned databases are removed too. Removing an untracked path is a no-op.
+func (w *WriteDetector) RemoveDatabase(path string) error {
+	w.mu.Lock()
+	w.removeDatabaseLocked(path)
+	w.mu.Unlock()
+
+	return w.flushDemotions()
+}
+
+// RemoveDatabases stops tracking every database matching the glob patterns,
+// which may use **. Tracked databases are matched even if their files no
+// longer exist.
+func (w *WriteDetector) RemoveDatabases(patterns []string) error {
+	for _, pattern := range patterns {
+		if !doublestar.ValidatePathPattern(pattern) {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, doublestar.ErrBadPattern)
+		}
+	}
+
+	w.mu.Lock()
+	for _, pattern := range patterns {
+		for path := range w.databases {
+			if ok, _ := doublestar.PathMatch(pattern, path); ok {
+				w.removeDatabaseLocked(path)
 			}
 		}
 	}
+	w.mu.Unlock()
+
+	return w.flushDemotions()
+}
+
+// removeDatabaseLocked drops a database's tracking state, queueing its
+// demotion if it is hot (must hold lock). Tracking is dropped even if the
+// demotion callback fails.
+func (w *WriteDetector) removeDatabaseLocked(path string) {
+	state, ok := w.databases[path]
+	if !ok {
+		return
+	}
+	delete(w.databases, path)
+
+	if state.IsHot {
+		w.removeFromHotListLocked(path)
+		w.queueDemotionLocked(path)
+	}
+}
+
+// seedState overrides the last seen modification time and size of a
+// tracked database, e.g. with values saved before a restart, so the next
+// scan compares against them
+func (w *WriteDetector) seedState(path string, modTime time.Time, size int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if state, ok := w.databases[path]; ok {
+		state.LastModTime = modTime
+		state.LastSize = size
+	}
+}
+
+// quiet stops counting changes to a database as writes while the manager
+// changes it itself, such as for a cold snapshot, until unquiet
+func (w *WriteDetector) quiet(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if state, ok := w.databases[path]; ok {
+		state.quiet = true
+	}
+}
+
+// unquiet counts changes to a database as writes again. If before and
+// after are set, they are stats of the database from just after quiet and
+// from now, and the changes between them are absorbed into the last seen
+// state so they aren't detected as a write. A change from before quiet
+// that no scan had seen yet is left to be detected.
+func (w *WriteDetector) unquiet(path string, before, after os.FileInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	state, ok := w.databases[path]
+	if !ok {
+		return
+	}
+	state.quiet = false
+	if before != nil && after != nil && state.LastModTime.Equal(before.ModTime()) && state.LastSize == before.Size() {
+		state.LastModTime = after.ModTime()
+		state.LastSize = after.Size()
+		if w.confirmWithHeader {
+			state.header, _ = readDBHeader(path)
+		}
+	}
+}
+
+// Pause stops scans and write events from changing tiers until Resume, and
+// makes PromoteNow, Pin, and DemoteNow fail with ErrPaused. A scan in
+// progress either finishes before Pause returns or discards its results.
+func (w *WriteDetector) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = true
+}
+
+// Resume undoes Pause and runs a full scan, which stats every database
+// including watched ones, so writes made while paused promote as usual
+func (w *WriteDetector) Resume() {
+	w.mu.Lock()
+	wasPaused := w.paused
+	w.paused = false
+	w.pollAll = w.pollAll || wasPaused
+	w.mu.Unlock()
+
+	if wasPaused {
+		w.requestRescan()
+	}
+}
+
+// Paused reports whether the detector is paused
+func (w *WriteDetector) Paused() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.paused
+}
+
+// writeStates returns a copy of every tracked database's state
+func (w *WriteDetector) writeStates() []WriteState {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	states := make([]WriteState, 0, len(w.databases))
+	for _, state := range w.databases {
+		states = append(states, *state)
+	}
+	return states
+}
+
+// PromoteNow promotes a tracked database to hot immediately. It then gets
+// the usual hot duration and minimum hot duration, so the next scan does
+// not demote it straight away.
+func (w *WriteDetector) PromoteNow(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	state, ok := w.databases[path]
+	if !ok {
+		return fmt.Errorf("database not tracked: %s", path)
+	}
+	return w.promoteNowLocked(state)
+}
+
+// promoteNowLocked marks a database hot, promoting it if needed (must hold lock)
+func (w *WriteDetector) promoteNowLocked(state *WriteState) error {
+	if w.paused {
+		return ErrPaused
+	}
+	now := time.Now()
+	if !state.IsHot {
+		if err := w.promoteToHotLocked(state.Path); err != nil {
+			return fmt.Errorf("promote to hot: %w", err)
+		}
+		state.IsHot = true
+		state.PromotedAt = now
+		w.hotList = append(w.hotList, state.Path)
+	}
+	state.HotUntil = now.Add(w.hotDuration)
+	return nil
+}
+
+// DemoteNow demotes a database to cold immediately. Pinned databases must
+// be unpinned first. Changes made before the call are treated as already
+// seen, so only writes after it promote the database again.
+func (w *WriteDetector) DemoteNow(path string) error {
+	w.mu.Lock()
+	err := w.demoteNowLocked(path)
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return w.flushDemotions()
+}
+
+// demoteNowLocked marks a database cold, queueing its demotion if it was
+// hot (must hold lock)
+func (w *WriteDetector) demoteNowLocked(path string) error {
+	state, ok := w.databases[path]
+	if !ok {
+		return fmt.Errorf("database not tracked: %s", path)
+	}
+	if state.Pinned {
+		return fmt.Errorf("database is pinned: %s", path)
+	}
+	if w.paused {
+		return ErrPaused
+	}
+
+	if state.IsHot {
+		w.queueDemotionLocked(path)
+		state.IsHot = false
+		w.removeFromHotListLocked(path)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		state.LastModTime = info.ModTime()
+		state.LastSize = info.Size()
+	}
+	return nil
+}
+
+// Pin promotes a database to hot and keeps it hot, exempt from expiry and
+// eviction, until Unpin is called.
+func (w *WriteDetector) Pin(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	state, ok := w.databases[path]
+	if !ok {
+		return fmt.Errorf("database not tracked: %s", path)
+	}
+	if err := w.promoteNowLocked(state); err != nil {
+		return err
+	}
+	state.Pinned = true
+	return nil
+}
 
+// Unpin returns a pinned database to normal expiry. It stays hot for one
+// more hot duration before it can be demoted.
+func (w *WriteDetector) Unpin(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	state, ok := w.databases[path]
+	if !ok {
+		return fmt.Errorf("database not tracked: %s", path)
+	}
+	if state.Pinned {
+		state.Pinned = false
+		state.HotUntil = time.Now().Add(w.hotDuration)
+	}
 	return nil
 }
 
+// IsPinned checks if a database is pinned hot
+func (w *WriteDetector) IsPinned(path string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if state, ok := w.databases[path]; ok {
+		return state.Pinned
+	}
+	return false
+}
+
+// removeFromHotListLocked drops a path from the hot list (must hold lock)
+func (w *WriteDetector) removeFromHotListLocked(path string) {
+	for i, p := range w.hotList {
+		if p == path {
+			w.hotList = append(w.hotList[:i], w.hotList[i+1:]...)
+			return
+		}
+	}
+}
+
 // promoteToHotLocked promotes a database to hot tier (must hold lock)
 func (w *WriteDetector) promoteToHotLocked(path string) error {
 	if w.onPromoteToHot != nil {
@@ -251,12 +1024,50 @@ func (w *WriteDetector) promoteToHotLocked(path string) error {
 	return nil
 }
 
-// demoteToColLocked demotes a database to cold tier (must hold lock)
-func (w *WriteDetector) demoteToColLocked(path string) error {
-	if w.onDemoteToCold != nil {
-		return w.onDemoteToCold(path)
+// queueDemotionLocked records a database marked cold, for flushDemotions
+// to run its demotion callback (must hold lock)
+func (w *WriteDetector) queueDemotionLocked(path string) {
+	w.pendingDemotions = append(w.pendingDemotions, path)
+}
+
+// flushDemotions runs the demotion callback of every queued database. It
+// must be called without the lock held: the callback's final replica sync
+// can take up to the demotion sync timeout, and other databases must be
+// able to change tiers meanwhile. A database promoted again while its
+// callback ran found itself still hot, so it is promoted once more after.
+// Failures are logged and returned together.
+func (w *WriteDetector) flushDemotions() error {
+	w.mu.Lock()
+	paths := w.pendingDemotions
+	w.pendingDemotions = nil
+	w.mu.Unlock()
+
+	var errs []error
+	for _, path := range paths {
+		if w.onDemoteToCold != nil {
+			if err := w.onDemoteToCold(path); err != nil {
+				slog.Error("failed to demote to cold", "path", path, "error", err)
+				errs = append(errs, fmt.Errorf("demote %s: %w", path, err))
+			}
+		}
+
+		w.mu.Lock()
+		if state, ok := w.databases[path]; ok && state.IsHot {
+			if err := w.promoteToHotLocked(path); err != nil {
+				slog.Error("failed to promote to hot", "path", path, "error", err)
+			}
+		}
+		w.mu.Unlock()
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// LastScan returns when the last scan finished, or the zero time if no
+// scan has run
+func (w *WriteDetector) LastScan() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lastScan
 }
 
 // GetHotDatabases returns the current list of hot databases
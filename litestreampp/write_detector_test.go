@@ -2,13 +2,18 @@ package litestreampp_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/litestream/litestreampp"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestWriteDetector(t *testing.T) {
@@ -33,6 +38,7 @@ func TestWriteDetector(t *testing.T) {
 			100*time.Millisecond, // scan interval
 			200*time.Millisecond, // hot duration
 			10,                   // max hot DBs
+			nil,                  // eviction policy (LRU)
 		)
 
 		detector.SetCallbacks(
@@ -107,6 +113,7 @@ func TestWriteDetector(t *testing.T) {
 			100*time.Millisecond,
 			200*time.Millisecond,
 			3, // max 3 hot DBs
+			nil,
 		)
 
 		// Track promotions
@@ -171,6 +178,7 @@ func TestWriteDetector(t *testing.T) {
 			100*time.Millisecond,
 			200*time.Millisecond,
 			10,
+			nil,
 		)
 
 		// Use glob pattern
@@ -187,6 +195,76 @@ func TestWriteDetector(t *testing.T) {
 		}
 	})
 
+	t.Run("RecursivePattern", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		// Databases at different depths, plus a non-database file
+		createTestFile(t, filepath.Join(tmpDir, "top.db"), "content")
+		for _, dir := range []string{"a", filepath.Join("a", "b"), filepath.Join("a", "b", "c")} {
+			os.MkdirAll(filepath.Join(tmpDir, dir), 0755)
+			createTestFile(t, filepath.Join(tmpDir, dir, "nested.db"), "content")
+		}
+		createTestFile(t, filepath.Join(tmpDir, "a", "notes.txt"), "content")
+
+		detector := litestreampp.NewWriteDetector(
+			100*time.Millisecond,
+			200*time.Millisecond,
+			10,
+			nil,
+		)
+
+		if err := detector.AddDatabases([]string{filepath.Join(tmpDir, "**", "*.db")}); err != nil {
+			t.Fatalf("failed to add databases: %v", err)
+		}
+		if total, _, _ := detector.GetStatistics(); total != 4 {
+			t.Errorf("expected 4 databases discovered, got %d", total)
+		}
+
+		// Single-level globs still only match one directory
+		detector = litestreampp.NewWriteDetector(
+			100*time.Millisecond,
+			200*time.Millisecond,
+			10,
+			nil,
+		)
+		if err := detector.AddDatabases([]string{filepath.Join(tmpDir, "*", "*.db")}); err != nil {
+			t.Fatalf("failed to add databases: %v", err)
+		}
+		if total, _, _ := detector.GetStatistics(); total != 1 {
+			t.Errorf("expected 1 database discovered, got %d", total)
+		}
+	})
+
+	t.Run("PatternErrors", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		createTestFile(t, filepath.Join(tmpDir, "db1.db"), "content")
+
+		detector := litestreampp.NewWriteDetector(
+			100*time.Millisecond,
+			200*time.Millisecond,
+			10,
+			nil,
+		)
+
+		// The broken pattern is reported, and the good one still processed
+		err := detector.AddDatabases([]string{
+			filepath.Join(tmpDir, "[*.db"),
+			filepath.Join(tmpDir, "*.db"),
+		})
+		var patternErr *litestreampp.PatternError
+		if !errors.As(err, &patternErr) || patternErr.Pattern != filepath.Join(tmpDir, "[*.db") {
+			t.Fatalf("expected a pattern error for the broken pattern, got %v", err)
+		}
+		if total, _, _ := detector.GetStatistics(); total != 1 {
+			t.Errorf("expected 1 database discovered, got %d", total)
+		}
+
+		// Patterns matching nothing are not errors
+		if err := detector.AddDatabases([]string{filepath.Join(tmpDir, "missing", "*.db")}); err != nil {
+			t.Errorf("expected no error for a pattern without matches, got %v", err)
+		}
+	})
+
 	t.Run("DeletedDatabase", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		db1 := filepath.Join(tmpDir, "db1.db")
@@ -197,6 +275,7 @@ func TestWriteDetector(t *testing.T) {
 			100*time.Millisecond,
 			200*time.Millisecond,
 			10,
+			nil,
 		)
 		detector.SetCallbacks(
 			func(path string) error { return nil },
@@ -240,6 +319,446 @@ func TestWriteDetector(t *testing.T) {
 			t.Errorf("deleted database should be removed from tracking, got %d", total)
 		}
 	})
+
+	t.Run("RemoveDatabase", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "tenants", "t1.db")
+		db2 := filepath.Join(tmpDir, "tenants", "t2.db")
+		db3 := filepath.Join(tmpDir, "other", "nested", "t3.db")
+		for _, path := range []string{db1, db2, db3} {
+			createTestFile(t, path, "content")
+		}
+
+		var mu sync.Mutex
+		demoted := make(map[string]int)
+		detector := litestreampp.NewWriteDetector(time.Hour, time.Hour, 10, nil)
+		detector.SetCallbacks(
+			func(path string) error { return nil },
+			func(path string) error {
+				mu.Lock()
+				demoted[path]++
+				mu.Unlock()
+				return nil
+			},
+		)
+		for _, path := range []string{db1, db2, db3} {
+			detector.AddDatabase(path)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		detector.Start(ctx)
+		defer detector.Stop()
+
+		if err := detector.PromoteNow(db1); err != nil {
+			t.Fatal(err)
+		}
+
+		// Removal takes effect immediately, without waiting for a scan
+		if err := detector.RemoveDatabase(db1); err != nil {
+			t.Fatal(err)
+		}
+		if total, hot, cold := detector.GetStatistics(); total != 2 || hot != 0 || cold != 2 {
+			t.Errorf("expected 2 total, 0 hot, 2 cold; got %d, %d, %d", total, hot, cold)
+		}
+		if detector.IsHot(db1) {
+			t.Error("removed db1 should not be hot")
+		}
+		mu.Lock()
+		if demoted[db1] != 1 {
+			t.Errorf("expected db1 demoted once, got %d", demoted[db1])
+		}
+		mu.Unlock()
+
+		// Removing an untracked database is a no-op
+		if err := detector.RemoveDatabase(db1); err != nil {
+			t.Errorf("unexpected error removing untracked database: %v", err)
+		}
+
+		// Bulk removal by pattern leaves other databases alone
+		if err := detector.RemoveDatabases([]string{filepath.Join(tmpDir, "tenants", "*.db")}); err != nil {
+			t.Fatal(err)
+		}
+		if total, _, _ := detector.GetStatistics(); total != 1 {
+			t.Errorf("expected 1 database left, got %d", total)
+		}
+		mu.Lock()
+		if demoted[db2] != 0 {
+			t.Error("cold db2 should not have been demoted")
+		}
+		mu.Unlock()
+
+		// ** matches databases in nested directories
+		if err := detector.RemoveDatabases([]string{filepath.Join(tmpDir, "**", "*.db")}); err != nil {
+			t.Fatal(err)
+		}
+		if total, _, _ := detector.GetStatistics(); total != 0 {
+			t.Errorf("expected no databases left, got %d", total)
+		}
+
+		if err := detector.RemoveDatabases([]string{"["}); err == nil {
+			t.Error("expected error for invalid pattern")
+		}
+	})
+
+	t.Run("MinHotDuration", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		createTestFile(t, db1, "content1")
+
+		var mu sync.Mutex
+		demoted := 0
+
+		// Hot duration shorter than the scan interval would normally demote
+		// on the very next scan
+		detector := litestreampp.NewWriteDetector(
+			50*time.Millisecond, // scan interval
+			10*time.Millisecond, // hot duration
+			10,                  // max hot DBs
+			nil,                 // eviction policy (LRU)
+		)
+		detector.SetMinHotDuration(400 * time.Millisecond)
+		detector.SetCallbacks(
+			func(path string) error { return nil },
+			func(path string) error {
+				mu.Lock()
+				demoted++
+				mu.Unlock()
+				return nil
+			},
+		)
+		detector.AddDatabase(db1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		detector.Start(ctx)
+		defer detector.Stop()
+
+		time.Sleep(75 * time.Millisecond)
+		createTestFile(t, db1, "modified content")
+
+		// Several scans later the grace window keeps it hot
+		time.Sleep(250 * time.Millisecond)
+		if !detector.IsHot(db1) {
+			t.Error("db1 should stay hot within the minimum hot duration")
+		}
+		mu.Lock()
+		if demoted != 0 {
+			t.Errorf("expected no demotions within grace window, got %d", demoted)
+		}
+		mu.Unlock()
+
+		// After the grace window it is demoted
+		time.Sleep(400 * time.Millisecond)
+		if detector.IsHot(db1) {
+			t.Error("db1 should be demoted after the minimum hot duration")
+		}
+	})
+
+	t.Run("ManualPromotionAndPinning", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		db1 := filepath.Join(tmpDir, "db1.db")
+		db2 := filepath.Join(tmpDir, "db2.db")
+		db3 := filepath.Join(tmpDir, "db3.db")
+		createTestFile(t, db1, "content1")
+		createTestFile(t, db2, "content2")
+		createTestFile(t, db3, "content3")
+
+		var mu sync.Mutex
+		promoted := make(map[string]int)
+		demoted := make(map[string]int)
+
+		detector := litestreampp.NewWriteDetector(
+			50*time.Millisecond,  // scan interval
+			100*time.Millisecond, // hot duration
+			1,                    // max hot DBs
+			nil,                  // eviction policy (LRU)
+		)
+		detector.SetCallbacks(
+			func(path string) error {
+				mu.Lock()
+				promoted[path]++
+				mu.Unlock()
+				return nil
+			},
+			func(path string) error {
+				mu.Lock()
+				demoted[path]++
+				mu.Unlock()
+				return nil
+			},
+		)
+		detector.AddDatabase(db1)
+		detector.AddDatabase(db2)
+		detector.AddDatabase(db3)
+
+		if err := detector.PromoteNow(filepath.Join(tmpDir, "missing.db")); err == nil {
+			t.Error("expected error promoting untracked database")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		detector.Start(ctx)
+		defer detector.Stop()
+
+		// Pinned databases stay hot past their hot duration and are not
+		// evicted to make room under the hot limit
+		if err := detector.Pin(db1); err != nil {
+			t.Fatal(err)
+		}
+		if err := detector.PromoteNow(db2); err != nil {
+			t.Fatal(err)
+		}
+		if !detector.IsHot(db1) || !detector.IsHot(db2) {
+			t.Fatal("pinned and promoted databases should be hot immediately")
+		}
+
+		time.Sleep(250 * time.Millisecond)
+		if !detector.IsHot(db1) || !detector.IsPinned(db1) {
+			t.Error("pinned database should stay hot")
+		}
+		if detector.IsHot(db2) {
+			t.Error("promoted database should expire like any other")
+		}
+
+		if err := detector.DemoteNow(db1); err == nil {
+			t.Error("expected error demoting pinned database")
+		}
+
+		// A write before DemoteNow must not re-promote on the next scan
+		createTestFile(t, db3, "modified before demote")
+		if err := detector.PromoteNow(db3); err != nil {
+			t.Fatal(err)
+		}
+		if err := detector.DemoteNow(db3); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		promotedBefore := promoted[db3]
+		mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		if detector.IsHot(db3) || promoted[db3] != promotedBefore {
+			t.Error("demoted database should not be re-promoted by an earlier write")
+		}
+		mu.Unlock()
+
+		// Unpinned databases expire normally
+		if err := detector.Unpin(db1); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(250 * time.Millisecond)
+		if detector.IsHot(db1) {
+			t.Error("unpinned database should expire")
+		}
+	})
+}
+
+func TestWriteDetectorWithWatcher(t *testing.T) {
+	tmpDir := t.TempDir()
+	db1 := filepath.Join(tmpDir, "db1.db")
+	db2 := filepath.Join(tmpDir, "db2.db")
+	createTestFile(t, db1, "content1")
+	createTestFile(t, db2, "content2")
+
+	var mu sync.Mutex
+	promoted := make(map[string]int)
+	demoted := make(map[string]int)
+
+	// Scan interval far longer than the test, so only watch events can
+	// promote within it
+	detector := litestreampp.NewWriteDetectorWithWatcher(time.Hour, time.Hour, 10, nil)
+	detector.SetCallbacks(
+		func(path string) error {
+			mu.Lock()
+			promoted[path]++
+			mu.Unlock()
+			return nil
+		},
+		func(path string) error {
+			mu.Lock()
+			demoted[path]++
+			mu.Unlock()
+			return nil
+		},
+	)
+	detector.AddDatabase(db1)
+	detector.AddDatabase(db2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	detector.Start(ctx)
+	defer detector.Stop()
+
+	// WAL writes count as writes to the database
+	createTestFile(t, db1+"-wal", "wal frames")
+
+	waitFor(t, time.Second, func() bool { return detector.IsHot(db1) })
+	if detector.IsHot(db2) {
+		t.Error("db2 should be cold")
+	}
+	mu.Lock()
+	if promoted[db1] != 1 {
+		t.Errorf("expected db1 promoted once, got %d", promoted[db1])
+	}
+	mu.Unlock()
+
+	// Removing a hot database demotes it and stops tracking it
+	if err := os.Remove(db1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, time.Second, func() bool {
+		total, _, _ := detector.GetStatistics()
+		return total == 1
+	})
+	if detector.IsHot(db1) {
+		t.Error("db1 should no longer be hot")
+	}
+	mu.Lock()
+	if demoted[db1] != 1 {
+		t.Errorf("expected db1 demoted once, got %d", demoted[db1])
+	}
+	mu.Unlock()
+}
+
+func TestWriteDetectorEvictionPolicy(t *testing.T) {
+	// db0 and db1 are written twice and db2 once, with db0's last write the
+	// oldest. LRU evicts db0; LFU evicts db2.
+	for _, tt := range []struct {
+		name    string
+		policy  litestreampp.EvictionPolicy
+		evicted int
+	}{
+		{"LRU", litestreampp.LRUEvictionPolicy{}, 0},
+		{"LFU", litestreampp.LFUEvictionPolicy{}, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			var dbs []string
+			for i := 0; i < 3; i++ {
+				db := filepath.Join(tmpDir, fmt.Sprintf("db%d.db", i))
+				createTestFile(t, db, "content")
+				dbs = append(dbs, db)
+			}
+
+			detector := litestreampp.NewWriteDetector(time.Hour, time.Hour, 2, tt.policy)
+			for _, db := range dbs {
+				detector.AddDatabase(db)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			detector.Start(ctx)
+			defer detector.Stop()
+
+			// Future mtimes give each write a distinct, ordered timestamp
+			base := time.Now().Add(time.Hour)
+			setModTime := func(db string, offset time.Duration) {
+				if err := os.Chtimes(db, base.Add(offset), base.Add(offset)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rescan := func() {
+				// Reconfigure with no changes triggers an immediate scan
+				detector.Reconfigure(0, 0, 0)
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			setModTime(dbs[0], 1*time.Second)
+			setModTime(dbs[1], 2*time.Second)
+			rescan()
+			setModTime(dbs[0], 3*time.Second)
+			setModTime(dbs[1], 4*time.Second)
+			setModTime(dbs[2], 5*time.Second)
+			rescan()
+
+			if _, hot, _ := detector.GetStatistics(); hot != 2 {
+				t.Fatalf("expected 2 hot databases, got %d", hot)
+			}
+			for i, db := range dbs {
+				if want := i != tt.evicted; detector.IsHot(db) != want {
+					t.Errorf("%s: expected hot=%v", filepath.Base(db), want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteDetectorProjectQuotas(t *testing.T) {
+	tmpDir := t.TempDir()
+	newDBs := func(project string, n int) []string {
+		dir := filepath.Join(tmpDir, project, "databases", "db", "branches", "main", "tenants")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		var dbs []string
+		for i := 0; i < n; i++ {
+			db := filepath.Join(dir, fmt.Sprintf("tenant%d.db", i))
+			createTestFile(t, db, "content")
+			dbs = append(dbs, db)
+		}
+		return dbs
+	}
+	noisy := newDBs("noisy", 5)
+	quiet := newDBs("quiet", 3)
+	other := newDBs("other", 2)
+
+	detector := litestreampp.NewWriteDetector(time.Hour, time.Hour, 100, nil)
+	detector.SetProjectQuotas(map[string]int{"quiet": 1}, 3)
+	for _, db := range append(append(append([]string{}, noisy...), quiet...), other...) {
+		detector.AddDatabase(db)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	detector.Start(ctx)
+	defer detector.Stop()
+
+	// Touch every database so all are written since the initial scan
+	future := time.Now().Add(time.Hour)
+	for _, db := range append(append(append([]string{}, noisy...), quiet...), other...) {
+		if err := os.Chtimes(db, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	detector.Reconfigure(0, 0, 0)
+	time.Sleep(50 * time.Millisecond)
+
+	countHot := func(dbs []string) int {
+		var n int
+		for _, db := range dbs {
+			if detector.IsHot(db) {
+				n++
+			}
+		}
+		return n
+	}
+	if n := countHot(noisy); n != 3 {
+		t.Errorf("expected default quota to keep 3 noisy databases hot, got %d", n)
+	}
+	if n := countHot(quiet); n != 1 {
+		t.Errorf("expected override to keep 1 quiet database hot, got %d", n)
+	}
+	if n := countHot(other); n != 2 {
+		t.Errorf("expected both databases of a project under quota to stay hot, got %d", n)
+	}
+
+	t.Run("Unlimited", func(t *testing.T) {
+		// Clearing the quotas lets the next writes promote every database
+		detector.SetProjectQuotas(nil, 0)
+		later := future.Add(time.Minute)
+		for _, db := range noisy {
+			if err := os.Chtimes(db, later, later); err != nil {
+				t.Fatal(err)
+			}
+		}
+		detector.Reconfigure(0, 0, 0)
+		time.Sleep(50 * time.Millisecond)
+
+		if n := countHot(noisy); n != 5 {
+			t.Errorf("expected all 5 noisy databases hot without quotas, got %d", n)
+		}
+	})
 }
 
 func TestWriteDetectorConcurrency(t *testing.T) {
@@ -256,6 +775,7 @@ func TestWriteDetectorConcurrency(t *testing.T) {
 		50*time.Millisecond,
 		100*time.Millisecond,
 		10,
+		nil,
 	)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -289,6 +809,230 @@ func TestWriteDetectorConcurrency(t *testing.T) {
 	}
 }
 
+func TestWriteDetectorConfirmWithHeader(t *testing.T) {
+	newDetector := func(t *testing.T, path string) *litestreampp.WriteDetector {
+		t.Helper()
+		detector := litestreampp.NewWriteDetector(20*time.Millisecond, time.Hour, 10, nil)
+		detector.SetConfirmWithHeader(true)
+		if err := detector.AddDatabase(path); err != nil {
+			t.Fatal(err)
+		}
+		detector.Start(context.Background())
+		t.Cleanup(detector.Stop)
+		return detector
+	}
+
+	// touch moves the mtime forward without changing the contents
+	touch := func(t *testing.T, path string) {
+		t.Helper()
+		time.Sleep(10 * time.Millisecond)
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("RollbackJournal", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db.db")
+		db := openTestSQLite(t, path, `CREATE TABLE t (id INTEGER PRIMARY KEY)`)
+		detector := newDetector(t, path)
+
+		touch(t, path)
+		time.Sleep(100 * time.Millisecond)
+		if detector.IsHot(path) {
+			t.Fatal("expected touched database to stay cold")
+		}
+
+		if _, err := db.Exec(`INSERT INTO t DEFAULT VALUES`); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, time.Second, func() bool { return detector.IsHot(path) })
+	})
+
+	t.Run("WAL", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db.db")
+		db := openTestSQLite(t, path,
+			`PRAGMA journal_mode = wal`,
+			`CREATE TABLE t (id INTEGER PRIMARY KEY)`,
+		)
+		detector := newDetector(t, path)
+
+		// A checkpoint rewrites the database file but commits nothing
+		if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, path)
+		time.Sleep(100 * time.Millisecond)
+		if detector.IsHot(path) {
+			t.Fatal("expected checkpointed database to stay cold")
+		}
+
+		// Commits only append to the WAL, which a reader touching the
+		// database file then picks up
+		if _, err := db.Exec(`INSERT INTO t DEFAULT VALUES`); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, path)
+		waitFor(t, time.Second, func() bool { return detector.IsHot(path) })
+	})
+
+	t.Run("NotSQLite", func(t *testing.T) {
+		// Files without a readable header fall back to size and mtime
+		path := filepath.Join(t.TempDir(), "db.db")
+		createTestFile(t, path, "content")
+		detector := newDetector(t, path)
+
+		touch(t, path)
+		waitFor(t, time.Second, func() bool { return detector.IsHot(path) })
+	})
+}
+
+func TestWriteDetectorSpreadScans(t *testing.T) {
+	tmpDir := t.TempDir()
+	var paths []string
+	for i := 0; i < 32; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("db%d.db", i))
+		createTestFile(t, path, "initial")
+		paths = append(paths, path)
+	}
+
+	var mu sync.Mutex
+	promotedAt := make(map[string]time.Time)
+
+	interval := 320 * time.Millisecond
+	detector := litestreampp.NewWriteDetector(interval, time.Hour, 100, nil)
+	detector.SetSpreadScans(true)
+	detector.SetCallbacks(func(path string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		promotedAt[path] = time.Now()
+		return nil
+	}, nil)
+	for _, path := range paths {
+		if err := detector.AddDatabase(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	detector.Start(context.Background())
+	defer detector.Stop()
+
+	// Write every database at once, after the initial full scan
+	time.Sleep(50 * time.Millisecond)
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte("modified"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every database is still checked within one interval
+	waitFor(t, 3*interval, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(promotedAt) == len(paths)
+	})
+
+	// but the checks are spread across it rather than made together
+	var first, last time.Time
+	for _, at := range promotedAt {
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	if spread := last.Sub(first); spread < interval/4 {
+		t.Errorf("expected promotions spread across the interval, got %v", spread)
+	}
+}
+
+func TestWriteDetectorScanMetrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	reg := prometheus.NewRegistry()
+	detector := litestreampp.NewWriteDetector(time.Hour, time.Hour, 100, nil)
+	detector.SetMetrics(litestreampp.NewHierarchicalMetricsWithRegistry(reg))
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("db%d.db", i))
+		createTestFile(t, path, "initial")
+		if err := detector.AddDatabase(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	detector.Start(context.Background())
+	defer detector.Stop()
+
+	// The initial full scan records its duration and the tracked databases
+	var scopes []string
+	var samples uint64
+	var tracked float64
+	var overruns bool
+	gather := func() {
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		scopes, samples = nil, 0
+		for _, mf := range families {
+			for _, m := range mf.GetMetric() {
+				switch mf.GetName() {
+				case "litestream_write_detector_scan_duration_seconds":
+					scopes = append(scopes, m.GetLabel()[0].GetValue())
+					samples += m.GetHistogram().GetSampleCount()
+				case "litestream_write_detector_tracked_databases":
+					tracked = m.GetGauge().GetValue()
+				case "litestream_write_detector_scan_overruns_total":
+					overruns = true
+				}
+			}
+		}
+	}
+	waitFor(t, time.Second, func() bool {
+		gather()
+		return samples > 0
+	})
+
+	if !reflect.DeepEqual(scopes, []string{"full"}) || samples != 1 {
+		t.Errorf("expected one full scan observed, got %d in %v", samples, scopes)
+	}
+	if tracked != 5 {
+		t.Errorf("expected 5 tracked databases, got %v", tracked)
+	}
+	if overruns {
+		t.Error("expected no overruns with an hour interval")
+	}
+}
+
+// openTestSQLite creates a SQLite database at path, runs stmts against it,
+// and returns the open connection, closed when the test ends
+func openTestSQLite(t *testing.T, path string, stmts ...string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// waitFor polls cond until it is true or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 // Helper function to create a test file
 func createTestFile(t *testing.T, path, content string) {
 	t.Helper()
@@ -301,4 +1045,38 @@ func createTestFile(t *testing.T, path, content string) {
 	}
 	// Small delay to ensure mtime changes are detectable
 	time.Sleep(10 * time.Millisecond)
-}
\ No newline at end of file
+}
+// BenchmarkWriteDetectorScan measures how long IsHot callers wait while
+// scans of 50K databases run back to back. max-wait-ns is the worst wait,
+// which is bounded by how long a scan holds the lock.
+func BenchmarkWriteDetectorScan(b *testing.B) {
+	tmpDir := b.TempDir()
+	paths := make([]string, 50000)
+	for i := range paths {
+		paths[i] = filepath.Join(tmpDir, fmt.Sprintf("db%d.db", i))
+		if err := os.WriteFile(paths[i], nil, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	detector := litestreampp.NewWriteDetector(time.Millisecond, time.Hour, 1000, nil)
+	if err := detector.AddDatabases([]string{filepath.Join(tmpDir, "*.db")}); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	detector.Start(ctx)
+	defer detector.Stop()
+
+	var maxWait time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		detector.IsHot(paths[i%len(paths)])
+		if wait := time.Since(start); wait > maxWait {
+			maxWait = wait
+		}
+	}
+	b.ReportMetric(float64(maxWait.Nanoseconds()), "max-wait-ns")
+}
@@ -0,0 +1,101 @@
+package litestreampp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// SQLite file format constants used to confirm writes
+const (
+	sqliteHeaderSize = 100
+	walHeaderSize    = 32
+	walFrameHeader   = 24
+)
+
+// sqliteMagic starts every SQLite database file
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// dbHeader is the part of a database's on-disk state that moves only when a
+// transaction commits. In rollback mode the file change counter is bumped on
+// every commit. In WAL mode commits append frames to the WAL, and a WAL
+// restart picks new salts; the database file's counter is ignored since a
+// checkpoint copies it in from the WAL.
+type dbHeader struct {
+	valid         bool
+	wal           bool // Database is in WAL mode
+	changeCounter uint32
+	walSalt       uint64
+	walFrames     int64
+}
+
+// newTransactions returns true if h shows a commit since prev. A checkpoint
+// or a read that only moves the mtime leaves both unchanged. An invalid
+// previous header can't rule anything out, so it counts as a change.
+func (h dbHeader) newTransactions(prev dbHeader) bool {
+	if !prev.valid || h.wal != prev.wal {
+		return true
+	}
+	if !h.wal {
+		return h.changeCounter != prev.changeCounter
+	}
+	if h.walFrames == 0 {
+		return false // Nothing written since the last checkpoint truncated it
+	}
+	return h.walSalt != prev.walSalt || h.walFrames > prev.walFrames
+}
+
+// readDBHeader reads the change counter of the database at path and the
+// salt and frame count of its WAL, if it has one
+func readDBHeader(path string) (dbHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return dbHeader{}, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sqliteHeaderSize)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return dbHeader{}, err
+	} else if string(buf[:len(sqliteMagic)]) != string(sqliteMagic) {
+		return dbHeader{}, errors.New("not a sqlite database")
+	}
+
+	h := dbHeader{
+		valid:         true,
+		wal:           buf[18] == 2, // Read version 2 is WAL mode
+		changeCounter: binary.BigEndian.Uint32(buf[24:28]),
+	}
+	if !h.wal {
+		return h, nil
+	}
+
+	pageSize := int64(binary.BigEndian.Uint16(buf[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+
+	wal, err := os.Open(path + "-wal")
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return dbHeader{}, err
+	}
+	defer wal.Close()
+
+	info, err := wal.Stat()
+	if err != nil {
+		return dbHeader{}, err
+	} else if info.Size() < walHeaderSize {
+		return h, nil // Empty or truncated after a checkpoint
+	}
+
+	walBuf := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(wal, walBuf); err != nil {
+		return dbHeader{}, err
+	}
+	h.walSalt = binary.BigEndian.Uint64(walBuf[16:24])
+	h.walFrames = (info.Size() - walHeaderSize) / (pageSize + walFrameHeader)
+	return h, nil
+}
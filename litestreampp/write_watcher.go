@@ -0,0 +1,134 @@
+package litestreampp
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// startWatcher creates the filesystem watcher and watches the directories
+// of databases tracked so far. If no watcher can be created, every database
+// is polled.
+func (w *WriteDetector) startWatcher() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("filesystem watcher unavailable, polling all databases", "error", err)
+		return
+	}
+
+	w.mu.Lock()
+	w.watcher = watcher
+	for _, state := range w.databases {
+		w.watchLocked(state)
+	}
+	// Writes between AddDatabase and the watch starting produced no events
+	w.pollAll = true
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go w.watchLoop(watcher)
+}
+
+// watchLocked watches the directory of a database. If the directory can't
+// be watched, e.g. once the inotify watch limit is hit, the database stays
+// on polling (must hold lock)
+func (w *WriteDetector) watchLocked(state *WriteState) {
+	dir := filepath.Dir(state.Path)
+	if !w.watchedDirs[dir] {
+		if err := w.watcher.Add(dir); err != nil {
+			slog.Warn("cannot watch database directory, polling instead", "dir", dir, "error", err)
+			return
+		}
+		w.watchedDirs[dir] = true
+	}
+	state.Watched = true
+}
+
+// watchLoop handles filesystem events until the detector stops
+func (w *WriteDetector) watchLoop(watcher *fsnotify.Watcher) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("filesystem watcher error", "error", err)
+
+			// Dropped events may have hidden writes, so poll everything once
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				w.mu.Lock()
+				w.pollAll = true
+				w.mu.Unlock()
+				select {
+				case w.rescanCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// handleEvent promotes the database an event belongs to. Writes to the WAL
+// or rollback journal count as writes to the database.
+func (w *WriteDetector) handleEvent(event fsnotify.Event) {
+	path := strings.TrimSuffix(strings.TrimSuffix(event.Name, "-wal"), "-journal")
+
+	// Deferred before the unlock so it runs after it
+	defer w.flushDemotions()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// While paused the change goes unrecorded, so Resume's scan finds it
+	state, ok := w.databases[path]
+	if !ok || !state.Watched || state.quiet || w.paused {
+		return
+	}
+
+	// Database file removed or renamed away
+	if event.Name == path && event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		w.removeDatabaseLocked(path)
+		return
+	}
+
+	if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	state.LastModTime = info.ModTime()
+	state.LastSize = info.Size()
+
+	if w.confirmWithHeader {
+		header, _ := readDBHeader(path)
+		committed := !header.valid || header.newTransactions(state.header)
+		state.header = header
+		if !committed {
+			return
+		}
+	}
+
+	wasHot := state.IsHot
+	w.markWrittenLocked(state, time.Now())
+	if !wasHot {
+		w.hotList = append(w.hotList, path)
+		w.hotList, _ = w.evictLocked(w.hotList)
+	}
+}
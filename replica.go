@@ -16,6 +16,8 @@ import (
 // Default replica settings.
 const (
 	DefaultSyncInterval = 1 * time.Second
+
+	DefaultUploadConcurrency = 1
 )
 
 // Replica connects a database to a replication destination via a ReplicaClient.
@@ -31,7 +33,7 @@ type Replica struct {
 	f   *os.File // long-running file descriptor to avoid non-OFD lock issues
 
 	wg     sync.WaitGroup
-	cancel func()
+	cancel func() // protected by mu, as DB.init may start the replica concurrently
 
 	// Client used to connect to the remote replica.
 	Client ReplicaClient
@@ -39,6 +41,10 @@ type Replica struct {
 	// Time between syncs with the shadow WAL.
 	SyncInterval time.Duration
 
+	// Maximum number of L0 LTX files uploaded in parallel during a sync.
+	// LTX files are already LZ4 compressed by the DB so uploads are sent as-is.
+	UploadConcurrency int
+
 	// If true, replica monitors database for changes automatically.
 	// Set to false if replica is being used synchronously (such as in tests).
 	MonitorEnabled bool
@@ -53,8 +59,9 @@ func NewReplica(db *DB) *Replica {
 		db:     db,
 		cancel: func() {},
 
-		SyncInterval:   DefaultSyncInterval,
-		MonitorEnabled: true,
+		SyncInterval:      DefaultSyncInterval,
+		UploadConcurrency: DefaultUploadConcurrency,
+		MonitorEnabled:    true,
 	}
 
 	return r
@@ -89,7 +96,10 @@ func (r *Replica) Start(ctx context.Context) error {
 	r.Stop(false)
 
 	// Wrap context with cancelation.
-	ctx, r.cancel = context.WithCancel(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.cancel = cancel
+	r.mu.Unlock()
 
 	// Start goroutine to replicate data.
 	r.wg.Add(1)
@@ -104,7 +114,10 @@ func (r *Replica) Start(ctx context.Context) error {
 // locks on per-process locks. Hard stops should only be performed when
 // stopping the entire process.
 func (r *Replica) Stop(hard bool) (err error) {
-	r.cancel()
+	r.mu.RLock()
+	cancel := r.cancel
+	r.mu.RUnlock()
+	cancel()
 	r.wg.Wait()
 
 	r.muf.Lock()
@@ -148,6 +161,9 @@ func (r *Replica) Sync(ctx context.Context) (err error) {
 	r.Logger().Debug("replica sync", "txid", dpos.TXID.String())
 
 	// Replicate all L0 LTX files since last replica position.
+	if r.UploadConcurrency > 1 {
+		return r.syncParallel(ctx, dpos.TXID)
+	}
 	for txID := r.Pos().TXID + 1; txID <= dpos.TXID; txID = r.Pos().TXID + 1 {
 		if err = r.uploadLTXFile(ctx, 0, txID, txID); err != nil {
 			return err
@@ -158,6 +174,60 @@ func (r *Replica) Sync(ctx context.Context) (err error) {
 	return nil
 }
 
+// syncParallel uploads L0 LTX files up to maxTXID in batches of
+// UploadConcurrency. The replica position only advances over a contiguous
+// run of successful uploads. If an upload in a batch fails, any later files
+// from the same batch are removed from the replica so the remote never has a
+// gap that calcPos would skip over.
+func (r *Replica) syncParallel(ctx context.Context, maxTXID ltx.TXID) error {
+	for minTXID := r.Pos().TXID + 1; minTXID <= maxTXID; minTXID = r.Pos().TXID + 1 {
+		n := ltx.TXID(r.UploadConcurrency)
+		if minTXID+n-1 > maxTXID {
+			n = maxTXID - minTXID + 1
+		}
+
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := ltx.TXID(0); i < n; i++ {
+			wg.Add(1)
+			go func(i ltx.TXID) {
+				defer wg.Done()
+				errs[i] = r.uploadLTXFile(ctx, 0, minTXID+i, minTXID+i)
+			}(i)
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			if err == nil {
+				continue
+			}
+
+			// Remove successful uploads past the first failure.
+			var orphans []*ltx.FileInfo
+			for j := i + 1; j < len(errs); j++ {
+				if errs[j] == nil {
+					txID := minTXID + ltx.TXID(j)
+					orphans = append(orphans, &ltx.FileInfo{Level: 0, MinTXID: txID, MaxTXID: txID})
+				}
+			}
+			if len(orphans) > 0 {
+				if e := r.Client.DeleteLTXFiles(ctx, orphans); e != nil {
+					r.Logger().Error("cannot remove out-of-order ltx files", "n", len(orphans), "error", e)
+				}
+			}
+
+			if i > 0 {
+				r.SetPos(ltx.Pos{TXID: minTXID + ltx.TXID(i) - 1})
+			}
+			return err
+		}
+
+		r.SetPos(ltx.Pos{TXID: minTXID + n - 1})
+	}
+
+	return nil
+}
+
 func (r *Replica) uploadLTXFile(ctx context.Context, level int, minTXID, maxTXID ltx.TXID) (err error) {
 	filename := r.db.LTXPath(level, minTXID, maxTXID)
 	f, err := os.Open(filename)
@@ -215,8 +285,8 @@ func (r *Replica) Pos() ltx.Pos {
 
 // SetPos sets the current replicated position.
 func (r *Replica) SetPos(pos ltx.Pos) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.pos = pos
 }
 
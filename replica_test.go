@@ -2,6 +2,13 @@ package litestream_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -224,3 +231,136 @@ func TestReplica_CalcRestorePlan(t *testing.T) {
 		}
 	})
 }
+
+func TestReplica_Sync_UploadConcurrency(t *testing.T) {
+	// writeTxns generates n L0 LTX files in the database's local directory.
+	writeTxns := func(t *testing.T, db *litestream.DB, sqldb *sql.DB, n int) ltx.TXID {
+		t.Helper()
+		if _, err := sqldb.Exec(`CREATE TABLE foo (bar TEXT);`); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			if _, err := sqldb.Exec(`INSERT INTO foo (bar) VALUES ('x');`); err != nil {
+				t.Fatal(err)
+			} else if err := db.Sync(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dpos, err := db.Pos()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dpos.TXID
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		db, sqldb := MustOpenDBs(t)
+		defer MustCloseDBs(t, db, sqldb)
+		maxTXID := writeTxns(t, db, sqldb, 7)
+
+		c := file.NewReplicaClient(t.TempDir())
+		r := litestream.NewReplicaWithClient(db, c)
+		r.UploadConcurrency = 3
+
+		if err := r.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := r.Pos().TXID, maxTXID; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		}
+
+		for txID := ltx.TXID(1); txID <= maxTXID; txID++ {
+			rd, err := c.OpenLTXFile(context.Background(), 0, txID, txID)
+			if err != nil {
+				t.Fatalf("missing ltx file %s: %v", txID, err)
+			}
+			_ = rd.Close()
+		}
+	})
+
+	// Ensure a failed upload stops the position at the last contiguous file
+	// and removes later uploads from the same batch.
+	t.Run("PartialFailure", func(t *testing.T) {
+		db, sqldb := MustOpenDBs(t)
+		defer MustCloseDBs(t, db, sqldb)
+		maxTXID := writeTxns(t, db, sqldb, 5)
+		if maxTXID < 4 {
+			t.Fatalf("expected at least 4 transactions, got %s", maxTXID)
+		}
+
+		// The client lists what was written and not deleted, so the
+		// replica resumes from what is actually stored
+		var mu sync.Mutex
+		var deleted []ltx.TXID
+		stored := make(map[ltx.TXID]int)
+		fail := true
+		var c mock.ReplicaClient
+		c.LTXFilesFunc = func(ctx context.Context, level int, seek ltx.TXID) (ltx.FileIterator, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var infos []*ltx.FileInfo
+			for txID := range stored {
+				infos = append(infos, &ltx.FileInfo{Level: level, MinTXID: txID, MaxTXID: txID})
+			}
+			sort.Slice(infos, func(i, j int) bool { return infos[i].MinTXID < infos[j].MinTXID })
+			return ltx.NewFileInfoSliceIterator(infos), nil
+		}
+		c.WriteLTXFileFunc = func(ctx context.Context, level int, minTXID, maxTXID ltx.TXID, r io.Reader) (*ltx.FileInfo, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if minTXID == 2 && fail {
+				return nil, errors.New("marker")
+			}
+			stored[minTXID]++
+			return &ltx.FileInfo{Level: level, MinTXID: minTXID, MaxTXID: maxTXID}, nil
+		}
+		c.DeleteLTXFilesFunc = func(ctx context.Context, a []*ltx.FileInfo) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, info := range a {
+				deleted = append(deleted, info.MinTXID)
+				delete(stored, info.MinTXID)
+			}
+			return nil
+		}
+
+		r := litestream.NewReplicaWithClient(db, &c)
+		r.UploadConcurrency = 4
+		r.SetPos(ltx.Pos{TXID: 0})
+
+		if err := r.Sync(context.Background()); err == nil || !strings.Contains(err.Error(), "marker") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		sort.Slice(deleted, func(i, j int) bool { return deleted[i] < deleted[j] })
+		if got, want := deleted, []ltx.TXID{3, 4}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("deleted=%v, want %v", got, want)
+		}
+
+		// Sync clears its position on error, and the position it resumes
+		// from stops at the last contiguous upload
+		if got := r.Pos(); !got.IsZero() {
+			t.Fatalf("pos=%s, want zero after a failed sync", got)
+		}
+		if info, err := r.MaxLTXFileInfo(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		} else if got := info.MaxTXID; got != 1 {
+			t.Fatalf("replica position=%s, want 1", got)
+		}
+
+		// The next sync resumes at TXID 2 without re-uploading TXID 1
+		mu.Lock()
+		fail = false
+		mu.Unlock()
+		if err := r.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Pos().TXID; got != maxTXID {
+			t.Fatalf("pos=%s, want %s", got, maxTXID)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if n := stored[1]; n != 1 {
+			t.Fatalf("TXID 1 uploaded %d times, want 1", n)
+		}
+	})
+}
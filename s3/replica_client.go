@@ -54,6 +54,10 @@ type ReplicaClient struct {
 	Endpoint       string
 	ForcePathStyle bool
 	SkipVerify     bool
+
+	// StorageClass is the S3 storage class of uploaded files, such as
+	// STANDARD_IA. Blank uses the bucket default (STANDARD).
+	StorageClass string
 }
 
 // NewReplicaClient returns a new instance of ReplicaClient.
@@ -241,11 +245,15 @@ func (c *ReplicaClient) WriteLTXFile(ctx context.Context, level int, minTXID, ma
 	startTime := time.Now()
 
 	rc := internal.NewReadCounter(rd)
-	if _, err := c.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+	input := &s3manager.UploadInput{
 		Bucket: aws.String(c.Bucket),
 		Key:    aws.String(key),
 		Body:   rc,
-	}); err != nil {
+	}
+	if c.StorageClass != "" {
+		input.StorageClass = aws.String(c.StorageClass)
+	}
+	if _, err := c.uploader.UploadWithContext(ctx, input); err != nil {
 		return info, err
 	}
 
@@ -108,7 +108,7 @@ func (s *Store) Open(ctx context.Context) error {
 }
 
 func (s *Store) Close() (err error) {
-	for _, db := range s.dbs {
+	for _, db := range s.DBs() {
 		if e := db.Close(context.Background()); e != nil && err == nil {
 			err = e
 		}
@@ -127,6 +127,33 @@ func (s *Store) DBs() []*DB {
 	return slices.Clone(s.dbs)
 }
 
+// AddDB adds a database to an open store so it is included in background
+// compactions and snapshots. The caller is responsible for opening the
+// database. Adding a database that is already in the store has no effect.
+func (s *Store) AddDB(db *DB) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Contains(s.dbs, db) {
+		return
+	}
+	s.dbs = append(s.dbs, db)
+}
+
+// RemoveDB removes a database from the store without closing it. Returns
+// false if the database was not in the store.
+func (s *Store) RemoveDB(db *DB) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.Index(s.dbs, db)
+	if i < 0 {
+		return false
+	}
+	s.dbs = slices.Delete(s.dbs, i, i+1)
+	return true
+}
+
 // SnapshotLevel returns a pseudo compaction level based on snapshot settings.
 func (s *Store) SnapshotLevel() *CompactionLevel {
 	return &CompactionLevel{
@@ -154,6 +181,11 @@ LOOP:
 			timer = time.NewTimer(time.Until(lvl.NextCompactionAt(time.Now())))
 
 			for _, db := range s.DBs() {
+				// Databases added while running may not have a replica yet.
+				if db.Replica == nil {
+					continue
+				}
+
 				// First attempt to compact the database.
 				if _, err := s.CompactDB(ctx, db, lvl); errors.Is(err, ErrNoCompaction) {
 					slog.Debug("no compaction", "level", lvl.Level, "path", db.Path())
@@ -194,3 +194,24 @@ func TestStore_Integration(t *testing.T) {
 		}
 	}
 }
+
+func TestStore_AddRemoveDB(t *testing.T) {
+	db0 := litestream.NewDB(filepath.Join(t.TempDir(), "db0"))
+	db1 := litestream.NewDB(filepath.Join(t.TempDir(), "db1"))
+
+	s := litestream.NewStore([]*litestream.DB{db0}, litestream.CompactionLevels{{Level: 0}})
+	s.AddDB(db1)
+	s.AddDB(db1)
+	if got := s.DBs(); len(got) != 2 || got[0] != db0 || got[1] != db1 {
+		t.Fatalf("unexpected dbs after add: %v", got)
+	}
+
+	if !s.RemoveDB(db0) {
+		t.Fatal("expected db0 to be removed")
+	} else if s.RemoveDB(db0) {
+		t.Fatal("expected second removal to report false")
+	}
+	if got := s.DBs(); len(got) != 1 || got[0] != db1 {
+		t.Fatalf("unexpected dbs after remove: %v", got)
+	}
+}
@@ -0,0 +1,231 @@
+package ultrasimple
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ETagS3Client is an S3Client that can report the ETag of a stored object
+type ETagS3Client interface {
+	S3Client
+	ETag(ctx context.Context, key string) (string, error)
+}
+
+// SSECustomerKey is the encryption ETagWithEncryption reports for an object
+// encrypted with a customer-provided key (SSE-C)
+const SSECustomerKey = "SSE-C"
+
+// EncryptionETagS3Client is an ETagS3Client that also reports how an object
+// is encrypted at rest. S3 only reports an MD5 ETag for unencrypted and
+// SSE-S3 objects, so audits skip objects encrypted with SSE-KMS or SSE-C.
+type EncryptionETagS3Client interface {
+	ETagS3Client
+	// ETagWithEncryption returns an object's ETag and its server-side
+	// encryption: "" or "AES256" for SSE-S3, "aws:kms" or "aws:kms:dsse"
+	// for SSE-KMS, or SSECustomerKey
+	ETagWithEncryption(ctx context.Context, key string) (etag, encryption string, err error)
+}
+
+// RepairReport summarizes an AuditAndRepair pass
+type RepairReport struct {
+	Checked    int      // Objects whose ETag was compared
+	Mismatched []string // Keys whose ETag did not match the upload
+	Repaired   []string // Keys re-uploaded from the live database
+	Failed     []string // Keys that could not be checked or repaired
+}
+
+// AuditAndRepair re-checks a random sample of the most recent backups and
+// re-uploads any whose stored ETag no longer matches what was uploaded.
+// sampleRate is the fraction of tracked databases to check, from 0 to 1.
+// Repairs read the live database, so a database deleted since its last
+// upload is reported as failed rather than repaired. So is a backup from
+// an earlier window, which the live database no longer matches; the live
+// database is uploaded to the current window's key instead.
+func (r *Replicator) AuditAndRepair(ctx context.Context, sampleRate float64) (RepairReport, error) {
+	var report RepairReport
+	
+	client, ok := r.s3Client.(ETagS3Client)
+	if !ok {
+		return report, fmt.Errorf("s3 client does not support ETag lookups")
+	}
+	
+	r.uploadsMu.Lock()
+	sample := make(map[string]uploadRecord)
+	for path, rec := range r.uploads {
+		if rand.Float64() < sampleRate {
+			sample[path] = rec
+		}
+	}
+	r.uploadsMu.Unlock()
+	
+	for path, rec := range sample {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		
+		etag, encryption, err := objectETag(ctx, client, rec.Key)
+		if err != nil {
+			slog.Error("audit failed for backup", "key", rec.Key, "error", err)
+			report.Failed = append(report.Failed, rec.Key)
+			continue
+		}
+		etag = strings.Trim(etag, `"`)
+		
+		// Multipart ETags (from large streamed uploads) and the ETags of
+		// objects encrypted with SSE-KMS or SSE-C are not an MD5 of the
+		// object, so they can't be compared
+		if strings.Contains(etag, "-") || (encryption != "" && encryption != "AES256") {
+			continue
+		}
+		report.Checked++
+		
+		if etag == rec.ETag {
+			continue
+		}
+		report.Mismatched = append(report.Mismatched, rec.Key)
+		
+		// The live database only matches the current window's backup
+		if current := r.currentKey(path, rec.Key); current != rec.Key {
+			slog.Error("backup is from an earlier window and can't be repaired", "path", path, "key", rec.Key)
+			report.Failed = append(report.Failed, rec.Key)
+			if err := r.repairUpload(ctx, path, current); err != nil {
+				slog.Error("upload failed", "path", path, "key", current, "error", err)
+			}
+			continue
+		}
+		
+		if err := r.repairUpload(ctx, path, rec.Key); err != nil {
+			slog.Error("repair failed", "path", path, "key", rec.Key, "error", err)
+			report.Failed = append(report.Failed, rec.Key)
+			continue
+		}
+		report.Repaired = append(report.Repaired, rec.Key)
+	}
+	
+	slog.Info("audit complete",
+		"checked", report.Checked,
+		"mismatched", len(report.Mismatched),
+		"repaired", len(report.Repaired),
+		"failed", len(report.Failed))
+	
+	return report, nil
+}
+
+// Verify checks that every database changed in the current backup window
+// has a backup object for that window, listing each database's key prefix.
+// Databases skipped because their checksum was unchanged are checked for
+// the window of their last upload instead. It returns the paths of
+// databases whose backup is missing.
+func (r *Replicator) Verify(ctx context.Context) ([]string, error) {
+	current := r.windowEnd(time.Now())
+	
+	r.uploadsMu.Lock()
+	uploads := make(map[string]uploadRecord, len(r.uploads))
+	for path, rec := range r.uploads {
+		uploads[path] = rec
+	}
+	r.uploadsMu.Unlock()
+	
+	var missing []string
+	for _, state := range r.ListDatabases() {
+		if state.TooLarge || !r.windowEnd(state.changedAt).Equal(current) {
+			continue // Not expected to have a backup this window
+		}
+		
+		prefix := r.keyPrefix(state.Path)
+		want := current
+		if rec, ok := uploads[state.Path]; ok && state.LastError == nil {
+			if t, ok := parseKeyTimestamp(rec.Key, prefix); ok {
+				want = t
+			}
+		}
+		
+		keys, err := r.s3Client.List(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", prefix, err)
+		}
+		found := false
+		for _, key := range keys {
+			if t, ok := parseKeyTimestamp(key, prefix); ok && t.Equal(want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, state.Path)
+		}
+	}
+	slog.Info("verify complete", "missing", len(missing))
+	return missing, nil
+}
+
+// objectETag returns an object's ETag and, if the client reports it, its
+// server-side encryption
+func objectETag(ctx context.Context, client ETagS3Client, key string) (etag, encryption string, err error) {
+	if ec, ok := client.(EncryptionETagS3Client); ok {
+		return ec.ETagWithEncryption(ctx, key)
+	}
+	etag, err = client.ETag(ctx, key)
+	return etag, "", err
+}
+
+// currentKey returns the current window's backup key for a database, as a
+// pointer key if key is one
+func (r *Replicator) currentKey(path, key string) string {
+	current := r.generateS3Key(path)
+	if strings.HasSuffix(key, pointerExtension) {
+		current = pointerKey(current)
+	}
+	return current
+}
+
+// repairUpload uploads the live database to a backup key of the current
+// window, or its blob and pointer in dedup mode
+func (r *Replicator) repairUpload(ctx context.Context, path, key string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("database no longer available: %w", err)
+	}
+	
+	release, err := r.acquireUpload(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	
+	data, err := r.readDatabaseSafely(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", filepath.Base(path), err)
+	}
+	
+	if strings.HasSuffix(key, pointerExtension) {
+		size, etag, err := r.uploadDedup(ctx, path, key, data)
+		if err != nil {
+			atomic.AddInt64(&r.stats.UploadErrors, 1)
+			return err
+		}
+		r.countUpload(path, size)
+		r.recordUpload(path, key, etag)
+		return nil
+	}
+	
+	compressed, release, err := r.prepareUpload(data)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := r.upload(ctx, path, key, compressed); err != nil {
+		atomic.AddInt64(&r.stats.UploadErrors, 1)
+		return fmt.Errorf("upload: %w", err)
+	}
+	
+	r.countUpload(path, int64(len(compressed)))
+	r.recordUpload(path, key, md5Hex(compressed))
+	return nil
+}
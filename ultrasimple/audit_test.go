@@ -0,0 +1,186 @@
+package ultrasimple
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// etagMockS3Client reports the MD5 of stored objects as their ETag
+type etagMockS3Client struct {
+	*MockS3Client
+}
+
+func (m *etagMockS3Client) ETag(ctx context.Context, key string) (string, error) {
+	data, err := m.Download(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+func TestReplicatorAuditAndRepair(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestDB(t, filepath.Join(tmpDir, "good.db"), "CREATE TABLE test (id INTEGER)")
+	createTestDB(t, filepath.Join(tmpDir, "rotten.db"), "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := &etagMockS3Client{MockS3Client: NewMockS3Client()}
+	config := S3Config{
+		Region:       "us-east-1",
+		Bucket:       "test-bucket",
+		PathTemplate: "backups",
+	}
+	
+	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+	r.scanAndSync(context.Background())
+	
+	// Corrupt one stored object
+	rottenKey := r.uploads[filepath.Join(tmpDir, "rotten.db")].Key
+	s3Client.mu.Lock()
+	original := s3Client.uploads[rottenKey]
+	s3Client.uploads[rottenKey] = []byte("bit rot")
+	s3Client.mu.Unlock()
+	
+	report, err := r.AuditAndRepair(context.Background(), 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	if report.Checked != 2 {
+		t.Errorf("Expected 2 checked, got %d", report.Checked)
+	}
+	if len(report.Mismatched) != 1 || report.Mismatched[0] != rottenKey {
+		t.Errorf("Expected %s mismatched, got %v", rottenKey, report.Mismatched)
+	}
+	if len(report.Repaired) != 1 || report.Repaired[0] != rottenKey {
+		t.Errorf("Expected %s repaired, got %v", rottenKey, report.Repaired)
+	}
+	
+	repaired := s3Client.GetUploads()[rottenKey]
+	if string(repaired) != string(original) {
+		t.Error("Repaired object does not match the live database")
+	}
+	
+	// A second pass finds nothing to repair
+	report, err = r.AuditAndRepair(context.Background(), 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Mismatched) != 0 {
+		t.Errorf("Expected no mismatches after repair, got %v", report.Mismatched)
+	}
+}
+
+// kmsMockS3Client reports every object as encrypted with SSE-KMS, whose
+// ETags are not an MD5 of the object
+type kmsMockS3Client struct {
+	*MockS3Client
+}
+
+func (m *kmsMockS3Client) ETag(ctx context.Context, key string) (string, error) {
+	return `"0123456789abcdef0123456789abcdef"`, nil
+}
+
+func (m *kmsMockS3Client) ETagWithEncryption(ctx context.Context, key string) (string, string, error) {
+	etag, err := m.ETag(ctx, key)
+	return etag, "aws:kms", err
+}
+
+func TestReplicatorAuditSkipsSSEKMS(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestDB(t, filepath.Join(tmpDir, "test.db"), "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := &kmsMockS3Client{MockS3Client: NewMockS3Client()}
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+	r.scanAndSync(context.Background())
+	
+	report, err := r.AuditAndRepair(context.Background(), 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 0 || len(report.Mismatched) != 0 {
+		t.Errorf("Expected SSE-KMS object to be skipped, got %+v", report)
+	}
+}
+
+func TestReplicatorAuditEarlierWindow(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "test.db")
+	createTestDB(t, path, "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := &etagMockS3Client{MockS3Client: NewMockS3Client()}
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+	r.scanAndSync(context.Background())
+	
+	// Point the record at a corrupted backup from yesterday's window
+	current := r.uploads[path].Key
+	s3Client.mu.Lock()
+	live := s3Client.uploads[current]
+	delete(s3Client.uploads, current)
+	s3Client.mu.Unlock()
+	
+	old := r.keyPrefix(path) + r.windowEnd(time.Now().Add(-24*time.Hour)).Format(backupTimestampFormat) +
+		backupExtension + r.s3Config.Compressor.Extension()
+	s3Client.Upload(context.Background(), old, []byte("bit rot"))
+	r.recordUpload(path, old, md5Hex(live))
+	
+	report, err := r.AuditAndRepair(context.Background(), 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Repaired) != 0 {
+		t.Errorf("Expected no repairs, got %v", report.Repaired)
+	}
+	if len(report.Failed) != 1 || report.Failed[0] != old {
+		t.Errorf("Expected %s failed, got %v", old, report.Failed)
+	}
+	
+	uploads := s3Client.GetUploads()
+	if string(uploads[old]) != "bit rot" {
+		t.Error("Earlier window's backup was overwritten with the live database")
+	}
+	if string(uploads[current]) != string(live) {
+		t.Error("Live database was not uploaded to the current window's key")
+	}
+}
+
+func TestReplicatorAuditRequiresETagClient(t *testing.T) {
+	r := New("*.db", S3Config{PathTemplate: "backups"}, NewMockS3Client())
+	if _, err := r.AuditAndRepair(context.Background(), 1.0); err == nil {
+		t.Error("Expected error for client without ETag support")
+	}
+}
+
+func TestReplicatorVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	present := filepath.Join(tmpDir, "present.db")
+	lost := filepath.Join(tmpDir, "lost.db")
+	createTestDB(t, present, "CREATE TABLE test (id INTEGER)")
+	createTestDB(t, lost, "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := NewMockS3Client()
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+	r.scanAndSync(context.Background())
+	
+	missing, err := r.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(missing) != 0 {
+		t.Fatalf("Expected no missing backups, got %v", missing)
+	}
+	
+	// Lose one database's object after a successful upload
+	keys, _ := s3Client.List(context.Background(), r.keyPrefix(lost))
+	s3Client.Delete(context.Background(), keys)
+	
+	missing, err = r.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(missing) != 1 || missing[0] != lost {
+		t.Errorf("Expected %s missing, got %v", lost, missing)
+	}
+}
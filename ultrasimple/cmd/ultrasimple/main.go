@@ -3,18 +3,32 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/benbjohnson/litestream/ultrasimple"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/yaml.v2"
 )
 
 // RealS3Client implements the S3Client interface with actual AWS SDK
@@ -23,10 +37,19 @@ type RealS3Client struct {
 	bucket string
 }
 
-func NewRealS3Client(region, bucket, accessKey, secretKey string) (*RealS3Client, error) {
+// NewRealS3Client returns a client for bucket. A non-empty endpoint targets
+// an S3-compatible store such as MinIO, Ceph, or LocalStack, which usually
+// needs forcePathStyle as well.
+func NewRealS3Client(region, bucket, endpoint string, forcePathStyle bool, accessKey, secretKey string) (*RealS3Client, error) {
 	config := &aws.Config{
 		Region: aws.String(region),
 	}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+	}
+	if forcePathStyle {
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
 	
 	// Use explicit credentials if provided
 	if accessKey != "" && secretKey != "" {
@@ -44,8 +67,8 @@ func NewRealS3Client(region, bucket, accessKey, secretKey string) (*RealS3Client
 	}, nil
 }
 
-func (c *RealS3Client) Upload(key string, data []byte) error {
-	_, err := c.s3.PutObject(&s3.PutObjectInput{
+func (c *RealS3Client) Upload(ctx context.Context, key string, data []byte) error {
+	_, err := c.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(c.bucket),
 		Key:    aws.String(key),
 		Body:   aws.ReadSeekCloser(bytes.NewReader(data)),
@@ -53,18 +76,157 @@ func (c *RealS3Client) Upload(key string, data []byte) error {
 	return err
 }
 
+// UploadStream uploads from a reader in parts, so memory use is bounded
+// by the part size rather than the database size
+func (c *RealS3Client) UploadStream(ctx context.Context, key string, r io.Reader, size int64, tags map[string]string) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	}
+	if len(tags) > 0 {
+		tagging := url.Values{}
+		for k, v := range tags {
+			tagging.Set(k, v)
+		}
+		input.Tagging = aws.String(tagging.Encode())
+	}
+	
+	_, err := s3manager.NewUploaderWithClient(c.s3).UploadWithContext(ctx, input)
+	return err
+}
+
+// UploadWithTags uploads an object with S3 object tagging
+func (c *RealS3Client) UploadWithTags(ctx context.Context, key string, data []byte, tags map[string]string) error {
+	tagging := url.Values{}
+	for k, v := range tags {
+		tagging.Set(k, v)
+	}
+	
+	_, err := c.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:  aws.String(c.bucket),
+		Key:     aws.String(key),
+		Body:    aws.ReadSeekCloser(bytes.NewReader(data)),
+		Tagging: aws.String(tagging.Encode()),
+	})
+	return err
+}
+
+func (c *RealS3Client) Download(ctx context.Context, key string) ([]byte, error) {
+	out, err := c.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil, fmt.Errorf("download %s: %w", key, ultrasimple.ErrObjectNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	
+	return io.ReadAll(out.Body)
+}
+
+// isNotFound reports whether err is S3's NoSuchKey response for a missing
+// object
+func isNotFound(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey
+}
+
+func (c *RealS3Client) List(ctx context.Context, prefix string) ([]string, error) {
+	objects, err := c.ListDetailed(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(objects))
+	for i, obj := range objects {
+		keys[i] = obj.Key
+	}
+	return keys, nil
+}
+
+// ListDetailed lists objects with their LastModified time, which cleanup
+// ages backups by
+func (c *RealS3Client) ListDetailed(ctx context.Context, prefix string) ([]ultrasimple.ObjectInfo, error) {
+	var objects []ultrasimple.ObjectInfo
+	err := c.s3.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			objects = append(objects, ultrasimple.ObjectInfo{
+				Key:          aws.StringValue(obj.Key),
+				LastModified: aws.TimeValue(obj.LastModified),
+			})
+		}
+		return !lastPage
+	})
+	return objects, err
+}
+
+// Delete deletes up to 1000 keys in one request
+func (c *RealS3Client) Delete(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	
+	objects := make([]*s3.ObjectIdentifier, len(keys))
+	for i, key := range keys {
+		objects[i] = &s3.ObjectIdentifier{
+			Key: aws.String(key),
+		}
+	}
+	
+	out, err := c.s3.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(c.bucket),
+		Delete: &s3.Delete{
+			Objects: objects,
+			Quiet:   aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	
+	// DeleteObjects succeeds even when individual keys fail
+	if len(out.Errors) > 0 {
+		e := out.Errors[0]
+		return fmt.Errorf("delete %s: %s (%d of %d keys failed)",
+			aws.StringValue(e.Key), aws.StringValue(e.Message), len(out.Errors), len(keys))
+	}
+	return nil
+}
+
 func main() {
 	// Command line flags
 	var (
-		pattern       = flag.String("pattern", "/data/*/databases/*/branches/*/tenants/*.db", "Database discovery pattern")
+		pattern       = flag.String("pattern", "/data/*/databases/*/branches/*/tenants/*.db", "Database discovery pattern (comma-separated for multiple roots)")
+		configPath    = flag.String("config", "", "YAML file of interval, retention-days, and concurrent, overriding their flags; reloaded on SIGHUP")
 		interval      = flag.Duration("interval", 30*time.Second, "Scan and sync interval")
+		flushTimeout  = flag.Duration("flush-timeout", 30*time.Second, "How long the final sync on shutdown may take")
 		region        = flag.String("region", "us-east-1", "AWS region")
 		bucket        = flag.String("bucket", "", "S3 bucket name (required)")
+		endpoint      = flag.String("endpoint", os.Getenv("AWS_ENDPOINT"), "Custom S3 endpoint for MinIO, Ceph, LocalStack, etc. (defaults to $AWS_ENDPOINT)")
+		pathStyle     = flag.Bool("path-style", false, "Use path-style bucket addressing (defaults to true when -endpoint is set)")
 		pathTemplate  = flag.String("path", "{{project}}/{{database}}/{{branch}}/{{tenant}}", "S3 path template")
+		granularity   = flag.Duration("granularity", time.Hour, "Backup window; at most one backup per database per window")
+		maxSize       = flag.Int64("max-db-size", 0, "Skip databases larger than this many bytes (0 for no limit)")
 		maxConcurrent = flag.Int("concurrent", 100, "Maximum concurrent uploads")
+		retentionDays = flag.Int("retention-days", 30, "Delete backups older than this many days")
+		maxRetries    = flag.Int("max-retries", 3, "Retries for a failed upload before giving up until the next change")
+		compression   = flag.String("compression", "lz4", "Compression codec: lz4, zstd, or none")
+		changeDetect  = flag.String("change-detection", "mtime", "Change detection mode: mtime or checksum")
+		retryBackoff  = flag.Duration("retry-backoff", 500*time.Millisecond, "Initial backoff between upload retries (doubles each attempt)")
+		busyTimeout   = flag.Duration("busy-timeout", 5*time.Second, "How long a WAL checkpoint waits for application locks")
 		accessKey     = flag.String("access-key", "", "AWS access key (uses default credentials if not set)")
 		secretKey     = flag.String("secret-key", "", "AWS secret key (uses default credentials if not set)")
 		dryRun        = flag.Bool("dry-run", false, "Scan only, don't upload")
+		keyFile       = flag.String("encryption-key-file", "", "File holding a hex-encoded 32-byte AES-256-GCM key; enables client-side encryption")
+		tags          = flag.String("tags", "", "Static object tags added to every upload (e.g. env=prod,team=core)")
+		metricsAddr   = flag.String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address (e.g. :9090)")
+		logFormat     = flag.String("log-format", "text", "Log format: text or json")
+		logLevel      = flag.String("log-level", "info", "Log level: debug, info, warn, or error")
 	)
 	
 	flag.Usage = func() {
@@ -78,6 +240,28 @@ func main() {
 	
 	flag.Parse()
 	
+	if err := setupLogging(*logFormat, *logLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -config: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg.Interval > 0 {
+			*interval = cfg.Interval
+		}
+		if cfg.RetentionDays > 0 {
+			*retentionDays = cfg.RetentionDays
+		}
+		if cfg.Concurrent > 0 {
+			*maxConcurrent = cfg.Concurrent
+		}
+	}
+	
 	// Validate required flags
 	if *bucket == "" && !*dryRun {
 		fmt.Fprintf(os.Stderr, "Error: -bucket is required unless -dry-run is set\n")
@@ -85,16 +269,56 @@ func main() {
 		os.Exit(1)
 	}
 	
+	staticTags, err := parseTags(*tags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid -tags: %v\n", err)
+		os.Exit(1)
+	}
+	
+	var encryptor ultrasimple.Encryptor
+	if *keyFile != "" {
+		enc, err := loadEncryptor(*keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -encryption-key-file: %v\n", err)
+			os.Exit(1)
+		}
+		encryptor = enc
+	}
+	
+	var compressor ultrasimple.Compressor
+	switch *compression {
+	case "lz4":
+		compressor = ultrasimple.LZ4Compressor{}
+	case "zstd":
+		compressor = ultrasimple.ZstdCompressor{}
+	case "none":
+		compressor = ultrasimple.NoopCompressor{}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid -compression: %s\n", *compression)
+		os.Exit(1)
+	}
+	
+	switch *changeDetect {
+	case ultrasimple.ChangeDetectionMTime, ultrasimple.ChangeDetectionChecksum:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid -change-detection: %s\n", *changeDetect)
+		os.Exit(1)
+	}
+	
 	// Print configuration
-	log.Printf("Ultra-Simple Replicator Starting")
-	log.Printf("Pattern: %s", *pattern)
-	log.Printf("Interval: %v", *interval)
+	patterns := strings.Split(*pattern, ",")
 	if !*dryRun {
-		log.Printf("S3: s3://%s/%s", *bucket, *pathTemplate)
-		log.Printf("Region: %s", *region)
-		log.Printf("Max Concurrent: %d", *maxConcurrent)
+		slog.Info("ultra-simple replicator starting",
+			"patterns", patterns,
+			"interval", *interval,
+			"bucket", *bucket,
+			"path_template", *pathTemplate,
+			"region", *region,
+			"max_concurrent", *maxConcurrent)
 	} else {
-		log.Printf("Mode: DRY RUN (no uploads)")
+		slog.Info("ultra-simple replicator starting in dry run mode, nothing will be uploaded",
+			"patterns", patterns,
+			"interval", *interval)
 	}
 	
 	// Create S3 client or mock for dry run
@@ -102,9 +326,18 @@ func main() {
 	if *dryRun {
 		s3Client = &DryRunClient{}
 	} else {
-		client, err := NewRealS3Client(*region, *bucket, *accessKey, *secretKey)
+		// Only AWS itself uses virtual-hosted addressing and it needs no
+		// endpoint, so path style is the default for custom endpoints
+		forcePathStyle := *endpoint != ""
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "path-style" {
+				forcePathStyle = *pathStyle
+			}
+		})
+		client, err := NewRealS3Client(*region, *bucket, *endpoint, forcePathStyle, *accessKey, *secretKey)
 		if err != nil {
-			log.Fatalf("Failed to create S3 client: %v", err)
+			slog.Error("failed to create s3 client", "error", err)
+			os.Exit(1)
 		}
 		s3Client = client
 	}
@@ -115,38 +348,256 @@ func main() {
 		Bucket:        *bucket,
 		PathTemplate:  *pathTemplate,
 		MaxConcurrent: *maxConcurrent,
+		RetentionDays: *retentionDays,
+		BackupGranularity: *granularity,
+		MaxDatabaseSize: *maxSize,
+		MaxRetries:    *maxRetries,
+		RetryBackoff:  *retryBackoff,
+		BusyTimeout:   *busyTimeout,
+		ChangeDetection: *changeDetect,
+		Tags:          staticTags,
+		Compressor:    compressor,
+		Encryptor:     encryptor,
 	}
 	
-	replicator := ultrasimple.New(*pattern, config, s3Client)
+	replicator := ultrasimple.NewMulti(patterns, config, s3Client)
+	
+	if *metricsAddr != "" {
+		reg := prometheus.NewRegistry()
+		if err := replicator.RegisterMetrics(reg); err != nil {
+			slog.Error("failed to register metrics", "error", err)
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				slog.Error("metrics server failed", "error", err)
+			}
+		}()
+	}
 	
 	// Set up signal handling
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	
 	go func() {
 		<-sigChan
-		log.Println("Shutting down...")
+		slog.Info("shutting down")
 		cancel()
 	}()
 	
+	// Reload the config file on SIGHUP, keeping the current settings if it
+	// is invalid
+	if *configPath != "" {
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		go func() {
+			for range hupChan {
+				cfg, err := loadConfig(*configPath)
+				if err != nil {
+					slog.Error("config reload failed, keeping current settings", "path", *configPath, "error", err)
+					continue
+				}
+				if err := replicator.Reconfigure(cfg.Interval, cfg.RetentionDays, cfg.Concurrent); err != nil {
+					slog.Error("config reload failed, keeping current settings", "path", *configPath, "error", err)
+				}
+			}
+		}()
+	}
+	
 	// Run replicator
 	if err := replicator.Run(ctx, *interval); err != nil && err != context.Canceled {
-		log.Fatalf("Replicator error: %v", err)
+		slog.Error("replicator failed", "error", err)
+		os.Exit(1)
 	}
 	
+	// Sync whatever changed since the last scan before exiting
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), *flushTimeout)
+	if err := replicator.Flush(flushCtx); err != nil {
+		slog.Error("final sync incomplete", "error", err)
+	}
+	flushCancel()
+	
 	// Print final stats
 	stats := replicator.GetStats()
-	log.Printf("Final stats: Scans=%d, Uploads=%d, Errors=%d, Bytes=%d",
-		stats.Scans, stats.Uploads, stats.UploadErrors, stats.BytesUploaded)
+	slog.Info("final stats",
+		"scans", stats.Scans,
+		"uploads", stats.Uploads,
+		"upload_errors", stats.UploadErrors,
+		"bytes", stats.BytesUploaded)
+	slog.Info("cleanup stats",
+		"cleanup_runs", stats.CleanupRuns,
+		"objects_deleted", stats.ObjectsDeleted,
+		"cleanup_errors", stats.CleanupErrors,
+		"last_cleanup", formatCleanupTime(stats.LastCleanupTime))
+	
+	// A table would break up JSON logs, so log one record per project instead
+	if *logFormat == "json" {
+		for project, stat := range replicator.GetProjectStats() {
+			slog.Info("project stats",
+				"project", project,
+				"databases", stat.Databases,
+				"uploads", stat.Uploads,
+				"bytes", stat.BytesUploaded)
+		}
+	} else {
+		printProjectStats(os.Stderr, replicator.GetProjectStats())
+	}
+}
+
+// setupLogging sets the default slog logger, writing to stderr in the given
+// format and at the given level
+func setupLogging(format, level string) error {
+	opts := &slog.HandlerOptions{}
+	switch strings.ToLower(level) {
+	case "debug":
+		opts.Level = slog.LevelDebug
+	case "info":
+		opts.Level = slog.LevelInfo
+	case "warn", "warning":
+		opts.Level = slog.LevelWarn
+	case "error":
+		opts.Level = slog.LevelError
+	default:
+		return fmt.Errorf("invalid -log-level: %s", level)
+	}
+	
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid -log-format: %s", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// printProjectStats writes a table of per-project stats, largest projects
+// by bytes uploaded first
+func printProjectStats(w io.Writer, stats map[string]ultrasimple.ProjectStat) {
+	projects := make([]string, 0, len(stats))
+	for project := range stats {
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		a, b := stats[projects[i]], stats[projects[j]]
+		if a.BytesUploaded != b.BytesUploaded {
+			return a.BytesUploaded > b.BytesUploaded
+		}
+		if a.Databases != b.Databases {
+			return a.Databases > b.Databases
+		}
+		return projects[i] < projects[j]
+	})
+	
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tDATABASES\tUPLOADS\tBYTES")
+	for _, project := range projects {
+		stat := stats[project]
+		name := project
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", name, stat.Databases, stat.Uploads, stat.BytesUploaded)
+	}
+	tw.Flush()
+}
+
+// formatCleanupTime formats when cleanup last ran, or "never"
+func formatCleanupTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
 }
 
 // DryRunClient for testing without actual uploads
 type DryRunClient struct{}
 
-func (d *DryRunClient) Upload(key string, data []byte) error {
-	log.Printf("[DRY RUN] Would upload: %s (%d bytes compressed)", key, len(data))
+func (d *DryRunClient) Upload(ctx context.Context, key string, data []byte) error {
+	slog.Info("dry run, would upload", "key", key, "bytes", len(data))
+	return nil
+}
+
+func (d *DryRunClient) Download(ctx context.Context, key string) ([]byte, error) {
+	return nil, fmt.Errorf("dry run: nothing was uploaded to download %s", key)
+}
+
+// List returns nothing, since nothing was uploaded
+func (d *DryRunClient) List(ctx context.Context, prefix string) ([]string, error) {
+	return nil, nil
+}
+
+func (d *DryRunClient) Delete(ctx context.Context, keys []string) error {
+	slog.Info("dry run, would delete", "objects", len(keys))
 	return nil
-}
\ No newline at end of file
+}
+
+// fileConfig holds the settings read from -config. They are the ones a
+// running replicator can change, so SIGHUP reloads them; an omitted setting
+// keeps its current value.
+type fileConfig struct {
+	Interval      time.Duration `yaml:"interval"`
+	RetentionDays int           `yaml:"retention-days"`
+	Concurrent    int           `yaml:"concurrent"`
+}
+
+// loadConfig reads and validates a config file
+func loadConfig(path string) (fileConfig, error) {
+	var cfg fileConfig
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	
+	if cfg.Interval < 0 {
+		return cfg, fmt.Errorf("interval must be positive, got %v", cfg.Interval)
+	}
+	if cfg.RetentionDays < 0 {
+		return cfg, fmt.Errorf("retention-days must be positive, got %d", cfg.RetentionDays)
+	}
+	if cfg.Concurrent < 0 {
+		return cfg, fmt.Errorf("concurrent must be positive, got %d", cfg.Concurrent)
+	}
+	return cfg, nil
+}
+
+// loadEncryptor reads a hex-encoded AES-256 key from a file
+func loadEncryptor(path string) (*ultrasimple.AESGCMEncryptor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+	return ultrasimple.NewAESGCMEncryptor(key)
+}
+
+// parseTags parses a comma-separated list of key=value pairs
+func parseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	if s == "" {
+		return tags, nil
+	}
+	
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
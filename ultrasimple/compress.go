@@ -1,20 +1,183 @@
 package ultrasimple
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
-// compressLZ4 compresses data using LZ4
-func compressLZ4(data []byte) []byte {
-	// Simple implementation - in production would handle errors
+// Compressor compresses database snapshots before upload. Extension is
+// appended to the ".db" key suffix (e.g. ".lz4") and may be empty.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Extension() string
+}
+
+// Decompressor is implemented by compressors that can reverse their own
+// output, which restores need. The built-in compressors all implement it.
+type Decompressor interface {
+	Decompress(data []byte) ([]byte, error)
+}
+
+// BufferCompressor is implemented by compressors that can compress into a
+// caller's buffer, so the replicator can reuse buffers across uploads.
+// CompressBuffer appends to dst[:0], growing it if it is too small.
+type BufferCompressor interface {
+	CompressBuffer(dst, data []byte) ([]byte, error)
+}
+
+// LZ4Compressor compresses with LZ4 block compression (the default)
+type LZ4Compressor struct{}
+
+func (LZ4Compressor) Compress(data []byte) ([]byte, error)   { return compressLZ4(data) }
+
+func (LZ4Compressor) CompressBuffer(dst, data []byte) ([]byte, error) {
+	return compressLZ4Buffer(dst, data)
+}
+func (LZ4Compressor) Decompress(data []byte) ([]byte, error) { return decompressLZ4(data) }
+func (LZ4Compressor) Extension() string                      { return ".lz4" }
+
+// CompressWriter compresses into the LZ4 frame format, which splits the
+// stream into independently compressed blocks. Decompress tells frames
+// from single blocks by the frame magic number.
+func (LZ4Compressor) CompressWriter(w io.Writer) (io.WriteCloser, error) {
+	return lz4.NewWriter(w), nil
+}
+
+// ZstdCompressor compresses with zstd, trading CPU for smaller uploads
+type ZstdCompressor struct {
+	Level zstd.EncoderLevel // Defaults to zstd.SpeedDefault
+}
+
+func (c ZstdCompressor) Compress(data []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(c.level()))
+	if err != nil {
+		return nil, fmt.Errorf("zstd encoder: %w", err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+}
+
+func (ZstdCompressor) Decompress(data []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("zstd decoder: %w", err)
+	}
+	defer dec.Close()
+	return dec.DecodeAll(data, nil)
+}
+
+func (ZstdCompressor) Extension() string { return ".zst" }
+
+func (c ZstdCompressor) CompressWriter(w io.Writer) (io.WriteCloser, error) {
+	enc, err := zstd.NewWriter(w, zstd.WithEncoderLevel(c.level()))
+	if err != nil {
+		return nil, fmt.Errorf("zstd encoder: %w", err)
+	}
+	return enc, nil
+}
+
+func (c ZstdCompressor) level() zstd.EncoderLevel {
+	if c.Level == 0 {
+		return zstd.SpeedDefault
+	}
+	return c.Level
+}
+
+// NoopCompressor uploads databases uncompressed
+type NoopCompressor struct{}
+
+func (NoopCompressor) Compress(data []byte) ([]byte, error)   { return data, nil }
+func (NoopCompressor) Decompress(data []byte) ([]byte, error) { return data, nil }
+func (NoopCompressor) Extension() string                      { return "" }
+
+func (NoopCompressor) CompressWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+// nopWriteCloser adds a no-op Close to a writer
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// builtinCompressors are checked when restoring a key whose suffix does not
+// match the configured compressor, so older backups stay restorable
+var builtinCompressors = []Compressor{LZ4Compressor{}, ZstdCompressor{}, NoopCompressor{}}
+
+// decompressorFor returns the decompressor for a backup key based on its suffix
+func (r *Replicator) decompressorFor(key string) (Decompressor, error) {
+	for _, c := range append([]Compressor{r.s3Config.Compressor}, builtinCompressors...) {
+		if !strings.HasSuffix(key, backupExtension+c.Extension()) {
+			continue
+		}
+		if d, ok := c.(Decompressor); ok {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("no decompressor for key: %s", key)
+}
+
+// compressLZ4 compresses data using LZ4. Failures are returned rather than
+// falling back to the raw data, since the object would still be named .lz4
+// and restores would fail to decompress it.
+func compressLZ4(data []byte) ([]byte, error) {
+	return compressLZ4Buffer(nil, data)
+}
+
+// compressLZ4Buffer compresses data into dst, reallocating it if it can't
+// hold the worst-case compressed size
+func compressLZ4Buffer(dst, data []byte) ([]byte, error) {
 	maxSize := lz4.CompressBlockBound(len(data))
-	compressed := make([]byte, maxSize)
+	if cap(dst) < maxSize {
+		dst = make([]byte, maxSize)
+	}
+	dst = dst[:maxSize]
 	
-	n, err := lz4.CompressBlock(data, compressed, nil)
+	n, err := lz4.CompressBlock(data, dst, nil)
 	if err != nil {
-		// Fallback to uncompressed
-		return data
+		return nil, fmt.Errorf("lz4 compress: %w", err)
+	}
+	return dst[:n], nil
+}
+// lz4MaxRatio is the largest expansion an LZ4 block can decode to
+const lz4MaxRatio = 255
+
+// lz4FrameMagic starts every LZ4 frame (little-endian 0x184D2204). A raw
+// block can't start with it, since its first match would reference data
+// before the start of the block.
+var lz4FrameMagic = []byte{0x04, 0x22, 0x4d, 0x18}
+
+// decompressLZ4 decompresses an LZ4 frame from the streaming path or a
+// block produced by compressLZ4. The block format does not record the
+// original size, so the output buffer is grown until the block fits.
+func decompressLZ4(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, lz4FrameMagic) {
+		out, err := io.ReadAll(lz4.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return nil, fmt.Errorf("lz4 decompress: %w", err)
+		}
+		return out, nil
+	}
+	
+	limit := len(data) * lz4MaxRatio
+	size := len(data) * 4
+	if size < 64*1024 {
+		size = 64 * 1024
 	}
 	
-	return compressed[:n]
-}
\ No newline at end of file
+	for {
+		buf := make([]byte, size)
+		n, err := lz4.UncompressBlock(data, buf)
+		if err == nil {
+			return buf[:n], nil
+		}
+		if err != lz4.ErrInvalidSourceShortBuffer || size >= limit {
+			return nil, fmt.Errorf("lz4 decompress: %w", err)
+		}
+		size *= 2
+	}
+}
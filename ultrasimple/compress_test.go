@@ -0,0 +1,200 @@
+package ultrasimple
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressors(t *testing.T) {
+	data := bytes.Repeat([]byte("SQLite format 3\x00 some repetitive page data "), 1000)
+	
+	for name, c := range map[string]Compressor{
+		"LZ4":  LZ4Compressor{},
+		"Zstd": ZstdCompressor{},
+		"Noop": NoopCompressor{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			compressed, err := c.Compress(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			
+			out, err := c.(Decompressor).Decompress(compressed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, data) {
+				t.Error("Round trip did not preserve data")
+			}
+		})
+	}
+}
+
+func TestReplicatorCompressor(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	createTestDB(t, dbPath, "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := NewMockS3Client()
+	config := S3Config{
+		PathTemplate: "backups",
+		Compressor:   ZstdCompressor{},
+	}
+	
+	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+	r.scanAndSync(context.Background())
+	
+	var key string
+	for k := range s3Client.GetUploads() {
+		key = k
+	}
+	if !strings.HasSuffix(key, ".db.zst") {
+		t.Fatalf("Expected .db.zst key, got %q", key)
+	}
+	
+	// Restore works for the configured codec and for older LZ4 backups
+	lz4Key := strings.TrimSuffix(key, ".db.zst") + ".db.lz4"
+	original, _ := os.ReadFile(dbPath)
+	s3Client.Upload(context.Background(), lz4Key, lz4Block(t, original))
+	
+	for _, k := range []string{key, lz4Key} {
+		dest := filepath.Join(tmpDir, "restored.db")
+		if err := r.restoreKey(context.Background(), k, false, dest); err != nil {
+			t.Fatalf("restore %s: %v", k, err)
+		}
+		restored, _ := os.ReadFile(dest)
+		if !bytes.Equal(restored, original) {
+			t.Errorf("Restored data from %s does not match", k)
+		}
+	}
+	
+	if err := r.RestoreAt(context.Background(), dbPath, time.Now().Add(2*time.Hour), filepath.Join(tmpDir, "at.db")); err != nil {
+		t.Errorf("RestoreAt with zstd backup: %v", err)
+	}
+}
+
+func TestReplicatorCompressionPool(t *testing.T) {
+	small := bytes.Repeat([]byte("small page "), 100)
+	large := bytes.Repeat([]byte("large page data "), 1000)
+	
+	r := New("", S3Config{CompressionBufferSize: 4096}, NewMockS3Client())
+	for _, data := range [][]byte{small, large, small} {
+		compressed, release, err := r.prepareUpload(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := decompressLZ4(compressed)
+		release()
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(out, data) {
+			t.Errorf("Round trip of %d bytes did not preserve data", len(data))
+		}
+	}
+}
+
+// failingCompressor names objects .lz4 but always fails to compress
+type failingCompressor struct{}
+
+func (failingCompressor) Compress([]byte) ([]byte, error) {
+	return nil, errors.New("compression failed")
+}
+func (failingCompressor) Extension() string { return ".lz4" }
+
+func TestCompressionFailure(t *testing.T) {
+	t.Run("Incompressible", func(t *testing.T) {
+		// Random data grows under LZ4 but is still stored as a valid block
+		data := make([]byte, 64*1024)
+		rand.New(rand.NewSource(1)).Read(data)
+		
+		compressed, err := LZ4Compressor{}.Compress(data)
+		if err != nil {
+			t.Fatal(err)
+		} else if bytes.Equal(compressed, data) {
+			t.Fatal("Expected LZ4 block, got raw data")
+		}
+		out, err := decompressLZ4(compressed)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(out, data) {
+			t.Error("Round trip did not preserve data")
+		}
+	})
+	
+	t.Run("Error", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		createTestDB(t, filepath.Join(tmpDir, "test.db"), "CREATE TABLE test (id INTEGER)")
+		
+		s3Client := NewMockS3Client()
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{
+			PathTemplate: "backups",
+			Compressor:   failingCompressor{},
+		}, s3Client)
+		r.scanAndSync(context.Background())
+		
+		// Nothing is uploaded under a .lz4 key that restore couldn't read
+		if uploads := s3Client.GetUploads(); len(uploads) != 0 {
+			t.Errorf("Expected no uploads, got %d", len(uploads))
+		}
+		if stats := r.GetStats(); stats.UploadErrors != 1 {
+			t.Errorf("Expected 1 upload error, got %d", stats.UploadErrors)
+		}
+	})
+}
+
+// lz4Block compresses data the way uploads do, for seeding mock backups
+func lz4Block(t *testing.T, data []byte) []byte {
+	t.Helper()
+	compressed, err := compressLZ4(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compressed
+}
+
+// BenchmarkPrepareUpload measures per-upload allocations, which the
+// compression buffer pool keeps to a minimum for repeated uploads
+func BenchmarkPrepareUpload(b *testing.B) {
+	data := bytes.Repeat([]byte("SQLite format 3\x00 some repetitive page data "), 100000)
+	r := New("", S3Config{}, NewMockS3Client())
+	
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		_, release, err := r.prepareUpload(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		release()
+	}
+}
+
+func TestCleanupMixedExtensions(t *testing.T) {
+	s3Client := NewMockS3Client()
+	r := New("", S3Config{PathTemplate: "backups", RetentionDays: 1}, s3Client)
+	
+	old := time.Now().AddDate(0, 0, -3).Format(backupTimestampFormat)
+	recent := time.Now().Format(backupTimestampFormat)
+	for _, ext := range []string{".lz4", ".zst", ""} {
+		s3Client.Upload(context.Background(), "backups/old-"+old+backupExtension+ext, []byte("x"))
+		s3Client.Upload(context.Background(), "backups/new-"+recent+backupExtension+ext, []byte("x"))
+	}
+	
+	r.cleanupOldBackups(context.Background())
+	
+	for key := range s3Client.GetUploads() {
+		if strings.Contains(key, "/old-") {
+			t.Errorf("Expected old backup to be deleted: %s", key)
+		}
+	}
+	if s3Client.GetUploadCount() != 3 {
+		t.Errorf("Expected 3 recent backups to remain, got %d", s3Client.GetUploadCount())
+	}
+}
@@ -0,0 +1,224 @@
+package ultrasimple
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Content-addressed storage for S3Config.Dedup
+const (
+	blobPrefix       = "blobs/" // Blobs are shared by every database, so they sit at the bucket root
+	pointerExtension = ".ref"   // Replaces the codec and encryption extensions of a backup key
+)
+
+// pointer is the backup object of a database in dedup mode. It names the
+// blob holding the database's contents.
+type pointer struct {
+	Blob   string `json:"blob"`
+	SHA256 string `json:"sha256"` // Of the uncompressed database
+}
+
+// pointerKey returns the key of the pointer replacing a backup key
+func pointerKey(key string) string {
+	return key[:strings.LastIndex(key, backupExtension)] + backupExtension + pointerExtension
+}
+
+// blobKey returns the key of the blob holding contents with the given hex
+// SHA-256
+func (r *Replicator) blobKey(sum string) string {
+	key := blobPrefix + sum + backupExtension + r.s3Config.Compressor.Extension()
+	if r.s3Config.Encryptor != nil {
+		key += encryptedExtension
+	}
+	return key
+}
+
+// blobUpload is the upload of a blob, which syncs of identical databases
+// running at the same time wait on instead of uploading it again
+type blobUpload struct {
+	done chan struct{}
+	err  error
+}
+
+// uploadDedup uploads a database's contents as a blob, unless a blob with
+// the same SHA-256 already exists, and a pointer to it at key. It returns
+// the bytes uploaded and the hex MD5 of the pointer.
+func (r *Replicator) uploadDedup(ctx context.Context, path, key string, data []byte) (int64, string, error) {
+	sum := sha256.Sum256(data)
+	ptr := pointer{SHA256: hex.EncodeToString(sum[:])}
+	ptr.Blob = r.blobKey(ptr.SHA256)
+	
+	size, err := r.ensureBlob(ctx, path, ptr.Blob, data)
+	if err != nil {
+		return 0, "", err
+	}
+	
+	body, err := json.Marshal(ptr)
+	if err != nil {
+		return 0, "", fmt.Errorf("encode pointer: %w", err)
+	}
+	if err := r.uploadWithRetry(ctx, path, func() error {
+		return r.upload(ctx, path, key, body)
+	}); err != nil {
+		return 0, "", fmt.Errorf("upload pointer: %w", err)
+	}
+	return size + int64(len(body)), md5Hex(body), nil
+}
+
+// ensureBlob makes sure a blob exists, uploading it unless it was already
+// uploaded or is listed in the bucket. Only one sync uploads a given blob at
+// a time. It returns the bytes uploaded.
+func (r *Replicator) ensureBlob(ctx context.Context, path, key string, data []byte) (int64, error) {
+	r.blobsMu.Lock()
+	if b, ok := r.blobs[key]; ok {
+		r.blobsMu.Unlock()
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		if b.err != nil {
+			return 0, b.err
+		}
+		atomic.AddInt64(&r.stats.DedupedUploads, 1)
+		return 0, nil
+	}
+	b := &blobUpload{done: make(chan struct{})}
+	r.blobs[key] = b
+	r.blobsMu.Unlock()
+	
+	size, err := r.uploadBlob(ctx, path, key, data)
+	if err != nil {
+		// Forget the failure so the next sync tries again
+		r.blobsMu.Lock()
+		delete(r.blobs, key)
+		r.blobsMu.Unlock()
+	}
+	b.err = err
+	close(b.done)
+	return size, err
+}
+
+// uploadBlob uploads a blob unless it is listed in the bucket already
+func (r *Replicator) uploadBlob(ctx context.Context, path, key string, data []byte) (int64, error) {
+	if err := r.waitLimiter(ctx); err != nil {
+		return 0, err
+	}
+	keys, err := r.s3Client.List(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("list %s: %w", key, err)
+	}
+	for _, k := range keys {
+		if k == key {
+			atomic.AddInt64(&r.stats.DedupedUploads, 1)
+			return 0, nil
+		}
+	}
+	
+	payload, release, err := r.prepareUpload(data)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	
+	// Blobs are shared, so they only get the static tags
+	if err := r.uploadWithRetry(ctx, path, func() error {
+		return r.upload(ctx, "", key, payload)
+	}); err != nil {
+		return 0, fmt.Errorf("upload blob: %w", err)
+	}
+	return int64(len(payload)), nil
+}
+
+// downloadPointer resolves a pointer to its blob and returns the blob's
+// decompressed contents, checked against the pointer's SHA-256, along with
+// the hex MD5 of the pointer as stored
+func (r *Replicator) downloadPointer(ctx context.Context, key string) ([]byte, string, error) {
+	body, err := r.s3Client.Download(ctx, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", key, err)
+	}
+	var ptr pointer
+	if err := json.Unmarshal(body, &ptr); err != nil {
+		return nil, "", fmt.Errorf("decode %s: %w", key, err)
+	}
+	
+	data, _, err := r.downloadBackup(ctx, ptr.Blob)
+	if err != nil {
+		return nil, "", err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != ptr.SHA256 {
+		return nil, "", fmt.Errorf("blob %s does not match the sha256 of %s", ptr.Blob, key)
+	}
+	return data, md5Hex(body), nil
+}
+
+// CleanupBlobs deletes blobs no longer referenced by any pointer, once
+// retention or PurgeDatabase has removed the pointers to them. It reads
+// every pointer in the bucket, so Run only calls it daily in dedup mode.
+// Scans are paused meanwhile so a blob uploaded for an in-flight sync isn't
+// deleted before its pointer exists. It returns how many blobs were deleted.
+func (r *Replicator) CleanupBlobs(ctx context.Context) (int, error) {
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+	
+	start := time.Now()
+	
+	if err := r.waitLimiter(ctx); err != nil {
+		return 0, err
+	}
+	keys, err := r.s3Client.List(ctx, "")
+	if err != nil {
+		return 0, fmt.Errorf("list: %w", err)
+	}
+	
+	var blobs []string
+	referenced := make(map[string]bool)
+	for _, key := range keys {
+		if strings.HasPrefix(key, blobPrefix) {
+			blobs = append(blobs, key)
+			continue
+		}
+		if !strings.HasSuffix(key, pointerExtension) {
+			continue
+		}
+		
+		// Any unreadable pointer aborts, since its blob can't be ruled out
+		body, err := r.s3Client.Download(ctx, key)
+		if err != nil {
+			return 0, fmt.Errorf("download %s: %w", key, err)
+		}
+		var ptr pointer
+		if err := json.Unmarshal(body, &ptr); err != nil {
+			return 0, fmt.Errorf("decode %s: %w", key, err)
+		}
+		referenced[ptr.Blob] = true
+	}
+	
+	var unreferenced []string
+	for _, key := range blobs {
+		if !referenced[key] {
+			unreferenced = append(unreferenced, key)
+		}
+	}
+	
+	r.blobsMu.Lock()
+	for _, key := range unreferenced {
+		delete(r.blobs, key)
+	}
+	r.blobsMu.Unlock()
+	
+	deleted, err := r.deleteKeys(ctx, unreferenced)
+	slog.Info("blob cleanup complete",
+		"deleted", deleted,
+		"blobs", len(blobs),
+		"duration", time.Since(start))
+	return deleted, err
+}
@@ -0,0 +1,127 @@
+package ultrasimple
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplicatorDedup(t *testing.T) {
+	// Three tenants cloned from one template
+	setup := func(t *testing.T) (string, []string) {
+		tmpDir := t.TempDir()
+		template := filepath.Join(t.TempDir(), "template.db")
+		createTestDB(t, template, "CREATE TABLE test (id INTEGER)")
+		data, err := os.ReadFile(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		
+		var paths []string
+		for _, name := range []string{"t1.db", "t2.db", "t3.db"} {
+			path := filepath.Join(tmpDir, name)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, path)
+		}
+		return tmpDir, paths
+	}
+	
+	// keysWith returns the stored keys matching a predicate
+	keysWith := func(s3Client *MockS3Client, match func(string) bool) []string {
+		var keys []string
+		for key := range s3Client.GetUploads() {
+			if match(key) {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+	isBlob := func(key string) bool { return strings.HasPrefix(key, blobPrefix) }
+	isPointer := func(key string) bool { return strings.HasSuffix(key, pointerExtension) }
+	
+	t.Run("SharesBlobs", func(t *testing.T) {
+		tmpDir, paths := setup(t)
+		s3Client := NewMockS3Client()
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups", Dedup: true}, s3Client)
+		
+		r.scanAndSync(context.Background())
+		
+		if blobs := keysWith(s3Client, isBlob); len(blobs) != 1 {
+			t.Errorf("Expected 1 shared blob, got %v", blobs)
+		}
+		if pointers := keysWith(s3Client, isPointer); len(pointers) != 3 {
+			t.Errorf("Expected 3 pointers, got %v", pointers)
+		}
+		if stats := r.GetStats(); stats.Uploads != 3 || stats.DedupedUploads != 2 {
+			t.Errorf("Expected 3 uploads with 2 deduplicated, got %d and %d", stats.Uploads, stats.DedupedUploads)
+		}
+		
+		original, _ := os.ReadFile(paths[0])
+		for _, path := range paths {
+			var buf bytes.Buffer
+			if err := r.Restore(context.Background(), path, &buf); err != nil {
+				t.Fatalf("Restore %s: %v", filepath.Base(path), err)
+			}
+			if !bytes.Equal(buf.Bytes(), original) {
+				t.Errorf("Restored %s does not match", filepath.Base(path))
+			}
+		}
+	})
+	
+	t.Run("FindsExistingBlobs", func(t *testing.T) {
+		tmpDir, paths := setup(t)
+		s3Client := NewMockS3Client()
+		config := S3Config{PathTemplate: "backups", Dedup: true}
+		New(filepath.Join(tmpDir, "*.db"), config, s3Client).scanAndSync(context.Background())
+		
+		// A restarted replicator lists the blob rather than uploading it again
+		r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+		r.scanAndSync(context.Background())
+		if stats := r.GetStats(); stats.DedupedUploads != int64(len(paths)) {
+			t.Errorf("Expected every upload deduplicated, got %d", stats.DedupedUploads)
+		}
+	})
+	
+	t.Run("CleanupBlobs", func(t *testing.T) {
+		tmpDir, paths := setup(t)
+		s3Client := NewMockS3Client()
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups", Dedup: true}, s3Client)
+		r.scanAndSync(context.Background())
+		
+		// t1 diverges from the template and gets a blob of its own
+		db, err := sql.Open("sqlite3", paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Exec("INSERT INTO test VALUES (1)")
+		db.Close()
+		r.scanAndSync(context.Background())
+		
+		if n, err := r.CleanupBlobs(context.Background()); err != nil || n != 0 {
+			t.Fatalf("Expected no unreferenced blobs, got %d (%v)", n, err)
+		}
+		
+		// Once t1 is purged its blob is unreferenced, and the template's
+		// blob is still used by t2 and t3
+		os.Remove(paths[0])
+		if err := r.PurgeDatabase(context.Background(), paths[0]); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := r.CleanupBlobs(context.Background()); err != nil || n != 1 {
+			t.Fatalf("Expected 1 unreferenced blob deleted, got %d (%v)", n, err)
+		}
+		if blobs := keysWith(s3Client, isBlob); len(blobs) != 1 {
+			t.Errorf("Expected the template blob to remain, got %v", blobs)
+		}
+		var buf bytes.Buffer
+		if err := r.Restore(context.Background(), paths[1], &buf); err != nil {
+			t.Errorf("Restore after cleanup: %v", err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package ultrasimple
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedExtension is appended to the key of every encrypted backup
+const encryptedExtension = ".enc"
+
+// Encryptor encrypts compressed backups before upload
+type Encryptor interface {
+	Encrypt(data []byte) ([]byte, error)
+}
+
+// Decryptor is implemented by encryptors that can reverse their own
+// output, which restores of encrypted backups need
+type Decryptor interface {
+	Decrypt(data []byte) ([]byte, error)
+}
+
+// AESGCMEncryptor encrypts with AES-256-GCM. Each object is sealed with a
+// random nonce, which is stored in front of the ciphertext.
+type AESGCMEncryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMEncryptor returns an encryptor for a 32-byte AES-256 key
+func NewAESGCMEncryptor(key []byte) (*AESGCMEncryptor, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("aes-gcm key must be 32 bytes, got %d", len(key))
+	}
+	
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("gcm: %w", err)
+	}
+	return &AESGCMEncryptor{aead: aead}, nil
+}
+
+func (e *AESGCMEncryptor) Encrypt(data []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(data)+e.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+	return e.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (e *AESGCMEncryptor) Decrypt(data []byte) ([]byte, error) {
+	n := e.aead.NonceSize()
+	if len(data) < n {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	
+	plaintext, err := e.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("aes-gcm open: %w", err)
+	}
+	return plaintext, nil
+}
+
+// encrypt applies the configured encryptor, if any
+func (r *Replicator) encrypt(data []byte) ([]byte, error) {
+	if r.s3Config.Encryptor == nil {
+		return data, nil
+	}
+	return r.s3Config.Encryptor.Encrypt(data)
+}
+
+// decrypt reverses encryption for keys with the encrypted extension and
+// returns the data with the key the compression codec is identified by
+func (r *Replicator) decrypt(key string, data []byte) ([]byte, string, error) {
+	if !strings.HasSuffix(key, encryptedExtension) {
+		return data, key, nil
+	}
+	
+	d, ok := r.s3Config.Encryptor.(Decryptor)
+	if !ok {
+		return nil, "", fmt.Errorf("encrypted backup requires a Decryptor: %s", key)
+	}
+	
+	plaintext, err := d.Decrypt(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("decrypt %s: %w", key, err)
+	}
+	return plaintext, strings.TrimSuffix(key, encryptedExtension), nil
+}
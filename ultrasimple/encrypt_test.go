@@ -0,0 +1,127 @@
+package ultrasimple
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEncryptionKey() []byte {
+	return bytes.Repeat([]byte{0x42}, 32)
+}
+
+func TestAESGCMEncryptor(t *testing.T) {
+	if _, err := NewAESGCMEncryptor([]byte("short")); err == nil {
+		t.Error("Expected error for short key")
+	}
+	
+	enc, err := NewAESGCMEncryptor(testEncryptionKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	original := bytes.Repeat([]byte("SQLite format 3\x00 tenant data "), 500)
+	compressed, err := ZstdCompressor{}.Compress(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	ciphertext, err := enc.Encrypt(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, compressed[:16]) {
+		t.Error("Ciphertext contains plaintext")
+	}
+	
+	// Each encryption uses a fresh nonce
+	again, _ := enc.Encrypt(compressed)
+	if bytes.Equal(ciphertext, again) {
+		t.Error("Expected different ciphertexts for the same plaintext")
+	}
+	
+	plaintext, err := enc.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ZstdCompressor{}.Decompress(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, original) {
+		t.Error("Round trip did not preserve data")
+	}
+	
+	// Tampering is detected
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	if _, err := enc.Decrypt(ciphertext); err == nil {
+		t.Error("Expected error decrypting tampered ciphertext")
+	}
+	
+	// A different key cannot decrypt
+	other, _ := NewAESGCMEncryptor(bytes.Repeat([]byte{0x01}, 32))
+	if _, err := other.Decrypt(again); err == nil {
+		t.Error("Expected error decrypting with the wrong key")
+	}
+}
+
+func TestReplicatorEncryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	createTestDB(t, dbPath, "CREATE TABLE secrets (ssn TEXT)")
+	
+	enc, err := NewAESGCMEncryptor(testEncryptionKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	s3Client := NewMockS3Client()
+	config := S3Config{
+		PathTemplate: "backups",
+		Encryptor:    enc,
+	}
+	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+	r.scanAndSync(context.Background())
+	
+	var key string
+	var stored []byte
+	for k, v := range s3Client.GetUploads() {
+		key, stored = k, v
+	}
+	if !strings.HasSuffix(key, ".db.lz4.enc") {
+		t.Fatalf("Expected .db.lz4.enc key, got %q", key)
+	}
+	if bytes.Contains(stored, []byte("CREATE TABLE secrets")) {
+		t.Error("Uploaded object contains plaintext schema")
+	}
+	
+	original, _ := os.ReadFile(dbPath)
+	var buf bytes.Buffer
+	if err := r.Restore(context.Background(), dbPath, &buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), original) {
+		t.Error("Restored data does not match original database")
+	}
+	
+	// Without the key the backup cannot be restored
+	plain := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+	if err := plain.Restore(context.Background(), dbPath, &buf); err == nil {
+		t.Error("Expected error restoring encrypted backup without a Decryptor")
+	}
+	
+	// Cleanup still recognizes encrypted keys
+	oldKey := "backups/test-" + time.Now().AddDate(0, 0, -40).Format(backupTimestampFormat) + ".db.lz4.enc"
+	s3Client.Upload(context.Background(), oldKey, stored)
+	r.cleanupOldBackups(context.Background())
+	if _, ok := s3Client.GetUploads()[oldKey]; ok {
+		t.Error("Expected old encrypted backup to be cleaned up")
+	}
+	if _, ok := s3Client.GetUploads()[key]; !ok {
+		t.Error("Expected current encrypted backup to be retained")
+	}
+}
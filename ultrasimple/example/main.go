@@ -3,12 +3,20 @@ package main
 import (
 	"bytes"
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/benbjohnson/litestream/ultrasimple"
 )
 
@@ -32,8 +40,8 @@ func NewRealS3Client(region, bucket string) (*RealS3Client, error) {
 	}, nil
 }
 
-func (c *RealS3Client) Upload(key string, data []byte) error {
-	_, err := c.s3.PutObject(&s3.PutObjectInput{
+func (c *RealS3Client) Upload(ctx context.Context, key string, data []byte) error {
+	_, err := c.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(c.bucket),
 		Key:    aws.String(key),
 		Body:   aws.ReadSeekCloser(bytes.NewReader(data)),
@@ -41,9 +49,118 @@ func (c *RealS3Client) Upload(key string, data []byte) error {
 	return err
 }
 
-func (c *RealS3Client) List(prefix string) ([]string, error) {
+// UploadWithStorageClass uploads an object into a storage class such as
+// STANDARD_IA, tagging it as UploadWithTags does
+func (c *RealS3Client) UploadWithStorageClass(ctx context.Context, key string, data []byte, storageClass string, tags map[string]string) error {
+	input := &s3.PutObjectInput{
+		Bucket:       aws.String(c.bucket),
+		Key:          aws.String(key),
+		Body:         aws.ReadSeekCloser(bytes.NewReader(data)),
+		StorageClass: aws.String(storageClass),
+	}
+	if len(tags) > 0 {
+		tagging := url.Values{}
+		for k, v := range tags {
+			tagging.Set(k, v)
+		}
+		input.Tagging = aws.String(tagging.Encode())
+	}
+	
+	_, err := c.s3.PutObjectWithContext(ctx, input)
+	return err
+}
+
+// UploadStream uploads from a reader in parts, so memory use is bounded
+// by the part size rather than the database size
+func (c *RealS3Client) UploadStream(ctx context.Context, key string, r io.Reader, size int64, tags map[string]string) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	}
+	if len(tags) > 0 {
+		tagging := url.Values{}
+		for k, v := range tags {
+			tagging.Set(k, v)
+		}
+		input.Tagging = aws.String(tagging.Encode())
+	}
+	
+	_, err := s3manager.NewUploaderWithClient(c.s3).UploadWithContext(ctx, input)
+	return err
+}
+
+// UploadWithTags uploads an object with S3 object tagging
+func (c *RealS3Client) UploadWithTags(ctx context.Context, key string, data []byte, tags map[string]string) error {
+	tagging := url.Values{}
+	for k, v := range tags {
+		tagging.Set(k, v)
+	}
+	
+	_, err := c.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:  aws.String(c.bucket),
+		Key:     aws.String(key),
+		Body:    aws.ReadSeekCloser(bytes.NewReader(data)),
+		Tagging: aws.String(tagging.Encode()),
+	})
+	return err
+}
+
+func (c *RealS3Client) Download(ctx context.Context, key string) ([]byte, error) {
+	out, err := c.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil, fmt.Errorf("download %s: %w", key, ultrasimple.ErrObjectNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	
+	return io.ReadAll(out.Body)
+}
+
+// isNotFound reports whether err is S3's response for a missing object.
+// GetObject reports NoSuchKey, while HeadObject, having no body, only
+// reports a 404 NotFound.
+func isNotFound(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	return aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound"
+}
+
+// ETag returns the ETag of a stored object
+func (c *RealS3Client) ETag(ctx context.Context, key string) (string, error) {
+	etag, _, err := c.ETagWithEncryption(ctx, key)
+	return etag, err
+}
+
+// ETagWithEncryption returns the ETag of a stored object and how it is
+// encrypted at rest
+func (c *RealS3Client) ETagWithEncryption(ctx context.Context, key string) (etag, encryption string, err error) {
+	out, err := c.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return "", "", fmt.Errorf("etag %s: %w", key, ultrasimple.ErrObjectNotFound)
+	} else if err != nil {
+		return "", "", err
+	}
+	
+	encryption = aws.StringValue(out.ServerSideEncryption)
+	if out.SSECustomerAlgorithm != nil {
+		encryption = ultrasimple.SSECustomerKey
+	}
+	return aws.StringValue(out.ETag), encryption, nil
+}
+
+func (c *RealS3Client) List(ctx context.Context, prefix string) ([]string, error) {
 	var keys []string
-	err := c.s3.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+	err := c.s3.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
 		Bucket: aws.String(c.bucket),
 		Prefix: aws.String(prefix),
 	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
@@ -55,7 +172,7 @@ func (c *RealS3Client) List(prefix string) ([]string, error) {
 	return keys, err
 }
 
-func (c *RealS3Client) Delete(keys []string) error {
+func (c *RealS3Client) Delete(ctx context.Context, keys []string) error {
 	if len(keys) == 0 {
 		return nil
 	}
@@ -68,7 +185,7 @@ func (c *RealS3Client) Delete(keys []string) error {
 		}
 	}
 	
-	_, err := c.s3.DeleteObjects(&s3.DeleteObjectsInput{
+	_, err := c.s3.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
 		Bucket: aws.String(c.bucket),
 		Delete: &s3.Delete{
 			Objects: objects,
@@ -78,7 +195,59 @@ func (c *RealS3Client) Delete(keys []string) error {
 	return err
 }
 
+// verifyRoundTrip checks the bucket is usable for backups and restores: it
+// uploads a compressed object, downloads it, and compares it to the
+// original after decompression. It then deletes the object and checks a
+// download of it reports ultrasimple.ErrObjectNotFound.
+func verifyRoundTrip(ctx context.Context, client *RealS3Client) error {
+	var compressor ultrasimple.LZ4Compressor
+	original := bytes.Repeat([]byte("ultrasimple round trip "), 1024)
+	key := fmt.Sprintf("roundtrip-check/%d.db%s", time.Now().UnixNano(), compressor.Extension())
+	
+	compressed, err := compressor.Compress(original)
+	if err != nil {
+		return fmt.Errorf("compress: %w", err)
+	}
+	if err := client.Upload(ctx, key, compressed); err != nil {
+		return fmt.Errorf("upload %s: %w", key, err)
+	}
+	
+	downloaded, err := client.Download(ctx, key)
+	if err != nil {
+		return err
+	}
+	data, err := compressor.Decompress(downloaded)
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+	if !bytes.Equal(data, original) {
+		return fmt.Errorf("downloaded %s does not match what was uploaded", key)
+	}
+	
+	if err := client.Delete(ctx, []string{key}); err != nil {
+		return fmt.Errorf("delete %s: %w", key, err)
+	}
+	if _, err := client.Download(ctx, key); !errors.Is(err, ultrasimple.ErrObjectNotFound) {
+		return fmt.Errorf("expected %s to be missing after delete, got %v", key, err)
+	}
+	return nil
+}
+
 func main() {
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	verify := flag.Bool("verify", false, "Check an upload round-trips through the bucket, then exit")
+	flag.Parse()
+	
+	// Log as JSON for log aggregation
+	switch *logFormat {
+	case "text":
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	default:
+		slog.Error("invalid -log-format", "format", *logFormat)
+		os.Exit(1)
+	}
+	
 	// Configuration
 	pattern := "/data/*/databases/*/branches/*/tenants/*.db"
 	region := "us-east-1"
@@ -87,7 +256,17 @@ func main() {
 	// Create S3 client
 	s3Client, err := NewRealS3Client(region, bucket)
 	if err != nil {
-		log.Fatalf("Failed to create S3 client: %v", err)
+		slog.Error("failed to create s3 client", "error", err)
+		os.Exit(1)
+	}
+	
+	if *verify {
+		if err := verifyRoundTrip(context.Background(), s3Client); err != nil {
+			slog.Error("round trip failed", "bucket", bucket, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("round trip succeeded", "bucket", bucket)
+		return
 	}
 	
 	// Create replicator
@@ -103,14 +282,14 @@ func main() {
 	
 	// Run replicator
 	ctx := context.Background()
-	log.Printf("Starting ultra-simple replicator")
-	log.Printf("Pattern: %s", pattern)
-	log.Printf("S3: s3://%s", bucket)
-	log.Printf("Interval: 15 seconds")
-	log.Printf("Retention: %d days", config.RetentionDays)
-	log.Printf("Features: Next-hour naming (natural rate limiting), automatic cleanup")
+	slog.Info("starting ultra-simple replicator",
+		"pattern", pattern,
+		"bucket", bucket,
+		"interval", 15*time.Second,
+		"retention_days", config.RetentionDays)
 	
 	if err := replicator.Run(ctx, 15*time.Second); err != nil {
-		log.Fatalf("Replicator error: %v", err)
+		slog.Error("replicator failed", "error", err)
+		os.Exit(1)
 	}
 }
\ No newline at end of file
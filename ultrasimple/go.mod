@@ -3,11 +3,24 @@ module github.com/benbjohnson/litestream/ultrasimple
 go 1.21
 
 require (
+	github.com/aws/aws-sdk-go v1.55.8
+	github.com/klauspost/compress v1.17.11
 	github.com/mattn/go-sqlite3 v1.14.19
 	github.com/pierrec/lz4/v4 v4.1.21
+	github.com/prometheus/client_golang v1.17.0
+	golang.org/x/time v0.5.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
-	github.com/aws/aws-sdk-go v1.55.8 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.4 // indirect
+	github.com/prometheus/client_model v0.4.1-0.20230718164431-9a2bf3000d16 // indirect
+	github.com/prometheus/common v0.44.0 // indirect
+	github.com/prometheus/procfs v0.11.1 // indirect
+	golang.org/x/sys v0.11.0 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
 )
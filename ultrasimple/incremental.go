@@ -0,0 +1,412 @@
+package ultrasimple
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Backup modes for S3Config.Mode
+const (
+	ModeFull        = "full"
+	ModeIncremental = "incremental"
+)
+
+// SQLite WAL format constants
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	walMagicLE         = 0x377f0682 // Checksums use little-endian words
+	walMagicBE         = 0x377f0683 // Checksums use big-endian words
+)
+
+// Keys of incremental objects share their base's timestamp, so retention
+// deletes them along with it
+const (
+	walIncrementExtension = ".wal"           // Followed by the increment number, before ".db"
+	manifestExtension     = ".manifest.json" // Replaces ".db" and the codec extensions
+)
+
+// errWALReset means the WAL was checkpointed and restarted since the last
+// sync, so frames written in between may never have been uploaded
+var errWALReset = errors.New("wal reset since last sync")
+
+// manifest lists the WAL increments uploaded on top of a base backup in
+// incremental mode. Restores only apply them to the base whose ETag
+// matches, so a base re-uploaded later in the window is never paired with
+// the increments of an earlier one.
+type manifest struct {
+	Base       string   `json:"base"`
+	BaseETag   string   `json:"base_etag"`
+	PageSize   int      `json:"page_size"`
+	Increments []string `json:"increments"`
+}
+
+// walPosition is how much of a database's WAL has been backed up
+type walPosition struct {
+	salt     uint64    // Salt of the WAL generation; zero if the WAL was empty
+	offset   int64     // End of the last backed-up commit frame
+	checksum [2]uint32 // Running WAL checksum at offset
+}
+
+// incrementalState is a database's place in its current incremental backup.
+// A database whose WAL was empty at the base has no WAL generation to
+// follow yet, so the first WAL seen is only trusted if the database file
+// hasn't been checkpointed into since.
+type incrementalState struct {
+	manifest  manifest
+	pos       walPosition
+	baseMTime time.Time // Database file mtime and size when the base was read
+	baseSize  int64
+}
+
+// syncIncremental uploads a database in incremental mode. The first sync of
+// each backup window uploads a full base; later syncs in the window upload
+// only the WAL frames committed since, as increments listed in the window's
+// manifest. A WAL checkpointed and restarted between syncs may have lost
+// frames, and a base re-uploaded by AuditAndRepair is newer than the
+// increments, so both start a new base.
+func (r *Replicator) syncIncremental(ctx context.Context, state *DatabaseState) {
+	path := state.Path
+	key := r.generateS3Key(path)
+	
+	r.mu.RLock()
+	inc := state.incremental
+	r.mu.RUnlock()
+	
+	r.uploadsMu.Lock()
+	rec := r.uploads[path]
+	r.uploadsMu.Unlock()
+	
+	var next *incrementalState
+	var err error
+	if inc != nil && inc.manifest.Base == key && rec.ETag == inc.manifest.BaseETag {
+		next, err = r.uploadIncrement(ctx, path, inc)
+		if errors.Is(err, errWALReset) {
+			slog.Info("wal reset since last sync, uploading new base", "path", path)
+			next, err = r.uploadBase(ctx, path, key)
+		}
+	} else {
+		next, err = r.uploadBase(ctx, path, key)
+	}
+	
+	if next != nil {
+		r.mu.Lock()
+		state.incremental = next
+		r.mu.Unlock()
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			r.resetChange(state) // Shutting down; not an upload failure
+			return
+		}
+		slog.Error("upload failed", "path", path, "key", key, "error", err)
+		atomic.AddInt64(&r.stats.UploadErrors, 1)
+	}
+	r.setSyncResult(state, state.Checksum, err)
+}
+
+// uploadBase uploads the database, with its committed WAL frames applied,
+// as the base of the backup window at key
+func (r *Replicator) uploadBase(ctx context.Context, path, key string) (*incrementalState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	data, pos, pageSize, err := r.readIncrementalBase(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	
+	payload, release, err := r.prepareUpload(data)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.uploadWithRetry(ctx, path, func() error {
+		return r.upload(ctx, path, key, payload)
+	}); err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+	
+	etag := md5Hex(payload)
+	r.countUpload(path, int64(len(payload)))
+	r.recordUpload(path, key, etag)
+	
+	return &incrementalState{
+		manifest:  manifest{Base: key, BaseETag: etag, PageSize: pageSize},
+		pos:       pos,
+		baseMTime: info.ModTime(),
+		baseSize:  info.Size(),
+	}, nil
+}
+
+// uploadIncrement uploads the WAL frames committed since inc's position and
+// adds them to the window's manifest. It returns errWALReset if the WAL
+// can't be continued from that position. If the manifest upload fails the
+// increment is still recorded, so the next manifest lists it.
+func (r *Replicator) uploadIncrement(ctx context.Context, path string, inc *incrementalState) (*incrementalState, error) {
+	if inc.pos.salt == 0 {
+		// Any checkpoint since the base may have folded in unseen frames
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("read: %w", err)
+		}
+		if !info.ModTime().Equal(inc.baseMTime) || info.Size() != inc.baseSize {
+			return nil, errWALReset
+		}
+	}
+	
+	wal, err := os.ReadFile(path + "-wal")
+	if os.IsNotExist(err) {
+		return nil, errWALReset
+	} else if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	frames, pos, pageSize, err := readWALFrames(wal, inc.pos)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return inc, nil // Nothing committed since the last sync
+	}
+	if pageSize != inc.manifest.PageSize {
+		return nil, errWALReset
+	}
+	
+	payload, release, err := r.prepareUpload(frames)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	m := inc.manifest
+	key := incrementKey(m.Base, len(m.Increments)+1)
+	if err := r.uploadWithRetry(ctx, path, func() error {
+		return r.upload(ctx, path, key, payload)
+	}); err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+	r.countUpload(path, int64(len(payload)))
+	atomic.AddInt64(&r.stats.WALIncrements, 1)
+	
+	m.Increments = append(m.Increments[:len(m.Increments):len(m.Increments)], key)
+	next := &incrementalState{manifest: m, pos: pos, baseMTime: inc.baseMTime, baseSize: inc.baseSize}
+	
+	data, err := json.Marshal(m)
+	if err != nil {
+		return next, fmt.Errorf("encode manifest: %w", err)
+	}
+	if err := r.uploadWithRetry(ctx, path, func() error {
+		return r.upload(ctx, path, manifestKey(m.Base), data)
+	}); err != nil {
+		return next, fmt.Errorf("upload manifest: %w", err)
+	}
+	return next, nil
+}
+
+// readIncrementalBase reads a database with its committed WAL frames
+// applied. A read transaction is held throughout so the WAL can't be
+// restarted under it, and checkpoints only copy frames that are also
+// applied from the WAL. Returns the WAL position the data includes and the
+// database's page size.
+func (r *Replicator) readIncrementalBase(ctx context.Context, path string) ([]byte, walPosition, int, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, walPosition{}, 0, fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+	
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", r.s3Config.BusyTimeout.Milliseconds())); err != nil {
+		return nil, walPosition{}, 0, fmt.Errorf("set busy timeout: %w", err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, walPosition{}, 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+	
+	// BEGIN is deferred, so read something to take the snapshot
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&n); err != nil {
+		return nil, walPosition{}, 0, fmt.Errorf("read schema: %w", err)
+	}
+	
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, walPosition{}, 0, err
+	}
+	if len(data) < 100 {
+		return nil, walPosition{}, 0, fmt.Errorf("database header truncated")
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	
+	wal, err := os.ReadFile(path + "-wal")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, walPosition{}, 0, err
+	}
+	if len(wal) < walHeaderSize {
+		return data, walPosition{}, pageSize, nil // Empty or truncated by a checkpoint
+	}
+	
+	frames, pos, walPageSize, err := readWALFrames(wal, walPosition{})
+	if err != nil {
+		return nil, walPosition{}, 0, err
+	} else if walPageSize != pageSize {
+		return nil, walPosition{}, 0, fmt.Errorf("wal page size %d does not match database page size %d", walPageSize, pageSize)
+	}
+	if data, err = applyWALFrames(data, frames, pageSize); err != nil {
+		return nil, walPosition{}, 0, err
+	}
+	return data, pos, pageSize, nil
+}
+
+// readWALFrames returns the frames of a WAL file committed after from,
+// verifying each against the WAL's salt and running checksum, along with
+// the position after the last commit and the WAL's page size. A zero from
+// starts at the first frame. Frames after the last commit, torn frames, and
+// frames left over from an earlier WAL generation are not returned.
+func readWALFrames(wal []byte, from walPosition) ([]byte, walPosition, int, error) {
+	if len(wal) < walHeaderSize {
+		return nil, walPosition{}, 0, errWALReset
+	}
+	hdr := wal[:walHeaderSize]
+	
+	var bo binary.ByteOrder
+	switch binary.BigEndian.Uint32(hdr[0:4]) {
+	case walMagicLE:
+		bo = binary.LittleEndian
+	case walMagicBE:
+		bo = binary.BigEndian
+	default:
+		return nil, walPosition{}, 0, fmt.Errorf("invalid wal header")
+	}
+	pageSize := int(binary.BigEndian.Uint32(hdr[8:12]))
+	salt := binary.BigEndian.Uint64(hdr[16:24])
+	checksum := [2]uint32{binary.BigEndian.Uint32(hdr[24:28]), binary.BigEndian.Uint32(hdr[28:32])}
+	if walChecksum(bo, [2]uint32{}, hdr[:24]) != checksum {
+		return nil, walPosition{}, 0, fmt.Errorf("invalid wal header checksum")
+	}
+	
+	pos := walPosition{salt: salt, offset: walHeaderSize, checksum: checksum}
+	if from.salt != 0 {
+		if from.salt != salt || from.offset > int64(len(wal)) {
+			return nil, walPosition{}, 0, errWALReset
+		}
+		pos = from
+	}
+	start := pos.offset
+	
+	frameSize := int64(walFrameHeaderSize + pageSize)
+	for off, sum := pos.offset, pos.checksum; off+frameSize <= int64(len(wal)); off += frameSize {
+		fh := wal[off : off+walFrameHeaderSize]
+		if binary.BigEndian.Uint64(fh[8:16]) != salt {
+			break
+		}
+		sum = walChecksum(bo, sum, fh[:8])
+		sum = walChecksum(bo, sum, wal[off+walFrameHeaderSize:off+frameSize])
+		if sum != [2]uint32{binary.BigEndian.Uint32(fh[16:20]), binary.BigEndian.Uint32(fh[20:24])} {
+			break
+		}
+		if binary.BigEndian.Uint32(fh[4:8]) != 0 {
+			pos.offset, pos.checksum = off+frameSize, sum // Commit frame
+		}
+	}
+	return wal[start:pos.offset], pos, pageSize, nil
+}
+
+// walChecksum continues SQLite's WAL checksum over b, whose length must be
+// a multiple of 8
+func walChecksum(bo binary.ByteOrder, s [2]uint32, b []byte) [2]uint32 {
+	for i := 0; i+8 <= len(b); i += 8 {
+		s[0] += bo.Uint32(b[i:]) + s[1]
+		s[1] += bo.Uint32(b[i+4:]) + s[0]
+	}
+	return s
+}
+
+// applyWALFrames writes each frame's page into a database image, resizing
+// it to the page count of the last commit frame
+func applyWALFrames(data, frames []byte, pageSize int) ([]byte, error) {
+	frameSize := walFrameHeaderSize + pageSize
+	if len(frames)%frameSize != 0 {
+		return nil, fmt.Errorf("wal frames truncated")
+	}
+	
+	for off := 0; off < len(frames); off += frameSize {
+		fh := frames[off : off+walFrameHeaderSize]
+		pgno := int(binary.BigEndian.Uint32(fh[0:4]))
+		if pgno == 0 {
+			return nil, fmt.Errorf("invalid page number in wal frame")
+		}
+		data = resizeDatabase(data, max(len(data), pgno*pageSize))
+		copy(data[(pgno-1)*pageSize:], frames[off+walFrameHeaderSize:off+frameSize])
+		
+		if commit := int(binary.BigEndian.Uint32(fh[4:8])); commit != 0 {
+			data = resizeDatabase(data, commit*pageSize)
+		}
+	}
+	return data, nil
+}
+
+// resizeDatabase truncates or zero-extends a database image to size bytes
+func resizeDatabase(data []byte, size int) []byte {
+	if size <= len(data) {
+		return data[:size]
+	}
+	return append(data, make([]byte, size-len(data))...)
+}
+
+// incrementKey returns the key of the nth WAL increment of a base backup
+func incrementKey(base string, n int) string {
+	i := strings.LastIndex(base, backupExtension)
+	return fmt.Sprintf("%s%s%06d%s", base[:i], walIncrementExtension, n, base[i:])
+}
+
+// manifestKey returns the key of the manifest of a base backup
+func manifestKey(base string) string {
+	return base[:strings.LastIndex(base, backupExtension)] + manifestExtension
+}
+
+// applyManifest downloads a base backup's manifest and applies the
+// increments it lists to the restored base data. A manifest written for a
+// different upload of the base is ignored.
+func (r *Replicator) applyManifest(ctx context.Context, key, etag string, data []byte) ([]byte, error) {
+	raw, err := r.s3Client.Download(ctx, manifestKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", manifestKey(key), err)
+	}
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", manifestKey(key), err)
+	}
+	if m.Base != key || m.BaseETag != etag {
+		return data, nil // Stale: the base was uploaded again since
+	}
+	
+	for _, inc := range m.Increments {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		frames, _, err := r.downloadBackup(ctx, inc)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = applyWALFrames(data, frames, m.PageSize); err != nil {
+			return nil, fmt.Errorf("apply %s: %w", inc, err)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,176 @@
+package ultrasimple
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestReplicatorIncremental(t *testing.T) {
+	// openWAL opens a WAL-mode database and keeps it open, as incremental
+	// mode requires
+	openWAL := func(t *testing.T, path string) *sql.DB {
+		db, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { db.Close() })
+		
+		for _, q := range []string{
+			"PRAGMA journal_mode=WAL",
+			"CREATE TABLE test (id INTEGER, data TEXT)",
+		} {
+			if _, err := db.Exec(q); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return db
+	}
+	
+	insert := func(t *testing.T, db *sql.DB, n int) {
+		for i := 0; i < n; i++ {
+			if _, err := db.Exec("INSERT INTO test (data) VALUES (?)", strings.Repeat("x", 100)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	
+	// restoredRows restores the newest backup and counts its rows
+	restoredRows := func(t *testing.T, r *Replicator, dbPath string) int {
+		dest := filepath.Join(t.TempDir(), "restored.db")
+		if err := r.RestoreLatest(context.Background(), dbPath, dest); err != nil {
+			t.Fatalf("RestoreLatest: %v", err)
+		}
+		db, err := sql.Open("sqlite3", dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM test").Scan(&n); err != nil {
+			t.Fatalf("query restored database: %v", err)
+		}
+		return n
+	}
+	
+	t.Run("UploadsIncrements", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		dbPath := filepath.Join(tmpDir, "test.db")
+		db := openWAL(t, dbPath)
+		insert(t, db, 200)
+		
+		s3Client := NewMockS3Client()
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups", Mode: ModeIncremental}, s3Client)
+		
+		r.scanAndSync(context.Background())
+		if n := s3Client.GetUploadCount(); n != 1 {
+			t.Fatalf("Expected base upload, got %d uploads", n)
+		}
+		base := r.generateS3Key(dbPath)
+		
+		insert(t, db, 1)
+		r.scanAndSync(context.Background())
+		
+		uploads := s3Client.GetUploads()
+		inc, ok := uploads[incrementKey(base, 1)]
+		if !ok {
+			t.Fatalf("Expected increment %s, got %v", incrementKey(base, 1), uploads)
+		}
+		if _, ok := uploads[manifestKey(base)]; !ok {
+			t.Fatal("Expected manifest upload")
+		}
+		if len(inc) >= len(uploads[base]) {
+			t.Errorf("Increment (%d bytes) should be smaller than base (%d bytes)", len(inc), len(uploads[base]))
+		}
+		if stats := r.GetStats(); stats.WALIncrements != 1 {
+			t.Errorf("Expected 1 WAL increment, got %d", stats.WALIncrements)
+		}
+		
+		if n := restoredRows(t, r, dbPath); n != 201 {
+			t.Errorf("Expected 201 restored rows, got %d", n)
+		}
+		
+		// A sync without new commits uploads nothing
+		count := s3Client.GetUploadCount()
+		r.scanAndSync(context.Background())
+		if n := s3Client.GetUploadCount(); n != count {
+			t.Errorf("Expected no uploads without commits, got %d", n-count)
+		}
+	})
+	
+	t.Run("RebasesAfterCheckpoint", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		dbPath := filepath.Join(tmpDir, "test.db")
+		db := openWAL(t, dbPath)
+		insert(t, db, 10)
+		
+		s3Client := NewMockS3Client()
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups", Mode: ModeIncremental}, s3Client)
+		
+		r.scanAndSync(context.Background())
+		insert(t, db, 5)
+		r.scanAndSync(context.Background())
+		
+		// Frames committed before a checkpoint restarts the WAL are lost to
+		// increments, so the next sync uploads a new base
+		insert(t, db, 5)
+		if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			t.Fatal(err)
+		}
+		insert(t, db, 3)
+		r.scanAndSync(context.Background())
+		
+		if stats := r.GetStats(); stats.WALIncrements != 1 {
+			t.Errorf("Expected no increment after the WAL reset, got %d", stats.WALIncrements)
+		}
+		if n := restoredRows(t, r, dbPath); n != 23 {
+			t.Errorf("Expected 23 restored rows from the new base, got %d", n)
+		}
+	})
+	
+	t.Run("IgnoresTornFrames", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		dbPath := filepath.Join(tmpDir, "test.db")
+		db := openWAL(t, dbPath)
+		insert(t, db, 1)
+		
+		wal, err := os.ReadFile(dbPath + "-wal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames, pos, pageSize, err := readWALFrames(wal, walPosition{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frames) == 0 || pos.offset != int64(len(wal)) {
+			t.Fatalf("Expected all %d WAL bytes committed, got offset %d", len(wal), pos.offset)
+		}
+		
+		// Corrupting the last frame drops its whole transaction
+		torn := bytes.Clone(wal)
+		torn[len(torn)-1] ^= 0xff
+		tornFrames, tornPos, _, err := readWALFrames(torn, walPosition{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tornPos.offset >= pos.offset || len(tornFrames) >= len(frames) {
+			t.Errorf("Expected torn transaction excluded, got offset %d of %d", tornPos.offset, pos.offset)
+		}
+		if len(tornFrames)%(walFrameHeaderSize+pageSize) != 0 {
+			t.Errorf("Expected whole frames, got %d bytes", len(tornFrames))
+		}
+		
+		// Reading on from a position in another WAL generation is a reset
+		if _, _, _, err := readWALFrames(wal, walPosition{salt: pos.salt + 1, offset: walHeaderSize}); err != errWALReset {
+			t.Errorf("Expected errWALReset, got %v", err)
+		}
+	})
+}
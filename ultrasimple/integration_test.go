@@ -52,7 +52,7 @@ func TestIntegrationScenario(t *testing.T) {
 	r := New(pattern, config, s3Client)
 	
 	// Initial scan - all databases should be uploaded
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	
 	if r.GetDatabaseCount() != dbCount {
 		t.Errorf("Expected %d databases, got %d", dbCount, r.GetDatabaseCount())
@@ -79,7 +79,7 @@ func TestIntegrationScenario(t *testing.T) {
 	
 	// Second scan - no changes, no uploads
 	initialCount := s3Client.GetUploadCount()
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	if s3Client.GetUploadCount() != initialCount {
 		t.Error("Uploaded unchanged databases")
 	}
@@ -106,7 +106,7 @@ func TestIntegrationScenario(t *testing.T) {
 	
 	// Third scan - should only upload modified databases
 	beforeModified := s3Client.GetUploadCount()
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	finalUploads := s3Client.GetUploadCount()
 	// Expect 2 uploads per modified database
 	// Backups might overwrite if in the same hour
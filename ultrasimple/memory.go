@@ -0,0 +1,223 @@
+package ultrasimple
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryS3Client is an S3Client that keeps objects in memory, for testing
+// code that uses a Replicator without a real bucket. It also implements
+// TaggingS3Client, StorageClassS3Client, DetailedListS3Client, and
+// ETagS3Client, recording tags and storage classes so tests can check them.
+// It is safe for concurrent use.
+type MemoryS3Client struct {
+	mu      sync.Mutex
+	objects map[string]memoryObject
+}
+
+// memoryObject is an object stored by a MemoryS3Client
+type memoryObject struct {
+	data         []byte
+	tags         map[string]string
+	storageClass string
+	lastModified time.Time
+}
+
+// NewMemoryS3Client returns an empty MemoryS3Client
+func NewMemoryS3Client() *MemoryS3Client {
+	return &MemoryS3Client{objects: make(map[string]memoryObject)}
+}
+
+// Upload stores a copy of data at key, replacing any existing object
+func (c *MemoryS3Client) Upload(ctx context.Context, key string, data []byte) error {
+	return c.UploadWithStorageClass(ctx, key, data, "", nil)
+}
+
+// UploadWithTags stores a copy of data at key along with its tags
+func (c *MemoryS3Client) UploadWithTags(ctx context.Context, key string, data []byte, tags map[string]string) error {
+	return c.UploadWithStorageClass(ctx, key, data, "", tags)
+}
+
+// UploadWithStorageClass stores a copy of data at key along with its
+// storage class and tags
+func (c *MemoryS3Client) UploadWithStorageClass(ctx context.Context, key string, data []byte, storageClass string, tags map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	
+	obj := memoryObject{
+		data:         append([]byte{}, data...),
+		storageClass: storageClass,
+		lastModified: time.Now(),
+	}
+	if len(tags) > 0 {
+		obj.tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			obj.tags[k] = v
+		}
+	}
+	
+	c.mu.Lock()
+	c.objects[key] = obj
+	c.mu.Unlock()
+	return nil
+}
+
+// Download returns a copy of the object at key. The error wraps
+// ErrObjectNotFound if there is none.
+func (c *MemoryS3Client) Download(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	obj, ok := c.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("download %s: %w", key, ErrObjectNotFound)
+	}
+	return append([]byte{}, obj.data...), nil
+}
+
+// List returns the keys starting with prefix in lexical order, as S3 does
+func (c *MemoryS3Client) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.keys(prefix), nil
+}
+
+// ListDetailed returns the keys starting with prefix in lexical order with
+// the time each object was last uploaded
+func (c *MemoryS3Client) ListDetailed(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	var infos []ObjectInfo
+	for key, obj := range c.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, ObjectInfo{Key: key, LastModified: obj.lastModified})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos, nil
+}
+
+// Delete removes the objects at keys. Keys with no object are ignored, as
+// S3 ignores them.
+func (c *MemoryS3Client) Delete(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	for _, key := range keys {
+		delete(c.objects, key)
+	}
+	return nil
+}
+
+// ETag returns the hex MD5 of the object at key, which is the ETag S3
+// reports for a single-part upload
+func (c *MemoryS3Client) ETag(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	obj, ok := c.objects[key]
+	if !ok {
+		return "", fmt.Errorf("etag %s: %w", key, ErrObjectNotFound)
+	}
+	return md5Hex(obj.data), nil
+}
+
+// Object returns a copy of the object at key and whether it exists
+func (c *MemoryS3Client) Object(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	obj, ok := c.objects[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte{}, obj.data...), true
+}
+
+// Put stores data at key without tags or a storage class, for seeding a
+// bucket or corrupting a backup in tests
+func (c *MemoryS3Client) Put(key string, data []byte) {
+	c.Upload(context.Background(), key, data)
+}
+
+// Tags returns a copy of the tags of the object at key, or nil if it has
+// none or doesn't exist
+func (c *MemoryS3Client) Tags(key string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	obj, ok := c.objects[key]
+	if !ok || obj.tags == nil {
+		return nil
+	}
+	tags := make(map[string]string, len(obj.tags))
+	for k, v := range obj.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// StorageClass returns the storage class the object at key was uploaded
+// into, or "" if none was given or it doesn't exist
+func (c *MemoryS3Client) StorageClass(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.objects[key].storageClass
+}
+
+// Keys returns every key in lexical order
+func (c *MemoryS3Client) Keys() []string {
+	return c.keys("")
+}
+
+// Len returns the number of stored objects
+func (c *MemoryS3Client) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.objects)
+}
+
+// Reset removes every object
+func (c *MemoryS3Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects = make(map[string]memoryObject)
+}
+
+// keys returns the keys starting with prefix in lexical order
+func (c *MemoryS3Client) keys(prefix string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	var keys []string
+	for key := range c.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package ultrasimple
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryS3Client(t *testing.T) {
+	t.Run("Replicator", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		dbPath := filepath.Join(tmpDir, "acme", "databases", "users", "branches", "main", "tenants", "t1.db")
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		createTestDB(t, dbPath, "CREATE TABLE test (id INTEGER)")
+		
+		s3Client := NewMemoryS3Client()
+		config := S3Config{
+			PathTemplate: "backups",
+			StorageClass: "STANDARD_IA",
+			Tags:         map[string]string{"env": "test"},
+		}
+		r := New(filepath.Join(tmpDir, "*", "databases", "*", "branches", "*", "tenants", "*.db"), config, s3Client)
+		r.scanAndSync(context.Background())
+		
+		keys := s3Client.Keys()
+		if len(keys) != 1 {
+			t.Fatalf("Expected 1 backup, got %v", keys)
+		}
+		if got := s3Client.StorageClass(keys[0]); got != "STANDARD_IA" {
+			t.Errorf("Expected STANDARD_IA storage class, got %q", got)
+		}
+		if tags := s3Client.Tags(keys[0]); tags["env"] != "test" || tags["tenant"] != "t1" {
+			t.Errorf("Expected static and path tags, got %v", tags)
+		}
+		
+		original, _ := os.ReadFile(dbPath)
+		var buf bytes.Buffer
+		if err := r.Restore(context.Background(), dbPath, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), original) {
+			t.Error("Restored database does not match")
+		}
+		
+		// ETags match what was uploaded, so an audit finds nothing to repair
+		report, err := r.AuditAndRepair(context.Background(), 1.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Checked != 1 || len(report.Mismatched) != 0 {
+			t.Errorf("Expected 1 clean check, got %+v", report)
+		}
+	})
+	
+	t.Run("Objects", func(t *testing.T) {
+		ctx := context.Background()
+		s3Client := NewMemoryS3Client()
+		s3Client.Put("b/2", []byte("two"))
+		s3Client.Put("a/1", []byte("one"))
+		s3Client.Put("b/1", []byte("one"))
+		
+		keys, err := s3Client.List(ctx, "b/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(keys, []string{"b/1", "b/2"}) {
+			t.Errorf("Expected sorted keys under b/, got %v", keys)
+		}
+		
+		// Stored data is copied both ways
+		data, _ := s3Client.Download(ctx, "a/1")
+		data[0] = 'X'
+		if got, _ := s3Client.Object("a/1"); string(got) != "one" {
+			t.Errorf("Expected stored object unchanged, got %q", got)
+		}
+		
+		if err := s3Client.Delete(ctx, []string{"a/1", "missing"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s3Client.Download(ctx, "a/1"); !errors.Is(err, ErrObjectNotFound) || !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected ErrObjectNotFound for a deleted object, got %v", err)
+		}
+		if n := s3Client.Len(); n != 2 {
+			t.Errorf("Expected 2 objects, got %d", n)
+		}
+		
+		s3Client.Reset()
+		if n := s3Client.Len(); n != 0 {
+			t.Errorf("Expected no objects after reset, got %d", n)
+		}
+	})
+}
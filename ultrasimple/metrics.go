@@ -0,0 +1,49 @@
+package ultrasimple
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stages of an upload recorded in the upload duration histogram
+const (
+	// StageCompress is compressing and encrypting a buffered upload
+	StageCompress = "compress"
+
+	// StageUpload is one attempt at sending an object to S3. Streamed
+	// uploads compress while they send, so their compression time is
+	// included here.
+	StageUpload = "upload"
+)
+
+// metrics are the Prometheus collectors exported by RegisterMetrics. They
+// are created with the replicator so uploads can record into them whether
+// or not they are registered.
+type metrics struct {
+	uploadDuration *prometheus.HistogramVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		uploadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "ultrasimple",
+			Name:      "upload_duration_seconds",
+			Help:      "Time spent per upload stage (compress or upload)",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14), // 5ms to ~41s
+		}, []string{"stage"}),
+	}
+}
+
+// RegisterMetrics registers the replicator's collectors with reg. Upload
+// latency percentiles come from the histogram, e.g.
+//
+//	histogram_quantile(0.99, sum by (le) (rate(ultrasimple_upload_duration_seconds_bucket{stage="upload"}[5m])))
+func (r *Replicator) RegisterMetrics(reg prometheus.Registerer) error {
+	return reg.Register(r.metrics.uploadDuration)
+}
+
+// observeStage records how long an upload stage took since start
+func (r *Replicator) observeStage(stage string, start time.Time) {
+	r.metrics.uploadDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+}
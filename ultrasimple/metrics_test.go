@@ -0,0 +1,83 @@
+package ultrasimple
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestReplicatorUploadDurationMetrics(t *testing.T) {
+	// stageCounts returns the number of observations per stage
+	stageCounts := func(t *testing.T, reg *prometheus.Registry) map[string]uint64 {
+		t.Helper()
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts := make(map[string]uint64)
+		for _, mf := range families {
+			if mf.GetName() != "ultrasimple_upload_duration_seconds" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, label := range m.GetLabel() {
+					if label.GetName() == "stage" {
+						counts[label.GetValue()] = m.GetHistogram().GetSampleCount()
+					}
+				}
+			}
+		}
+		return counts
+	}
+
+	t.Run("Buffered", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		createTestDB(t, filepath.Join(tmpDir, "a.db"), "CREATE TABLE test (id INTEGER)")
+		createTestDB(t, filepath.Join(tmpDir, "b.db"), "CREATE TABLE test (id INTEGER)")
+
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, NewMockS3Client())
+		reg := prometheus.NewRegistry()
+		if err := r.RegisterMetrics(reg); err != nil {
+			t.Fatal(err)
+		}
+		r.scanAndSync(context.Background())
+
+		counts := stageCounts(t, reg)
+		if counts[StageCompress] != 2 || counts[StageUpload] != 2 {
+			t.Errorf("expected 2 compress and 2 upload observations, got %v", counts)
+		}
+	})
+
+	t.Run("Streamed", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		createTestDB(t, filepath.Join(tmpDir, "a.db"), "CREATE TABLE test (id INTEGER)")
+
+		s3Client := &streamingMockS3Client{MockS3Client: NewMockS3Client()}
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+		reg := prometheus.NewRegistry()
+		if err := r.RegisterMetrics(reg); err != nil {
+			t.Fatal(err)
+		}
+		r.scanAndSync(context.Background())
+
+		// Streamed uploads compress while sending, so only the upload stage is recorded
+		counts := stageCounts(t, reg)
+		if counts[StageCompress] != 0 || counts[StageUpload] != 1 {
+			t.Errorf("expected only 1 upload observation, got %v", counts)
+		}
+	})
+
+	t.Run("RegisterTwice", func(t *testing.T) {
+		r := New("*.db", S3Config{}, NewMockS3Client())
+		reg := prometheus.NewRegistry()
+		if err := r.RegisterMetrics(reg); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.RegisterMetrics(reg); err == nil {
+			t.Error("expected error registering the same collectors twice")
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package ultrasimple
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// PurgeDatabase deletes every backup of an offboarded database and stops
+// tracking it. The database file should be removed first, or the next scan
+// discovers and uploads it again. Scans are paused while the purge runs so
+// an in-flight upload can't recreate a deleted object.
+func (r *Replicator) PurgeDatabase(ctx context.Context, dbPath string) error {
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+	
+	start := time.Now()
+	dbPath = filepath.Clean(dbPath)
+	
+	keys, err := r.backupKeys(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	
+	deleted, err := r.deleteKeys(ctx, keys)
+	if err != nil {
+		return fmt.Errorf("delete backups: %w", err)
+	}
+	
+	r.mu.Lock()
+	delete(r.databases, dbPath)
+	r.mu.Unlock()
+	
+	r.uploadsMu.Lock()
+	delete(r.uploads, dbPath)
+	r.uploadsMu.Unlock()
+	
+	slog.Info("database purged", "path", dbPath, "deleted", deleted, "duration", time.Since(start))
+	return nil
+}
+
+// PurgeDatabaseDryRun returns the keys PurgeDatabase would delete for a
+// database without deleting anything
+func (r *Replicator) PurgeDatabaseDryRun(ctx context.Context, dbPath string) ([]string, error) {
+	return r.backupKeys(ctx, filepath.Clean(dbPath))
+}
+
+// backupKeys lists every backup object of a database, including WAL
+// increments and manifests. Keys of other databases sharing the prefix
+// (e.g. "app-2" for "app") are skipped since their remainder doesn't parse
+// as a timestamp.
+func (r *Replicator) backupKeys(ctx context.Context, dbPath string) ([]string, error) {
+	prefix := r.keyPrefix(dbPath)
+	
+	if err := r.waitLimiter(ctx); err != nil {
+		return nil, err
+	}
+	keys, err := r.s3Client.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", prefix, err)
+	}
+	
+	var matched []string
+	for _, key := range keys {
+		if _, ok := parseWindowTimestamp(key, prefix); ok {
+			matched = append(matched, key)
+		}
+	}
+	return matched, nil
+}
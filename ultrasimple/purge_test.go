@@ -0,0 +1,57 @@
+package ultrasimple
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestReplicatorPurgeDatabase(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := filepath.Join(tmpDir, "app.db")
+	other := filepath.Join(tmpDir, "app-2.db")
+	createTestDB(t, app, "CREATE TABLE test (id INTEGER)")
+	createTestDB(t, other, "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := NewMockS3Client()
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+	r.scanAndSync(context.Background())
+	
+	// A backup of app from an earlier window, with an incremental-mode
+	// increment and manifest
+	old := r.keyPrefix(app) + "20240101-000000" + backupExtension + ".lz4"
+	s3Client.Upload(context.Background(), old, []byte("old"))
+	s3Client.Upload(context.Background(), incrementKey(old, 1), []byte("frames"))
+	s3Client.Upload(context.Background(), manifestKey(old), []byte("{}"))
+	
+	keys, err := r.PurgeDatabaseDryRun(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 4 {
+		t.Fatalf("Expected 4 keys, got %v", keys)
+	}
+	if s3Client.GetUploadCount() != 5 {
+		t.Fatalf("Dry run should not delete anything, have %d objects", s3Client.GetUploadCount())
+	}
+	
+	if err := r.PurgeDatabase(context.Background(), app); err != nil {
+		t.Fatal(err)
+	}
+	
+	// Only app-2's backup is left
+	var remaining []string
+	for key := range s3Client.GetUploads() {
+		remaining = append(remaining, key)
+	}
+	sort.Strings(remaining)
+	if len(remaining) != 1 || filepath.Base(remaining[0])[:6] != "app-2-" {
+		t.Errorf("Expected only app-2 backup to remain, got %v", remaining)
+	}
+	if _, ok := r.GetDatabaseStatus(app); ok {
+		t.Error("Purged database should no longer be tracked")
+	}
+	if _, ok := r.GetDatabaseStatus(other); !ok {
+		t.Error("Other database should still be tracked")
+	}
+}
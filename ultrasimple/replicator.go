@@ -2,30 +2,73 @@ package ultrasimple
 
 import (
 	"context"
+	"crypto/md5"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"hash/crc32"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/time/rate"
 )
 
 // Replicator handles multi-database replication with ultra-simple design
 type Replicator struct {
-	pattern   string
+	patterns  []string
 	s3Config  S3Config
 	databases map[string]*DatabaseState
 	
-	s3Client  S3Client
-	uploadSem chan struct{}
+	s3Client    S3Client
+	uploadSlots *uploadSlots  // Bounds concurrent uploads to s3Config.MaxConcurrent
+	limiter     *rate.Limiter // Paces S3 requests; nil if unlimited
 	
-	stats Stats
-	mu    sync.RWMutex
+	stats   Stats
+	metrics *metrics
+	
+	// Uploads and bytes per project parsed from the database path
+	projects   map[string]*ProjectStat
+	projectsMu sync.Mutex
+	
+	lastCleanup int64 // UnixNano of the last completed cleanup; accessed atomically
+	
+	// Settings Reconfigure can change while running: scanInterval, and
+	// s3Config.MaxConcurrent and RetentionDays
+	settingsMu   sync.Mutex
+	scanInterval time.Duration // Set by Run, to spread scans over
+	reconfigured chan struct{} // Wakes Run to reset its ticker
+	
+	// Reused compression buffers (*[]byte) for databases up to
+	// CompressionBufferSize
+	compressBufs sync.Pool
+	mu      sync.RWMutex // Protects databases
+	scanMu sync.Mutex   // Serializes scans
+	
+	// Most recent upload per database, used by AuditAndRepair
+	uploads   map[string]uploadRecord
+	uploadsMu sync.Mutex
+	
+	// Blobs uploaded, found, or being uploaded in dedup mode
+	blobs   map[string]*blobUpload
+	blobsMu sync.Mutex
+}
+
+// uploadRecord is what was last uploaded for a database
+type uploadRecord struct {
+	Key  string
+	ETag string // hex MD5 of the uploaded bytes
 }
 
 // DatabaseState tracks a single database
@@ -33,7 +76,13 @@ type DatabaseState struct {
 	Path         string
 	LastModTime  time.Time
 	LastSize     int64
-	LastSyncTime time.Time
+	LastSyncTime time.Time // When the database was last uploaded successfully
+	Checksum     uint32    // CRC-32 of the last uploaded contents (checksum mode only)
+	LastError    error     // Why the most recent sync failed; nil once it succeeds
+	TooLarge     bool      // Larger than MaxDatabaseSize, so not being uploaded
+	
+	changedAt   time.Time         // When the last change was detected, whether or not it uploaded
+	incremental *incrementalState // Current base and WAL position in incremental mode
 }
 
 // S3Config holds S3 configuration
@@ -43,47 +92,299 @@ type S3Config struct {
 	PathTemplate  string
 	MaxConcurrent int
 	RetentionDays int // Number of days to retain backups (default 30)
+	
+	// RetentionPolicy compacts backups older than its Hourly window to
+	// one per day, on the same hourly schedule as retention cleanup. The
+	// zero value keeps every backup until RetentionDays.
+	RetentionPolicy RetentionPolicy
+	
+	// MaxUploadsPerSecond paces uploads, and the List and Delete calls of
+	// retention cleanup, to stay under S3 per-prefix request limits when a
+	// scan finds many changed databases. Requests are spaced evenly rather
+	// than sent in bursts. Zero means no limit.
+	MaxUploadsPerSecond float64
+	
+	// MaxDatabaseSize skips uploading databases larger than this many
+	// bytes, since each upload reads the whole file into memory. Zero
+	// means no limit.
+	MaxDatabaseSize int64
+	
+	// Mode selects what each sync uploads. ModeFull (default) checkpoints
+	// the WAL and uploads the whole database. ModeIncremental uploads a
+	// full base on the first sync of each backup window and afterwards only
+	// the WAL frames committed since the last sync, listed in a manifest
+	// that restores replay on top of the base. Incremental mode never
+	// checkpoints, uses the buffered upload path, and ignores
+	// ChangeDetection.
+	Mode string
+	
+	// ReadMode selects how a consistent copy of a database is read for
+	// upload. ReadModeCheckpoint (default) checkpoints the WAL into the
+	// database file and reads that, which writes to the live database and
+	// can contend with the application's writer. ReadModeBackup opens the
+	// database read-only and copies it with VACUUM INTO a temporary file,
+	// leaving the database and its WAL untouched; the copy needs free space
+	// in os.TempDir() and is rebuilt, so it isn't byte-identical to the
+	// original. Incremental mode ignores ReadMode.
+	ReadMode string
+	
+	// Dedup stores database contents once per distinct SHA-256, as blobs
+	// under "blobs/" shared by every database. Each backup key then holds
+	// a small pointer to its blob, so databases cloned from a template
+	// cost one upload between them. Dedup uses the buffered upload path
+	// and applies to ModeFull only.
+	Dedup bool
+	
+	// CompressionBufferSize is the largest database, in bytes, compressed
+	// into a pooled buffer (default 16MB). Larger databases get a fresh
+	// buffer per upload so the pool never pins oversized allocations.
+	// Only compressors implementing BufferCompressor use the pool.
+	CompressionBufferSize int
+	
+	// BackupGranularity is the window each backup key covers (default
+	// time.Hour). Keys are stamped with the end of the current window, so
+	// each database keeps at most one object per window and later uploads
+	// in the same window overwrite it. Use time.Minute for critical
+	// databases or 24*time.Hour for daily backups.
+	BackupGranularity time.Duration
+	
+	// MaxRetries is how many times a failed upload is retried before it is
+	// counted as an error. Retries back off exponentially from RetryBackoff
+	// (default 500ms) with jitter, and hold the upload slot while waiting.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	
+	// ChangeDetection selects how changed databases are detected:
+	// ChangeDetectionMTime (default) uploads whenever size or mtime moves,
+	// ChangeDetectionChecksum additionally skips uploads whose contents
+	// hash the same as the last upload.
+	ChangeDetection string
+	
+	// Compressor compresses uploads and sets the key extension
+	// (default LZ4Compressor)
+	Compressor Compressor
+	
+	// Encryptor, if set, encrypts compressed uploads and adds ".enc" to
+	// their keys. It must also implement Decryptor to restore them.
+	Encryptor Encryptor
+	
+	// AuditInterval enables a periodic AuditAndRepair pass when non-zero,
+	// checking AuditSampleRate of databases each time (default 0.01)
+	AuditInterval   time.Duration
+	AuditSampleRate float64
+	
+	// SpreadScans checks each database at its own offset within the Run
+	// interval, derived from a hash of its path, instead of checking every
+	// database as soon as the scan finds it. Uploads and S3 requests are
+	// smoothed out while each database is still checked once per interval.
+	// A scan then takes about one interval, during which PurgeDatabase
+	// and CleanupBlobs wait.
+	SpreadScans bool
+	
+	// ScanWorkers is how many top-level directories of each pattern are
+	// walked concurrently during a scan (default 16)
+	ScanWorkers int
+	
+	// ExcludePatterns are globs whose matches are never tracked or
+	// uploaded. A pattern without a separator matches file names in any
+	// directory. SQLite -wal, -shm, and -journal files are always skipped.
+	ExcludePatterns []string
+	
+	// StorageClass is the S3 storage class of uploads, such as STANDARD_IA
+	// or GLACIER_IR for rarely restored backups. Blank uses the bucket
+	// default (STANDARD). It is only sent if the S3Client implements
+	// StorageClassS3Client, and uploads use the buffered path when set.
+	StorageClass string
+	
+	// Tags are static object tags (e.g. environment) added to every upload
+	// alongside the project/database/branch/tenant parsed from the path.
+	// Tags are only sent if the S3Client implements TaggingS3Client.
+	Tags map[string]string
+	
+	// BusyTimeout is how long a WAL checkpoint waits for locks held by the
+	// application before giving up with SQLITE_BUSY (default 5s)
+	BusyTimeout time.Duration
 }
 
 // S3Client interface for testing
 type S3Client interface {
-	Upload(key string, data []byte) error
-	List(prefix string) ([]string, error)
-	Delete(keys []string) error
+	Upload(ctx context.Context, key string, data []byte) error
+	Download(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, keys []string) error
 }
 
+// ErrObjectNotFound is wrapped by the error an S3Client returns from
+// Download for a key with no object, so callers can tell a missing backup
+// from a failed request. It matches fs.ErrNotExist as well.
+var ErrObjectNotFound = fmt.Errorf("object not found: %w", fs.ErrNotExist)
+
+// TaggingS3Client is an S3Client that can attach object tags to uploads
+type TaggingS3Client interface {
+	S3Client
+	UploadWithTags(ctx context.Context, key string, data []byte, tags map[string]string) error
+}
+
+// StorageClassS3Client is an S3Client that can upload into a chosen S3
+// storage class. It is used instead of Upload and UploadWithTags when
+// S3Config.StorageClass is set, and should also apply tags if it can.
+type StorageClassS3Client interface {
+	S3Client
+	UploadWithStorageClass(ctx context.Context, key string, data []byte, storageClass string, tags map[string]string) error
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	LastModified time.Time // Zero if unknown
+}
+
+// DetailedListS3Client is an S3Client that can list objects with their
+// last modified time. Retention cleanup then ages backups by when they were
+// written rather than by the timestamp in their key.
+type DetailedListS3Client interface {
+	S3Client
+	ListDetailed(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// Read modes for S3Config.ReadMode
+const (
+	ReadModeCheckpoint = "checkpoint"
+	ReadModeBackup     = "backup"
+)
+
+// Change detection modes for S3Config.ChangeDetection
+const (
+	ChangeDetectionMTime    = "mtime"
+	ChangeDetectionChecksum = "checksum"
+)
+
+// backupTimestampFormat is the layout of the timestamp embedded in backup keys
+const backupTimestampFormat = "20060102-150405"
+
+// spreadSlots is how many parts the scan interval is divided into when
+// S3Config.SpreadScans is set
+const spreadSlots = 16
+
+// backupExtension precedes the compressor's extension in every backup key
+const backupExtension = ".db"
+
 // Stats tracks replication statistics
 type Stats struct {
-	Scans         int64
-	Uploads       int64
-	UploadErrors  int64
+	Scans           int64
+	Uploads         int64
+	UploadErrors    int64
+	BytesUploaded   int64
+	RetriedUploads  int64 // Uploads that failed at least once before succeeding
+	SkippedUploads  int64 // Changed databases whose checksum matched the last upload
+	SkippedTooLarge int64 // Changed databases not uploaded for exceeding MaxDatabaseSize
+	WALIncrements   int64 // WAL increments uploaded in incremental mode, also counted in Uploads
+	DedupedUploads  int64 // Uploads in dedup mode whose blob already existed, so only a pointer was sent
+	
+	// Retention cleanup. A cleanup that stops deleting while CleanupRuns
+	// grows points at a retention or permission problem.
+	CleanupRuns     int64     // Cleanups started
+	ObjectsDeleted  int64     // Expired or compacted backups deleted by cleanup
+	CleanupErrors   int64     // Cleanups that failed to list or to delete some backups
+	LastCleanupTime time.Time // When the last cleanup that listed the bucket finished
+	
+	// RateLimitWait is the total time uploads spent waiting on
+	// MaxUploadsPerSecond; if it grows quickly the limit is too low
+	RateLimitWait time.Duration
+}
+
+// ProjectStat summarizes one project's databases, the project being parsed
+// from database paths as for the {{project}} key template variable
+type ProjectStat struct {
+	Databases     int   // Databases currently tracked
+	Uploads       int64 // Uploads since the replicator started
 	BytesUploaded int64
 }
 
-// New creates a new ultra-simple replicator
+// New creates a new ultra-simple replicator for a single discovery pattern
 func New(pattern string, config S3Config, s3Client S3Client) *Replicator {
+	return NewMulti([]string{pattern}, config, s3Client)
+}
+
+// NewMulti creates a replicator that discovers databases from several glob
+// patterns. A database matched by more than one pattern is tracked once.
+func NewMulti(patterns []string, config S3Config, s3Client S3Client) *Replicator {
 	if config.MaxConcurrent == 0 {
 		config.MaxConcurrent = 100
 	}
 	if config.RetentionDays == 0 {
 		config.RetentionDays = 30
 	}
+	if config.BackupGranularity == 0 {
+		config.BackupGranularity = time.Hour
+	}
+	if config.ScanWorkers == 0 {
+		config.ScanWorkers = 16
+	}
+	if config.Compressor == nil {
+		config.Compressor = LZ4Compressor{}
+	}
+	if config.Mode == "" {
+		config.Mode = ModeFull
+	}
+	if config.ReadMode == "" {
+		config.ReadMode = ReadModeCheckpoint
+	}
+	if config.ChangeDetection == "" {
+		config.ChangeDetection = ChangeDetectionMTime
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = 500 * time.Millisecond
+	}
+	if config.AuditSampleRate == 0 {
+		config.AuditSampleRate = 0.01
+	}
+	if config.BusyTimeout == 0 {
+		config.BusyTimeout = 5 * time.Second
+	}
+	if config.CompressionBufferSize == 0 {
+		config.CompressionBufferSize = 16 << 20
+	}
+	
+	var limiter *rate.Limiter
+	if config.MaxUploadsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(config.MaxUploadsPerSecond), 1)
+	}
 	
 	return &Replicator{
-		pattern:   pattern,
-		s3Config:  config,
-		databases: make(map[string]*DatabaseState),
-		s3Client:  s3Client,
-		uploadSem: make(chan struct{}, config.MaxConcurrent),
+		patterns:     patterns,
+		s3Config:     config,
+		databases:    make(map[string]*DatabaseState),
+		s3Client:     s3Client,
+		uploadSlots:  newUploadSlots(config.MaxConcurrent),
+		limiter:      limiter,
+		uploads:      make(map[string]uploadRecord),
+		blobs:        make(map[string]*blobUpload),
+		projects:     make(map[string]*ProjectStat),
+		metrics:      newMetrics(),
+		reconfigured: make(chan struct{}, 1),
 	}
 }
 
 // Run starts the replication loop
 func (r *Replicator) Run(ctx context.Context, interval time.Duration) error {
-	log.Printf("Starting ultra-simple replicator (interval: %v, retention: %d days)", interval, r.s3Config.RetentionDays)
+	slog.Info("ultra-simple replicator started",
+		"interval", interval,
+		"granularity", r.s3Config.BackupGranularity,
+		"retention_days", r.retentionDays())
+	if time.Duration(r.retentionDays())*24*time.Hour < r.s3Config.BackupGranularity {
+		slog.Warn("retention is shorter than backup granularity, databases may be left without a backup")
+	}
+	if _, ok := r.s3Client.(StorageClassS3Client); r.s3Config.StorageClass != "" && !ok {
+		slog.Warn("s3 client does not support storage classes, uploading to the bucket default")
+	}
+	
+	r.settingsMu.Lock()
+	r.scanInterval = interval
+	r.settingsMu.Unlock()
 	
 	// Initial scan
-	r.scanAndSync()
+	r.scanAndSync(ctx)
 	
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -92,69 +393,289 @@ func (r *Replicator) Run(ctx context.Context, interval time.Duration) error {
 	cleanupTicker := time.NewTicker(time.Hour)
 	defer cleanupTicker.Stop()
 	
+	// Audit ticker - only runs if configured
+	var auditC <-chan time.Time
+	if r.s3Config.AuditInterval > 0 {
+		auditTicker := time.NewTicker(r.s3Config.AuditInterval)
+		defer auditTicker.Stop()
+		auditC = auditTicker.C
+	}
+	
+	// Blob cleanup ticker - only runs in dedup mode
+	var blobC <-chan time.Time
+	if r.s3Config.Dedup {
+		blobTicker := time.NewTicker(24 * time.Hour)
+		defer blobTicker.Stop()
+		blobC = blobTicker.C
+	}
+	
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			r.scanAndSync()
+			r.scanAndSync(ctx)
+		case <-r.reconfigured:
+			ticker.Reset(r.interval())
 		case <-cleanupTicker.C:
-			r.cleanupOldBackups()
+			r.cleanupOldBackups(ctx)
+			r.compactBackups(ctx)
+		case <-auditC:
+			if _, err := r.AuditAndRepair(ctx, r.s3Config.AuditSampleRate); err != nil {
+				slog.Error("audit failed", "error", err)
+			}
+		case <-blobC:
+			if _, err := r.CleanupBlobs(ctx); err != nil {
+				slog.Error("blob cleanup failed", "error", err)
+			}
 		}
 	}
 }
 
-// scanAndSync performs a single scan and sync cycle
-func (r *Replicator) scanAndSync() {
-	start := time.Now()
+// Reconfigure changes the scan interval, retention, and upload concurrency
+// of a running replicator. Zero values leave a setting unchanged, and
+// negative values are rejected without applying any change. The new
+// interval takes effect from the next tick and the new retention from the
+// next cleanup. Concurrency changes as described by SetMaxConcurrent.
+func (r *Replicator) Reconfigure(interval time.Duration, retentionDays, maxConcurrent int) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid interval: %v", interval)
+	}
+	if retentionDays < 0 {
+		return fmt.Errorf("invalid retention days: %d", retentionDays)
+	}
+	if maxConcurrent < 0 {
+		return fmt.Errorf("invalid max concurrent: %d", maxConcurrent)
+	}
 	
-	matches, err := filepath.Glob(r.pattern)
-	if err != nil {
-		log.Printf("Glob error: %v", err)
-		return
+	r.settingsMu.Lock()
+	if interval > 0 && interval != r.scanInterval {
+		r.scanInterval = interval
+		select {
+		case r.reconfigured <- struct{}{}:
+		default:
+		}
+	}
+	if retentionDays > 0 {
+		r.s3Config.RetentionDays = retentionDays
+	}
+	if maxConcurrent > 0 {
+		r.s3Config.MaxConcurrent = maxConcurrent
+		r.uploadSlots.resize(maxConcurrent)
 	}
+	interval, retentionDays, maxConcurrent = r.scanInterval, r.s3Config.RetentionDays, r.s3Config.MaxConcurrent
+	r.settingsMu.Unlock()
 	
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	slog.Info("ultra-simple replicator reconfigured",
+		"interval", interval,
+		"retention_days", retentionDays,
+		"max_concurrent", maxConcurrent)
+	return nil
+}
+
+// SetMaxConcurrent changes how many uploads may run at once, without
+// restarting the replicator. Uploads in flight are not interrupted: when
+// the limit is lowered they finish, and new uploads start only once fewer
+// than n are running. When it is raised, waiting uploads start at once.
+func (r *Replicator) SetMaxConcurrent(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid max concurrent: %d", n)
+	}
+	
+	r.settingsMu.Lock()
+	r.s3Config.MaxConcurrent = n
+	r.uploadSlots.resize(n)
+	r.settingsMu.Unlock()
+	
+	slog.Info("upload concurrency changed", "max_concurrent", n)
+	return nil
+}
+
+// interval returns the current scan interval, zero before Run starts
+func (r *Replicator) interval() time.Duration {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	return r.scanInterval
+}
+
+// retentionDays returns the current retention period
+func (r *Replicator) retentionDays() int {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+	return r.s3Config.RetentionDays
+}
+
+// acquireUpload waits for the rate limiter and a free upload slot. The
+// returned release frees the slot.
+func (r *Replicator) acquireUpload(ctx context.Context) (release func(), err error) {
+	if err := r.waitUpload(ctx); err != nil {
+		return nil, err
+	}
+	if err := r.uploadSlots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return r.uploadSlots.release, nil
+}
+
+// uploadSlots is a counting semaphore whose size can change while slots
+// are held. Held slots count against a new limit, so lowering it never
+// lets more than the new limit start, and nothing in flight is canceled.
+type uploadSlots struct {
+	mu      sync.Mutex
+	limit   int
+	inUse   int
+	changed chan struct{} // Closed and replaced when a slot frees or the limit changes
+}
+
+func newUploadSlots(limit int) *uploadSlots {
+	return &uploadSlots{limit: limit, changed: make(chan struct{})}
+}
+
+// acquire waits until fewer than limit slots are in use and takes one
+func (s *uploadSlots) acquire(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if s.inUse < s.limit {
+			s.inUse++
+			s.mu.Unlock()
+			return nil
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *uploadSlots) release() {
+	s.mu.Lock()
+	s.inUse--
+	s.notifyLocked()
+	s.mu.Unlock()
+}
+
+func (s *uploadSlots) resize(limit int) {
+	s.mu.Lock()
+	s.limit = limit
+	s.notifyLocked()
+	s.mu.Unlock()
+}
+
+// used returns the slots in use and the limit
+func (s *uploadSlots) used() (inUse, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inUse, s.limit
+}
+
+// notifyLocked wakes every waiting acquire to recheck the limit
+func (s *uploadSlots) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Flush runs one last scan and sync of every database, without spreading
+// it over the interval, so changes made since the previous scan are not
+// lost on shutdown. Call it after Run returns, with a context bounding how
+// long shutdown may take; it returns the context's error if the flush was
+// cut short.
+func (r *Replicator) Flush(ctx context.Context) error {
+	slog.Info("flushing changed databases before shutdown")
+	r.scan(ctx, false)
+	return ctx.Err()
+}
+
+// scanAndSync performs a single scan and sync cycle
+func (r *Replicator) scanAndSync(ctx context.Context) {
+	r.scan(ctx, true)
+}
+
+// scan checks every database and syncs those that changed. Matched paths
+// stream into the upload stage as they are discovered, or at their offset in
+// the interval if spread and SpreadScans are set, and r.mu is only held while
+// updating r.databases. Canceling ctx aborts in-flight uploads and skips any
+// not yet started; their changes are left for the next scan.
+func (r *Replicator) scan(ctx context.Context, spread bool) {
+	r.scanMu.Lock()
+	defer r.scanMu.Unlock()
+	
+	start := time.Now()
 	
 	var wg sync.WaitGroup
 	synced := 0
 	
-	for _, path := range matches {
+	check := func(path string) {
 		info, err := os.Stat(path)
 		if err != nil {
-			continue
+			return
+		}
+		size, modTime := info.Size(), info.ModTime()
+		if r.s3Config.Mode == ModeIncremental {
+			// Commits only reach the database file when the WAL is checkpointed
+			if wal, err := os.Stat(path + "-wal"); err == nil {
+				size += wal.Size()
+				if wal.ModTime().After(modTime) {
+					modTime = wal.ModTime()
+				}
+			}
 		}
 		
+		r.mu.Lock()
 		state, exists := r.databases[path]
 		if !exists {
 			state = &DatabaseState{
 				Path:        path,
-				LastModTime: info.ModTime(),
-				LastSize:    info.Size(),
+				LastModTime: modTime,
+				LastSize:    size,
 			}
 			r.databases[path] = state
 		}
 		
 		// Check if changed (size or mtime) or new
-		if !exists || info.Size() != state.LastSize || info.ModTime().After(state.LastModTime) {
-			synced++
-			
+		changed := !exists || size != state.LastSize || modTime.After(state.LastModTime)
+		if changed {
 			// Update state immediately
-			state.LastModTime = info.ModTime()
-			state.LastSize = info.Size()
-			state.LastSyncTime = time.Now()
+			state.LastModTime = modTime
+			state.LastSize = size
+			state.changedAt = time.Now()
+		}
+		tooLarge := r.checkTooLarge(state, info.Size())
+		r.mu.Unlock()
+		
+		if !changed {
+			return
+		}
+		if tooLarge {
+			atomic.AddInt64(&r.stats.SkippedTooLarge, 1)
+			return
+		}
+		synced++
+		
+		// Sync in background
+		wg.Add(1)
+		go func(state *DatabaseState) {
+			defer wg.Done()
 			
-			// Sync in background
-			wg.Add(1)
-			go func(dbPath string) {
-				defer wg.Done()
-				
-				r.uploadSem <- struct{}{}
-				defer func() { <-r.uploadSem }()
-				
-				r.syncDatabase(dbPath)
-			}(path)
+			release, err := r.acquireUpload(ctx)
+			if err != nil {
+				r.resetChange(state)
+				return
+			}
+			defer release()
+			
+			r.syncDatabase(ctx, state)
+		}(state)
+	}
+	
+	if interval := r.interval(); spread && r.s3Config.SpreadScans && interval > 0 {
+		r.spreadChecks(ctx, start, interval, r.discover(ctx), check)
+	} else {
+		for path := range r.discover(ctx) {
+			check(path)
 		}
 	}
 	
@@ -162,59 +683,413 @@ func (r *Replicator) scanAndSync() {
 	
 	atomic.AddInt64(&r.stats.Scans, 1)
 	
-	log.Printf("Scan complete: %d databases, %d synced (took %v)",
-		len(r.databases), synced, time.Since(start))
+	slog.Info("scan complete",
+		"databases", r.GetDatabaseCount(),
+		"synced", synced,
+		"duration", time.Since(start))
+}
+
+// resetChange forgets that a database's change was seen, so the next scan
+// syncs it even if the file hasn't changed again. It is used when a sync is
+// canceled before it could upload.
+func (r *Replicator) resetChange(state *DatabaseState) {
+	r.mu.Lock()
+	state.LastModTime = time.Time{}
+	r.mu.Unlock()
+}
+
+// spreadChecks runs check on each discovered path at the path's offset
+// within the scan interval, measured from start. Paths are grouped into
+// spreadSlots parts of the interval so waiting takes one timer per part.
+func (r *Replicator) spreadChecks(ctx context.Context, start time.Time, interval time.Duration, paths <-chan string, check func(string)) {
+	var slots [spreadSlots][]string
+	for path := range paths {
+		slot := spreadSlot(path)
+		slots[slot] = append(slots[slot], path)
+	}
+	
+	step := interval / spreadSlots
+	for i, slot := range slots {
+		if wait := time.Until(start.Add(time.Duration(i) * step)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+		for _, path := range slot {
+			check(path)
+		}
+	}
+}
+
+// spreadSlot returns the part of the scan interval a database is checked
+// in when scans are spread
+func spreadSlot(path string) int {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return int(h.Sum32() % spreadSlots)
+}
+
+// checkTooLarge reports whether a database exceeds MaxDatabaseSize, logging
+// only when it first crosses the limit or drops back under it. It must be
+// called with r.mu held.
+func (r *Replicator) checkTooLarge(state *DatabaseState, size int64) bool {
+	max := r.s3Config.MaxDatabaseSize
+	tooLarge := max > 0 && size > max
+	
+	if tooLarge && !state.TooLarge {
+		slog.Warn("skipping database larger than max database size",
+			"path", state.Path, "size", size, "max_size", max)
+	} else if !tooLarge && state.TooLarge {
+		slog.Info("database no longer exceeds max database size, resuming",
+			"path", state.Path, "size", size)
+	}
+	state.TooLarge = tooLarge
+	return tooLarge
 }
 
-// syncDatabase uploads a single database
-func (r *Replicator) syncDatabase(path string) {
-	data, err := r.readDatabaseSafely(path)
+// syncDatabase uploads a single database. Scans are serialized by r.scanMu
+// and each changed database gets one upload per scan, so this goroutine is
+// the only writer of state.Checksum and state.LastError; writes still take
+// r.mu for readers of GetDatabaseStatus and ListDatabases.
+func (r *Replicator) syncDatabase(ctx context.Context, state *DatabaseState) {
+	if r.s3Config.Mode == ModeIncremental {
+		r.syncIncremental(ctx, state)
+		return
+	}
+	
+	start := time.Now()
+	path := state.Path
+	stream := r.canStream()
+	
+	var data []byte
+	var src string
+	var err error
+	if stream {
+		var cleanup func()
+		if src, cleanup, err = r.snapshotFile(path); err == nil {
+			defer cleanup()
+		}
+	} else {
+		data, err = r.readDatabaseSafely(path)
+	}
 	if err != nil {
-		log.Printf("Read error %s: %v", filepath.Base(path), err)
+		slog.Error("read failed", "path", path, "error", err)
+		r.setSyncResult(state, state.Checksum, fmt.Errorf("read: %w", err))
 		return
 	}
 	
-	compressed := compressLZ4(data)
-	key := r.generateS3Key(path)
+	// Hash what was read after the checkpoint so it matches what is uploaded
+	var checksum uint32
+	if r.s3Config.ChangeDetection == ChangeDetectionChecksum {
+		if stream {
+			checksum, err = fileChecksum(src)
+		} else {
+			checksum = crc32.ChecksumIEEE(data)
+		}
+		if err != nil {
+			slog.Error("read failed", "path", path, "error", err)
+			r.setSyncResult(state, state.Checksum, fmt.Errorf("read: %w", err))
+			return
+		}
+		if state.Checksum != 0 && checksum == state.Checksum {
+			atomic.AddInt64(&r.stats.SkippedUploads, 1)
+			return
+		}
+	}
 	
-	err = r.s3Client.Upload(key, compressed)
+	key := r.generateS3Key(path)
+	var size int64
+	var etag string
+	if stream {
+		err = r.uploadWithRetry(ctx, path, func() error {
+			var err error
+			size, etag, err = r.uploadStream(ctx, path, src, key)
+			return err
+		})
+	} else if r.s3Config.Dedup {
+		key = pointerKey(key)
+		size, etag, err = r.uploadDedup(ctx, path, key, data)
+	} else {
+		var payload []byte
+		var release func()
+		if payload, release, err = r.prepareUpload(data); err != nil {
+			slog.Error("prepare upload failed", "path", path, "error", err)
+			atomic.AddInt64(&r.stats.UploadErrors, 1)
+			r.setSyncResult(state, state.Checksum, err)
+			return
+		}
+		size, etag = int64(len(payload)), md5Hex(payload)
+		err = r.uploadWithRetry(ctx, path, func() error {
+			return r.upload(ctx, path, key, payload)
+		})
+		release()
+	}
 	if err != nil {
-		log.Printf("Upload error %s: %v", filepath.Base(path), err)
+		if ctx.Err() != nil {
+			r.resetChange(state) // Shutting down; not an upload failure
+			return
+		}
+		slog.Error("upload failed", "path", path, "key", key, "error", err)
 		atomic.AddInt64(&r.stats.UploadErrors, 1)
+		r.setSyncResult(state, state.Checksum, fmt.Errorf("upload: %w", err))
 		return
 	}
 	
-	atomic.AddInt64(&r.stats.Uploads, 1)
-	atomic.AddInt64(&r.stats.BytesUploaded, int64(len(compressed)))
+	r.countUpload(path, size)
+	r.recordUpload(path, key, etag)
+	r.setSyncResult(state, checksum, nil)
+	slog.Debug("database uploaded", "path", path, "key", key, "bytes", size, "duration", time.Since(start))
+}
+
+// prepareUpload compresses and, if configured, encrypts a database. The
+// payload may be a pooled buffer, so release must be called once it is no
+// longer used.
+func (r *Replicator) prepareUpload(data []byte) (payload []byte, release func(), err error) {
+	defer r.observeStage(StageCompress, time.Now())
+	
+	compressed, release, err := r.compress(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("compress: %w", err)
+	}
+	if r.s3Config.Encryptor == nil {
+		return compressed, release, nil
+	}
+	
+	// Encryption copies the data, so the compression buffer is free now
+	defer release()
+	encrypted, err := r.encrypt(compressed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypt: %w", err)
+	}
+	return encrypted, func() {}, nil
+}
+
+// compress compresses data into a pooled buffer if the compressor supports
+// it and data is no larger than CompressionBufferSize. release returns the
+// buffer to the pool.
+func (r *Replicator) compress(data []byte) (compressed []byte, release func(), err error) {
+	bc, ok := r.s3Config.Compressor.(BufferCompressor)
+	if !ok || len(data) > r.s3Config.CompressionBufferSize {
+		compressed, err = r.s3Config.Compressor.Compress(data)
+		return compressed, func() {}, err
+	}
+	
+	buf, _ := r.compressBufs.Get().(*[]byte)
+	if buf == nil {
+		buf = new([]byte)
+	}
+	if compressed, err = bc.CompressBuffer(*buf, data); err != nil {
+		r.compressBufs.Put(buf)
+		return nil, nil, err
+	}
+	
+	// Keep the buffer if compression had to grow it
+	*buf = compressed[:0]
+	return compressed, func() { r.compressBufs.Put(buf) }, nil
+}
+
+// waitUpload blocks until the rate limiter allows another upload, adding
+// the time spent to RateLimitWait
+func (r *Replicator) waitUpload(ctx context.Context) error {
+	start := time.Now()
+	err := r.waitLimiter(ctx)
+	atomic.AddInt64((*int64)(&r.stats.RateLimitWait), int64(time.Since(start)))
+	return err
+}
+
+// waitLimiter blocks until the rate limiter allows another S3 request
+func (r *Replicator) waitLimiter(ctx context.Context) error {
+	if r.limiter == nil {
+		return nil
+	}
+	return r.limiter.Wait(ctx)
+}
+
+// setSyncResult records the outcome of a sync on a database's state
+func (r *Replicator) setSyncResult(state *DatabaseState, checksum uint32, err error) {
+	r.mu.Lock()
+	state.Checksum = checksum
+	state.LastError = err
+	if err == nil {
+		state.LastSyncTime = time.Now()
+	}
+	r.mu.Unlock()
+}
+
+// uploadWithRetry runs an upload, retrying transient failures with
+// exponential backoff and jitter up to MaxRetries times
+func (r *Replicator) uploadWithRetry(ctx context.Context, path string, upload func() error) error {
+	backoff := r.s3Config.RetryBackoff
+	
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = upload(); err == nil {
+			if attempt > 0 {
+				atomic.AddInt64(&r.stats.RetriedUploads, 1)
+			}
+			return nil
+		}
+		if attempt >= r.s3Config.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+		
+		// Sleep between half and the full backoff so retries from a burst
+		// of failures don't all hit S3 at the same moment
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		slog.Warn("upload failed, retrying",
+			"path", path,
+			"attempt", attempt+1,
+			"max_attempts", r.s3Config.MaxRetries+1,
+			"delay", delay,
+			"error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// upload sends a database's backup object, tagging it when supported
+func (r *Replicator) upload(ctx context.Context, path, key string, data []byte) error {
+	defer r.observeStage(StageUpload, time.Now())
+	
+	if sc, ok := r.s3Client.(StorageClassS3Client); ok && r.s3Config.StorageClass != "" {
+		return sc.UploadWithStorageClass(ctx, key, data, r.s3Config.StorageClass, r.objectTags(path))
+	}
+	if tc, ok := r.s3Client.(TaggingS3Client); ok {
+		return tc.UploadWithTags(ctx, key, data, r.objectTags(path))
+	}
+	return r.s3Client.Upload(ctx, key, data)
+}
+
+// recordUpload remembers the key and checksum of a database's latest upload
+func (r *Replicator) recordUpload(path, key, etag string) {
+	r.uploadsMu.Lock()
+	r.uploads[path] = uploadRecord{Key: key, ETag: etag}
+	r.uploadsMu.Unlock()
+}
+
+// md5Hex returns the hex MD5 of data, which is the ETag S3 reports for a
+// single-part upload
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // readDatabaseSafely reads database with WAL handling
 func (r *Replicator) readDatabaseSafely(path string) ([]byte, error) {
+	src, cleanup, err := r.snapshotFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	return os.ReadFile(src)
+}
+
+// snapshotFile returns a file holding a consistent copy of a database, as
+// selected by ReadMode: the database itself once its WAL is checkpointed,
+// or a VACUUM INTO copy. cleanup removes any copy once it has been read.
+func (r *Replicator) snapshotFile(path string) (src string, cleanup func(), err error) {
+	if r.s3Config.ReadMode != ReadModeBackup {
+		if err := r.checkpointWAL(path); err != nil {
+			return "", nil, err
+		}
+		return path, func() {}, nil
+	}
+	
+	dir, err := os.MkdirTemp("", "ultrasimple-")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	
+	src = filepath.Join(dir, filepath.Base(path))
+	if err := r.vacuumInto(path, src); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return src, cleanup, nil
+}
+
+// vacuumInto copies a database to dest with VACUUM INTO, reading it through
+// a read-only connection so the database and its WAL are never written
+func (r *Replicator) vacuumInto(path, dest string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+	
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", r.s3Config.BusyTimeout.Milliseconds())); err != nil {
+		return fmt.Errorf("set busy timeout: %w", err)
+	}
+	if _, err := db.Exec("VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("vacuum into: %w", err)
+	}
+	return nil
+}
+
+// checkpointWAL folds a non-empty WAL back into the database file so the
+// file alone holds the current data
+func (r *Replicator) checkpointWAL(path string) error {
 	walPath := path + "-wal"
 	if info, err := os.Stat(walPath); err == nil && info.Size() > 0 {
 		// WAL exists - try to checkpoint
 		db, err := sql.Open("sqlite3", path)
 		if err != nil {
-			return nil, fmt.Errorf("open database: %w", err)
+			return fmt.Errorf("open database: %w", err)
 		}
 		defer db.Close()
 		
+		// Keep the pragma and checkpoint on the same connection
+		db.SetMaxOpenConns(1)
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", r.s3Config.BusyTimeout.Milliseconds())); err != nil {
+			return fmt.Errorf("set busy timeout: %w", err)
+		}
+		
 		_, err = db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
 		if err != nil {
-			log.Printf("Checkpoint failed for %s: %v", path, err)
+			slog.Error("checkpoint failed", "path", path, "error", err)
 		}
 	}
-	
-	return os.ReadFile(path)
+	return nil
 }
 
 // generateS3Key creates S3 key from path template
 func (r *Replicator) generateS3Key(path string) string {
+	// Use the end of the current window (this ensures natural overwriting)
+	timestamp := r.windowEnd(time.Now()).Format(backupTimestampFormat)
+	
+	key := r.keyPrefix(path) + timestamp + backupExtension + r.s3Config.Compressor.Extension()
+	if r.s3Config.Encryptor != nil {
+		key += encryptedExtension
+	}
+	return key
+}
+
+// windowEnd returns the end of the backup window containing t, which is
+// the timestamp of that window's backup key
+func (r *Replicator) windowEnd(t time.Time) time.Time {
+	g := r.s3Config.BackupGranularity
+	return t.Add(g).Truncate(g)
+}
+
+// parseDBPath extracts project, database, branch, and tenant from a path
+// of the form <root>/project/databases/database/branches/branch/tenants/tenant.db.
+// The project is the directory before "databases", so any root works; a path
+// without a "databases" directory falls back to the directory after "data".
+func parseDBPath(path string) (project, database, branch, tenant string) {
 	parts := strings.Split(path, "/")
 	
-	var project, database, branch, tenant string
 	for i, part := range parts {
-		if i > 0 && parts[i-1] == "data" {
+		if i+1 < len(parts) && parts[i+1] == "databases" {
+			project = part
+		} else if i > 0 && parts[i-1] == "data" && project == "" {
 			project = part
 		} else if i > 0 && parts[i-1] == "databases" {
 			database = part
@@ -224,6 +1099,33 @@ func (r *Replicator) generateS3Key(path string) string {
 			tenant = strings.TrimSuffix(part, ".db")
 		}
 	}
+	return
+}
+
+// objectTags returns the tags to attach to a database's backup objects
+func (r *Replicator) objectTags(path string) map[string]string {
+	tags := make(map[string]string, len(r.s3Config.Tags)+4)
+	for k, v := range r.s3Config.Tags {
+		tags[k] = v
+	}
+	
+	project, database, branch, tenant := parseDBPath(path)
+	for k, v := range map[string]string{
+		"project":  project,
+		"database": database,
+		"branch":   branch,
+		"tenant":   tenant,
+	} {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+	return tags
+}
+
+// keyPrefix returns the portion of a database's S3 key before the timestamp
+func (r *Replicator) keyPrefix(path string) string {
+	project, database, branch, tenant := parseDBPath(path)
 	
 	key := r.s3Config.PathTemplate
 	key = strings.ReplaceAll(key, "{{project}}", project)
@@ -235,21 +1137,191 @@ func (r *Replicator) generateS3Key(path string) string {
 	dbName := filepath.Base(path)
 	dbName = strings.TrimSuffix(dbName, ".db")
 	
-	// Use the NEXT hour timestamp (this ensures natural overwriting)
-	nextHour := time.Now().Add(time.Hour).Truncate(time.Hour)
-	timestamp := nextHour.Format("20060102-150000")
+	return fmt.Sprintf("%s/%s-", key, dbName)
+}
+
+// parseKeyTimestamp extracts the backup timestamp from a key with the given
+// prefix. Any compression extension after ".db" is accepted.
+func parseKeyTimestamp(key, prefix string) (time.Time, bool) {
+	if !strings.HasPrefix(key, prefix) {
+		return time.Time{}, false
+	}
+	
+	ts, _, ok := strings.Cut(strings.TrimPrefix(key, prefix), backupExtension)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(backupTimestampFormat, ts, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// listObjects lists objects with their last modified time if the client
+// supports it, and with a zero time otherwise
+func (r *Replicator) listObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	if dc, ok := r.s3Client.(DetailedListS3Client); ok {
+		return dc.ListDetailed(ctx, prefix)
+	}
+	
+	keys, err := r.s3Client.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]ObjectInfo, len(keys))
+	for i, key := range keys {
+		objects[i] = ObjectInfo{Key: key}
+	}
+	return objects, nil
+}
+
+// isBackupObject reports whether a key is a backup, increment, pointer, or
+// manifest that retention may delete. Dedup blobs are shared across windows
+// and are left to CleanupBlobs.
+func isBackupObject(key string) bool {
+	if strings.HasPrefix(key, blobPrefix) {
+		return false
+	}
+	name := key[strings.LastIndex(key, "/")+1:]
+	return strings.Contains(name, backupExtension) || strings.HasSuffix(name, manifestExtension)
+}
+
+// keyTimestampPattern matches the timestamp ending a backup object's name,
+// optionally with legacy fractional seconds. Whatever follows (".db.lz4",
+// ".wal000001.db", ".manifest.json") never matches again.
+var keyTimestampPattern = regexp.MustCompile(`-(\d{8}-\d{6})(?:\.\d+)?\.`)
+
+// keyTimestamp extracts the window timestamp from any backup object key
+// without knowing the database it belongs to, for cleanup across the whole
+// bucket. Keys are "<template>/<database>-<timestamp>.<extensions>", and the
+// template and database name may themselves contain hyphens and digits
+// (e.g. "team-2024"), so the last match in the name is used.
+func keyTimestamp(key string) (time.Time, bool) {
+	_, t, ok := splitBackupKey(key)
+	return t, ok
+}
+
+// splitBackupKey splits a backup object key into the key prefix of its
+// database and its window timestamp, as keyTimestamp does
+func splitBackupKey(key string) (prefix string, t time.Time, ok bool) {
+	dir := strings.LastIndex(key, "/") + 1
+	matches := keyTimestampPattern.FindAllStringSubmatchIndex(key[dir:], -1)
+	if len(matches) == 0 {
+		return "", time.Time{}, false
+	}
+	m := matches[len(matches)-1]
+	t, err := time.ParseInLocation(backupTimestampFormat, key[dir+m[2]:dir+m[3]], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return key[:dir+m[0]+1], t, true
+}
+
+// parseWindowTimestamp extracts the backup timestamp from any object of a
+// backup window: the backup itself and, in incremental mode, its WAL
+// increments and manifest
+func parseWindowTimestamp(key, prefix string) (time.Time, bool) {
+	if !strings.HasPrefix(key, prefix) {
+		return time.Time{}, false
+	}
 	
-	return fmt.Sprintf("%s/%s-%s.db.lz4", key, dbName, timestamp)
+	ts, _, ok := strings.Cut(strings.TrimPrefix(key, prefix), ".")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(backupTimestampFormat, ts, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
 }
 
 // GetStats returns current statistics
 func (r *Replicator) GetStats() Stats {
-	return Stats{
-		Scans:         atomic.LoadInt64(&r.stats.Scans),
-		Uploads:       atomic.LoadInt64(&r.stats.Uploads),
-		UploadErrors:  atomic.LoadInt64(&r.stats.UploadErrors),
-		BytesUploaded: atomic.LoadInt64(&r.stats.BytesUploaded),
+	stats := Stats{
+		Scans:           atomic.LoadInt64(&r.stats.Scans),
+		Uploads:         atomic.LoadInt64(&r.stats.Uploads),
+		UploadErrors:    atomic.LoadInt64(&r.stats.UploadErrors),
+		BytesUploaded:   atomic.LoadInt64(&r.stats.BytesUploaded),
+		RetriedUploads:  atomic.LoadInt64(&r.stats.RetriedUploads),
+		SkippedUploads:  atomic.LoadInt64(&r.stats.SkippedUploads),
+		SkippedTooLarge: atomic.LoadInt64(&r.stats.SkippedTooLarge),
+		WALIncrements:   atomic.LoadInt64(&r.stats.WALIncrements),
+		DedupedUploads:  atomic.LoadInt64(&r.stats.DedupedUploads),
+		CleanupRuns:     atomic.LoadInt64(&r.stats.CleanupRuns),
+		ObjectsDeleted:  atomic.LoadInt64(&r.stats.ObjectsDeleted),
+		CleanupErrors:   atomic.LoadInt64(&r.stats.CleanupErrors),
+		RateLimitWait:   time.Duration(atomic.LoadInt64((*int64)(&r.stats.RateLimitWait))),
 	}
+	if ns := atomic.LoadInt64(&r.lastCleanup); ns != 0 {
+		stats.LastCleanupTime = time.Unix(0, ns)
+	}
+	return stats
+}
+
+// GetProjectStats returns stats per project. Databases whose path has no
+// project are grouped under "".
+func (r *Replicator) GetProjectStats() map[string]ProjectStat {
+	stats := make(map[string]ProjectStat)
+	r.projectsMu.Lock()
+	for project, stat := range r.projects {
+		stats[project] = *stat
+	}
+	r.projectsMu.Unlock()
+	
+	r.mu.RLock()
+	for path := range r.databases {
+		project, _, _, _ := parseDBPath(path)
+		stat := stats[project]
+		stat.Databases++
+		stats[project] = stat
+	}
+	r.mu.RUnlock()
+	return stats
+}
+
+// countUpload adds a completed upload of a database to the stats
+func (r *Replicator) countUpload(path string, size int64) {
+	atomic.AddInt64(&r.stats.Uploads, 1)
+	atomic.AddInt64(&r.stats.BytesUploaded, size)
+	
+	project, _, _, _ := parseDBPath(path)
+	r.projectsMu.Lock()
+	stat, ok := r.projects[project]
+	if !ok {
+		stat = &ProjectStat{}
+		r.projects[project] = stat
+	}
+	stat.Uploads++
+	stat.BytesUploaded += size
+	r.projectsMu.Unlock()
+}
+
+// GetDatabaseStatus returns a copy of the tracked state of a database
+func (r *Replicator) GetDatabaseStatus(path string) (DatabaseState, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	state, ok := r.databases[filepath.Clean(path)]
+	if !ok {
+		return DatabaseState{}, false
+	}
+	return *state, true
+}
+
+// ListDatabases returns copies of the state of every tracked database,
+// sorted by path
+func (r *Replicator) ListDatabases() []DatabaseState {
+	r.mu.RLock()
+	states := make([]DatabaseState, 0, len(r.databases))
+	for _, state := range r.databases {
+		states = append(states, *state)
+	}
+	r.mu.RUnlock()
+	
+	sort.Slice(states, func(i, j int) bool { return states[i].Path < states[j].Path })
+	return states
 }
 
 // GetDatabaseCount returns the number of tracked databases
@@ -260,92 +1332,96 @@ func (r *Replicator) GetDatabaseCount() int {
 }
 
 
-// cleanupOldBackups removes backups older than retention period
-func (r *Replicator) cleanupOldBackups() {
+// cleanupOldBackups removes backups older than retention period. Backups
+// are aged by their last modified time if the client implements
+// DetailedListS3Client, and by the timestamp in their key otherwise.
+func (r *Replicator) cleanupOldBackups(ctx context.Context) {
 	start := time.Now()
-	cutoff := start.AddDate(0, 0, -r.s3Config.RetentionDays)
+	atomic.AddInt64(&r.stats.CleanupRuns, 1)
 	
-	log.Printf("Starting cleanup of backups older than %s", cutoff.Format("2006-01-02"))
+	// Key timestamps are window ends, so align the cutoff to a window
+	// boundary to delete whole windows only
+	g := r.s3Config.BackupGranularity
+	cutoff := start.AddDate(0, 0, -r.retentionDays()).Truncate(g)
+	
+	slog.Info("cleanup started", "cutoff", cutoff)
 	
 	// List all files in the bucket
-	allKeys, err := r.s3Client.List("")
+	if err := r.waitLimiter(ctx); err != nil {
+		return
+	}
+	objects, err := r.listObjects(ctx, "")
 	if err != nil {
-		log.Printf("Failed to list S3 objects for cleanup: %v", err)
+		slog.Error("failed to list s3 objects for cleanup", "error", err)
+		atomic.AddInt64(&r.stats.CleanupErrors, 1)
 		return
 	}
 	
 	var toDelete []string
 	
-	for _, key := range allKeys {
-		// Extract timestamp from key
-		// Format: path/dbname-20060102-150405.999999999.db.lz4
-		// or: path/dbname-20060102-150000.snapshot.db.lz4
-		// Extract timestamp from key by finding the date pattern
-		// Format: path/dbname-20060102-150405.999999999.db.lz4
-		// or: path/dbname-20060102-150000.db.lz4 (hourly)
-		
-		// Find the date pattern (8 digits starting with 20)
-		parts := strings.Split(key, "-")
-		if len(parts) < 3 {
+	for _, obj := range objects {
+		// Only keys we wrote are deleted, however they are aged
+		if !isBackupObject(obj.Key) {
 			continue
 		}
-		
-		var dateStr, timeStr string
-		for i := len(parts) - 2; i < len(parts); i++ {
-			if i < 0 {
-				continue
-			}
-			part := parts[i]
-			if len(part) >= 8 && strings.HasPrefix(part, "20") {
-				dateStr = part[:8]
-				if i+1 < len(parts) {
-					// Time part is in the next segment
-					timePart := strings.Split(parts[i+1], ".")[0]
-					if len(timePart) >= 6 {
-						timeStr = timePart[:6]
-					}
-				}
-				break
-			}
-		}
-		
-		if dateStr == "" || timeStr == "" {
+		timestamp, ok := keyTimestamp(obj.Key)
+		if !ok {
 			continue
 		}
 		
-		// Parse timestamp
-		timestamp, err := time.Parse("20060102150405", dateStr+timeStr)
-		if err != nil {
-			continue
+		// Age by last modified time when the client reports one
+		if !obj.LastModified.IsZero() {
+			timestamp = obj.LastModified
 		}
 		
 		// Check if older than cutoff
 		if timestamp.Before(cutoff) {
-			toDelete = append(toDelete, key)
+			toDelete = append(toDelete, obj.Key)
 		}
 	}
 	
 	if len(toDelete) == 0 {
-		log.Printf("No old backups to clean up")
+		atomic.StoreInt64(&r.lastCleanup, time.Now().UnixNano())
+		slog.Info("no old backups to clean up")
 		return
 	}
 	
-	// Delete in batches of 1000 (S3 limit)
+	deleted, err := r.deleteKeys(ctx, toDelete)
+	atomic.AddInt64(&r.stats.ObjectsDeleted, int64(deleted))
+	if err != nil {
+		atomic.AddInt64(&r.stats.CleanupErrors, 1)
+	}
+	atomic.StoreInt64(&r.lastCleanup, time.Now().UnixNano())
+	
+	slog.Info("cleanup complete",
+		"deleted", deleted,
+		"expired", len(toDelete),
+		"duration", time.Since(start))
+}
+
+// deleteKeys deletes keys in batches of 1000 (the S3 limit), continuing
+// past failed batches. It returns how many keys were deleted and the
+// errors of any batches that failed.
+func (r *Replicator) deleteKeys(ctx context.Context, keys []string) (int, error) {
+	var errs []error
 	deleted := 0
-	for i := 0; i < len(toDelete); i += 1000 {
+	for i := 0; i < len(keys); i += 1000 {
 		end := i + 1000
-		if end > len(toDelete) {
-			end = len(toDelete)
+		if end > len(keys) {
+			end = len(keys)
 		}
 		
-		batch := toDelete[i:end]
-		if err := r.s3Client.Delete(batch); err != nil {
-			log.Printf("Failed to delete batch of %d objects: %v", len(batch), err)
+		batch := keys[i:end]
+		if err := r.waitLimiter(ctx); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := r.s3Client.Delete(ctx, batch); err != nil {
+			slog.Error("failed to delete batch", "objects", len(batch), "error", err)
+			errs = append(errs, err)
 		} else {
 			deleted += len(batch)
 		}
 	}
-	
-	log.Printf("Cleanup complete: deleted %d of %d old backups (took %v)", 
-		deleted, len(toDelete), time.Since(start))
-}
\ No newline at end of file
+	return deleted, errors.Join(errs...)
+}
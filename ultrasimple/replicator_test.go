@@ -1,10 +1,12 @@
 package ultrasimple
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -16,10 +18,12 @@ import (
 
 // MockS3Client for testing
 type MockS3Client struct {
-	mu       sync.Mutex
-	uploads  map[string][]byte
-	errors   int
-	failNext bool
+	mu         sync.Mutex
+	uploads    map[string][]byte
+	errors     int
+	failNext   bool
+	failN      int  // Fail this many uploads before succeeding
+	failDelete bool // Fail every Delete
 }
 
 func NewMockS3Client() *MockS3Client {
@@ -28,7 +32,7 @@ func NewMockS3Client() *MockS3Client {
 	}
 }
 
-func (m *MockS3Client) Upload(key string, data []byte) error {
+func (m *MockS3Client) Upload(ctx context.Context, key string, data []byte) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
@@ -37,13 +41,29 @@ func (m *MockS3Client) Upload(key string, data []byte) error {
 		m.errors++
 		return fmt.Errorf("mock upload error")
 	}
+	if m.failN > 0 {
+		m.failN--
+		m.errors++
+		return fmt.Errorf("mock upload error")
+	}
 	
 	// Store with unique key to avoid overwrites
 	m.uploads[key] = append([]byte{}, data...) // Copy data
 	return nil
 }
 
-func (m *MockS3Client) List(prefix string) ([]string, error) {
+func (m *MockS3Client) Download(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	data, ok := m.uploads[key]
+	if !ok {
+		return nil, fmt.Errorf("mock key not found: %s", key)
+	}
+	return append([]byte{}, data...), nil
+}
+
+func (m *MockS3Client) List(ctx context.Context, prefix string) ([]string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
@@ -56,10 +76,13 @@ func (m *MockS3Client) List(prefix string) ([]string, error) {
 	return keys, nil
 }
 
-func (m *MockS3Client) Delete(keys []string) error {
+func (m *MockS3Client) Delete(ctx context.Context, keys []string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
+	if m.failDelete {
+		return fmt.Errorf("mock delete error")
+	}
 	for _, key := range keys {
 		delete(m.uploads, key)
 	}
@@ -119,7 +142,7 @@ func TestReplicatorBasic(t *testing.T) {
 	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
 	
 	// Run one scan
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	
 	// Check results
 	if r.GetDatabaseCount() != 1 {
@@ -151,14 +174,14 @@ func TestReplicatorChangeDetection(t *testing.T) {
 	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
 	
 	// First scan
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	initialUploads := s3Client.GetUploadCount()
 	if initialUploads != 1 {
 		t.Fatalf("Expected 1 initial upload, got %d", initialUploads)
 	}
 	
 	// Second scan without changes - should not upload
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	if s3Client.GetUploadCount() != initialUploads {
 		t.Error("Uploaded unchanged database")
 	}
@@ -170,7 +193,7 @@ func TestReplicatorChangeDetection(t *testing.T) {
 	db.Close()
 	
 	// Third scan - should upload
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	finalUploads := s3Client.GetUploadCount()
 	
 	// Debug output
@@ -188,6 +211,56 @@ func TestReplicatorChangeDetection(t *testing.T) {
 	}
 }
 
+func TestReplicatorChecksumChangeDetection(t *testing.T) {
+	for _, mode := range []string{ChangeDetectionMTime, ChangeDetectionChecksum} {
+		t.Run(mode, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			dbPath := filepath.Join(tmpDir, "test.db")
+			createTestDB(t, dbPath, "CREATE TABLE test (id INTEGER)")
+			
+			s3Client := NewMockS3Client()
+			config := S3Config{
+				PathTemplate:    "backups",
+				ChangeDetection: mode,
+			}
+			
+			r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+			r.scanAndSync(context.Background())
+			
+			// Bump mtime without changing contents
+			future := time.Now().Add(time.Minute)
+			if err := os.Chtimes(dbPath, future, future); err != nil {
+				t.Fatal(err)
+			}
+			r.scanAndSync(context.Background())
+			
+			stats := r.GetStats()
+			wantUploads, wantSkipped := int64(2), int64(0)
+			if mode == ChangeDetectionChecksum {
+				wantUploads, wantSkipped = 1, 1
+			}
+			if stats.Uploads != wantUploads {
+				t.Errorf("Expected %d uploads, got %d", wantUploads, stats.Uploads)
+			}
+			if stats.SkippedUploads != wantSkipped {
+				t.Errorf("Expected %d skipped uploads, got %d", wantSkipped, stats.SkippedUploads)
+			}
+			
+			// A real content change is always uploaded
+			db, _ := sql.Open("sqlite3", dbPath)
+			db.Exec("INSERT INTO test VALUES (1)")
+			db.Close()
+			later := future.Add(time.Minute)
+			os.Chtimes(dbPath, later, later)
+			r.scanAndSync(context.Background())
+			
+			if got := r.GetStats().Uploads; got != wantUploads+1 {
+				t.Errorf("Expected %d uploads after content change, got %d", wantUploads+1, got)
+			}
+		})
+	}
+}
+
 func TestReplicatorWALHandling(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -212,7 +285,7 @@ func TestReplicatorWALHandling(t *testing.T) {
 	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
 	
 	// Should handle WAL correctly
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	
 	if s3Client.GetUploadCount() != 1 {
 		t.Errorf("Expected 1 upload with WAL, got %d", s3Client.GetUploadCount())
@@ -221,6 +294,144 @@ func TestReplicatorWALHandling(t *testing.T) {
 	db.Close()
 }
 
+func TestReplicatorCheckpointBusyTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	
+	// Keep a connection open so the WAL is not checkpointed on close
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Exec("PRAGMA journal_mode=WAL")
+	db.Exec("CREATE TABLE test (id INTEGER)")
+	db.Exec("INSERT INTO test VALUES (1)")
+	
+	// holdLock takes the write lock and releases it after d
+	holdLock := func(d time.Duration) {
+		t.Helper()
+		writer, err := sql.Open("sqlite3", dbPath+"?_txlock=immediate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := writer.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Exec("INSERT INTO test VALUES (2)"); err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			time.Sleep(d)
+			tx.Commit()
+			writer.Close()
+		}()
+	}
+	
+	walSize := func() int64 {
+		info, err := os.Stat(dbPath + "-wal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	
+	// A short timeout gives up while the lock is held
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{BusyTimeout: time.Millisecond}, NewMockS3Client())
+	holdLock(300 * time.Millisecond)
+	if err := r.checkpointWAL(dbPath); err != nil {
+		t.Fatal(err)
+	}
+	if walSize() == 0 {
+		t.Fatal("expected checkpoint to be blocked by the write lock")
+	}
+	time.Sleep(400 * time.Millisecond)
+	
+	// The default timeout waits for the lock and truncates the WAL
+	r = New(filepath.Join(tmpDir, "*.db"), S3Config{}, NewMockS3Client())
+	if r.s3Config.BusyTimeout != 5*time.Second {
+		t.Errorf("expected default busy timeout 5s, got %v", r.s3Config.BusyTimeout)
+	}
+	holdLock(200 * time.Millisecond)
+	if err := r.checkpointWAL(dbPath); err != nil {
+		t.Fatal(err)
+	}
+	if size := walSize(); size != 0 {
+		t.Errorf("expected WAL truncated after waiting for the lock, got %d bytes", size)
+	}
+}
+
+func TestReplicatorReadModeBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	
+	// Keep a connection open so the WAL is not checkpointed on close
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Exec("PRAGMA journal_mode=WAL")
+	db.Exec("CREATE TABLE test (id INTEGER)")
+	db.Exec("INSERT INTO test VALUES (1)")
+	
+	// An application writer holds the write lock with an uncommitted row
+	writer, err := sql.Open("sqlite3", dbPath+"?_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	tx, err := writer.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO test VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+	
+	walBefore, err := os.ReadFile(dbPath + "-wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	s3Client := NewMockS3Client()
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups", ReadMode: ReadModeBackup, BusyTimeout: time.Millisecond}, s3Client)
+	r.scanAndSync(context.Background())
+	if n := s3Client.GetUploadCount(); n != 1 {
+		t.Fatalf("Expected 1 upload despite the write lock, got %d", n)
+	}
+	
+	// The WAL was neither checkpointed nor truncated
+	walAfter, err := os.ReadFile(dbPath + "-wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(walBefore, walAfter) {
+		t.Error("Expected backup mode to leave the WAL untouched")
+	}
+	
+	// The copy holds the committed row, read from the WAL, but not the
+	// uncommitted one
+	dest := filepath.Join(tmpDir, "restored.db")
+	if err := r.RestoreLatest(context.Background(), dbPath, dest); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := sql.Open("sqlite3", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	var n int
+	if err := restored.QueryRow("SELECT COUNT(*) FROM test").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 committed row in the copy, got %d", n)
+	}
+}
+
 func TestReplicatorPathTemplate(t *testing.T) {
 	tmpDir := t.TempDir()
 	
@@ -242,7 +453,7 @@ func TestReplicatorPathTemplate(t *testing.T) {
 	pattern := filepath.Join(tmpDir, "data/*/databases/*/branches/*/tenants/*.db")
 	r := New(pattern, config, s3Client)
 	
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	
 	// Check that path was parsed correctly
 	found := false
@@ -258,6 +469,127 @@ func TestReplicatorPathTemplate(t *testing.T) {
 	}
 }
 
+func TestReplicatorProjectStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	
+	// Two databases in project1, one in project2
+	for _, db := range []struct{ project, tenant string }{
+		{"project1", "acme"},
+		{"project1", "globex"},
+		{"project2", "initech"},
+	} {
+		dbDir := filepath.Join(tmpDir, "data", db.project, "databases", "userdb", "branches", "main", "tenants")
+		os.MkdirAll(dbDir, 0755)
+		createTestDB(t, filepath.Join(dbDir, db.tenant+".db"), "CREATE TABLE test (id INTEGER)")
+	}
+	
+	s3Client := NewMockS3Client()
+	pattern := filepath.Join(tmpDir, "data/*/databases/*/branches/*/tenants/*.db")
+	r := New(pattern, S3Config{PathTemplate: "{{project}}/{{database}}/{{branch}}/{{tenant}}"}, s3Client)
+	r.scanAndSync(context.Background())
+	
+	stats := r.GetProjectStats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 projects, got %v", stats)
+	}
+	for project, want := range map[string]int{"project1": 2, "project2": 1} {
+		stat := stats[project]
+		if stat.Databases != want || stat.Uploads != int64(want) {
+			t.Errorf("Expected %d databases and uploads in %s, got %+v", want, project, stat)
+		}
+	}
+	
+	var total int64
+	for _, stat := range stats {
+		total += stat.BytesUploaded
+	}
+	if total != r.GetStats().BytesUploaded {
+		t.Errorf("Expected project bytes to add up to %d, got %d", r.GetStats().BytesUploaded, total)
+	}
+}
+
+func TestReplicatorMultiplePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	
+	// Two distinct roots that can't be expressed as a single glob
+	dataDir := filepath.Join(tmpDir, "data", "acme", "databases", "users", "branches", "main", "tenants")
+	fastDir := filepath.Join(tmpDir, "mnt", "fast", "globex", "databases", "orders", "branches", "dev", "tenants")
+	os.MkdirAll(dataDir, 0755)
+	os.MkdirAll(fastDir, 0755)
+	createTestDB(t, filepath.Join(dataDir, "t1.db"), "CREATE TABLE test (id INTEGER)")
+	createTestDB(t, filepath.Join(fastDir, "t2.db"), "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := NewMockS3Client()
+	config := S3Config{
+		PathTemplate: "{{project}}/{{database}}/{{branch}}/{{tenant}}",
+	}
+	
+	r := NewMulti([]string{
+		filepath.Join(tmpDir, "data/*/databases/*/branches/*/tenants/*.db"),
+		filepath.Join(tmpDir, "mnt/fast/*/databases/*/branches/*/tenants/*.db"),
+		// Overlaps the first pattern; matches must not be synced twice
+		filepath.Join(tmpDir, "data/acme/databases/*/branches/*/tenants/*.db"),
+	}, config, s3Client)
+	
+	r.scanAndSync(context.Background())
+	
+	if r.GetDatabaseCount() != 2 {
+		t.Errorf("Expected 2 databases, got %d", r.GetDatabaseCount())
+	}
+	if stats := r.GetStats(); stats.Uploads != 2 {
+		t.Errorf("Expected 2 uploads, got %d", stats.Uploads)
+	}
+	
+	for _, want := range []string{"acme/users/main/t1/t1-", "globex/orders/dev/t2/t2-"} {
+		found := false
+		for key := range s3Client.GetUploads() {
+			if strings.HasPrefix(key, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected key with prefix %q", want)
+		}
+	}
+}
+
+func TestReplicatorExcludePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	
+	createTestDB(t, filepath.Join(tmpDir, "app.db"), "CREATE TABLE test (id INTEGER)")
+	createTestDB(t, filepath.Join(tmpDir, "users.fixture.db"), "CREATE TABLE test (id INTEGER)")
+	os.MkdirAll(filepath.Join(tmpDir, "staging"), 0755)
+	createTestDB(t, filepath.Join(tmpDir, "staging", "import.db"), "CREATE TABLE test (id INTEGER)")
+	
+	// Sidecars matched by a loose pattern are never uploaded either
+	os.WriteFile(filepath.Join(tmpDir, "app.db-journal"), []byte("journal"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "app.db-wal"), []byte("wal"), 0644)
+	
+	s3Client := NewMockS3Client()
+	config := S3Config{
+		ExcludePatterns: []string{"*.fixture.db", filepath.Join(tmpDir, "staging", "*")},
+	}
+	
+	r := NewMulti([]string{
+		filepath.Join(tmpDir, "*.db*"),
+		filepath.Join(tmpDir, "*", "*.db"),
+	}, config, s3Client)
+	
+	r.scanAndSync(context.Background())
+	
+	if r.GetDatabaseCount() != 1 {
+		t.Errorf("Expected 1 database, got %d", r.GetDatabaseCount())
+	}
+	for key := range s3Client.GetUploads() {
+		if !strings.HasPrefix(path.Base(key), "app-") {
+			t.Errorf("Unexpected upload of excluded path: %s", key)
+		}
+	}
+	if stats := r.GetStats(); stats.Uploads != 1 {
+		t.Errorf("Expected 1 upload, got %d", stats.Uploads)
+	}
+}
+
 func TestReplicatorConcurrency(t *testing.T) {
 	tmpDir := t.TempDir()
 	
@@ -278,7 +610,7 @@ func TestReplicatorConcurrency(t *testing.T) {
 	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
 	
 	start := time.Now()
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	duration := time.Since(start)
 	
 	// Debug: print uploaded keys
@@ -300,6 +632,34 @@ func TestReplicatorConcurrency(t *testing.T) {
 	}
 }
 
+func TestReplicatorRateLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		createTestDB(t, filepath.Join(tmpDir, fmt.Sprintf("test%d.db", i)), "CREATE TABLE test (id INTEGER)")
+	}
+	
+	s3Client := NewMockS3Client()
+	config := S3Config{
+		PathTemplate:        "backups",
+		MaxUploadsPerSecond: 20,
+	}
+	
+	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+	
+	// 5 uploads at 20/s are spaced 50ms apart after the first
+	start := time.Now()
+	r.scanAndSync(context.Background())
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected uploads to take at least 200ms, took %v", elapsed)
+	}
+	if s3Client.GetUploadCount() != 5 {
+		t.Errorf("Expected 5 uploads, got %d", s3Client.GetUploadCount())
+	}
+	if wait := r.GetStats().RateLimitWait; wait < 200*time.Millisecond {
+		t.Errorf("Expected at least 200ms of rate limit wait, got %v", wait)
+	}
+}
+
 func TestReplicatorErrorHandling(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -317,7 +677,7 @@ func TestReplicatorErrorHandling(t *testing.T) {
 	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
 	
 	// First scan - should fail
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	
 	stats := r.GetStats()
 	if stats.UploadErrors != 1 {
@@ -331,7 +691,7 @@ func TestReplicatorErrorHandling(t *testing.T) {
 	
 	// Ultra-simple design: only retries if database changes
 	// Second scan without changes - should NOT retry
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	if s3Client.GetUploadCount() != 0 {
 		t.Error("Should not retry unchanged database")
 	}
@@ -343,13 +703,466 @@ func TestReplicatorErrorHandling(t *testing.T) {
 	db.Close()
 	
 	// Third scan - should upload successfully
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	// Should now have 1 upload (might be same key if within same hour)
 	if s3Client.GetUploadCount() != 1 {
 		t.Errorf("Expected 1 total upload after change, got %d", s3Client.GetUploadCount())
 	}
 }
 
+func TestReplicatorRetry(t *testing.T) {
+	t.Run("SucceedsAfterFailures", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		createTestDB(t, filepath.Join(tmpDir, "test.db"), "CREATE TABLE test (id INTEGER)")
+		
+		s3Client := NewMockS3Client()
+		s3Client.failN = 2
+		
+		config := S3Config{
+			PathTemplate: "backups",
+			MaxRetries:   3,
+			RetryBackoff: time.Millisecond,
+		}
+		
+		r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+		r.scanAndSync(context.Background())
+		
+		stats := r.GetStats()
+		if stats.Uploads != 1 {
+			t.Errorf("Expected 1 upload, got %d", stats.Uploads)
+		}
+		if stats.UploadErrors != 0 {
+			t.Errorf("Expected 0 errors, got %d", stats.UploadErrors)
+		}
+		if stats.RetriedUploads != 1 {
+			t.Errorf("Expected 1 retried upload, got %d", stats.RetriedUploads)
+		}
+		if s3Client.errors != 2 {
+			t.Errorf("Expected 2 failed attempts, got %d", s3Client.errors)
+		}
+	})
+	
+	t.Run("GivesUpAfterMaxRetries", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		createTestDB(t, filepath.Join(tmpDir, "test.db"), "CREATE TABLE test (id INTEGER)")
+		
+		s3Client := NewMockS3Client()
+		s3Client.failN = 5
+		
+		config := S3Config{
+			PathTemplate: "backups",
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+		}
+		
+		r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+		r.scanAndSync(context.Background())
+		
+		stats := r.GetStats()
+		if stats.UploadErrors != 1 {
+			t.Errorf("Expected 1 error, got %d", stats.UploadErrors)
+		}
+		if stats.RetriedUploads != 0 {
+			t.Errorf("Expected 0 retried uploads, got %d", stats.RetriedUploads)
+		}
+		if s3Client.errors != 3 {
+			t.Errorf("Expected 3 attempts, got %d", s3Client.errors)
+		}
+		if s3Client.GetUploadCount() != 0 {
+			t.Errorf("Expected 0 uploads, got %d", s3Client.GetUploadCount())
+		}
+	})
+}
+
+// blockingMockS3Client holds every upload until its context is canceled
+type blockingMockS3Client struct {
+	*MockS3Client
+	started chan struct{}
+}
+
+func (m *blockingMockS3Client) Upload(ctx context.Context, key string, data []byte) error {
+	m.started <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReplicatorCancelInFlightUploads(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		createTestDB(t, filepath.Join(tmpDir, fmt.Sprintf("test%d.db", i)), "CREATE TABLE test (id INTEGER)")
+	}
+	
+	s3Client := &blockingMockS3Client{
+		MockS3Client: NewMockS3Client(),
+		started:      make(chan struct{}, 5),
+	}
+	config := S3Config{
+		PathTemplate:  "backups",
+		MaxConcurrent: 2,
+		MaxRetries:    3,
+		RetryBackoff:  time.Hour,
+	}
+	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.scanAndSync(ctx)
+		close(done)
+	}()
+	
+	<-s3Client.started
+	cancel()
+	
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scanAndSync did not return after context cancel")
+	}
+	
+	if stats := r.GetStats(); stats.UploadErrors != 0 || stats.Uploads != 0 {
+		t.Errorf("Expected no uploads or errors on shutdown, got %d uploads, %d errors",
+			stats.Uploads, stats.UploadErrors)
+	}
+}
+
+func TestReplicatorFlush(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		createTestDB(t, filepath.Join(tmpDir, fmt.Sprintf("test%d.db", i)), "CREATE TABLE test (id INTEGER)")
+	}
+	
+	s3Client := &blockingMockS3Client{
+		MockS3Client: NewMockS3Client(),
+		started:      make(chan struct{}, 5),
+	}
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups", MaxConcurrent: 2}, s3Client)
+	
+	// Shut down while the scan's uploads are in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.scanAndSync(ctx)
+		close(done)
+	}()
+	<-s3Client.started
+	cancel()
+	<-done
+	
+	// The flush syncs the databases the canceled scan saw change but never
+	// uploaded, even though their files haven't changed since
+	r.s3Client = s3Client.MockS3Client
+	if err := r.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := s3Client.GetUploadCount(); n != 5 {
+		t.Errorf("Expected all 5 databases flushed, got %d uploads", n)
+	}
+	
+	// A flush out of time reports it
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	if err := r.Flush(expired); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestReplicatorReconfigure(t *testing.T) {
+	t.Run("RejectsInvalid", func(t *testing.T) {
+		r := New("", S3Config{PathTemplate: "backups", MaxConcurrent: 4}, NewMockS3Client())
+		
+		if err := r.Reconfigure(time.Minute, -1, 8); err == nil {
+			t.Fatal("Expected error for negative retention")
+		}
+		_, n := r.uploadSlots.used()
+		if days := r.retentionDays(); days != 30 || n != 4 {
+			t.Errorf("Expected settings unchanged, got %d days and %d slots", days, n)
+		}
+	})
+	
+	t.Run("ResizesConcurrency", func(t *testing.T) {
+		r := New("", S3Config{PathTemplate: "backups", MaxConcurrent: 1}, NewMockS3Client())
+		
+		release, err := r.acquireUpload(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Reconfigure(0, 7, 3); err != nil {
+			t.Fatal(err)
+		}
+		if days := r.retentionDays(); days != 7 {
+			t.Errorf("Expected 7 retention days, got %d", days)
+		}
+		
+		// The new limit applies at once, counting the slot taken before
+		// the change
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		for i := 0; i < 2; i++ {
+			if _, err := r.acquireUpload(ctx); err != nil {
+				t.Fatalf("Expected slot %d of 3: %v", i+2, err)
+			}
+		}
+		release()
+		if n, limit := r.uploadSlots.used(); n != 2 || limit != 3 {
+			t.Errorf("Expected 2 of 3 slots in use, got %d of %d", n, limit)
+		}
+	})
+	
+	t.Run("SetMaxConcurrent", func(t *testing.T) {
+		r := New("", S3Config{PathTemplate: "backups", MaxConcurrent: 3}, NewMockS3Client())
+		
+		for _, n := range []int{0, -1} {
+			if err := r.SetMaxConcurrent(n); err == nil {
+				t.Errorf("Expected error for max concurrent %d", n)
+			}
+		}
+		
+		var releases []func()
+		for i := 0; i < 3; i++ {
+			release, err := r.acquireUpload(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			releases = append(releases, release)
+		}
+		
+		// Lowering the limit lets held slots finish, and new uploads wait
+		// until fewer than the new limit are running
+		if err := r.SetMaxConcurrent(1); err != nil {
+			t.Fatal(err)
+		}
+		acquired := make(chan func(), 1)
+		go func() {
+			release, err := r.acquireUpload(context.Background())
+			if err == nil {
+				acquired <- release
+			}
+		}()
+		releases[0]()
+		releases[1]()
+		select {
+		case <-acquired:
+			t.Fatal("Expected upload to wait while the held slot is still over the new limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+		releases[2]()
+		select {
+		case release := <-acquired:
+			release()
+		case <-time.After(time.Second):
+			t.Fatal("Expected upload to start once below the new limit")
+		}
+		
+		// Raising it starts waiting uploads at once
+		hold, _ := r.acquireUpload(context.Background())
+		defer hold()
+		go func() {
+			release, err := r.acquireUpload(context.Background())
+			if err == nil {
+				acquired <- release
+			}
+		}()
+		if err := r.SetMaxConcurrent(2); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case release := <-acquired:
+			release()
+		case <-time.After(time.Second):
+			t.Fatal("Expected waiting upload to start after raising the limit")
+		}
+	})
+	
+	t.Run("ResetsInterval", func(t *testing.T) {
+		r := New(filepath.Join(t.TempDir(), "*.db"), S3Config{PathTemplate: "backups"}, NewMockS3Client())
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go r.Run(ctx, time.Hour)
+		
+		// Wait for the initial scan, then shorten the interval
+		deadline := time.Now().Add(time.Second)
+		for r.GetStats().Scans < 1 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if err := r.Reconfigure(10*time.Millisecond, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		for r.GetStats().Scans < 3 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if scans := r.GetStats().Scans; scans < 3 {
+			t.Errorf("Expected scans at the new interval, got %d", scans)
+		}
+	})
+}
+
+func TestReplicatorDatabaseStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	okPath := filepath.Join(tmpDir, "a.db")
+	failPath := filepath.Join(tmpDir, "b.db")
+	createTestDB(t, okPath, "CREATE TABLE test (id INTEGER)")
+	createTestDB(t, failPath, "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := NewMockS3Client()
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+	
+	if _, ok := r.GetDatabaseStatus(okPath); ok {
+		t.Fatal("Expected no status before the first scan")
+	}
+	
+	r.scanAndSync(context.Background())
+	
+	state, ok := r.GetDatabaseStatus(okPath)
+	if !ok {
+		t.Fatal("Expected status for synced database")
+	}
+	if state.LastSyncTime.IsZero() || state.LastSize == 0 || state.LastError != nil {
+		t.Errorf("Unexpected status after successful sync: %+v", state)
+	}
+	
+	synced, _ := r.GetDatabaseStatus(failPath)
+	
+	// Make b.db fail its next upload
+	time.Sleep(10 * time.Millisecond)
+	db, _ := sql.Open("sqlite3", failPath)
+	db.Exec("INSERT INTO test VALUES (1)")
+	db.Close()
+	s3Client.mu.Lock()
+	s3Client.failNext = true
+	s3Client.mu.Unlock()
+	r.scanAndSync(context.Background())
+	
+	state, _ = r.GetDatabaseStatus(failPath)
+	if state.LastError == nil {
+		t.Error("Expected LastError after failed upload")
+	}
+	if !state.LastSyncTime.Equal(synced.LastSyncTime) {
+		t.Errorf("Expected LastSyncTime to stay at the last successful sync %v, got %v", synced.LastSyncTime, state.LastSyncTime)
+	}
+	
+	states := r.ListDatabases()
+	if len(states) != 2 || states[0].Path != okPath || states[1].Path != failPath {
+		t.Fatalf("Unexpected ListDatabases result: %+v", states)
+	}
+	if states[0].LastError != nil {
+		t.Errorf("Expected no error for %s, got %v", okPath, states[0].LastError)
+	}
+	
+	// Returned states are copies
+	states[0].LastSize = -1
+	if state, _ := r.GetDatabaseStatus(okPath); state.LastSize == -1 {
+		t.Error("ListDatabases returned internal state instead of a copy")
+	}
+}
+
+func TestReplicatorBackupGranularity(t *testing.T) {
+	for _, g := range []time.Duration{time.Minute, time.Hour, 24 * time.Hour} {
+		t.Run(g.String(), func(t *testing.T) {
+			r := New("", S3Config{PathTemplate: "backups", BackupGranularity: g}, NewMockS3Client())
+			
+			before := time.Now()
+			key := r.generateS3Key("/tmp/test.db")
+			
+			ts, ok := parseKeyTimestamp(key, r.keyPrefix("/tmp/test.db"))
+			if !ok {
+				t.Fatalf("Could not parse timestamp from %s", key)
+			}
+			if !ts.Equal(ts.Truncate(g)) {
+				t.Errorf("Key timestamp %v is not aligned to %v", ts, g)
+			}
+			if !ts.After(before) || ts.Sub(before) > g {
+				t.Errorf("Key timestamp %v is not the end of the current %v window", ts, g)
+			}
+		})
+	}
+	
+	t.Run("Default", func(t *testing.T) {
+		r := New("", S3Config{}, NewMockS3Client())
+		if r.s3Config.BackupGranularity != time.Hour {
+			t.Errorf("Expected hourly default, got %v", r.s3Config.BackupGranularity)
+		}
+	})
+	
+	t.Run("Cleanup", func(t *testing.T) {
+		s3Client := NewMockS3Client()
+		r := New("", S3Config{PathTemplate: "backups", BackupGranularity: time.Minute, RetentionDays: 1}, s3Client)
+		
+		now := time.Now().Truncate(time.Minute)
+		oldKey := "backups/test-" + now.AddDate(0, 0, -1).Add(-time.Minute).Format(backupTimestampFormat) + ".db.lz4"
+		newKey := "backups/test-" + now.AddDate(0, 0, -1).Add(time.Minute).Format(backupTimestampFormat) + ".db.lz4"
+		s3Client.Upload(context.Background(), oldKey, []byte("x"))
+		s3Client.Upload(context.Background(), newKey, []byte("x"))
+		
+		r.cleanupOldBackups(context.Background())
+		
+		uploads := s3Client.GetUploads()
+		if _, ok := uploads[oldKey]; ok {
+			t.Errorf("Expected %s to be deleted", oldKey)
+		}
+		if _, ok := uploads[newKey]; !ok {
+			t.Errorf("Expected %s to be retained", newKey)
+		}
+	})
+}
+
+func TestReplicatorMaxDatabaseSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	smallPath := filepath.Join(tmpDir, "small.db")
+	bigPath := filepath.Join(tmpDir, "big.db")
+	createTestDB(t, smallPath, "CREATE TABLE test (id INTEGER)")
+	createTestDB(t, bigPath, "CREATE TABLE test (id INTEGER)")
+	
+	info, _ := os.Stat(smallPath)
+	
+	// Grow big.db well past the limit
+	db, _ := sql.Open("sqlite3", bigPath)
+	db.Exec("CREATE TABLE pad (v BLOB)")
+	db.Exec("INSERT INTO pad VALUES (zeroblob(100000))")
+	db.Close()
+	
+	s3Client := NewMockS3Client()
+	config := S3Config{
+		PathTemplate:    "backups",
+		MaxDatabaseSize: info.Size(),
+	}
+	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+	r.scanAndSync(context.Background())
+	
+	stats := r.GetStats()
+	if stats.Uploads != 1 {
+		t.Errorf("Expected 1 upload, got %d", stats.Uploads)
+	}
+	if stats.SkippedTooLarge != 1 {
+		t.Errorf("Expected 1 skipped database, got %d", stats.SkippedTooLarge)
+	}
+	
+	// Skipped databases are still tracked
+	state, ok := r.GetDatabaseStatus(bigPath)
+	if !ok || !state.TooLarge {
+		t.Fatalf("Expected big.db to be tracked as too large, got %+v (ok=%v)", state, ok)
+	}
+	
+	// An unchanged oversized database is not counted again
+	r.scanAndSync(context.Background())
+	if got := r.GetStats().SkippedTooLarge; got != 1 {
+		t.Errorf("Expected skipped count to stay 1, got %d", got)
+	}
+	
+	// Raising the limit lets the next change upload
+	r.s3Config.MaxDatabaseSize = 0
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(bigPath, future, future)
+	r.scanAndSync(context.Background())
+	if got := r.GetStats().Uploads; got != 2 {
+		t.Errorf("Expected 2 uploads after raising the limit, got %d", got)
+	}
+	if state, _ := r.GetDatabaseStatus(bigPath); state.TooLarge {
+		t.Error("Expected big.db to no longer be marked too large")
+	}
+}
+
 func TestReplicatorContext(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -408,7 +1221,7 @@ func TestReplicatorNextHourBackups(t *testing.T) {
 	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
 	
 	// First scan - both databases are new
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	
 	// Should have 2 uploads
 	if s3Client.GetUploadCount() != 2 {
@@ -444,7 +1257,7 @@ func TestReplicatorNextHourBackups(t *testing.T) {
 	
 	// Next scan might create 0-1 new uploads (overwrites if still in same hour)
 	initialCount := s3Client.GetUploadCount()
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	finalCount := s3Client.GetUploadCount()
 	
 	if finalCount < initialCount || finalCount > initialCount+1 {
@@ -473,11 +1286,11 @@ func TestReplicatorCleanup(t *testing.T) {
 	s3Client.uploads[oldKey] = []byte("old data")
 	
 	// Create a recent upload
-	r.scanAndSync()
+	r.scanAndSync(context.Background())
 	initialCount := s3Client.GetUploadCount()
 	
 	// Run cleanup
-	r.cleanupOldBackups()
+	r.cleanupOldBackups(context.Background())
 	
 	// Old file should be deleted
 	if s3Client.GetUploadCount() != initialCount-1 {
@@ -490,6 +1303,131 @@ func TestReplicatorCleanup(t *testing.T) {
 	if _, exists := uploads[oldKey]; exists {
 		t.Error("Old backup key still exists after cleanup")
 	}
+	
+	stats := r.GetStats()
+	if stats.CleanupRuns != 1 || stats.ObjectsDeleted != 1 || stats.CleanupErrors != 0 {
+		t.Errorf("Expected 1 run deleting 1 object without errors, got %d runs, %d deleted, %d errors",
+			stats.CleanupRuns, stats.ObjectsDeleted, stats.CleanupErrors)
+	}
+	if stats.LastCleanupTime.IsZero() {
+		t.Error("Expected LastCleanupTime to be set")
+	}
+	
+	// Failed deletes are counted as errors, not deletions
+	s3Client.uploads[oldKey] = []byte("old data")
+	s3Client.failDelete = true
+	r.cleanupOldBackups(context.Background())
+	stats = r.GetStats()
+	if stats.CleanupRuns != 2 || stats.ObjectsDeleted != 1 || stats.CleanupErrors != 1 {
+		t.Errorf("Expected a failed second run, got %d runs, %d deleted, %d errors",
+			stats.CleanupRuns, stats.ObjectsDeleted, stats.CleanupErrors)
+	}
+}
+
+func TestReplicatorCleanupHyphenatedNames(t *testing.T) {
+	s3Client := NewMockS3Client()
+	r := New("", S3Config{PathTemplate: "{{project}}/{{database}}/{{branch}}/{{tenant}}", RetentionDays: 30}, s3Client)
+	
+	old := time.Now().AddDate(0, 0, -40)
+	recent := time.Now().AddDate(0, 0, -1)
+	
+	// Names full of hyphens and year-like numbers, some of them timestamps
+	var oldKeys, recentKeys []string
+	for _, path := range []string{
+		"/data/team-2024/databases/db-20231231/branches/release-2023/tenants/t-2019.db",
+		"/data/acme/databases/users/branches/main/tenants/t1-20991231-235959.db",
+		"/data/20240101-120000/databases/x/branches/main/tenants/2024.db",
+	} {
+		oldKey := r.keyPrefix(path) + old.Format(backupTimestampFormat) + backupExtension + ".lz4"
+		recentKey := r.keyPrefix(path) + recent.Format(backupTimestampFormat) + backupExtension + ".lz4"
+		oldKeys = append(oldKeys, oldKey, incrementKey(oldKey, 1), manifestKey(oldKey))
+		recentKeys = append(recentKeys, recentKey, incrementKey(recentKey, 1), manifestKey(recentKey))
+	}
+	
+	// Dedup blobs carry no timestamp and are left to CleanupBlobs
+	blob := blobPrefix + "20240101" + backupExtension + ".lz4"
+	recentKeys = append(recentKeys, blob)
+	
+	for _, key := range append(oldKeys, recentKeys...) {
+		s3Client.Upload(context.Background(), key, []byte("x"))
+	}
+	
+	r.cleanupOldBackups(context.Background())
+	
+	uploads := s3Client.GetUploads()
+	for _, key := range oldKeys {
+		if _, ok := uploads[key]; ok {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+	for _, key := range recentKeys {
+		if _, ok := uploads[key]; !ok {
+			t.Errorf("Expected %s to be retained", key)
+		}
+	}
+}
+
+// detailedMockS3Client reports last modified times for the keys in
+// modified, and a zero time for the rest
+type detailedMockS3Client struct {
+	*MockS3Client
+	modified map[string]time.Time
+}
+
+func (m *detailedMockS3Client) ListDetailed(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	keys, err := m.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]ObjectInfo, len(keys))
+	for i, key := range keys {
+		objects[i] = ObjectInfo{Key: key, LastModified: m.modified[key]}
+	}
+	return objects, nil
+}
+
+func TestReplicatorCleanupLastModified(t *testing.T) {
+	s3Client := &detailedMockS3Client{MockS3Client: NewMockS3Client(), modified: make(map[string]time.Time)}
+	r := New("", S3Config{PathTemplate: "backups", RetentionDays: 30}, s3Client)
+	
+	old := time.Now().AddDate(0, 0, -40)
+	recent := time.Now().AddDate(0, 0, -1)
+	
+	put := func(key string, modified time.Time) string {
+		s3Client.Upload(context.Background(), key, []byte("x"))
+		if !modified.IsZero() {
+			s3Client.modified[key] = modified
+		}
+		return key
+	}
+	
+	// The last modified time wins over the key
+	staleByTime := put("backups/a-"+recent.Format(backupTimestampFormat)+".db.lz4", old)
+	freshByTime := put("backups/b-"+old.Format(backupTimestampFormat)+".db.lz4", recent)
+	
+	// Objects without one fall back to the key
+	staleByKey := put("backups/c-"+old.Format(backupTimestampFormat)+".db.lz4", time.Time{})
+	
+	// Blobs and objects that aren't backups are never aged out, even when
+	// their name looks like a database
+	blob := put(blobPrefix+"abc.db.lz4", old)
+	other := put("backups/notes.txt", old)
+	notes := put("proj/notes.db.txt", old)
+	dump := put("proj/x.dbdump", old)
+	
+	r.cleanupOldBackups(context.Background())
+	
+	uploads := s3Client.GetUploads()
+	for _, key := range []string{staleByTime, staleByKey} {
+		if _, ok := uploads[key]; ok {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+	for _, key := range []string{freshByTime, blob, other, notes, dump} {
+		if _, ok := uploads[key]; !ok {
+			t.Errorf("Expected %s to be retained", key)
+		}
+	}
 }
 
 func TestReplicator15SecondInterval(t *testing.T) {
@@ -530,6 +1468,104 @@ func TestReplicator15SecondInterval(t *testing.T) {
 	}
 }
 
+// taggingMockS3Client records the tags passed with each upload
+type taggingMockS3Client struct {
+	*MockS3Client
+	tags map[string]map[string]string
+}
+
+func (m *taggingMockS3Client) UploadWithTags(ctx context.Context, key string, data []byte, tags map[string]string) error {
+	m.mu.Lock()
+	m.tags[key] = tags
+	m.mu.Unlock()
+	return m.Upload(ctx, key, data)
+}
+
+// storageClassMockS3Client records the storage class requested per key
+type storageClassMockS3Client struct {
+	*MockS3Client
+	classes map[string]string
+}
+
+func (m *storageClassMockS3Client) UploadWithStorageClass(ctx context.Context, key string, data []byte, storageClass string, tags map[string]string) error {
+	m.mu.Lock()
+	m.classes[key] = storageClass
+	m.mu.Unlock()
+	return m.Upload(ctx, key, data)
+}
+
+func TestReplicatorStorageClass(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestDB(t, filepath.Join(tmpDir, "test.db"), "CREATE TABLE test (id INTEGER)")
+	
+	for _, storageClass := range []string{"", "STANDARD_IA"} {
+		s3Client := &storageClassMockS3Client{MockS3Client: NewMockS3Client(), classes: make(map[string]string)}
+		r := New(filepath.Join(tmpDir, "*.db"), S3Config{
+			PathTemplate: "backups",
+			StorageClass: storageClass,
+		}, s3Client)
+		r.scanAndSync(context.Background())
+		
+		if s3Client.GetUploadCount() != 1 {
+			t.Fatalf("Expected 1 upload, got %d", s3Client.GetUploadCount())
+		}
+		
+		// A blank class leaves uploads to the plain Upload path
+		want := 0
+		if storageClass != "" {
+			want = 1
+		}
+		if len(s3Client.classes) != want {
+			t.Fatalf("Expected %d storage class uploads, got %v", want, s3Client.classes)
+		}
+		for key, got := range s3Client.classes {
+			if got != storageClass {
+				t.Errorf("Expected %s uploaded as %q, got %q", key, storageClass, got)
+			}
+		}
+	}
+}
+
+func TestReplicatorObjectTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbDir := filepath.Join(tmpDir, "data", "project1", "databases", "userdb",
+		"branches", "main", "tenants")
+	os.MkdirAll(dbDir, 0755)
+	createTestDB(t, filepath.Join(dbDir, "acme.db"), "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := &taggingMockS3Client{
+		MockS3Client: NewMockS3Client(),
+		tags:         make(map[string]map[string]string),
+	}
+	config := S3Config{
+		Region:       "us-east-1",
+		Bucket:       "test-bucket",
+		PathTemplate: "{{project}}/{{database}}/{{branch}}/{{tenant}}",
+		Tags:         map[string]string{"environment": "staging"},
+	}
+	
+	r := New(filepath.Join(tmpDir, "data/*/databases/*/branches/*/tenants/*.db"), config, s3Client)
+	r.scanAndSync(context.Background())
+	
+	if len(s3Client.tags) != 1 {
+		t.Fatalf("Expected 1 tagged upload, got %d", len(s3Client.tags))
+	}
+	for _, tags := range s3Client.tags {
+		want := map[string]string{
+			"environment": "staging",
+			"project":     "project1",
+			"database":    "userdb",
+			"branch":      "main",
+			"tenant":      "acme",
+		}
+		for k, v := range want {
+			if tags[k] != v {
+				t.Errorf("Tag %s = %q, want %q", k, tags[k], v)
+			}
+		}
+	}
+}
+
 // Helper to create test database
 func createTestDB(t *testing.T, path string, schema string) {
 	db, err := sql.Open("sqlite3", path)
@@ -0,0 +1,159 @@
+package ultrasimple
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Restore writes the newest backup of a database to w. dbPath is the
+// database path as matched by the discovery pattern and is used to derive
+// the backup key prefix.
+func (r *Replicator) Restore(ctx context.Context, dbPath string, w io.Writer) error {
+	key, incremental, err := r.findBackup(ctx, dbPath, time.Time{})
+	if err != nil {
+		return err
+	}
+	
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	
+	data, err := r.downloadDatabase(ctx, key, incremental)
+	if err != nil {
+		return err
+	}
+	
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", key, err)
+	}
+	return nil
+}
+
+// RestoreLatest restores the newest backup of a database to dest
+func (r *Replicator) RestoreLatest(ctx context.Context, dbPath string, dest string) error {
+	key, incremental, err := r.findBackup(ctx, dbPath, time.Time{})
+	if err != nil {
+		return err
+	}
+	
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	
+	return r.restoreKey(ctx, key, incremental, dest)
+}
+
+// RestoreAt restores the newest backup of a database taken at or before the
+// given time. dbPath is the database path as matched by the discovery pattern
+// and is used to derive the backup key prefix. The restored database is
+// written to dest.
+func (r *Replicator) RestoreAt(ctx context.Context, dbPath string, at time.Time, dest string) error {
+	key, incremental, err := r.findBackup(ctx, dbPath, at)
+	if err != nil {
+		return err
+	}
+	
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	
+	return r.restoreKey(ctx, key, incremental, dest)
+}
+
+// findBackup returns the key of the newest backup of a database taken at or
+// before at, and whether it has a manifest of WAL increments from
+// incremental mode. A zero at selects the newest backup overall.
+func (r *Replicator) findBackup(ctx context.Context, dbPath string, at time.Time) (string, bool, error) {
+	prefix := r.keyPrefix(dbPath)
+	
+	keys, err := r.s3Client.List(ctx, prefix)
+	if err != nil {
+		return "", false, fmt.Errorf("list backups: %w", err)
+	}
+	
+	var bestKey string
+	var bestTime time.Time
+	manifests := make(map[string]bool)
+	for _, key := range keys {
+		if strings.HasSuffix(key, manifestExtension) {
+			manifests[key] = true
+		}
+		ts, ok := parseKeyTimestamp(key, prefix)
+		if !ok || (!at.IsZero() && ts.After(at)) {
+			continue
+		}
+		if bestKey == "" || ts.After(bestTime) {
+			bestKey, bestTime = key, ts
+		}
+	}
+	
+	if bestKey == "" {
+		if at.IsZero() {
+			return "", false, fmt.Errorf("no backup of %s exists", filepath.Base(dbPath))
+		}
+		return "", false, fmt.Errorf("no backup of %s exists at or before %s", filepath.Base(dbPath), at.Format(time.RFC3339))
+	}
+	return bestKey, manifests[manifestKey(bestKey)], nil
+}
+
+// restoreKey downloads a backup, with its WAL increments if it has a
+// manifest, and writes the decompressed database to dest.
+func (r *Replicator) restoreKey(ctx context.Context, key string, incremental bool, dest string) error {
+	data, err := r.downloadDatabase(ctx, key, incremental)
+	if err != nil {
+		return err
+	}
+	
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", dest, err)
+	}
+	
+	return nil
+}
+
+// downloadDatabase downloads a backup and, if it has a manifest, applies
+// the WAL increments uploaded on top of it in incremental mode
+func (r *Replicator) downloadDatabase(ctx context.Context, key string, incremental bool) ([]byte, error) {
+	data, etag, err := r.downloadBackup(ctx, key)
+	if err != nil || !incremental {
+		return data, err
+	}
+	return r.applyManifest(ctx, key, etag, data)
+}
+
+// downloadBackup downloads a backup object, decrypts it if its key has the
+// encrypted extension, and decompresses it with the codec matching its key.
+// Pointers from dedup mode are resolved to their blob. It also returns the
+// hex MD5 of the object as stored.
+func (r *Replicator) downloadBackup(ctx context.Context, key string) ([]byte, string, error) {
+	if strings.HasSuffix(key, pointerExtension) {
+		return r.downloadPointer(ctx, key)
+	}
+	
+	compressed, err := r.s3Client.Download(ctx, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", key, err)
+	}
+	etag := md5Hex(compressed)
+	
+	compressed, codecKey, err := r.decrypt(key, compressed)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	d, err := r.decompressorFor(codecKey)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	data, err := d.Decompress(compressed)
+	if err != nil {
+		return nil, "", fmt.Errorf("decompress %s: %w", key, err)
+	}
+	return data, etag, nil
+}
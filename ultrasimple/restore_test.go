@@ -0,0 +1,118 @@
+package ultrasimple
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestReplicatorRestoreAt(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	createTestDB(t, dbPath, "CREATE TABLE test (id INTEGER)")
+	
+	s3Client := NewMockS3Client()
+	config := S3Config{
+		Region:       "us-east-1",
+		Bucket:       "test-bucket",
+		PathTemplate: "backups",
+	}
+	
+	r := New(filepath.Join(tmpDir, "*.db"), config, s3Client)
+	
+	// Store three versions of the database at different hours
+	base := time.Now().Truncate(time.Hour).Add(-10 * time.Hour)
+	versions := make(map[time.Time][]byte)
+	for i := 0; i < 3; i++ {
+		db, _ := sql.Open("sqlite3", dbPath)
+		db.Exec("INSERT INTO test VALUES (?)", i)
+		db.Close()
+		
+		data, err := os.ReadFile(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		
+		ts := base.Add(time.Duration(i) * time.Hour)
+		versions[ts] = data
+		key := r.keyPrefix(dbPath) + ts.Format(backupTimestampFormat) + backupExtension + ".lz4"
+		s3Client.Upload(context.Background(), key, lz4Block(t, data))
+	}
+	
+	// A key for a different database sharing the name prefix must be ignored
+	s3Client.Upload(context.Background(), fmt.Sprintf("backups/test-other-%s%s", base.Add(time.Hour).Format(backupTimestampFormat), backupExtension+".lz4"), []byte("x"))
+	
+	dest := filepath.Join(tmpDir, "restored.db")
+	
+	// Between the second and third backup selects the second
+	if err := r.RestoreAt(context.Background(), dbPath, base.Add(90*time.Minute), dest); err != nil {
+		t.Fatalf("RestoreAt failed: %v", err)
+	}
+	restored, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, versions[base.Add(time.Hour)]) {
+		t.Error("Restored data does not match the backup at or before the requested time")
+	}
+	
+	// Exactly at a backup time selects that backup
+	if err := r.RestoreAt(context.Background(), dbPath, base, dest); err != nil {
+		t.Fatalf("RestoreAt failed: %v", err)
+	}
+	restored, _ = os.ReadFile(dest)
+	if !bytes.Equal(restored, versions[base]) {
+		t.Error("Restored data does not match the backup at the requested time")
+	}
+	
+	// Before any backup is an error
+	err = r.RestoreAt(context.Background(), dbPath, base.Add(-time.Minute), dest)
+	if err == nil || !strings.Contains(err.Error(), "no backup") {
+		t.Errorf("Expected no backup error, got %v", err)
+	}
+}
+
+func TestReplicatorRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	
+	s3Client := NewMockS3Client()
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups"}, s3Client)
+	
+	// No backups yet
+	var buf bytes.Buffer
+	if err := r.Restore(context.Background(), dbPath, &buf); err == nil {
+		t.Fatal("Expected error restoring database with no backups")
+	}
+	
+	// Several hourly backups; the newest must win regardless of listing order
+	base := time.Now().Truncate(time.Hour).Add(-5 * time.Hour)
+	for _, i := range []int{2, 0, 3, 1} {
+		key := r.keyPrefix(dbPath) + base.Add(time.Duration(i)*time.Hour).Format(backupTimestampFormat) + backupExtension + ".lz4"
+		s3Client.Upload(context.Background(), key, lz4Block(t, []byte(fmt.Sprintf("version %d", i))))
+	}
+	
+	buf.Reset()
+	if err := r.Restore(context.Background(), dbPath, &buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got := buf.String(); got != "version 3" {
+		t.Errorf("Expected newest backup, got %q", got)
+	}
+	
+	dest := filepath.Join(tmpDir, "latest.db")
+	if err := r.RestoreLatest(context.Background(), dbPath, dest); err != nil {
+		t.Fatalf("RestoreLatest failed: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "version 3" {
+		t.Errorf("Expected newest backup in file, got %q", got)
+	}
+}
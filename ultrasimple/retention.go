@@ -0,0 +1,106 @@
+package ultrasimple
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// RetentionPolicy thins out older backups instead of keeping every window
+// until RetentionDays. Backups newer than Hourly are all kept. Older ones
+// are compacted to the newest backup of each day, and those older than
+// Daily are deleted. A zero Daily keeps daily backups until RetentionDays,
+// which still applies on top of the policy.
+type RetentionPolicy struct {
+	Hourly time.Duration
+	Daily  time.Duration
+}
+
+// enabled reports whether the policy compacts anything
+func (p RetentionPolicy) enabled() bool {
+	return p.Hourly > 0
+}
+
+// compactBackups applies the RetentionPolicy, deleting per database all but
+// the newest backup window of each day older than Hourly, and every window
+// older than Daily. All objects of a window (base, increments, manifest,
+// pointer) share its timestamp, so windows are kept or deleted whole.
+func (r *Replicator) compactBackups(ctx context.Context) {
+	policy := r.s3Config.RetentionPolicy
+	if !policy.enabled() {
+		return
+	}
+	start := time.Now()
+	
+	if err := r.waitLimiter(ctx); err != nil {
+		return
+	}
+	keys, err := r.s3Client.List(ctx, "")
+	if err != nil {
+		slog.Error("failed to list s3 objects for compaction", "error", err)
+		atomic.AddInt64(&r.stats.CleanupErrors, 1)
+		return
+	}
+	
+	toDelete := compactKeys(keys, policy, start)
+	if len(toDelete) == 0 {
+		return
+	}
+	
+	deleted, err := r.deleteKeys(ctx, toDelete)
+	atomic.AddInt64(&r.stats.ObjectsDeleted, int64(deleted))
+	if err != nil {
+		atomic.AddInt64(&r.stats.CleanupErrors, 1)
+	}
+	
+	slog.Info("compaction complete",
+		"deleted", deleted,
+		"thinned", len(toDelete),
+		"duration", time.Since(start))
+}
+
+// compactKeys returns the keys a RetentionPolicy deletes as of now
+func compactKeys(keys []string, policy RetentionPolicy, now time.Time) []string {
+	hourlyCutoff := now.Add(-policy.Hourly)
+	var dailyCutoff time.Time
+	if policy.Daily > 0 {
+		dailyCutoff = now.Add(-policy.Daily)
+	}
+	
+	// Newest window of each day of each database
+	type day struct {
+		prefix string
+		date   string
+	}
+	type object struct {
+		key string
+		day day
+		ts  time.Time
+	}
+	var older []object
+	newest := make(map[day]time.Time)
+	for _, key := range keys {
+		if !isBackupObject(key) {
+			continue
+		}
+		prefix, ts, ok := splitBackupKey(key)
+		if !ok || !ts.Before(hourlyCutoff) {
+			continue
+		}
+		
+		obj := object{key: key, day: day{prefix, ts.Format("20060102")}, ts: ts}
+		older = append(older, obj)
+		if ts.After(newest[obj.day]) {
+			newest[obj.day] = ts
+		}
+	}
+	
+	var toDelete []string
+	for _, obj := range older {
+		if obj.ts.Before(newest[obj.day]) || (!dailyCutoff.IsZero() && obj.ts.Before(dailyCutoff)) {
+			toDelete = append(toDelete, obj.key)
+		}
+	}
+	return toDelete
+}
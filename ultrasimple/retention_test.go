@@ -0,0 +1,85 @@
+package ultrasimple
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplicatorCompactBackups(t *testing.T) {
+	s3Client := NewMockS3Client()
+	r := New("", S3Config{
+		PathTemplate:    "backups",
+		RetentionPolicy: RetentionPolicy{Hourly: 48 * time.Hour, Daily: 10 * 24 * time.Hour},
+	}, s3Client)
+	
+	// Hourly windows of two databases over the last two weeks, plus the
+	// increments and manifest of one old window
+	now := time.Now().Truncate(time.Hour)
+	key := func(db string, ts time.Time) string {
+		return "backups/" + db + "-" + ts.Format(backupTimestampFormat) + backupExtension + ".lz4"
+	}
+	for h := 0; h < 14*24; h++ {
+		ts := now.Add(-time.Duration(h) * time.Hour)
+		s3Client.Upload(context.Background(), key("app", ts), []byte("x"))
+		s3Client.Upload(context.Background(), key("app-2", ts), []byte("x"))
+	}
+	
+	// The last window of a compacted day is kept with its increments
+	fiveDaysAgo := now.AddDate(0, 0, -5)
+	kept := time.Date(fiveDaysAgo.Year(), fiveDaysAgo.Month(), fiveDaysAgo.Day(), 23, 0, 0, 0, time.Local)
+	dropped := kept.Add(-time.Hour)
+	for _, base := range []string{key("app", kept), key("app", dropped)} {
+		s3Client.Upload(context.Background(), incrementKey(base, 1), []byte("x"))
+		s3Client.Upload(context.Background(), manifestKey(base), []byte("x"))
+	}
+	
+	r.compactBackups(context.Background())
+	
+	uploads := s3Client.GetUploads()
+	perDay := make(map[string]int)
+	for k := range uploads {
+		prefix, ts, ok := splitBackupKey(k)
+		if !ok {
+			t.Fatalf("Unexpected key %s", k)
+		}
+		if ts.Before(now.Add(-10 * 24 * time.Hour)) {
+			t.Errorf("Expected %s older than Daily to be deleted", k)
+		}
+		if ts.Before(now.Add(-48*time.Hour)) && !strings.Contains(k, walIncrementExtension) && strings.HasSuffix(k, backupExtension+".lz4") {
+			perDay[prefix+ts.Format("20060102")]++
+		}
+	}
+	for day, n := range perDay {
+		if n != 1 {
+			t.Errorf("Expected 1 backup for %s, got %d", day, n)
+		}
+	}
+	
+	// Every window within Hourly is kept
+	for h := 0; h < 48; h++ {
+		if _, ok := uploads[key("app", now.Add(-time.Duration(h)*time.Hour))]; !ok {
+			t.Errorf("Expected hourly backup %dh ago to be kept", h)
+		}
+	}
+	
+	for _, k := range []string{key("app", kept), incrementKey(key("app", kept), 1), manifestKey(key("app", kept))} {
+		if _, ok := uploads[k]; !ok {
+			t.Errorf("Expected %s to be kept with its window", k)
+		}
+	}
+	for _, k := range []string{key("app", dropped), incrementKey(key("app", dropped), 1), manifestKey(key("app", dropped))} {
+		if _, ok := uploads[k]; ok {
+			t.Errorf("Expected %s to be deleted with its window", k)
+		}
+	}
+	
+	// A zero policy compacts nothing
+	count := s3Client.GetUploadCount()
+	r.s3Config.RetentionPolicy = RetentionPolicy{}
+	r.compactBackups(context.Background())
+	if n := s3Client.GetUploadCount(); n != count {
+		t.Errorf("Expected no deletions without a policy, got %d of %d objects left", n, count)
+	}
+}
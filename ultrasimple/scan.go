@@ -0,0 +1,138 @@
+package ultrasimple
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// discover streams the paths matched by all discovery patterns, each path
+// once. Each pattern is expanded up to its first wildcard component and the
+// resulting directories are globbed by a pool of ScanWorkers goroutines, so
+// deep trees are walked in parallel. A bad pattern is logged and skipped so
+// the others are still scanned. Excluded paths are dropped. The channel is
+// closed when discovery ends.
+func (r *Replicator) discover(ctx context.Context) <-chan string {
+	out := make(chan string, r.s3Config.ScanWorkers)
+	
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	emit := func(path string) bool {
+		path = filepath.Clean(path)
+		if r.excluded(path) {
+			return true
+		}
+		
+		mu.Lock()
+		dup := seen[path]
+		seen[path] = true
+		mu.Unlock()
+		if dup {
+			return true
+		}
+		
+		select {
+		case out <- path:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	
+	// Workers glob the remainder of a pattern below one top-level match
+	type job struct{ top, rest string }
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < r.s3Config.ScanWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				matches, err := filepath.Glob(filepath.Join(j.top, j.rest))
+				if err != nil {
+					slog.Error("glob failed", "pattern", filepath.Join(j.top, j.rest), "error", err)
+					continue
+				}
+				for _, path := range matches {
+					if !emit(path) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	
+	go func() {
+		defer close(out)
+		defer wg.Wait()
+		defer close(jobs)
+		
+		for _, pattern := range r.patterns {
+			top, rest := splitPattern(pattern)
+			
+			matches, err := filepath.Glob(top)
+			if err != nil {
+				slog.Error("glob failed", "pattern", pattern, "error", err)
+				continue
+			}
+			
+			for _, m := range matches {
+				if rest == "" {
+					if !emit(m) {
+						return
+					}
+					continue
+				}
+				
+				select {
+				case jobs <- job{top: m, rest: rest}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	
+	return out
+}
+
+// sqliteSidecarSuffixes are the files SQLite keeps next to a database
+var sqliteSidecarSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// excluded returns true if path is a SQLite sidecar file or matches one of
+// the ExcludePatterns
+func (r *Replicator) excluded(path string) bool {
+	for _, suffix := range sqliteSidecarSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range r.s3Config.ExcludePatterns {
+		name := path
+		if !strings.ContainsRune(pattern, filepath.Separator) {
+			name = filepath.Base(path)
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPattern splits a glob pattern after its first wildcard component.
+// Expanding top yields the directories to fan out over, and rest is the
+// pattern to glob below each of them. rest is empty when the wildcard is
+// in the last component or the pattern has none.
+func splitPattern(pattern string) (top, rest string) {
+	sep := string(filepath.Separator)
+	parts := strings.Split(pattern, sep)
+	
+	for i, part := range parts {
+		if strings.ContainsAny(part, `*?[\`) {
+			return strings.Join(parts[:i+1], sep), strings.Join(parts[i+1:], sep)
+		}
+	}
+	return pattern, ""
+}
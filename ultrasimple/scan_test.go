@@ -0,0 +1,143 @@
+package ultrasimple
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSplitPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, top, rest string
+	}{
+		{"/data/*/databases/*/tenants/*.db", "/data/*", "databases/*/tenants/*.db"},
+		{"/data/acme/*.db", "/data/acme/*.db", ""},
+		{"/data/acme/t1.db", "/data/acme/t1.db", ""},
+		{"data/[ab]*/x.db", "data/[ab]*", "x.db"},
+	} {
+		top, rest := splitPattern(tt.pattern)
+		if top != tt.top || rest != tt.rest {
+			t.Errorf("splitPattern(%q) = (%q, %q), want (%q, %q)", tt.pattern, top, rest, tt.top, tt.rest)
+		}
+	}
+}
+
+func TestReplicatorDiscover(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := createTestTree(t, tmpDir, 5, 4)
+	
+	pattern := filepath.Join(root, "*/databases/*/branches/*/tenants/*.db")
+	want, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	r := New(pattern, S3Config{ScanWorkers: 3}, NewMockS3Client())
+	
+	var got []string
+	for path := range r.discover(context.Background()) {
+		got = append(got, path)
+	}
+	sort.Strings(got)
+	
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("discover returned %d paths, glob returned %d", len(got), len(want))
+	}
+}
+
+// createTestTree creates projects*dbsPerProject empty database files in the
+// multi-tenant layout and returns the data root
+func createTestTree(tb testing.TB, dir string, projects, dbsPerProject int) string {
+	root := filepath.Join(dir, "data")
+	for p := 0; p < projects; p++ {
+		tenants := filepath.Join(root, fmt.Sprintf("project%d", p), "databases", "db", "branches", "main", "tenants")
+		if err := os.MkdirAll(tenants, 0755); err != nil {
+			tb.Fatal(err)
+		}
+		for i := 0; i < dbsPerProject; i++ {
+			if err := os.WriteFile(filepath.Join(tenants, fmt.Sprintf("t%d.db", i)), nil, 0644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+// BenchmarkDiscover compares a single filepath.Glob with the parallel
+// discovery over a synthetic 10K-database tree. first-ns/op is the time
+// until the first path is available to the upload stage. The gain in total
+// time depends on cores and filesystem latency; on a warm page cache with
+// one CPU the channel overhead can outweigh it.
+func BenchmarkDiscover(b *testing.B) {
+	root := createTestTree(b, b.TempDir(), 100, 100)
+	pattern := filepath.Join(root, "*/databases/*/branches/*/tenants/*.db")
+	
+	b.Run("Glob", func(b *testing.B) {
+		var first time.Duration
+		for i := 0; i < b.N; i++ {
+			start := time.Now()
+			matches, err := filepath.Glob(pattern)
+			if err != nil || len(matches) != 10000 {
+				b.Fatalf("matches=%d err=%v", len(matches), err)
+			}
+			first += time.Since(start)
+		}
+		b.ReportMetric(float64(first.Nanoseconds())/float64(b.N), "first-ns/op")
+	})
+	
+	b.Run("Parallel", func(b *testing.B) {
+		r := New(pattern, S3Config{}, NewMockS3Client())
+		var first time.Duration
+		for i := 0; i < b.N; i++ {
+			start := time.Now()
+			n := 0
+			for range r.discover(context.Background()) {
+				if n == 0 {
+					first += time.Since(start)
+				}
+				n++
+			}
+			if n != 10000 {
+				b.Fatalf("matches=%d", n)
+			}
+		}
+		b.ReportMetric(float64(first.Nanoseconds())/float64(b.N), "first-ns/op")
+	})
+}
+
+func TestReplicatorSpreadScans(t *testing.T) {
+	tmpDir := t.TempDir()
+	lastSlot := 0
+	for i := 0; i < 32; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("t%d.db", i))
+		createTestDB(t, path, "CREATE TABLE test (id INTEGER)")
+		lastSlot = max(lastSlot, spreadSlot(path))
+	}
+	
+	s3Client := NewMockS3Client()
+	r := New(filepath.Join(tmpDir, "*.db"), S3Config{PathTemplate: "backups", SpreadScans: true}, s3Client)
+	r.scanInterval = 320 * time.Millisecond
+	
+	// Each database waits for its part of the interval, but all are synced
+	start := time.Now()
+	r.scanAndSync(context.Background())
+	if elapsed, want := time.Since(start), time.Duration(lastSlot)*r.scanInterval/spreadSlots; elapsed < want {
+		t.Errorf("Expected the scan to take at least %v, took %v", want, elapsed)
+	}
+	if n := s3Client.GetUploadCount(); n != 32 {
+		t.Errorf("Expected 32 uploads, got %d", n)
+	}
+	
+	// Canceling stops waiting for later parts
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	r.scanAndSync(ctx)
+	if elapsed := time.Since(start); elapsed > r.scanInterval/2 {
+		t.Errorf("Expected a canceled scan to return promptly, took %v", elapsed)
+	}
+}
@@ -0,0 +1,118 @@
+package ultrasimple
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// StreamingS3Client is an S3Client that can upload from a reader, so large
+// databases never have to be held in memory. size is the length of the
+// stream, or -1 if unknown (as it is for compressed streams). tags are the
+// object tags the buffered path would send via TaggingS3Client.
+type StreamingS3Client interface {
+	S3Client
+	UploadStream(ctx context.Context, key string, r io.Reader, size int64, tags map[string]string) error
+}
+
+// StreamCompressor is a Compressor that can compress incrementally. Its
+// stream output must be readable by the same codec's Decompress.
+type StreamCompressor interface {
+	Compressor
+	CompressWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// canStream reports whether uploads can be piped from the file through the
+// compressor to the client. Encryption seals whole objects, so it always
+// uses the buffered path.
+func (r *Replicator) canStream() bool {
+	if _, ok := r.s3Client.(StreamingS3Client); !ok {
+		return false
+	}
+	if _, ok := r.s3Config.Compressor.(StreamCompressor); !ok {
+		return false
+	}
+	return r.s3Config.Encryptor == nil && r.s3Config.StorageClass == "" && !r.s3Config.Dedup
+}
+
+// uploadStream pipes src, a consistent copy of the database at path,
+// through the compressor into the client, keeping memory bounded per upload.
+// It returns the number of bytes uploaded and their hex MD5.
+func (r *Replicator) uploadStream(ctx context.Context, path, src, key string) (int64, string, error) {
+	defer r.observeStage(StageUpload, time.Now())
+	
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	
+	size := int64(-1)
+	if _, ok := r.s3Config.Compressor.(NoopCompressor); ok {
+		if info, err := f.Stat(); err == nil {
+			size = info.Size()
+		}
+	}
+	
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		
+		cw, err := r.s3Config.Compressor.(StreamCompressor).CompressWriter(pw)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(cw, f); err != nil {
+			cw.Close()
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(cw.Close())
+	}()
+	
+	h := md5.New()
+	counter := &countingWriter{}
+	body := io.TeeReader(pr, io.MultiWriter(h, counter))
+	
+	err = r.s3Client.(StreamingS3Client).UploadStream(ctx, key, body, size, r.objectTags(path))
+	
+	// Unblock the compressor if the client stopped reading early
+	pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	
+	if err != nil {
+		return 0, "", err
+	}
+	return counter.n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// fileChecksum returns the CRC-32 of a file without reading it into memory
+func fileChecksum(path string) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, f); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}